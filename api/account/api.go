@@ -1,19 +1,31 @@
 package account
 
 import (
+	"encoding/json"
 	"errors"
+	"net"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
+	events_api "github.com/cloudtrust/keycloak-bridge/api/events"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	kc "github.com/cloudtrust/keycloak-client"
 )
 
 // AccountRepresentation struct
 type AccountRepresentation struct {
-	Username    *string `json:"username,omitempty"`
-	Email       *string `json:"email,omitempty"`
-	FirstName   *string `json:"firstName,omitempty"`
-	LastName    *string `json:"lastName,omitempty"`
-	PhoneNumber *string `json:"phoneNumber,omitempty"`
+	Username           *string              `json:"username,omitempty"`
+	Email              *string              `json:"email,omitempty"`
+	FirstName          *string              `json:"firstName,omitempty"`
+	LastName           *string              `json:"lastName,omitempty"`
+	PhoneNumber        *string              `json:"phoneNumber,omitempty"`
+	PendingEmail       *string              `json:"pendingEmail,omitempty"`
+	PendingPhoneNumber *string              `json:"pendingPhoneNumber,omitempty"`
+	Attributes         *map[string][]string `json:"attributes,omitempty"`
+	DeletedAttributes  *[]string            `json:"deletedAttributes,omitempty"`
 }
 
 // CredentialRepresentation struct
@@ -29,10 +41,54 @@ type CredentialRepresentation struct {
 
 // Configuration struct
 type Configuration struct {
-	ShowAuthenticatorsTab     *bool `json:"show_authenticators_tab"`
-	ShowPasswordTab           *bool `json:"show_password_tab"`
-	ShowMailEditing           *bool `json:"show_mail_editing"`
-	ShowAccountDeletionButton *bool `json:"show_account_deletion_button"`
+	ShowAuthenticatorsTab      *bool `json:"show_authenticators_tab"`
+	ShowPasswordTab            *bool `json:"show_password_tab"`
+	ShowMailEditing            *bool `json:"show_mail_editing"`
+	ShowAccountDeletionButton  *bool `json:"show_account_deletion_button"`
+	AccountDeletionGracePeriod *int  `json:"account_deletion_grace_period,omitempty"`
+}
+
+// PersonalDataRepresentation is the export of the data held about the current user
+type PersonalDataRepresentation struct {
+	ExportedAt  int64                            `json:"exportedAt"`
+	Account     AccountRepresentation            `json:"account"`
+	Credentials []CredentialRepresentation       `json:"credentials"`
+	Events      []events_api.AuditRepresentation `json:"events"`
+}
+
+// ActivityRepresentation is an event of the activity of the current user
+type ActivityRepresentation struct {
+	Time      int64  `json:"time"`
+	Type      string `json:"type"`
+	ClientID  string `json:"clientId,omitempty"`
+	IPAddress string `json:"ipAddress,omitempty"`
+}
+
+// SessionRepresentation is an active Keycloak session of the current user
+type SessionRepresentation struct {
+	ID         *string   `json:"id,omitempty"`
+	IPAddress  *string   `json:"ipAddress,omitempty"`
+	Start      *int64    `json:"start,omitempty"`
+	LastAccess *int64    `json:"lastAccess,omitempty"`
+	Clients    *[]string `json:"clients,omitempty"`
+	Current    bool      `json:"current"`
+}
+
+// OTPEnrollmentRepresentation is the OTP secret generated for the current user. The QR payload is the otpauth URI read by
+// the OTP applications, the other fields allow to register the secret manually.
+type OTPEnrollmentRepresentation struct {
+	Secret    string `json:"secret"`
+	QRPayload string `json:"qrPayload"`
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Period    int    `json:"period"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// OTPVerificationBody is the definition of the expected body content of the verification of an OTP enrollment
+type OTPVerificationBody struct {
+	Code      string  `json:"code"`
+	UserLabel *string `json:"userLabel,omitempty"`
 }
 
 // UpdatePasswordBody is the definition of the expected body content of UpdatePassword method
@@ -42,6 +98,11 @@ type UpdatePasswordBody struct {
 	ConfirmPassword string `json:"confirmPassword"`
 }
 
+// ConfirmationCodeBody is the definition of the expected body content of the confirmations of email and phone number changes
+type ConfirmationCodeBody struct {
+	Code string `json:"code"`
+}
+
 // LabelBody struct
 type LabelBody struct {
 	Label string `json:"label,omitempty"`
@@ -60,6 +121,60 @@ func ConvertCredential(credKc *kc.CredentialRepresentation) CredentialRepresenta
 	return cred
 }
 
+// ConvertToAPISession creates an API session from a KC session
+func ConvertToAPISession(sessionKc kc.UserSessionRepresentation, currentSessionID string) SessionRepresentation {
+	var session = SessionRepresentation{
+		ID:         sessionKc.Id,
+		IPAddress:  sessionKc.IpAddress,
+		Start:      sessionKc.Start,
+		LastAccess: sessionKc.LastAccess,
+		Current:    sessionKc.Id != nil && *sessionKc.Id == currentSessionID,
+	}
+	if sessionKc.Clients != nil {
+		// Keycloak maps the internal IDs of the clients to their client IDs, only the client IDs are exposed
+		var clients = []string{}
+		for _, clientID := range *sessionKc.Clients {
+			clients = append(clients, clientID)
+		}
+		sort.Strings(clients)
+		session.Clients = &clients
+	}
+	return session
+}
+
+// ConvertToActivity creates an activity from an audit event. Only the details that the user may see are kept and the
+// IP address is partially masked.
+func ConvertToActivity(event events_api.AuditRepresentation) ActivityRepresentation {
+	var activity = ActivityRepresentation{
+		Time:     event.AuditTime,
+		Type:     event.CtEventType,
+		ClientID: event.ClientID,
+	}
+	var details map[string]string
+	if json.Unmarshal([]byte(event.AdditionalInfo), &details) == nil {
+		activity.IPAddress = MaskIPAddress(details["ip_address"])
+	}
+	return activity
+}
+
+// MaskIPAddress hides the end of an IP address: the last two bytes of an IPv4 address, all but the first three groups of
+// an IPv6 address. Values which are not IP addresses are dropped.
+func MaskIPAddress(value string) string {
+	var ip = net.ParseIP(value)
+	if ip == nil {
+		return ""
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		var bytes = strings.Split(ipv4.String(), ".")
+		return bytes[0] + "." + bytes[1] + ".*.*"
+	}
+	var groups []string
+	for i := 0; i < 6; i += 2 {
+		groups = append(groups, strconv.FormatUint(uint64(ip[i])<<8|uint64(ip[i+1]), 16))
+	}
+	return strings.Join(groups, ":") + ":*"
+}
+
 // ConvertToAPIAccount creates an API account representation from  a KC user representation
 func ConvertToAPIAccount(userKc kc.UserRepresentation) AccountRepresentation {
 	var userRep AccountRepresentation
@@ -76,6 +191,16 @@ func ConvertToAPIAccount(userKc kc.UserRepresentation) AccountRepresentation {
 			var phoneNumber = m["phoneNumber"][0]
 			userRep.PhoneNumber = &phoneNumber
 		}
+
+		var customAttributes = make(map[string][]string)
+		for key, values := range m {
+			if !internal.IsReservedUserAttribute(key) {
+				customAttributes[key] = values
+			}
+		}
+		if len(customAttributes) > 0 {
+			userRep.Attributes = &customAttributes
+		}
 	}
 	return userRep
 }
@@ -126,9 +251,43 @@ func (user AccountRepresentation) Validate() error {
 		return errors.New("Invalid phone number")
 	}
 
+	if user.Attributes != nil {
+		for name, values := range *user.Attributes {
+			if !matchesRegExp(name, RegExpAttributeName) || internal.IsReservedUserAttribute(name) {
+				return errors.New("Invalid attribute")
+			}
+			for _, value := range values {
+				if !matchesRegExp(value, RegExpAttributeValue) {
+					return errors.New("Invalid attribute")
+				}
+			}
+		}
+	}
+
+	if user.DeletedAttributes != nil {
+		for _, name := range *user.DeletedAttributes {
+			if !matchesRegExp(name, RegExpAttributeName) || internal.IsReservedUserAttribute(name) {
+				return errors.New("Invalid deleted attribute")
+			}
+		}
+	}
+
 	return nil
 }
 
+// ValidateAttributes checks the custom attributes of the account against the attribute schema of the realm
+func (user AccountRepresentation) ValidateAttributes(schema []dto.AttributeDefinition) error {
+	var attributes map[string][]string
+	if user.Attributes != nil {
+		attributes = *user.Attributes
+	}
+	var deleted []string
+	if user.DeletedAttributes != nil {
+		deleted = *user.DeletedAttributes
+	}
+	return internal.ValidateUserAttributes(schema, internal.AttributeEditorSelfService, attributes, deleted, false)
+}
+
 // Validate is a validator for UpdatePasswordBody
 func (updatePwd UpdatePasswordBody) Validate() error {
 	if !matchesRegExp(updatePwd.CurrentPassword, RegExpPassword) {
@@ -146,6 +305,28 @@ func (updatePwd UpdatePasswordBody) Validate() error {
 	return nil
 }
 
+// Validate is a validator for ConfirmationCodeBody
+func (body ConfirmationCodeBody) Validate() error {
+	if !matchesRegExp(body.Code, RegExpCode) {
+		return errors.New("Invalid code")
+	}
+
+	return nil
+}
+
+// Validate is a validator for OTPVerificationBody
+func (body OTPVerificationBody) Validate() error {
+	if !matchesRegExp(body.Code, RegExpOTPCode) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.Code)
+	}
+
+	if body.UserLabel != nil && !matchesRegExp(*body.UserLabel, RegExpLabel) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.Label)
+	}
+
+	return nil
+}
+
 // Validate is a validator for CredentialRepresentation
 func (credential CredentialRepresentation) Validate() error {
 	if credential.ID != nil && !matchesRegExp(*credential.ID, RegExpID) {
@@ -177,10 +358,22 @@ const (
 
 	// Password
 	RegExpPassword = `^.{1,255}$`
+	// Confirmation code
+	RegExpCode = `^[a-zA-Z0-9_-]{1,128}$`
+	// Code of an OTP application
+	RegExpOTPCode = `^[0-9]{6,8}$`
+	// Format of the personal data export
+	RegExpExportFormat = `^(json|zip)$`
+	// Paging of the activity
+	RegExpFirst = `^\d{1,9}$`
+	RegExpMax   = `^([1-9]\d?|100)$`
 	// User
 	RegExpUsername    = `^[a-zA-Z0-9-_.]{1,128}$`
 	RegExpEmail       = `^.+\@.+\..+`
 	RegExpFirstName   = `^.{1,128}$`
 	RegExpLastName    = `^.{1,128}$`
-	RegExpPhoneNumber = `^\+[1-9]\d{1,14}$`
+	RegExpPhoneNumber = `^\+?[0-9 ()./-]{4,30}$`
+	// Custom attributes
+	RegExpAttributeName  = `^[a-zA-Z0-9_-]{1,128}$`
+	RegExpAttributeValue = `^.{0,255}$`
 )
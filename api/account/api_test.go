@@ -1,8 +1,10 @@
 package account
 
 import (
+	"strings"
 	"testing"
 
+	events_api "github.com/cloudtrust/keycloak-bridge/api/events"
 	kc "github.com/cloudtrust/keycloak-client"
 	"github.com/stretchr/testify/assert"
 )
@@ -37,6 +39,16 @@ func TestConvertToAPIAccount(t *testing.T) {
 	attributes["phoneNumber"] = []string{"+41221234567"}
 	kcUser = kc.UserRepresentation{Attributes: &attributes}
 	assert.Equal(t, "+41221234567", *ConvertToAPIAccount(kcUser).PhoneNumber)
+	assert.Nil(t, ConvertToAPIAccount(kcUser).Attributes)
+
+	attributes["department"] = []string{"IT"}
+	assert.Equal(t, map[string][]string{"department": {"IT"}}, *ConvertToAPIAccount(kcUser).Attributes)
+
+	// the pending changes are not read from the attributes, which the users can write
+	attributes["emailToValidate"] = []string{"new@mail.com"}
+	var account = ConvertToAPIAccount(kcUser)
+	assert.Nil(t, account.PendingEmail)
+	assert.Equal(t, map[string][]string{"department": {"IT"}, "emailToValidate": {"new@mail.com"}}, *account.Attributes)
 }
 
 func TestConvertToKCUser(t *testing.T) {
@@ -56,7 +68,7 @@ func TestValidateAccountRepresentation(t *testing.T) {
 	var invalidPhone = "+412212345AB"
 	var accounts []AccountRepresentation
 
-	for i := 0; i < 5; i++ {
+	for i := 0; i < 7; i++ {
 		accounts = append(accounts, createValidAccountRepresentation())
 	}
 
@@ -67,6 +79,8 @@ func TestValidateAccountRepresentation(t *testing.T) {
 	accounts[2].LastName = &invalidName
 	accounts[3].Email = &invalidEmail
 	accounts[4].PhoneNumber = &invalidPhone
+	accounts[5].Attributes = &map[string][]string{"phoneNumberVerified": {"true"}}
+	accounts[6].DeletedAttributes = &[]string{"invalid name"}
 
 	for _, account := range accounts {
 		assert.NotNil(t, account.Validate())
@@ -101,6 +115,23 @@ func TestValidateUpdatePasswordRepresentation(t *testing.T) {
 
 }
 
+func TestValidateConfirmationCodeBody(t *testing.T) {
+	assert.Nil(t, ConfirmationCodeBody{Code: "123456"}.Validate())
+	assert.Nil(t, ConfirmationCodeBody{Code: "dGhpcyBpcyBhIGxpbmsgdG9rZW4-_"}.Validate())
+	assert.NotNil(t, ConfirmationCodeBody{Code: ""}.Validate())
+	assert.NotNil(t, ConfirmationCodeBody{Code: "12 34"}.Validate())
+}
+
+func TestValidateOTPVerificationBody(t *testing.T) {
+	var label = "My phone"
+	var invalidLabel = strings.Repeat("a", 256)
+	assert.Nil(t, OTPVerificationBody{Code: "123456"}.Validate())
+	assert.Nil(t, OTPVerificationBody{Code: "12345678", UserLabel: &label}.Validate())
+	assert.NotNil(t, OTPVerificationBody{Code: "12345"}.Validate())
+	assert.NotNil(t, OTPVerificationBody{Code: "12345a"}.Validate())
+	assert.NotNil(t, OTPVerificationBody{Code: "123456", UserLabel: &invalidLabel}.Validate())
+}
+
 func TestValidateCredentialRepresentation(t *testing.T) {
 	{
 		credential := createValidCredentialRepresentation()
@@ -168,3 +199,24 @@ func createValidCredentialRepresentation() CredentialRepresentation {
 		UserLabel:      &userLabel,
 	}
 }
+
+func TestConvertToActivity(t *testing.T) {
+	var event = events_api.AuditRepresentation{
+		AuditTime:      1234,
+		CtEventType:    "LOGON_OK",
+		ClientID:       "account",
+		AgentUserID:    "1234-789",
+		AdditionalInfo: `{"ip_address":"192.168.10.23","session_id":"abcd"}`,
+	}
+	assert.Equal(t, ActivityRepresentation{Time: 1234, Type: "LOGON_OK", ClientID: "account", IPAddress: "192.168.*.*"}, ConvertToActivity(event))
+
+	event.AdditionalInfo = ""
+	assert.Equal(t, "", ConvertToActivity(event).IPAddress)
+}
+
+func TestMaskIPAddress(t *testing.T) {
+	assert.Equal(t, "10.0.*.*", MaskIPAddress("10.0.12.1"))
+	assert.Equal(t, "2001:db8:0:*", MaskIPAddress("2001:db8::8a2e:370:7334"))
+	assert.Equal(t, "", MaskIPAddress(""))
+	assert.Equal(t, "", MaskIPAddress("not an IP"))
+}
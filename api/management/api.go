@@ -1,32 +1,40 @@
 package management_api
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
 	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	kc "github.com/cloudtrust/keycloak-client"
 )
 
 // UserRepresentation struct
 type UserRepresentation struct {
-	ID                  *string   `json:"id,omitempty"`
-	Username            *string   `json:"username,omitempty"`
-	Email               *string   `json:"email,omitempty"`
-	Enabled             *bool     `json:"enabled,omitempty"`
-	EmailVerified       *bool     `json:"emailVerified,omitempty"`
-	PhoneNumberVerified *bool     `json:"phoneNumberVerified,omitempty"`
-	FirstName           *string   `json:"firstName,omitempty"`
-	LastName            *string   `json:"lastName,omitempty"`
-	PhoneNumber         *string   `json:"phoneNumber,omitempty"`
-	Label               *string   `json:"label,omitempty"`
-	Gender              *string   `json:"gender,omitempty"`
-	BirthDate           *string   `json:"birthDate,omitempty"`
-	CreatedTimestamp    *int64    `json:"createdTimestamp,omitempty"`
-	Groups              *[]string `json:"groups,omitempty"`
-	Roles               *[]string `json:"roles,omitempty"`
-	Locale              *string   `json:"locale,omitempty"`
+	ID                  *string              `json:"id,omitempty"`
+	Username            *string              `json:"username,omitempty"`
+	Email               *string              `json:"email,omitempty"`
+	Enabled             *bool                `json:"enabled,omitempty"`
+	EmailVerified       *bool                `json:"emailVerified,omitempty"`
+	PhoneNumberVerified *bool                `json:"phoneNumberVerified,omitempty"`
+	FirstName           *string              `json:"firstName,omitempty"`
+	LastName            *string              `json:"lastName,omitempty"`
+	PhoneNumber         *string              `json:"phoneNumber,omitempty"`
+	Label               *string              `json:"label,omitempty"`
+	Gender              *string              `json:"gender,omitempty"`
+	BirthDate           *string              `json:"birthDate,omitempty"`
+	CreatedTimestamp    *int64               `json:"createdTimestamp,omitempty"`
+	Groups              *[]string            `json:"groups,omitempty"`
+	Roles               *[]string            `json:"roles,omitempty"`
+	Locale              *string              `json:"locale,omitempty"`
+	Attributes          *map[string][]string `json:"attributes,omitempty"`
+	DeletedAttributes   *[]string            `json:"deletedAttributes,omitempty"`
 }
 
 // UsersPageRepresentation used to manage paging in GetUsers
@@ -44,20 +52,69 @@ type RealmRepresentation struct {
 	Enabled         *bool   `json:"enabled,omitempty"`
 }
 
+// RealmSettingsRepresentation struct
+type RealmSettingsRepresentation struct {
+	PasswordPolicy      *string                            `json:"passwordPolicy,omitempty"`
+	OTPPolicy           *OTPPolicyRepresentation           `json:"otpPolicy,omitempty"`
+	BruteForceDetection *BruteForceDetectionRepresentation `json:"bruteForceDetection,omitempty"`
+	SMTPFrom            *string                            `json:"smtpFrom,omitempty"`
+	LoginTheme          *string                            `json:"loginTheme,omitempty"`
+	SupportedLocales    *[]string                          `json:"supportedLocales,omitempty"`
+}
+
+// OTPPolicyRepresentation struct
+type OTPPolicyRepresentation struct {
+	Type            *string `json:"type,omitempty"`
+	Algorithm       *string `json:"algorithm,omitempty"`
+	Digits          *int32  `json:"digits,omitempty"`
+	Period          *int32  `json:"period,omitempty"`
+	LookAheadWindow *int32  `json:"lookAheadWindow,omitempty"`
+}
+
+// BruteForceDetectionRepresentation struct
+type BruteForceDetectionRepresentation struct {
+	Enabled               *bool  `json:"enabled,omitempty"`
+	PermanentLockout      *bool  `json:"permanentLockout,omitempty"`
+	MaxLoginFailures      *int32 `json:"maxLoginFailures,omitempty"`
+	WaitIncrementSeconds  *int32 `json:"waitIncrementSeconds,omitempty"`
+	MaxFailureWaitSeconds *int32 `json:"maxFailureWaitSeconds,omitempty"`
+	FailureResetSeconds   *int32 `json:"failureResetSeconds,omitempty"`
+}
+
+// maxBruteForceDelay is the longest delay (one day) which can be configured for the brute force detection
+const maxBruteForceDelay int32 = 86400
+
 // ClientRepresentation struct
 type ClientRepresentation struct {
-	ID       *string `json:"id,omitempty"`
-	Name     *string `json:"name,omitempty"`
-	BaseURL  *string `json:"baseUrl,omitempty"`
-	ClientID *string `json:"clientId,omitempty"`
-	Protocol *string `json:"protocol,omitempty"`
-	Enabled  *bool   `json:"enabled,omitempty"`
+	ID           *string   `json:"id,omitempty"`
+	Name         *string   `json:"name,omitempty"`
+	Description  *string   `json:"description,omitempty"`
+	BaseURL      *string   `json:"baseUrl,omitempty"`
+	ClientID     *string   `json:"clientId,omitempty"`
+	Protocol     *string   `json:"protocol,omitempty"`
+	Enabled      *bool     `json:"enabled,omitempty"`
+	AccessType   *string   `json:"accessType,omitempty"`
+	RedirectURIs *[]string `json:"redirectUris,omitempty"`
+	WebOrigins   *[]string `json:"webOrigins,omitempty"`
 }
 
+// ClientSecretRepresentation struct
+type ClientSecretRepresentation struct {
+	Value *string `json:"value,omitempty"`
+}
+
+// Access types of the clients
+const (
+	AccessTypePublic       = "public"
+	AccessTypeConfidential = "confidential"
+	AccessTypeBearerOnly   = "bearer-only"
+)
+
 // CredentialRepresentation struct
 type CredentialRepresentation struct {
 	ID             *string `json:"id,omitempty"`
 	Type           *string `json:"type,omitempty"`
+	Category       *string `json:"category,omitempty"`
 	UserLabel      *string `json:"userLabel,omitempty"`
 	CreatedDate    *int64  `json:"createdDate,omitempty"`
 	CredentialData *string `json:"credentialData,omitempty"`
@@ -88,26 +145,146 @@ type PasswordRepresentation struct {
 
 // RealmCustomConfiguration struct
 type RealmCustomConfiguration struct {
-	DefaultClientID                     *string `json:"default_client_id"`
-	DefaultRedirectURI                  *string `json:"default_redirect_uri"`
-	APISelfAuthenticatorDeletionEnabled *bool   `json:"api_self_authenticator_deletion_enabled"`
-	APISelfPasswordChangeEnabled        *bool   `json:"api_self_password_change_enabled"`
-	APISelfMailEditingEnabled           *bool   `json:"api_self_mail_editing_enabled"`
-	APISelfAccountDeletionEnabled       *bool   `json:"api_self_account_deletion_enabled"`
-	ShowAuthenticatorsTab               *bool   `json:"show_authenticators_tab"`
-	ShowPasswordTab                     *bool   `json:"show_password_tab"`
-	ShowMailEditing                     *bool   `json:"show_mail_editing"`
-	ShowAccountDeletionButton           *bool   `json:"show_account_deletion_button"`
+	DefaultClientID                       *string                `json:"default_client_id"`
+	DefaultRedirectURI                    *string                `json:"default_redirect_uri"`
+	APISelfAuthenticatorDeletionEnabled   *bool                  `json:"api_self_authenticator_deletion_enabled"`
+	APISelfPasswordChangeEnabled          *bool                  `json:"api_self_password_change_enabled"`
+	APISelfMailEditingEnabled             *bool                  `json:"api_self_mail_editing_enabled"`
+	APISelfAccountDeletionEnabled         *bool                  `json:"api_self_account_deletion_enabled"`
+	APISelfActivityLogEnabled             *bool                  `json:"api_self_activity_log_enabled"`
+	APISelfSessionsEnabled                *bool                  `json:"api_self_sessions_enabled"`
+	APISelfOTPEnrollmentEnabled           *bool                  `json:"api_self_otp_enrollment_enabled"`
+	APISelfAuthenticatorListingEnabled    *bool                  `json:"api_self_authenticator_listing_enabled"`
+	APISelfAuthenticatorLabellingEnabled  *bool                  `json:"api_self_authenticator_labelling_enabled"`
+	APISelfAuthenticatorReorderingEnabled *bool                  `json:"api_self_authenticator_reordering_enabled"`
+	APISelfPhoneNumberEditingEnabled      *bool                  `json:"api_self_phone_number_editing_enabled"`
+	APISelfNameEditingEnabled             *bool                  `json:"api_self_name_editing_enabled"`
+	ShowAuthenticatorsTab                 *bool                  `json:"show_authenticators_tab"`
+	ShowPasswordTab                       *bool                  `json:"show_password_tab"`
+	ShowMailEditing                       *bool                  `json:"show_mail_editing"`
+	ShowAccountDeletionButton             *bool                  `json:"show_account_deletion_button"`
+	UserAttributes                        *[]AttributeDefinition `json:"user_attributes"`
+	PasswordPolicy                        *string                `json:"password_policy"`
+	BreachedPasswordCheck                 *bool                  `json:"breached_password_check"`
+	MFARequired                           *bool                  `json:"mfa_required"`
+	DuplicateCheck                        *[]string              `json:"duplicate_check"`
+	AllowedPhoneNumberCountries           *[]string              `json:"allowed_phone_number_countries"`
+	AccountDeletionGracePeriod            *int                   `json:"account_deletion_grace_period"`
+}
+
+// MaxAccountDeletionGracePeriod is the longest grace period, in days, before the deletion of an account requested by its user
+const MaxAccountDeletionGracePeriod = 365
+
+// Criteria of the detection of duplicate users which can be enabled in the configuration of a realm
+const (
+	DuplicateCheckEmail       = "email"
+	DuplicateCheckPhoneNumber = "phoneNumber"
+	DuplicateCheckIdentity    = "identity"
+)
+
+// RealmCustomConfigurationVersion struct
+type RealmCustomConfigurationVersion struct {
+	Version       int                      `json:"version"`
+	Author        *string                  `json:"author,omitempty"`
+	Comment       *string                  `json:"comment,omitempty"`
+	Time          *int64                   `json:"time,omitempty"`
+	Configuration RealmCustomConfiguration `json:"configuration"`
+}
+
+// AttributeDefinition struct
+type AttributeDefinition struct {
+	Name                  *string `json:"name"`
+	Type                  *string `json:"type"`
+	RegExp                *string `json:"regexp,omitempty"`
+	Required              *bool   `json:"required"`
+	EditableBySelfService *bool   `json:"editable_by_self_service"`
+	EditableByBackOffice  *bool   `json:"editable_by_back_office"`
+}
+
+// UserSessionRepresentation struct
+type UserSessionRepresentation struct {
+	ID         *string   `json:"id,omitempty"`
+	Username   *string   `json:"username,omitempty"`
+	UserID     *string   `json:"userId,omitempty"`
+	IPAddress  *string   `json:"ipAddress,omitempty"`
+	Start      *int64    `json:"start,omitempty"`
+	LastAccess *int64    `json:"lastAccess,omitempty"`
+	Clients    *[]string `json:"clients,omitempty"`
+}
+
+// UserStatusRepresentation struct
+type UserStatusRepresentation struct {
+	Enabled             *bool            `json:"enabled,omitempty"`
+	UserEnabled         *bool            `json:"userEnabled,omitempty"`
+	EmailVerified       *bool            `json:"emailVerified,omitempty"`
+	PhoneNumberVerified *bool            `json:"phoneNumberVerified,omitempty"`
+	MFAConfigured       *bool            `json:"mfaConfigured,omitempty"`
+	Credentials         *map[string]bool `json:"credentials,omitempty"`
+	RequiredActions     *[]string        `json:"requiredActions,omitempty"`
+	TemporarilyLocked   *bool            `json:"temporarilyLocked,omitempty"`
+	LastLogin           *int64           `json:"lastLogin,omitempty"`
+	PasswordAge         *int64           `json:"passwordAge,omitempty"`
+}
+
+// UserChangeRepresentation struct
+type UserChangeRepresentation struct {
+	Time           *int64                      `json:"time,omitempty"`
+	Origin         *string                     `json:"origin,omitempty"`
+	AgentUsername  *string                     `json:"agentUsername,omitempty"`
+	AgentRealmName *string                     `json:"agentRealmName,omitempty"`
+	Changes        []FieldChangeRepresentation `json:"changes"`
+}
+
+// FieldChangeRepresentation struct
+type FieldChangeRepresentation struct {
+	Field    string  `json:"field"`
+	OldValue *string `json:"oldValue,omitempty"`
+	NewValue *string `json:"newValue,omitempty"`
 }
 
 // RequiredAction type
 type RequiredAction string
 
+// BulkUserActionRepresentation struct
+type BulkUserActionRepresentation struct {
+	Action          *string           `json:"action,omitempty"`
+	UserIDs         *[]string         `json:"userIds,omitempty"`
+	GroupIDs        *[]string         `json:"groupIds,omitempty"`
+	Search          *string           `json:"search,omitempty"`
+	RequiredActions *[]RequiredAction `json:"requiredActions,omitempty"`
+}
+
+// BulkUserActionResultRepresentation struct
+type BulkUserActionResultRepresentation struct {
+	UserID *string `json:"userId,omitempty"`
+	Status *string `json:"status,omitempty"`
+	Error  *string `json:"error,omitempty"`
+}
+
+// Actions which can be applied to several users at once
+const (
+	BulkActionLock                = "lock"
+	BulkActionUnlock              = "unlock"
+	BulkActionDelete              = "delete"
+	BulkActionExecuteActionsEmail = "executeActionsEmail"
+	BulkActionSendReminderEmail   = "sendReminderEmail"
+)
+
+// Outcomes of a bulk action for a given user
+const (
+	BulkStatusSuccess = "success"
+	BulkStatusFailure = "failure"
+)
+
 // ConvertCredential creates an API credential from a KC credential
 func ConvertCredential(credKc *kc.CredentialRepresentation) CredentialRepresentation {
 	var cred CredentialRepresentation
 	cred.ID = credKc.Id
 	cred.Type = credKc.Type
+	if credKc.Type != nil {
+		var category = internal.GetCredentialCategory(*credKc.Type)
+		cred.Category = &category
+	}
 	cred.UserLabel = credKc.UserLabel
 	cred.CreatedDate = credKc.CreatedDate
 	cred.CredentialData = credKc.CredentialData
@@ -162,10 +339,164 @@ func ConvertToAPIUser(userKc kc.UserRepresentation) UserRepresentation {
 			var locale = m["locale"][0]
 			userRep.Locale = &locale
 		}
+
+		var customAttributes = make(map[string][]string)
+		for key, values := range m {
+			if !internal.IsReservedUserAttribute(key) {
+				customAttributes[key] = values
+			}
+		}
+		if len(customAttributes) > 0 {
+			userRep.Attributes = &customAttributes
+		}
 	}
 	return userRep
 }
 
+// ConvertToAPIRealmSettings extracts the API realm settings from a KC realm
+func ConvertToAPIRealmSettings(realmKc kc.RealmRepresentation) RealmSettingsRepresentation {
+	var settings RealmSettingsRepresentation
+
+	settings.PasswordPolicy = realmKc.PasswordPolicy
+	settings.OTPPolicy = &OTPPolicyRepresentation{
+		Type:            realmKc.OtpPolicyType,
+		Algorithm:       realmKc.OtpPolicyAlgorithm,
+		Digits:          realmKc.OtpPolicyDigits,
+		Period:          realmKc.OtpPolicyPeriod,
+		LookAheadWindow: realmKc.OtpPolicyLookAheadWindow,
+	}
+	settings.BruteForceDetection = &BruteForceDetectionRepresentation{
+		Enabled:               realmKc.BruteForceProtected,
+		PermanentLockout:      realmKc.PermanentLockout,
+		MaxLoginFailures:      realmKc.FailureFactor,
+		WaitIncrementSeconds:  realmKc.WaitIncrementSeconds,
+		MaxFailureWaitSeconds: realmKc.MaxFailureWaitSeconds,
+		FailureResetSeconds:   realmKc.MaxDeltaTimeSeconds,
+	}
+	if realmKc.SmtpServer != nil {
+		if from, ok := (*realmKc.SmtpServer)["from"]; ok {
+			settings.SMTPFrom = &from
+		}
+	}
+	settings.LoginTheme = realmKc.LoginTheme
+	settings.SupportedLocales = realmKc.SupportedLocales
+
+	return settings
+}
+
+// ConvertToKCRealmSettings creates a KC realm containing only the provided settings. The SMTP server configuration
+// is replaced as a whole by Keycloak, the current one is thus needed to change the from address.
+func ConvertToKCRealmSettings(settings RealmSettingsRepresentation, currentSMTPServer *map[string]string) kc.RealmRepresentation {
+	var realmKc kc.RealmRepresentation
+
+	realmKc.PasswordPolicy = settings.PasswordPolicy
+	if settings.OTPPolicy != nil {
+		realmKc.OtpPolicyType = settings.OTPPolicy.Type
+		realmKc.OtpPolicyAlgorithm = settings.OTPPolicy.Algorithm
+		realmKc.OtpPolicyDigits = settings.OTPPolicy.Digits
+		realmKc.OtpPolicyPeriod = settings.OTPPolicy.Period
+		realmKc.OtpPolicyLookAheadWindow = settings.OTPPolicy.LookAheadWindow
+	}
+	if settings.BruteForceDetection != nil {
+		realmKc.BruteForceProtected = settings.BruteForceDetection.Enabled
+		realmKc.PermanentLockout = settings.BruteForceDetection.PermanentLockout
+		realmKc.FailureFactor = settings.BruteForceDetection.MaxLoginFailures
+		realmKc.WaitIncrementSeconds = settings.BruteForceDetection.WaitIncrementSeconds
+		realmKc.MaxFailureWaitSeconds = settings.BruteForceDetection.MaxFailureWaitSeconds
+		realmKc.MaxDeltaTimeSeconds = settings.BruteForceDetection.FailureResetSeconds
+	}
+	if settings.SMTPFrom != nil {
+		var smtpServer = make(map[string]string)
+		if currentSMTPServer != nil {
+			for key, value := range *currentSMTPServer {
+				smtpServer[key] = value
+			}
+		}
+		smtpServer["from"] = *settings.SMTPFrom
+		realmKc.SmtpServer = &smtpServer
+	}
+	realmKc.LoginTheme = settings.LoginTheme
+	if settings.SupportedLocales != nil {
+		var internationalizationEnabled = len(*settings.SupportedLocales) > 0
+		realmKc.SupportedLocales = settings.SupportedLocales
+		realmKc.InternationalizationEnabled = &internationalizationEnabled
+	}
+
+	return realmKc
+}
+
+// ConvertToAPIClient creates an API client from a KC client
+func ConvertToAPIClient(clientKc kc.ClientRepresentation) ClientRepresentation {
+	var clientRep ClientRepresentation
+
+	clientRep.ID = clientKc.Id
+	clientRep.Name = clientKc.Name
+	clientRep.Description = clientKc.Description
+	clientRep.BaseURL = clientKc.BaseUrl
+	clientRep.ClientID = clientKc.ClientId
+	clientRep.Protocol = clientKc.Protocol
+	clientRep.Enabled = clientKc.Enabled
+	clientRep.RedirectURIs = clientKc.RedirectUris
+	clientRep.WebOrigins = clientKc.WebOrigins
+
+	var accessType = AccessTypeConfidential
+	if clientKc.BearerOnly != nil && *clientKc.BearerOnly {
+		accessType = AccessTypeBearerOnly
+	} else if clientKc.PublicClient != nil && *clientKc.PublicClient {
+		accessType = AccessTypePublic
+	}
+	clientRep.AccessType = &accessType
+
+	return clientRep
+}
+
+// ConvertToKCClient creates a KC client from an API client
+func ConvertToKCClient(client ClientRepresentation) kc.ClientRepresentation {
+	var clientKc kc.ClientRepresentation
+
+	clientKc.Name = client.Name
+	clientKc.Description = client.Description
+	clientKc.BaseUrl = client.BaseURL
+	clientKc.ClientId = client.ClientID
+	clientKc.Protocol = client.Protocol
+	clientKc.Enabled = client.Enabled
+	clientKc.RedirectUris = client.RedirectURIs
+	clientKc.WebOrigins = client.WebOrigins
+
+	if client.AccessType != nil {
+		var publicClient = *client.AccessType == AccessTypePublic
+		var bearerOnly = *client.AccessType == AccessTypeBearerOnly
+		clientKc.PublicClient = &publicClient
+		clientKc.BearerOnly = &bearerOnly
+	}
+
+	return clientKc
+}
+
+// ConvertToAPIUserSession creates an API user session representation from a KC user session representation
+func ConvertToAPIUserSession(sessionKc kc.UserSessionRepresentation) UserSessionRepresentation {
+	var sessionRep UserSessionRepresentation
+
+	sessionRep.ID = sessionKc.Id
+	sessionRep.Username = sessionKc.Username
+	sessionRep.UserID = sessionKc.UserId
+	sessionRep.IPAddress = sessionKc.IpAddress
+	sessionRep.Start = sessionKc.Start
+	sessionRep.LastAccess = sessionKc.LastAccess
+
+	if sessionKc.Clients != nil {
+		// Keycloak maps the internal IDs of the clients to their client IDs, only the client IDs are exposed
+		var clients = []string{}
+		for _, clientID := range *sessionKc.Clients {
+			clients = append(clients, clientID)
+		}
+		sort.Strings(clients)
+		sessionRep.Clients = &clients
+	}
+
+	return sessionRep
+}
+
 // ConvertToAPIUsersPage converts paged users results from KC model to API one
 func ConvertToAPIUsersPage(users kc.UsersPageRepresentation) UsersPageRepresentation {
 	var slice = []UserRepresentation{}
@@ -184,6 +515,21 @@ func ConvertToAPIUsersPage(users kc.UsersPageRepresentation) UsersPageRepresenta
 	}
 }
 
+// ComputeETag computes the entity tag of a user representation. It is sent in the ETag header of GetUser and must be sent
+// back in the If-Match header when updating the user.
+func ComputeETag(user UserRepresentation) string {
+	var userJSON, _ = json.Marshal(user)
+	var hash = sha256.Sum256(userJSON)
+
+	return `"` + hex.EncodeToString(hash[:]) + `"`
+}
+
+// MatchesETag checks if the value of an If-Match header is the given entity tag. The wildcard and lists of entity tags
+// are rejected: the client must send the version it read.
+func MatchesETag(ifMatch string, etag string) bool {
+	return strings.TrimSpace(ifMatch) == etag
+}
+
 // ConvertToKCUser creates a KC user representation from an API user
 func ConvertToKCUser(user UserRepresentation) kc.UserRepresentation {
 	var userRep kc.UserRepresentation
@@ -199,6 +545,12 @@ func ConvertToKCUser(user UserRepresentation) kc.UserRepresentation {
 
 	var attributes = make(map[string][]string)
 
+	if user.Attributes != nil {
+		for key, values := range *user.Attributes {
+			attributes[key] = values
+		}
+	}
+
 	if user.PhoneNumber != nil {
 		attributes["phoneNumber"] = []string{*user.PhoneNumber}
 	}
@@ -290,6 +642,105 @@ func (user UserRepresentation) Validate() error {
 		return errors.New(internal.MsgErrInvalidParam + "." + internal.Locale)
 	}
 
+	if user.Attributes != nil {
+		for name, values := range *user.Attributes {
+			if !matchesRegExp(name, RegExpAttributeName) || internal.IsReservedUserAttribute(name) {
+				return errors.New(internal.MsgErrInvalidParam + "." + internal.Attributes + "." + name)
+			}
+			for _, value := range values {
+				if !matchesRegExp(value, RegExpAttributeValue) {
+					return errors.New(internal.MsgErrInvalidParam + "." + internal.Attributes + "." + name)
+				}
+			}
+		}
+	}
+
+	if user.DeletedAttributes != nil {
+		for _, name := range *user.DeletedAttributes {
+			if !matchesRegExp(name, RegExpAttributeName) || internal.IsReservedUserAttribute(name) {
+				return errors.New(internal.MsgErrInvalidParam + "." + internal.DeletedAttributes + "." + name)
+			}
+			if user.Attributes != nil {
+				if _, ok := (*user.Attributes)[name]; ok {
+					return errors.New(internal.MsgErrInvalidParam + "." + internal.DeletedAttributes + "." + name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateAttributes checks the custom attributes of the user against the attribute schema of the realm
+func (user UserRepresentation) ValidateAttributes(schema []dto.AttributeDefinition, creation bool) error {
+	var attributes map[string][]string
+	if user.Attributes != nil {
+		attributes = *user.Attributes
+	}
+	var deleted []string
+	if user.DeletedAttributes != nil {
+		deleted = *user.DeletedAttributes
+	}
+	return internal.ValidateUserAttributes(schema, internal.AttributeEditorBackOffice, attributes, deleted, creation)
+}
+
+// ClientURLScheme returns the lowercased custom scheme of a client URL, or an empty string for relative and http(s) URLs
+func ClientURLScheme(clientURL string) string {
+	if matchesRegExp(clientURL, RegExpClientURL) {
+		return ""
+	}
+	if idx := strings.Index(clientURL, ":"); idx > 0 {
+		return strings.ToLower(clientURL[:idx])
+	}
+	return ""
+}
+
+func isClientURL(clientURL string) bool {
+	return matchesRegExp(clientURL, RegExpClientURL) || matchesRegExp(clientURL, RegExpClientAppURL)
+}
+
+// Validate is a validator for ClientRepresentation
+func (client ClientRepresentation) Validate() error {
+	if client.ClientID != nil && !matchesRegExp(*client.ClientID, RegExpClientID) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.ClientID)
+	}
+
+	if client.Name != nil && !matchesRegExp(*client.Name, RegExpClientName) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.Name)
+	}
+
+	if client.Description != nil && !matchesRegExp(*client.Description, RegExpDescription) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.Description)
+	}
+
+	if client.BaseURL != nil && !isClientURL(*client.BaseURL) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.BaseURL)
+	}
+
+	if client.Protocol != nil && !matchesRegExp(*client.Protocol, RegExpProtocol) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.Protocol)
+	}
+
+	if client.AccessType != nil && !matchesRegExp(*client.AccessType, RegExpAccessType) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.AccessType)
+	}
+
+	if client.RedirectURIs != nil {
+		for _, redirectURI := range *client.RedirectURIs {
+			if !isClientURL(redirectURI) {
+				return errors.New(internal.MsgErrInvalidParam + "." + internal.RedirectURIs)
+			}
+		}
+	}
+
+	if client.WebOrigins != nil {
+		for _, webOrigin := range *client.WebOrigins {
+			if !matchesRegExp(webOrigin, RegExpWebOrigin) {
+				return errors.New(internal.MsgErrInvalidParam + "." + internal.WebOrigins)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -333,6 +784,287 @@ func (config RealmCustomConfiguration) Validate() error {
 		return errors.New(internal.MsgErrInvalidParam + "." + internal.DefaultRedirectURI)
 	}
 
+	if config.UserAttributes != nil {
+		var names = make(map[string]bool)
+		for _, attribute := range *config.UserAttributes {
+			if err := attribute.Validate(); err != nil {
+				return err
+			}
+			if names[*attribute.Name] {
+				return errors.New(internal.MsgErrInvalidParam + "." + internal.UserAttributes + "." + *attribute.Name)
+			}
+			names[*attribute.Name] = true
+		}
+	}
+
+	if config.PasswordPolicy != nil && *config.PasswordPolicy != "" {
+		if _, err := internal.ParsePasswordPolicy(*config.PasswordPolicy); err != nil {
+			return err
+		}
+	}
+
+	if config.DuplicateCheck != nil {
+		for _, criterion := range *config.DuplicateCheck {
+			switch criterion {
+			case DuplicateCheckEmail, DuplicateCheckPhoneNumber, DuplicateCheckIdentity:
+			default:
+				return errors.New(internal.MsgErrInvalidParam + "." + internal.DuplicateCheck)
+			}
+		}
+	}
+
+	if config.AllowedPhoneNumberCountries != nil {
+		for _, country := range *config.AllowedPhoneNumberCountries {
+			if !internal.IsValidPhoneNumberCountry(country) {
+				return errors.New(internal.MsgErrInvalidParam + "." + internal.AllowedPhoneNumberCountries)
+			}
+		}
+	}
+
+	if config.AccountDeletionGracePeriod != nil && (*config.AccountDeletionGracePeriod < 0 || *config.AccountDeletionGracePeriod > MaxAccountDeletionGracePeriod) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.AccountDeletionGracePeriod)
+	}
+
+	return nil
+}
+
+// Validate is a validator for RealmSettingsRepresentation
+func (settings RealmSettingsRepresentation) Validate() error {
+	if settings.PasswordPolicy != nil && *settings.PasswordPolicy != "" {
+		if _, err := internal.ParsePasswordPolicy(*settings.PasswordPolicy); err != nil {
+			return err
+		}
+	}
+
+	if settings.OTPPolicy != nil {
+		var otpPolicy = settings.OTPPolicy
+		if otpPolicy.Type != nil && !matchesRegExp(*otpPolicy.Type, RegExpOTPType) {
+			return errors.New(internal.MsgErrInvalidParam + "." + internal.OTPPolicy + ".type")
+		}
+		if otpPolicy.Algorithm != nil && !matchesRegExp(*otpPolicy.Algorithm, RegExpOTPAlgorithm) {
+			return errors.New(internal.MsgErrInvalidParam + "." + internal.OTPPolicy + ".algorithm")
+		}
+		if otpPolicy.Digits != nil && *otpPolicy.Digits != 6 && *otpPolicy.Digits != 8 {
+			return errors.New(internal.MsgErrInvalidParam + "." + internal.OTPPolicy + ".digits")
+		}
+		if !isInRange(otpPolicy.Period, 1, 120) {
+			return errors.New(internal.MsgErrInvalidParam + "." + internal.OTPPolicy + ".period")
+		}
+		if !isInRange(otpPolicy.LookAheadWindow, 0, 10) {
+			return errors.New(internal.MsgErrInvalidParam + "." + internal.OTPPolicy + ".lookAheadWindow")
+		}
+	}
+
+	if settings.BruteForceDetection != nil {
+		var bruteForce = settings.BruteForceDetection
+		if !isInRange(bruteForce.MaxLoginFailures, 1, 100) {
+			return errors.New(internal.MsgErrInvalidParam + "." + internal.BruteForceDetection + ".maxLoginFailures")
+		}
+		if !isInRange(bruteForce.WaitIncrementSeconds, 0, maxBruteForceDelay) {
+			return errors.New(internal.MsgErrInvalidParam + "." + internal.BruteForceDetection + ".waitIncrementSeconds")
+		}
+		if !isInRange(bruteForce.MaxFailureWaitSeconds, 0, maxBruteForceDelay) {
+			return errors.New(internal.MsgErrInvalidParam + "." + internal.BruteForceDetection + ".maxFailureWaitSeconds")
+		}
+		if !isInRange(bruteForce.FailureResetSeconds, 0, maxBruteForceDelay) {
+			return errors.New(internal.MsgErrInvalidParam + "." + internal.BruteForceDetection + ".failureResetSeconds")
+		}
+	}
+
+	if settings.SMTPFrom != nil && !matchesRegExp(*settings.SMTPFrom, RegExpEmail) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.SMTPFrom)
+	}
+
+	if settings.LoginTheme != nil && !matchesRegExp(*settings.LoginTheme, RegExpTheme) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.LoginTheme)
+	}
+
+	if settings.SupportedLocales != nil {
+		for _, locale := range *settings.SupportedLocales {
+			if !matchesRegExp(locale, RegExpLocale) {
+				return errors.New(internal.MsgErrInvalidParam + "." + internal.SupportedLocales)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isInRange(value *int32, min, max int32) bool {
+	return value == nil || (*value >= min && *value <= max)
+}
+
+// Validate is a validator for AttributeDefinition
+func (attribute AttributeDefinition) Validate() error {
+	if attribute.Name == nil || !matchesRegExp(*attribute.Name, RegExpAttributeName) || internal.IsReservedUserAttribute(*attribute.Name) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.UserAttributes + "." + internal.Name)
+	}
+
+	if attribute.Type != nil && !internal.IsValidAttributeType(*attribute.Type) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.UserAttributes + "." + internal.Type)
+	}
+
+	if attribute.RegExp != nil {
+		if _, err := regexp.Compile(*attribute.RegExp); err != nil {
+			return errors.New(internal.MsgErrInvalidParam + "." + internal.UserAttributes + "." + internal.RegExp)
+		}
+	}
+
+	return nil
+}
+
+// ConvertToDTOAttributeDefinitions converts attribute definitions from the API model to the DTO one
+func ConvertToDTOAttributeDefinitions(attributes []AttributeDefinition) []dto.AttributeDefinition {
+	var res = []dto.AttributeDefinition{}
+	for _, attribute := range attributes {
+		res = append(res, dto.AttributeDefinition(attribute))
+	}
+	return res
+}
+
+// ConvertToAPIAttributeDefinitions converts attribute definitions from the DTO model to the API one
+func ConvertToAPIAttributeDefinitions(attributes []dto.AttributeDefinition) []AttributeDefinition {
+	var res = []AttributeDefinition{}
+	for _, attribute := range attributes {
+		res = append(res, AttributeDefinition(attribute))
+	}
+	return res
+}
+
+// ConvertToAPIRealmCustomConfiguration converts a realm configuration from the DTO model to the API one
+func ConvertToAPIRealmCustomConfiguration(config dto.RealmConfiguration) RealmCustomConfiguration {
+	var userAttributes = []AttributeDefinition{}
+	if config.UserAttributes != nil {
+		userAttributes = ConvertToAPIAttributeDefinitions(*config.UserAttributes)
+	}
+
+	return RealmCustomConfiguration{
+		DefaultClientID:                       config.DefaultClientID,
+		DefaultRedirectURI:                    config.DefaultRedirectURI,
+		APISelfAuthenticatorDeletionEnabled:   config.APISelfAuthenticatorDeletionEnabled,
+		APISelfPasswordChangeEnabled:          config.APISelfPasswordChangeEnabled,
+		APISelfMailEditingEnabled:             config.APISelfMailEditingEnabled,
+		APISelfAccountDeletionEnabled:         config.APISelfAccountDeletionEnabled,
+		APISelfActivityLogEnabled:             config.APISelfActivityLogEnabled,
+		APISelfSessionsEnabled:                config.APISelfSessionsEnabled,
+		APISelfOTPEnrollmentEnabled:           config.APISelfOTPEnrollmentEnabled,
+		APISelfAuthenticatorListingEnabled:    config.APISelfAuthenticatorListingEnabled,
+		APISelfAuthenticatorLabellingEnabled:  config.APISelfAuthenticatorLabellingEnabled,
+		APISelfAuthenticatorReorderingEnabled: config.APISelfAuthenticatorReorderingEnabled,
+		APISelfPhoneNumberEditingEnabled:      config.APISelfPhoneNumberEditingEnabled,
+		APISelfNameEditingEnabled:             config.APISelfNameEditingEnabled,
+		ShowAuthenticatorsTab:                 config.ShowAuthenticatorsTab,
+		ShowPasswordTab:                       config.ShowPasswordTab,
+		ShowMailEditing:                       config.ShowMailEditing,
+		ShowAccountDeletionButton:             config.ShowAccountDeletionButton,
+		UserAttributes:                        &userAttributes,
+		PasswordPolicy:                        config.PasswordPolicy,
+		BreachedPasswordCheck:                 config.BreachedPasswordCheck,
+		MFARequired:                           config.MFARequired,
+		DuplicateCheck:                        config.DuplicateCheck,
+		AllowedPhoneNumberCountries:           config.AllowedPhoneNumberCountries,
+		AccountDeletionGracePeriod:            config.AccountDeletionGracePeriod,
+	}
+}
+
+// ConvertToDTORealmConfiguration converts a realm configuration from the API model to the DTO one
+func ConvertToDTORealmConfiguration(customConfig RealmCustomConfiguration) dto.RealmConfiguration {
+	var config = dto.RealmConfiguration{
+		DefaultClientID:                       customConfig.DefaultClientID,
+		DefaultRedirectURI:                    customConfig.DefaultRedirectURI,
+		APISelfAuthenticatorDeletionEnabled:   customConfig.APISelfAuthenticatorDeletionEnabled,
+		APISelfPasswordChangeEnabled:          customConfig.APISelfPasswordChangeEnabled,
+		APISelfMailEditingEnabled:             customConfig.APISelfMailEditingEnabled,
+		APISelfAccountDeletionEnabled:         customConfig.APISelfAccountDeletionEnabled,
+		APISelfActivityLogEnabled:             customConfig.APISelfActivityLogEnabled,
+		APISelfSessionsEnabled:                customConfig.APISelfSessionsEnabled,
+		APISelfOTPEnrollmentEnabled:           customConfig.APISelfOTPEnrollmentEnabled,
+		APISelfAuthenticatorListingEnabled:    customConfig.APISelfAuthenticatorListingEnabled,
+		APISelfAuthenticatorLabellingEnabled:  customConfig.APISelfAuthenticatorLabellingEnabled,
+		APISelfAuthenticatorReorderingEnabled: customConfig.APISelfAuthenticatorReorderingEnabled,
+		APISelfPhoneNumberEditingEnabled:      customConfig.APISelfPhoneNumberEditingEnabled,
+		APISelfNameEditingEnabled:             customConfig.APISelfNameEditingEnabled,
+		ShowAuthenticatorsTab:                 customConfig.ShowAuthenticatorsTab,
+		ShowPasswordTab:                       customConfig.ShowPasswordTab,
+		ShowMailEditing:                       customConfig.ShowMailEditing,
+		ShowAccountDeletionButton:             customConfig.ShowAccountDeletionButton,
+		PasswordPolicy:                        customConfig.PasswordPolicy,
+		BreachedPasswordCheck:                 customConfig.BreachedPasswordCheck,
+		MFARequired:                           customConfig.MFARequired,
+		DuplicateCheck:                        customConfig.DuplicateCheck,
+		AllowedPhoneNumberCountries:           customConfig.AllowedPhoneNumberCountries,
+		AccountDeletionGracePeriod:            customConfig.AccountDeletionGracePeriod,
+	}
+	if customConfig.UserAttributes != nil {
+		var userAttributes = ConvertToDTOAttributeDefinitions(*customConfig.UserAttributes)
+		config.UserAttributes = &userAttributes
+	}
+	return config
+}
+
+// ConvertToAPIRealmCustomConfigurationVersion converts a stored version of a realm configuration from the DTO model to the API one
+func ConvertToAPIRealmCustomConfigurationVersion(version dto.RealmConfigurationVersion) RealmCustomConfigurationVersion {
+	var res = RealmCustomConfigurationVersion{
+		Version:       version.Version,
+		Time:          &version.Time,
+		Configuration: ConvertToAPIRealmCustomConfiguration(version.Configuration),
+	}
+	if version.Author != "" {
+		res.Author = &version.Author
+	}
+	if version.Comment != "" {
+		res.Comment = &version.Comment
+	}
+	return res
+}
+
+// Validate is a validator for BulkUserActionRepresentation
+func (bulk BulkUserActionRepresentation) Validate() error {
+	if bulk.Action == nil || !matchesRegExp(*bulk.Action, RegExpBulkAction) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.BulkAction)
+	}
+
+	if (bulk.UserIDs == nil || len(*bulk.UserIDs) == 0) && (bulk.GroupIDs == nil || len(*bulk.GroupIDs) == 0) {
+		return errors.New(internal.MsgErrMissingParam + "." + internal.UserIDs + "Or" + internal.GroudIDs)
+	}
+
+	// the users are either given or selected with a filter: a filter sent with explicit IDs would be ignored
+	if bulk.UserIDs != nil && len(*bulk.UserIDs) > 0 && (bulk.GroupIDs != nil || bulk.Search != nil) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.UserIDs + "Or" + internal.GroudIDs)
+	}
+
+	if bulk.UserIDs != nil {
+		for _, userID := range *bulk.UserIDs {
+			if !matchesRegExp(userID, RegExpID) {
+				return errors.New(internal.MsgErrInvalidParam + "." + internal.UserID)
+			}
+		}
+	}
+
+	if bulk.GroupIDs != nil {
+		for _, groupID := range *bulk.GroupIDs {
+			if !matchesRegExp(groupID, RegExpID) {
+				return errors.New(internal.MsgErrInvalidParam + "." + internal.GroudID)
+			}
+		}
+	}
+
+	if bulk.Search != nil && !matchesRegExp(*bulk.Search, RegExpSearch) {
+		return errors.New(internal.MsgErrInvalidParam + "." + internal.Search)
+	}
+
+	if *bulk.Action == BulkActionExecuteActionsEmail && (bulk.RequiredActions == nil || len(*bulk.RequiredActions) == 0) {
+		return errors.New(internal.MsgErrMissingParam + "." + internal.RequiredAction)
+	}
+
+	if bulk.RequiredActions != nil {
+		for _, requiredAction := range *bulk.RequiredActions {
+			if err := requiredAction.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -355,19 +1087,29 @@ const (
 	RegExpID = `^[a-z0-9]{8}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{12}$`
 
 	// Client
-	RegExpClientID = `^[a-zA-Z0-9-_.]{1,255}$`
+	RegExpClientID     = `^[a-zA-Z0-9-_.]{1,255}$`
+	RegExpClientName   = `^.{1,255}$`
+	RegExpClientURL    = `^(\/[^\s]*|https?:\/\/[^\s]+)$`
+	RegExpClientAppURL = `^[a-zA-Z][a-zA-Z0-9+.-]*:(\/?\/?)[^\s]+$`
+	RegExpWebOrigin    = `^(\+|\*|https?:\/\/[^\s\/]+)$`
+	RegExpProtocol     = `^(openid-connect|saml)$`
+	RegExpAccessType   = `^(public|confidential|bearer-only)$`
 
 	// User
 	RegExpUsername    = `^[a-zA-Z0-9-_.]{1,128}$`
 	RegExpEmail       = `^.+\@.+\..+`
 	RegExpFirstName   = `^.{1,128}$`
 	RegExpLastName    = `^.{1,128}$`
-	RegExpPhoneNumber = `^\+[1-9]\d{1,14}$`
+	RegExpPhoneNumber = `^\+?[0-9 ()./-]{4,30}$`
 	RegExpLabel       = `^.{1,255}$`
 	RegExpGender      = `^[MF]$`
 	RegExpBirthDate   = `^(\d{4}-(0[1-9]|1[0-2])-(0[1-9]|[12]\d|3[01]))$`
 	RegExpLocale      = `^[a-z]{2}$`
 
+	// Custom attributes
+	RegExpAttributeName  = `^[a-zA-Z0-9_-]{1,128}$`
+	RegExpAttributeValue = `^.{0,255}$`
+
 	// Role
 	RegExpName        = `^[a-zA-Z0-9-_]{1,128}$`
 	RegExpDescription = `^.{1,255}$`
@@ -375,16 +1117,30 @@ const (
 	// Password
 	RegExpPassword = `^.{1,255}$`
 
+	// RealmSettings
+	RegExpOTPType      = `^(totp|hotp)$`
+	RegExpOTPAlgorithm = `^(HmacSHA1|HmacSHA256|HmacSHA512)$`
+	RegExpTheme        = `^[a-zA-Z0-9-_.]{1,64}$`
+
 	// RealmCustomConfiguration
 	RegExpRedirectURI = `^\w+:(\/?\/?)[^\s]+$`
 
 	// RequiredAction
 	RegExpRequiredAction = `^[a-zA-Z0-9-_]{1,255}$`
 
+	// Credentials
+	RegExpCredentialCategory = `^(otp|webauthn|sms)$`
+
+	// BulkUserAction
+	RegExpBulkAction = `^(lock|unlock|delete|executeActionsEmail|sendReminderEmail)$`
+
 	// Others
 	RegExpRealmName = `^[a-zA-Z0-9_-]{1,36}$`
 	RegExpSearch    = `^.{1,128}$`
 	RegExpLifespan  = `^[0-9]{1,10}$`
 	RegExpGroupIds  = `^([a-z0-9]{8}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{12})(,[a-z0-9]{8}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{12}){0,20}$`
 	RegExpNumber    = `^\d+$`
+	RegExpBool      = `^(true|false)$`
+	RegExpUserSort  = `^-?(username|firstName|lastName|email|birthDate|createdTimestamp)$`
+	RegExpComment   = `^.{1,255}$`
 )
@@ -21,13 +21,197 @@ func TestConvertCredential(t *testing.T) {
 
 	assert.Equal(t, credKc.Type, ConvertCredential(&credKc).Type)
 	assert.Equal(t, credKc.Id, ConvertCredential(&credKc).ID)
+	assert.Equal(t, "password", *ConvertCredential(&credKc).Category)
 	assert.Nil(t, ConvertCredential(&credKc).CredentialData)
 
+	var otpType = "totp"
+	credKc.Type = &otpType
+	assert.Equal(t, "otp", *ConvertCredential(&credKc).Category)
+	credKc.Type = nil
+	assert.Nil(t, ConvertCredential(&credKc).Category)
+	credKc.Type = &credType
+
 	credKc.CredentialData = &configKc
 	assert.NotNil(t, ConvertCredential(&credKc).CredentialData)
 	assert.Equal(t, "{}", *ConvertCredential(&credKc).CredentialData)
 }
 
+func TestConvertToKCRealmSettings(t *testing.T) {
+	var smtpFrom = "bridge@example.com"
+	var locales = []string{"en", "de"}
+	var noLocales = []string{}
+	var digits = int32(8)
+	var maxFailures = int32(3)
+
+	t.Run("Empty settings", func(t *testing.T) {
+		assert.Equal(t, kc.RealmRepresentation{}, ConvertToKCRealmSettings(RealmSettingsRepresentation{}, nil))
+	})
+
+	t.Run("All settings", func(t *testing.T) {
+		var settings = RealmSettingsRepresentation{
+			OTPPolicy:           &OTPPolicyRepresentation{Digits: &digits},
+			BruteForceDetection: &BruteForceDetectionRepresentation{MaxLoginFailures: &maxFailures},
+			SMTPFrom:            &smtpFrom,
+			SupportedLocales:    &locales,
+		}
+		var realmKc = ConvertToKCRealmSettings(settings, nil)
+		assert.Equal(t, digits, *realmKc.OtpPolicyDigits)
+		assert.Equal(t, maxFailures, *realmKc.FailureFactor)
+		assert.Equal(t, map[string]string{"from": smtpFrom}, *realmKc.SmtpServer)
+		assert.Equal(t, locales, *realmKc.SupportedLocales)
+		assert.True(t, *realmKc.InternationalizationEnabled)
+	})
+
+	t.Run("Removing the supported locales disables the internationalization", func(t *testing.T) {
+		var realmKc = ConvertToKCRealmSettings(RealmSettingsRepresentation{SupportedLocales: &noLocales}, nil)
+		assert.False(t, *realmKc.InternationalizationEnabled)
+	})
+}
+
+func TestValidateRealmSettingsRepresentation(t *testing.T) {
+	var passwordPolicy = "length(8) and digits(1)"
+	var otpType = "totp"
+	var algorithm = "HmacSHA256"
+	var digits = int32(6)
+	var period = int32(30)
+	var enabled = true
+	var maxFailures = int32(5)
+	var wait = int32(60)
+	var smtpFrom = "noreply@example.com"
+	var theme = "cloudtrust"
+	var locales = []string{"en", "fr"}
+	var settings = RealmSettingsRepresentation{
+		PasswordPolicy:      &passwordPolicy,
+		OTPPolicy:           &OTPPolicyRepresentation{Type: &otpType, Algorithm: &algorithm, Digits: &digits, Period: &period},
+		BruteForceDetection: &BruteForceDetectionRepresentation{Enabled: &enabled, MaxLoginFailures: &maxFailures, WaitIncrementSeconds: &wait},
+		SMTPFrom:            &smtpFrom,
+		LoginTheme:          &theme,
+		SupportedLocales:    &locales,
+	}
+	assert.Nil(t, settings.Validate())
+	assert.Nil(t, RealmSettingsRepresentation{}.Validate())
+
+	var invalidPolicy = "length(10) and maxLength(5)"
+	var invalidType = "sms"
+	var invalidAlgorithm = "MD5"
+	var invalidDigits = int32(7)
+	var invalidPeriod = int32(0)
+	var invalidMaxFailures = int32(0)
+	var invalidWait = int32(-1)
+	var invalidFrom = "noreply"
+	var invalidTheme = "my theme"
+	var invalidLocales = []string{"en", "french"}
+	var invalids = []RealmSettingsRepresentation{
+		{PasswordPolicy: &invalidPolicy},
+		{OTPPolicy: &OTPPolicyRepresentation{Type: &invalidType}},
+		{OTPPolicy: &OTPPolicyRepresentation{Algorithm: &invalidAlgorithm}},
+		{OTPPolicy: &OTPPolicyRepresentation{Digits: &invalidDigits}},
+		{OTPPolicy: &OTPPolicyRepresentation{Period: &invalidPeriod}},
+		{BruteForceDetection: &BruteForceDetectionRepresentation{MaxLoginFailures: &invalidMaxFailures}},
+		{BruteForceDetection: &BruteForceDetectionRepresentation{MaxFailureWaitSeconds: &invalidWait}},
+		{SMTPFrom: &invalidFrom},
+		{LoginTheme: &invalidTheme},
+		{SupportedLocales: &invalidLocales},
+	}
+	for idx, invalid := range invalids {
+		assert.NotNil(t, invalid.Validate(), "Check %d should fail", idx)
+	}
+}
+
+func TestConvertToAPIClient(t *testing.T) {
+	var id = "1234-5678"
+	var clientID = "my-app"
+	var redirectURIs = []string{"https://my-app.com/*"}
+	var trueBool = true
+	var clientKc = kc.ClientRepresentation{
+		Id:           &id,
+		ClientId:     &clientID,
+		RedirectUris: &redirectURIs,
+	}
+
+	var client = ConvertToAPIClient(clientKc)
+	assert.Equal(t, id, *client.ID)
+	assert.Equal(t, clientID, *client.ClientID)
+	assert.Equal(t, redirectURIs, *client.RedirectURIs)
+	assert.Equal(t, AccessTypeConfidential, *client.AccessType)
+
+	clientKc.PublicClient = &trueBool
+	assert.Equal(t, AccessTypePublic, *ConvertToAPIClient(clientKc).AccessType)
+
+	clientKc.BearerOnly = &trueBool
+	assert.Equal(t, AccessTypeBearerOnly, *ConvertToAPIClient(clientKc).AccessType)
+}
+
+func TestConvertToKCClient(t *testing.T) {
+	var clientID = "my-app"
+	var webOrigins = []string{"+"}
+	var client = ClientRepresentation{
+		ClientID:   &clientID,
+		WebOrigins: &webOrigins,
+	}
+
+	var clientKc = ConvertToKCClient(client)
+	assert.Equal(t, clientID, *clientKc.ClientId)
+	assert.Equal(t, webOrigins, *clientKc.WebOrigins)
+	assert.Nil(t, clientKc.PublicClient)
+	assert.Nil(t, clientKc.BearerOnly)
+
+	for accessType, expected := range map[string][]bool{AccessTypePublic: {true, false}, AccessTypeConfidential: {false, false}, AccessTypeBearerOnly: {false, true}} {
+		var value = accessType
+		client.AccessType = &value
+		clientKc = ConvertToKCClient(client)
+		assert.Equal(t, expected[0], *clientKc.PublicClient, accessType)
+		assert.Equal(t, expected[1], *clientKc.BearerOnly, accessType)
+	}
+}
+
+func TestValidateClientRepresentation(t *testing.T) {
+	var clientID = "my-app"
+	var name = "My application"
+	var baseURL = "/my-app"
+	var protocol = "openid-connect"
+	var accessType = "confidential"
+	var redirectURIs = []string{"https://my-app.com/*", "/relative/*", "com.my-app:/callback"}
+	var webOrigins = []string{"+", "*", "https://my-app.com:8443"}
+	var client = ClientRepresentation{
+		ClientID:     &clientID,
+		Name:         &name,
+		BaseURL:      &baseURL,
+		Protocol:     &protocol,
+		AccessType:   &accessType,
+		RedirectURIs: &redirectURIs,
+		WebOrigins:   &webOrigins,
+	}
+	assert.Nil(t, client.Validate())
+	assert.Nil(t, ClientRepresentation{}.Validate())
+
+	var invalidClientID = "my app"
+	var invalidBaseURL = "my-app"
+	var invalidProtocol = "cas"
+	var invalidAccessType = "private"
+	var invalidRedirectURIs = []string{"https://my-app.com/*", "my app"}
+	var invalidWebOrigins = []string{"https://my-app.com/path"}
+	var invalids = []ClientRepresentation{
+		{ClientID: &invalidClientID},
+		{BaseURL: &invalidBaseURL},
+		{Protocol: &invalidProtocol},
+		{AccessType: &invalidAccessType},
+		{RedirectURIs: &invalidRedirectURIs},
+		{WebOrigins: &invalidWebOrigins},
+	}
+	for idx, invalid := range invalids {
+		assert.NotNil(t, invalid.Validate(), "Check %d should fail", idx)
+	}
+}
+
+func TestClientURLScheme(t *testing.T) {
+	assert.Equal(t, "", ClientURLScheme("/relative/*"))
+	assert.Equal(t, "", ClientURLScheme("https://my-app.com/*"))
+	assert.Equal(t, "", ClientURLScheme("http://localhost:8080/callback"))
+	assert.Equal(t, "com.my-app", ClientURLScheme("com.My-App:/callback"))
+	assert.Equal(t, "javascript", ClientURLScheme("javascript:alert(1)"))
+}
+
 func TestConvertToAPIUser(t *testing.T) {
 	var kcUser kc.UserRepresentation
 	m := make(map[string][]string)
@@ -67,6 +251,22 @@ func TestConvertToAPIUser(t *testing.T) {
 	kcUser.Attributes = &m
 	m["locale"] = []string{"en"}
 	assert.NotNil(t, *ConvertToAPIUser(kcUser).Locale)
+
+	// Custom attributes
+	assert.Nil(t, ConvertToAPIUser(kcUser).Attributes)
+	m["department"] = []string{"IT"}
+	assert.Equal(t, map[string][]string{"department": {"IT"}}, *ConvertToAPIUser(kcUser).Attributes)
+}
+
+func TestConvertToAPIUserSession(t *testing.T) {
+	var sessionID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"
+	var kcSession = kc.UserSessionRepresentation{Id: &sessionID}
+
+	assert.Equal(t, sessionID, *ConvertToAPIUserSession(kcSession).ID)
+	assert.Nil(t, ConvertToAPIUserSession(kcSession).Clients)
+
+	kcSession.Clients = &map[string]string{"1": "clientB", "2": "clientA"}
+	assert.Equal(t, []string{"clientA", "clientB"}, *ConvertToAPIUserSession(kcSession).Clients)
 }
 
 func TestConvertToAPIUsersPage(t *testing.T) {
@@ -126,6 +326,29 @@ func TestConvertToKCUser(t *testing.T) {
 	assert.Equal(t, locale, (*ConvertToKCUser(user).Attributes)["locale"][0])
 }
 
+func TestComputeETag(t *testing.T) {
+	var user = createValidUserRepresentation()
+	var etag = ComputeETag(user)
+
+	assert.Equal(t, etag, ComputeETag(createValidUserRepresentation()))
+	assert.True(t, strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`))
+
+	var lastName = "Other"
+	user.LastName = &lastName
+	assert.NotEqual(t, etag, ComputeETag(user))
+}
+
+func TestMatchesETag(t *testing.T) {
+	var etag = `"abcd"`
+
+	assert.True(t, MatchesETag(`"abcd"`, etag))
+	assert.True(t, MatchesETag(` "abcd" `, etag))
+	assert.False(t, MatchesETag(`"1234", "abcd"`, etag))
+	assert.False(t, MatchesETag("*", etag))
+	assert.False(t, MatchesETag(`"1234"`, etag))
+	assert.False(t, MatchesETag(`abcd`, etag))
+}
+
 func TestValidateUserRepresentation(t *testing.T) {
 	{
 		user := createValidUserRepresentation()
@@ -135,7 +358,7 @@ func TestValidateUserRepresentation(t *testing.T) {
 	id := "#12345"
 	username := "username!"
 	email := "usernamcompany.com"
-	phoneNumber := "415174234"
+	phoneNumber := "41517423A"
 	label := ""
 	gender := "Male"
 	birthDate := "1990-13-28"
@@ -145,7 +368,7 @@ func TestValidateUserRepresentation(t *testing.T) {
 	empty := ""
 
 	var users []UserRepresentation
-	for i := 0; i < 12; i++ {
+	for i := 0; i < 16; i++ {
 		users = append(users, createValidUserRepresentation())
 	}
 
@@ -161,6 +384,10 @@ func TestValidateUserRepresentation(t *testing.T) {
 	users[9].Locale = &locale
 	users[10].FirstName = &empty
 	users[11].LastName = &empty
+	users[12].Attributes = &map[string][]string{"phoneNumber": {"+415174234"}}
+	users[13].Attributes = &map[string][]string{"invalid name": {"value"}}
+	users[14].DeletedAttributes = &[]string{"locale"}
+	users[15].DeletedAttributes = &[]string{"department"}
 
 	for _, user := range users {
 		assert.NotNil(t, user.Validate())
@@ -214,14 +441,33 @@ func TestValidateRealmCustomConfiguration(t *testing.T) {
 
 	defaultClientID := "something$invalid"
 	defaultRedirectURI := "ht//tp://company.com"
+	reservedName := "birthDate"
+	attrName := "department"
+	invalidType := "list"
+	invalidRegExp := "^(abc$"
+	invalidPasswordPolicy := "length(abc)"
+	invalidDuplicateCheck := []string{DuplicateCheckEmail, "address"}
+	invalidCountries := []string{"CH", "ch"}
+	negativeGracePeriod := -1
+	tooLongGracePeriod := MaxAccountDeletionGracePeriod + 1
 
 	var configs []RealmCustomConfiguration
-	for i := 0; i < 2; i++ {
+	for i := 0; i < 12; i++ {
 		configs = append(configs, createValidRealmCustomConfiguration())
 	}
 
 	configs[0].DefaultClientID = &defaultClientID
 	configs[1].DefaultRedirectURI = &defaultRedirectURI
+	configs[2].UserAttributes = &[]AttributeDefinition{{Name: &reservedName}}
+	configs[3].UserAttributes = &[]AttributeDefinition{{Name: &attrName, Type: &invalidType}}
+	configs[4].UserAttributes = &[]AttributeDefinition{{Name: &attrName, RegExp: &invalidRegExp}}
+	configs[5].UserAttributes = &[]AttributeDefinition{{Name: &attrName}, {Name: &attrName}}
+	configs[6].UserAttributes = &[]AttributeDefinition{{}}
+	configs[7].PasswordPolicy = &invalidPasswordPolicy
+	configs[8].DuplicateCheck = &invalidDuplicateCheck
+	configs[9].AllowedPhoneNumberCountries = &invalidCountries
+	configs[10].AccountDeletionGracePeriod = &negativeGracePeriod
+	configs[11].AccountDeletionGracePeriod = &tooLongGracePeriod
 
 	for _, config := range configs {
 		assert.NotNil(t, config.Validate())
@@ -238,6 +484,64 @@ func TestValidateRequiredAction(t *testing.T) {
 	assert.NotNil(t, action.Validate())
 }
 
+func TestValidateBulkUserActionRepresentation(t *testing.T) {
+	{
+		bulk := createValidBulkUserActionRepresentation()
+		assert.Nil(t, bulk.Validate())
+	}
+
+	invalidAction := "promote"
+	executeActionsEmail := BulkActionExecuteActionsEmail
+	invalidID := "123"
+	emptyIDs := []string{}
+	invalidIDs := []string{invalidID}
+	invalidSearch := ""
+	validSearch := "john"
+	validGroupIDs := []string{"f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"}
+	invalidRequiredActions := []RequiredAction{"^"}
+
+	var bulks []BulkUserActionRepresentation
+	for i := 0; i < 9; i++ {
+		bulks = append(bulks, createValidBulkUserActionRepresentation())
+	}
+
+	bulks[0].Action = nil
+	bulks[1].Action = &invalidAction
+	bulks[2].UserIDs = &emptyIDs
+	bulks[3].UserIDs = &invalidIDs
+	bulks[4].UserIDs = nil
+	bulks[4].GroupIDs = &invalidIDs
+	bulks[5].GroupIDs = &validGroupIDs
+	bulks[5].Search = &invalidSearch
+	bulks[6].Action = &executeActionsEmail
+	bulks[6].RequiredActions = &invalidRequiredActions
+	bulks[7].GroupIDs = &validGroupIDs
+	bulks[8].Search = &validSearch
+
+	for _, bulk := range bulks {
+		assert.NotNil(t, bulk.Validate())
+	}
+
+	// executeActionsEmail needs at least one required action
+	{
+		bulk := createValidBulkUserActionRepresentation()
+		bulk.Action = &executeActionsEmail
+		assert.NotNil(t, bulk.Validate())
+
+		var requiredActions = []RequiredAction{createValidRequiredAction()}
+		bulk.RequiredActions = &requiredActions
+		assert.Nil(t, bulk.Validate())
+	}
+
+	// users selected by groups
+	{
+		bulk := createValidBulkUserActionRepresentation()
+		bulk.UserIDs = nil
+		bulk.GroupIDs = &validGroupIDs
+		assert.Nil(t, bulk.Validate())
+	}
+}
+
 func createValidUserRepresentation() UserRepresentation {
 	var groups = []string{"f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee", "7767ed7c-0a1d-4eee-9bb8-669c6f89c007"}
 	var roles = []string{"abcded7c-0a1d-4eee-9bb8-669c6f89c0ee", "7767ed7c-0a1d-4eee-9bb8-669c6f898888"}
@@ -270,6 +574,8 @@ func createValidUserRepresentation() UserRepresentation {
 	user.Groups = &groups
 	user.Roles = &roles
 	user.Locale = &locale
+	user.Attributes = &map[string][]string{"department": {"IT"}}
+	user.DeletedAttributes = &[]string{"office"}
 
 	return user
 }
@@ -302,10 +608,35 @@ func createValidPasswordRepresentation() PasswordRepresentation {
 func createValidRealmCustomConfiguration() RealmCustomConfiguration {
 	defaultClientID := "backofficeid"
 	defaultRedirectURI := "http://company.com"
+	attrName := "department"
+	attrType := "string"
+	attrRegExp := "^[A-Z]+$"
+	boolTrue := true
+	passwordPolicy := "length(10) and digits(2) and notUsername(undefined)"
+	duplicateCheck := []string{DuplicateCheckEmail, DuplicateCheckIdentity}
+	countries := []string{"CH", "FR"}
+	gracePeriod := 30
 
 	return RealmCustomConfiguration{
 		DefaultClientID:    &defaultClientID,
 		DefaultRedirectURI: &defaultRedirectURI,
+		UserAttributes: &[]AttributeDefinition{
+			{Name: &attrName, Type: &attrType, RegExp: &attrRegExp, EditableByBackOffice: &boolTrue},
+		},
+		PasswordPolicy:              &passwordPolicy,
+		DuplicateCheck:              &duplicateCheck,
+		AllowedPhoneNumberCountries: &countries,
+		AccountDeletionGracePeriod:  &gracePeriod,
+	}
+}
+
+func createValidBulkUserActionRepresentation() BulkUserActionRepresentation {
+	action := BulkActionLock
+	userIDs := []string{"f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee", "7767ed7c-0a1d-4eee-9bb8-669c6f89c007"}
+
+	return BulkUserActionRepresentation{
+		Action:  &action,
+		UserIDs: &userIDs,
 	}
 }
 
@@ -95,6 +95,12 @@ func main() {
 			Timeout:           c.GetDuration("keycloak-timeout"),
 		}
 
+		// Technical user of the bridge
+		technicalRealm         = c.GetString("technical-realm")
+		technicalUsername      = c.GetString("technical-username")
+		technicalPassword      = c.GetString("technical-password")
+		technicalTokenValidity = c.GetDuration("technical-token-validity")
+
 		// Enabled units
 		pprofRouteEnabled = c.GetBool("pprof-route-enabled")
 
@@ -113,11 +119,12 @@ func main() {
 
 		// Rate limiting
 		rateLimit = map[string]int{
-			"event":      c.GetInt("rate-event"),
-			"account":    c.GetInt("rate-account"),
-			"management": c.GetInt("rate-management"),
-			"statistics": c.GetInt("rate-statistics"),
-			"events":     c.GetInt("rate-events"),
+			"event":          c.GetInt("rate-event"),
+			"account":        c.GetInt("rate-account"),
+			"management":     c.GetInt("rate-management"),
+			"statistics":     c.GetInt("rate-statistics"),
+			"events":         c.GetInt("rate-events"),
+			"account-export": c.GetInt("rate-account-export"),
 		}
 
 		corsOptions = cors.Options{
@@ -130,6 +137,26 @@ func main() {
 		}
 
 		logLevel = c.GetString("log-level")
+
+		// Fields whose values are not kept in the history of the users
+		historyMaskedFields = c.GetStringSlice("user-history-masked-fields")
+
+		// Custom schemes of native applications allowed in the client URLs
+		clientURLSchemes = c.GetStringSlice("client-url-custom-schemes")
+
+		// Local copy of the breached passwords database
+		breachedPasswordsDBPath = c.GetString("breached-passwords-db-path")
+
+		// Gateway sending the verification codes of the new emails and phone numbers
+		notifierURI     = c.GetString("notifier-uri")
+		notifierTimeout = c.GetDuration("notifier-timeout")
+
+		// Cache of the custom configurations
+		configCacheTTL          = c.GetDuration("config-cache-ttl")
+		configCachePollInterval = c.GetDuration("config-cache-poll-interval")
+
+		// Interval between two runs of the deletion of the accounts whose grace period is over
+		accountDeletionInterval = c.GetDuration("account-deletion-interval")
 	)
 
 	// Unique ID generator
@@ -191,6 +218,37 @@ func main() {
 		}
 	}
 
+	// Breached passwords checker
+	var breachedPasswordChecker keycloakb.BreachedPasswordChecker
+	{
+		if breachedPasswordsDBPath == "" {
+			logger.Info("msg", "no breached passwords database configured, passwords are not checked against breached passwords")
+			breachedPasswordChecker = keycloakb.NewDisabledBreachedPasswordChecker()
+		} else {
+			var err error
+			breachedPasswordChecker, err = keycloakb.NewOfflineBreachedPasswordChecker(breachedPasswordsDBPath)
+
+			if err != nil {
+				logger.Error("msg", "could not open the breached passwords database", "error", err)
+				return
+			}
+		}
+	}
+
+	// Notifier of the verification codes
+	var notifier keycloakb.Notifier
+	{
+		if notifierURI == "" {
+			logger.Info("msg", "no notification gateway configured, the changes of email and phone number are applied without verification")
+			notifier = keycloakb.NewDisabledNotifier()
+		} else {
+			notifier = keycloakb.NewHTTPNotifier(notifierURI, notifierTimeout)
+		}
+	}
+
+	// Token of the technical user, used by the account API for the calls the connected user is not allowed to make
+	var technicalTokenProvider = keycloakb.NewTechnicalTokenProvider(keycloakClient, technicalRealm, technicalUsername, technicalPassword, technicalTokenValidity, log.With(logger, "unit", "technical-token"))
+
 	// Keycloak adaptor for common-service library
 	commonKcAdaptor := keycloakb.NewKeycloakAuthClient(keycloakClient, logger)
 
@@ -263,26 +321,31 @@ func main() {
 		}
 	}
 
-	var configurationRwDBConn database.CloudtrustDB
+	var configurationRwDBConn keycloakb.DBConfiguration
 	{
-		var err error
-		configurationRwDBConn, err = configRwDbParams.OpenDatabase()
+		dbConn, err := configRwDbParams.OpenDatabase()
 		if err != nil {
 			logger.Error("msg", "could not create DB connection for configuration storage (RW)", "error", err)
 			return
 		}
+		configurationRwDBConn = keycloakb.NewConfigurationDB(dbConn)
 	}
 
-	var configurationRoDBConn database.CloudtrustDB
+	var configurationRoDBConn keycloakb.DBConfiguration
 	{
-		var err error
-		configurationRoDBConn, err = configRoDbParams.OpenDatabase()
+		dbConn, err := configRoDbParams.OpenDatabase()
 		if err != nil {
 			logger.Error("msg", "could not create DB connection for configuration storage (RO)", "error", err)
 			return
 		}
+		configurationRoDBConn = keycloakb.NewConfigurationDB(dbConn)
 	}
 
+	// Accounts whose deletion has been requested by their users
+	var accountDeletionDBModule = keycloakb.NewAccountDeletionDBModule(configurationRwDBConn)
+	var otpEnrollmentDBModule = keycloakb.NewOTPEnrollmentDBModule(configurationRwDBConn)
+	var pendingChangeDBModule = keycloakb.NewPendingChangeDBModule(configurationRwDBConn)
+
 	// Event service.
 	var eventEndpoints = event.Endpoints{}
 	{
@@ -322,9 +385,17 @@ func main() {
 			eventAdminComponent = event.MakeAdminComponentTracingMW(tracer)(eventAdminComponent)
 		}
 
+		// A login cancels the deletion of the account, the deletion is done once the grace period is over by the instance holding the lock
+		var accountDeletionScheduler = account.NewAccountDeletionScheduler(keycloakClient, technicalTokenProvider, accountDeletionDBModule, eventsDBModule, ComponentID, 2*accountDeletionInterval, log.With(eventLogger, "unit", "account_deletion"))
+		go func() {
+			var tic = time.NewTicker(accountDeletionInterval)
+			defer tic.Stop()
+			accountDeletionScheduler.Run(tic.C)
+		}()
+
 		var eventComponent event.Component
 		{
-			var fns = []event.FuncEvent{consoleModule.Print, statisticModule.Stats, eventsDBModule.Store}
+			var fns = []event.FuncEvent{consoleModule.Print, statisticModule.Stats, eventsDBModule.Store, accountDeletionScheduler.CancelOnLogin}
 			eventComponent = event.NewComponent(fns, fns)
 			eventComponent = event.MakeComponentInstrumentingMW(influxMetrics.NewHistogram("component"))(eventComponent)
 			eventComponent = event.MakeComponentLoggingMW(log.With(eventLogger, "mw", "component", "unit", "event"))(eventComponent)
@@ -392,6 +463,25 @@ func main() {
 		}
 	}
 
+	// Custom configurations read by the account API. As they are read on each account request, they are kept in memory.
+	// They are dropped when they are stored through the management API of this instance and when their version changes.
+	var configDBReader = keycloakb.NewConfigurationDBModule(configurationRoDBConn)
+	var configDBCache keycloakb.ConfigurationDBModuleCache
+	if configCacheTTL > 0 {
+		var configDBModule keycloakb.ConfigurationDBModule = configDBReader
+		configDBModule = keycloakb.MakeConfigurationDBModuleInstrumentingMW(influxMetrics.NewHistogram("configDB_module"))(configDBModule)
+		configDBModule = management.MakeConfigurationDBModuleLoggingMW(log.With(logger, "mw", "module", "unit", "configDB"))(configDBModule)
+		configDBModule = management.MakeConfigurationDBModuleTracingMW(tracer)(configDBModule)
+
+		configDBCache = keycloakb.MakeConfigurationDBModuleCacheMW(configCacheTTL, influxMetrics.NewCounter("configDB_cache_hit"),
+			influxMetrics.NewCounter("configDB_cache_miss"), log.With(logger, "mw", "module", "unit", "configDBCache"))(configDBModule)
+		go func() {
+			var tic = time.NewTicker(configCachePollInterval)
+			defer tic.Stop()
+			configDBCache.PollVersions(configDBReader, tic.C)
+		}()
+	}
+
 	// Management service.
 	var managementEndpoints = management.Endpoints{}
 	{
@@ -407,43 +497,63 @@ func main() {
 			configDBModule = keycloakb.MakeConfigurationDBModuleInstrumentingMW(influxMetrics.NewHistogram("configDB_module"))(configDBModule)
 			configDBModule = management.MakeConfigurationDBModuleLoggingMW(log.With(managementLogger, "mw", "module", "unit", "configDB"))(configDBModule)
 			configDBModule = management.MakeConfigurationDBModuleTracingMW(tracer)(configDBModule)
+			if configDBCache != nil {
+				configDBModule = keycloakb.MakeConfigurationDBModuleInvalidationMW(configDBCache)(configDBModule)
+			}
 		}
 
 		var keycloakComponent management.Component
 		{
-			keycloakComponent = management.NewComponent(keycloakClient, eventsDBModule, configDBModule, managementLogger)
+			keycloakComponent = management.NewComponent(keycloakClient, eventsDBModule, eventsRODBModule, configDBModule, breachedPasswordChecker, historyMaskedFields, clientURLSchemes, managementLogger)
 			keycloakComponent = management.MakeAuthorizationManagementComponentMW(log.With(managementLogger, "mw", "endpoint"), authorizationManager)(keycloakComponent)
 		}
 
 		managementEndpoints = management.Endpoints{
-			GetRealms:                      prepareEndpoint(management.MakeGetRealmsEndpoint(keycloakComponent), "realms_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetRealm:                       prepareEndpoint(management.MakeGetRealmEndpoint(keycloakComponent), "realm_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetClients:                     prepareEndpoint(management.MakeGetClientsEndpoint(keycloakComponent), "get_clients_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetClient:                      prepareEndpoint(management.MakeGetClientEndpoint(keycloakComponent), "get_client_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			CreateUser:                     prepareEndpoint(management.MakeCreateUserEndpoint(keycloakComponent), "create_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetUser:                        prepareEndpoint(management.MakeGetUserEndpoint(keycloakComponent), "get_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			UpdateUser:                     prepareEndpoint(management.MakeUpdateUserEndpoint(keycloakComponent), "update_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			DeleteUser:                     prepareEndpoint(management.MakeDeleteUserEndpoint(keycloakComponent), "delete_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetUsers:                       prepareEndpoint(management.MakeGetUsersEndpoint(keycloakComponent), "get_users_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetUserAccountStatus:           prepareEndpoint(management.MakeGetUserAccountStatusEndpoint(keycloakComponent), "get_user_accountstatus", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetGroupsOfUser:                prepareEndpoint(management.MakeGetGroupsOfUserEndpoint(keycloakComponent), "get_user_groups", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetRolesOfUser:                 prepareEndpoint(management.MakeGetRolesOfUserEndpoint(keycloakComponent), "get_user_roles", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetRoles:                       prepareEndpoint(management.MakeGetRolesEndpoint(keycloakComponent), "get_roles_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetRole:                        prepareEndpoint(management.MakeGetRoleEndpoint(keycloakComponent), "get_role_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetGroups:                      prepareEndpoint(management.MakeGetGroupsEndpoint(keycloakComponent), "get_groups_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetClientRoles:                 prepareEndpoint(management.MakeGetClientRolesEndpoint(keycloakComponent), "get_client_roles_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			CreateClientRole:               prepareEndpoint(management.MakeCreateClientRoleEndpoint(keycloakComponent), "create_client_role_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetClientRoleForUser:           prepareEndpoint(management.MakeGetClientRolesForUserEndpoint(keycloakComponent), "get_client_roles_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			AddClientRoleToUser:            prepareEndpoint(management.MakeAddClientRolesToUserEndpoint(keycloakComponent), "get_client_roles_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			ResetPassword:                  prepareEndpoint(management.MakeResetPasswordEndpoint(keycloakComponent), "reset_password_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			SendVerifyEmail:                prepareEndpoint(management.MakeSendVerifyEmailEndpoint(keycloakComponent), "send_verify_email_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			ExecuteActionsEmail:            prepareEndpoint(management.MakeExecuteActionsEmailEndpoint(keycloakComponent), "execute_actions_email_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			SendReminderEmail:              prepareEndpoint(management.MakeSendReminderEmailEndpoint(keycloakComponent), "send_reminder_email_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			SendNewEnrolmentCode:           prepareEndpoint(management.MakeSendNewEnrolmentCodeEndpoint(keycloakComponent), "send_new_enrolment_code_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetCredentialsForUser:          prepareEndpoint(management.MakeGetCredentialsForUserEndpoint(keycloakComponent), "get_credentials_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			DeleteCredentialsForUser:       prepareEndpoint(management.MakeDeleteCredentialsForUserEndpoint(keycloakComponent), "delete_credentials_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			GetRealmCustomConfiguration:    prepareEndpoint(management.MakeGetRealmCustomConfigurationEndpoint(keycloakComponent), "get_realm_custom_config_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
-			UpdateRealmCustomConfiguration: prepareEndpoint(management.MakeUpdateRealmCustomConfigurationEndpoint(keycloakComponent), "update_realm_custom_config_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetRealms:                          prepareEndpoint(management.MakeGetRealmsEndpoint(keycloakComponent), "realms_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetRealm:                           prepareEndpoint(management.MakeGetRealmEndpoint(keycloakComponent), "realm_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetRealmSettings:                   prepareEndpoint(management.MakeGetRealmSettingsEndpoint(keycloakComponent), "get_realm_settings_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			UpdateRealmSettings:                prepareEndpoint(management.MakeUpdateRealmSettingsEndpoint(keycloakComponent), "update_realm_settings_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetClients:                         prepareEndpoint(management.MakeGetClientsEndpoint(keycloakComponent), "get_clients_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetClient:                          prepareEndpoint(management.MakeGetClientEndpoint(keycloakComponent), "get_client_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			CreateClient:                       prepareEndpoint(management.MakeCreateClientEndpoint(keycloakComponent), "create_client_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			UpdateClient:                       prepareEndpoint(management.MakeUpdateClientEndpoint(keycloakComponent), "update_client_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			EnableClient:                       prepareEndpoint(management.MakeEnableClientEndpoint(keycloakComponent), "enable_client_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			DisableClient:                      prepareEndpoint(management.MakeDisableClientEndpoint(keycloakComponent), "disable_client_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			RegenerateClientSecret:             prepareEndpoint(management.MakeRegenerateClientSecretEndpoint(keycloakComponent), "regenerate_client_secret_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			CreateUser:                         prepareEndpoint(management.MakeCreateUserEndpoint(keycloakComponent), "create_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetUser:                            prepareEndpoint(management.MakeGetUserEndpoint(keycloakComponent), "get_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			UpdateUser:                         prepareEndpoint(management.MakeUpdateUserEndpoint(keycloakComponent), "update_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			DeleteUser:                         prepareEndpoint(management.MakeDeleteUserEndpoint(keycloakComponent), "delete_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetUsers:                           prepareEndpoint(management.MakeGetUsersEndpoint(keycloakComponent), "get_users_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetUserAccountStatus:               prepareEndpoint(management.MakeGetUserAccountStatusEndpoint(keycloakComponent), "get_user_accountstatus", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetGroupsOfUser:                    prepareEndpoint(management.MakeGetGroupsOfUserEndpoint(keycloakComponent), "get_user_groups", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetRolesOfUser:                     prepareEndpoint(management.MakeGetRolesOfUserEndpoint(keycloakComponent), "get_user_roles", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetRoles:                           prepareEndpoint(management.MakeGetRolesEndpoint(keycloakComponent), "get_roles_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetRole:                            prepareEndpoint(management.MakeGetRoleEndpoint(keycloakComponent), "get_role_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetGroups:                          prepareEndpoint(management.MakeGetGroupsEndpoint(keycloakComponent), "get_groups_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetClientRoles:                     prepareEndpoint(management.MakeGetClientRolesEndpoint(keycloakComponent), "get_client_roles_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			CreateClientRole:                   prepareEndpoint(management.MakeCreateClientRoleEndpoint(keycloakComponent), "create_client_role_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetClientRoleForUser:               prepareEndpoint(management.MakeGetClientRolesForUserEndpoint(keycloakComponent), "get_client_roles_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			AddClientRoleToUser:                prepareEndpoint(management.MakeAddClientRolesToUserEndpoint(keycloakComponent), "get_client_roles_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			ResetPassword:                      prepareEndpoint(management.MakeResetPasswordEndpoint(keycloakComponent), "reset_password_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			SendVerifyEmail:                    prepareEndpoint(management.MakeSendVerifyEmailEndpoint(keycloakComponent), "send_verify_email_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			ExecuteActionsEmail:                prepareEndpoint(management.MakeExecuteActionsEmailEndpoint(keycloakComponent), "execute_actions_email_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			SendReminderEmail:                  prepareEndpoint(management.MakeSendReminderEmailEndpoint(keycloakComponent), "send_reminder_email_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			SendNewEnrolmentCode:               prepareEndpoint(management.MakeSendNewEnrolmentCodeEndpoint(keycloakComponent), "send_new_enrolment_code_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetCredentialsForUser:              prepareEndpoint(management.MakeGetCredentialsForUserEndpoint(keycloakComponent), "get_credentials_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			DeleteCredentialsForUser:           prepareEndpoint(management.MakeDeleteCredentialsForUserEndpoint(keycloakComponent), "delete_credentials_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			ResetCredentialsForUser:            prepareEndpoint(management.MakeResetCredentialsForUserEndpoint(keycloakComponent), "reset_credentials_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetRealmCustomConfiguration:        prepareEndpoint(management.MakeGetRealmCustomConfigurationEndpoint(keycloakComponent), "get_realm_custom_config_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			UpdateRealmCustomConfiguration:     prepareEndpoint(management.MakeUpdateRealmCustomConfigurationEndpoint(keycloakComponent), "update_realm_custom_config_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			PatchRealmCustomConfiguration:      prepareEndpoint(management.MakePatchRealmCustomConfigurationEndpoint(keycloakComponent), "patch_realm_custom_config_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetRealmCustomConfigurationHistory: prepareEndpoint(management.MakeGetRealmCustomConfigurationHistoryEndpoint(keycloakComponent), "get_realm_custom_config_history_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetRealmCustomConfigurationDiff:    prepareEndpoint(management.MakeGetRealmCustomConfigurationDiffEndpoint(keycloakComponent), "get_realm_custom_config_diff_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			RollbackRealmCustomConfiguration:   prepareEndpoint(management.MakeRollbackRealmCustomConfigurationEndpoint(keycloakComponent), "rollback_realm_custom_config_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			BulkUserAction:                     prepareEndpoint(management.MakeBulkUserActionEndpoint(keycloakComponent), "bulk_user_action_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetUserHistory:                     prepareEndpoint(management.MakeGetUserHistoryEndpoint(keycloakComponent), "get_user_history_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			GetUserSessions:                    prepareEndpoint(management.MakeGetUserSessionsEndpoint(keycloakComponent), "get_user_sessions_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			LogoutUser:                         prepareEndpoint(management.MakeLogoutUserEndpoint(keycloakComponent), "logout_user_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
+			RevokeSession:                      prepareEndpoint(management.MakeRevokeSessionEndpoint(keycloakComponent), "revoke_session_endpoint", influxMetrics, managementLogger, tracer, rateLimit["management"]),
 		}
 	}
 
@@ -457,20 +567,24 @@ func main() {
 
 		// module for retrieving the custom configuration
 		var configDBModule account.ConfigurationDBModule
-		{
-			configDBModule = keycloakb.NewConfigurationDBModule(configurationRoDBConn)
+		if configDBCache != nil {
+			configDBModule = configDBCache
+		} else {
+			configDBModule = configDBReader
 			configDBModule = keycloakb.MakeConfigurationDBModuleInstrumentingMW(influxMetrics.NewHistogram("configDB_module"))(configDBModule)
 			configDBModule = management.MakeConfigurationDBModuleLoggingMW(log.With(logger, "mw", "module", "unit", "configDB"))(configDBModule)
 			configDBModule = management.MakeConfigurationDBModuleTracingMW(tracer)(configDBModule)
 		}
 
 		// new module for account service
-		accountComponent := account.NewComponent(keycloakClient.AccountClient(), eventsDBModule, configDBModule, accountLogger)
+		accountComponent := account.NewComponent(keycloakClient.AccountClient(), keycloakClient, technicalTokenProvider, eventsDBModule, configDBModule, accountDeletionDBModule, otpEnrollmentDBModule, pendingChangeDBModule, eventsRODBModule, breachedPasswordChecker, notifier, accountLogger)
 		accountComponent = account.MakeAuthorizationAccountComponentMW(log.With(accountLogger, "mw", "endpoint"), configDBModule)(accountComponent)
 
 		accountEndpoints = account.Endpoints{
 			GetAccount:                prepareEndpoint(account.MakeGetAccountEndpoint(accountComponent), "get_account", influxMetrics, accountLogger, tracer, rateLimit["account"]),
 			UpdateAccount:             prepareEndpoint(account.MakeUpdateAccountEndpoint(accountComponent), "update_account", influxMetrics, accountLogger, tracer, rateLimit["account"]),
+			ConfirmEmailChange:        prepareEndpoint(account.MakeConfirmEmailChangeEndpoint(accountComponent), "confirm_email_change", influxMetrics, accountLogger, tracer, rateLimit["account"]),
+			ConfirmPhoneNumberChange:  prepareEndpoint(account.MakeConfirmPhoneNumberChangeEndpoint(accountComponent), "confirm_phone_number_change", influxMetrics, accountLogger, tracer, rateLimit["account"]),
 			DeleteAccount:             prepareEndpoint(account.MakeDeleteAccountEndpoint(accountComponent), "delete_account", influxMetrics, accountLogger, tracer, rateLimit["account"]),
 			UpdatePassword:            prepareEndpoint(account.MakeUpdatePasswordEndpoint(accountComponent), "update_password", influxMetrics, accountLogger, tracer, rateLimit["account"]),
 			GetCredentials:            prepareEndpoint(account.MakeGetCredentialsEndpoint(accountComponent), "get_credentials", influxMetrics, accountLogger, tracer, rateLimit["account"]),
@@ -479,6 +593,13 @@ func main() {
 			UpdateLabelCredential:     prepareEndpoint(account.MakeUpdateLabelCredentialEndpoint(accountComponent), "update_label_credential", influxMetrics, accountLogger, tracer, rateLimit["account"]),
 			MoveCredential:            prepareEndpoint(account.MakeMoveCredentialEndpoint(accountComponent), "move_credential", influxMetrics, accountLogger, tracer, rateLimit["account"]),
 			GetConfiguration:          prepareEndpoint(account.MakeGetConfigurationEndpoint(accountComponent), "get_configuration", influxMetrics, accountLogger, tracer, rateLimit["account"]),
+			ExportPersonalData:        prepareEndpoint(account.MakeExportPersonalDataEndpoint(accountComponent), "export_personal_data", influxMetrics, accountLogger, tracer, rateLimit["account-export"]),
+			GetActivity:               prepareEndpoint(account.MakeGetActivityEndpoint(accountComponent), "get_activity", influxMetrics, accountLogger, tracer, rateLimit["account"]),
+			GetSessions:               prepareEndpoint(account.MakeGetSessionsEndpoint(accountComponent), "get_sessions", influxMetrics, accountLogger, tracer, rateLimit["account"]),
+			DeleteSession:             prepareEndpoint(account.MakeDeleteSessionEndpoint(accountComponent), "delete_session", influxMetrics, accountLogger, tracer, rateLimit["account"]),
+			DeleteOtherSessions:       prepareEndpoint(account.MakeDeleteOtherSessionsEndpoint(accountComponent), "delete_other_sessions", influxMetrics, accountLogger, tracer, rateLimit["account"]),
+			StartOTPEnrollment:        prepareEndpoint(account.MakeStartOTPEnrollmentEndpoint(accountComponent), "start_otp_enrollment", influxMetrics, accountLogger, tracer, rateLimit["account"]),
+			VerifyOTPEnrollment:       prepareEndpoint(account.MakeVerifyOTPEnrollmentEndpoint(accountComponent), "verify_otp_enrollment", influxMetrics, accountLogger, tracer, rateLimit["account"]),
 		}
 	}
 
@@ -565,9 +686,16 @@ func main() {
 
 		var getRealmsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealms)
 		var getRealmHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealm)
+		var getRealmSettingsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmSettings)
+		var updateRealmSettingsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateRealmSettings)
 
 		var getClientsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetClients)
 		var getClientHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetClient)
+		var createClientHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.CreateClient)
+		var updateClientHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateClient)
+		var enableClientHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.EnableClient)
+		var disableClientHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.DisableClient)
+		var regenerateClientSecretHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.RegenerateClientSecret)
 
 		var createUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.CreateUser)
 		var getUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUser)
@@ -577,6 +705,12 @@ func main() {
 		var getRolesForUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRolesOfUser)
 		var getGroupsForUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetGroupsOfUser)
 		var getUserAccountStatusHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUserAccountStatus)
+		var bulkUserActionHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.BulkUserAction)
+		var getUserHistoryHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUserHistory)
+
+		var getUserSessionsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUserSessions)
+		var logoutUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.LogoutUser)
+		var revokeSessionHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.RevokeSession)
 
 		var getClientRoleForUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetClientRoleForUser)
 		var addClientRoleToUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.AddClientRoleToUser)
@@ -596,27 +730,41 @@ func main() {
 
 		var getCredentialsForUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetCredentialsForUser)
 		var deleteCredentialsForUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.DeleteCredentialsForUser)
+		var resetCredentialsForUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.ResetCredentialsForUser)
 
 		var getRealmCustomConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmCustomConfiguration)
 		var updateRealmCustomConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateRealmCustomConfiguration)
+		var patchRealmCustomConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.PatchRealmCustomConfiguration)
+		var getRealmCustomConfigurationHistoryHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmCustomConfigurationHistory)
+		var getRealmCustomConfigurationDiffHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmCustomConfigurationDiff)
+		var rollbackRealmCustomConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.RollbackRealmCustomConfiguration)
 
 		//realms
 		managementSubroute.Path("/realms").Methods("GET").Handler(getRealmsHandler)
 		managementSubroute.Path("/realms/{realm}").Methods("GET").Handler(getRealmHandler)
+		managementSubroute.Path("/realms/{realm}/settings").Methods("GET").Handler(getRealmSettingsHandler)
+		managementSubroute.Path("/realms/{realm}/settings").Methods("PUT").Handler(updateRealmSettingsHandler)
 
 		//clients
 		managementSubroute.Path("/realms/{realm}/clients").Methods("GET").Handler(getClientsHandler)
+		managementSubroute.Path("/realms/{realm}/clients").Methods("POST").Handler(createClientHandler)
 		managementSubroute.Path("/realms/{realm}/clients/{clientID}").Methods("GET").Handler(getClientHandler)
+		managementSubroute.Path("/realms/{realm}/clients/{clientID}").Methods("PUT").Handler(updateClientHandler)
+		managementSubroute.Path("/realms/{realm}/clients/{clientID}/enable").Methods("POST").Handler(enableClientHandler)
+		managementSubroute.Path("/realms/{realm}/clients/{clientID}/disable").Methods("POST").Handler(disableClientHandler)
+		managementSubroute.Path("/realms/{realm}/clients/{clientID}/client-secret").Methods("POST").Handler(regenerateClientSecretHandler)
 
 		//users
 		managementSubroute.Path("/realms/{realm}/users").Methods("GET").Handler(getUsersHandler)
 		managementSubroute.Path("/realms/{realm}/users").Methods("POST").Handler(createUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/bulk-actions").Methods("POST").Handler(bulkUserActionHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}").Methods("GET").Handler(getUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}").Methods("PUT").Handler(updateUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}").Methods("DELETE").Handler(deleteUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/groups").Methods("GET").Handler(getGroupsForUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/roles").Methods("GET").Handler(getRolesForUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/status").Methods("GET").Handler(getUserAccountStatusHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/history").Methods("GET").Handler(getUserHistoryHandler)
 
 		//role mappings
 		managementSubroute.Path("/realms/{realm}/users/{userID}/role-mappings/clients/{clientID}").Methods("GET").Handler(getClientRoleForUserHandler)
@@ -630,8 +778,14 @@ func main() {
 
 		// Credentials
 		managementSubroute.Path("/realms/{realm}/users/{userID}/credentials").Methods("GET").Handler(getCredentialsForUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/credentials").Methods("DELETE").Handler(resetCredentialsForUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/credentials/{credentialID}").Methods("DELETE").Handler(deleteCredentialsForUserHandler)
 
+		// Sessions
+		managementSubroute.Path("/realms/{realm}/users/{userID}/sessions").Methods("GET").Handler(getUserSessionsHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/sessions/{sessionID}").Methods("DELETE").Handler(revokeSessionHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/logout").Methods("POST").Handler(logoutUserHandler)
+
 		//roles
 		managementSubroute.Path("/realms/{realm}/roles").Methods("GET").Handler(getRolesHandler)
 		managementSubroute.Path("/realms/{realm}/roles-by-id/{roleID}").Methods("GET").Handler(getRoleHandler)
@@ -644,6 +798,10 @@ func main() {
 		// custom configuration par realm
 		managementSubroute.Path("/realms/{realm}/configuration").Methods("GET").Handler(getRealmCustomConfigurationHandler)
 		managementSubroute.Path("/realms/{realm}/configuration").Methods("PUT").Handler(updateRealmCustomConfigurationHandler)
+		managementSubroute.Path("/realms/{realm}/configuration").Methods("PATCH").Handler(patchRealmCustomConfigurationHandler)
+		managementSubroute.Path("/realms/{realm}/configuration/history").Methods("GET").Handler(getRealmCustomConfigurationHistoryHandler)
+		managementSubroute.Path("/realms/{realm}/configuration/diff").Methods("GET").Handler(getRealmCustomConfigurationDiffHandler)
+		managementSubroute.Path("/realms/{realm}/configuration/history/{version}/rollback").Methods("POST").Handler(rollbackRealmCustomConfigurationHandler)
 
 		c := cors.New(corsOptions)
 		errc <- http.ListenAndServe(httpAddrManagement, c.Handler(route))
@@ -669,18 +827,37 @@ func main() {
 		var moveCredentialHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.MoveCredential)
 		var getAccountHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.GetAccount)
 		var updateAccountHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.UpdateAccount)
+		var confirmEmailChangeHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.ConfirmEmailChange)
+		var confirmPhoneNumberChangeHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.ConfirmPhoneNumberChange)
 		var deleteAccountHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.DeleteAccount)
 		var getGetConfiguration = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.GetConfiguration)
+		var exportPersonalDataHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.ExportPersonalData)
+		var getActivityHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.GetActivity)
+		var getSessionsHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.GetSessions)
+		var deleteSessionHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.DeleteSession)
+		var deleteOtherSessionsHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.DeleteOtherSessions)
+		var startOTPEnrollmentHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.StartOTPEnrollment)
+		var verifyOTPEnrollmentHandler = configureAccountHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(accountEndpoints.VerifyOTPEnrollment)
 
 		route.Path("/account").Methods("GET").Handler(getAccountHandler)
 		route.Path("/account").Methods("POST").Handler(updateAccountHandler)
 		route.Path("/account").Methods("DELETE").Handler(deleteAccountHandler)
+		route.Path("/account/email/confirm").Methods("POST").Handler(confirmEmailChangeHandler)
+		route.Path("/account/phone-number/confirm").Methods("POST").Handler(confirmPhoneNumberChangeHandler)
+		route.Path("/account/export").Methods("GET").Handler(exportPersonalDataHandler)
+		route.Path("/account/events").Methods("GET").Handler(getActivityHandler)
+
+		route.Path("/account/sessions").Methods("GET").Handler(getSessionsHandler)
+		route.Path("/account/sessions").Methods("DELETE").Handler(deleteOtherSessionsHandler)
+		route.Path("/account/sessions/{sessionID}").Methods("DELETE").Handler(deleteSessionHandler)
 
 		route.Path("/account/configuration").Methods("GET").Handler(getGetConfiguration)
 
 		route.Path("/account/credentials").Methods("GET").Handler(getCredentialsHandler)
 		route.Path("/account/credentials/password").Methods("POST").Handler(updatePasswordHandler)
 		route.Path("/account/credentials/registrators").Methods("GET").Handler(getCredentialRegistratorsHandler)
+		route.Path("/account/credentials/otp").Methods("POST").Handler(startOTPEnrollmentHandler)
+		route.Path("/account/credentials/otp/verify").Methods("POST").Handler(verifyOTPEnrollmentHandler)
 		route.Path("/account/credentials/{credentialID}").Methods("DELETE").Handler(deleteCredentialHandler)
 		route.Path("/account/credentials/{credentialID}").Methods("PUT").Handler(updateLabelCredentialHandler)
 		route.Path("/account/credentials/{credentialID}/after/{previousCredentialID}").Methods("POST").Handler(moveCredentialHandler)
@@ -734,6 +911,12 @@ func config(logger log.Logger) *viper.Viper {
 	v.SetDefault("keycloak-oidc-uri", "http://127.0.0.1:8080 http://localhost:8080")
 	v.SetDefault("keycloak-timeout", "5s")
 
+	// Technical user default.
+	v.SetDefault("technical-realm", "master")
+	v.SetDefault("technical-username", "")
+	v.SetDefault("technical-password", "")
+	v.SetDefault("technical-token-validity", "30s")
+
 	// Storage events in DB (read/write)
 	v.SetDefault("events-db", false)
 	database.ConfigureDbDefault(v, "db-audit-rw", "CT_BRIDGE_DB_AUDIT_RW_USERNAME", "CT_BRIDGE_DB_AUDIT_RW_PASSWORD")
@@ -761,6 +944,27 @@ func config(logger log.Logger) *viper.Viper {
 	v.SetDefault("rate-management", 1000)
 	v.SetDefault("rate-statistics", 1000)
 	v.SetDefault("rate-events", 1000)
+	v.SetDefault("rate-account-export", 10)
+
+	// User history
+	v.SetDefault("user-history-masked-fields", []string{})
+
+	// Clients
+	v.SetDefault("client-url-custom-schemes", []string{})
+
+	// Breached passwords database
+	v.SetDefault("breached-passwords-db-path", "")
+
+	// Notification gateway
+	v.SetDefault("notifier-uri", "")
+	v.SetDefault("notifier-timeout", "5s")
+
+	// Cache of the custom configurations (a TTL of 0 disables the cache)
+	v.SetDefault("config-cache-ttl", "0s")
+	v.SetDefault("config-cache-poll-interval", "10s")
+
+	// Deletion of the accounts whose grace period is over
+	v.SetDefault("account-deletion-interval", "1h")
 
 	// Influx DB client default.
 	v.SetDefault("influx", false)
@@ -809,6 +1013,10 @@ func config(logger log.Logger) *viper.Viper {
 	v.BindEnv("event-basic-auth-token", "CT_BRIDGE_EVENT_BASIC_AUTH")
 	censoredParameters["event-basic-auth-token"] = true
 
+	v.BindEnv("technical-username", "CT_BRIDGE_TECHNICAL_USERNAME")
+	v.BindEnv("technical-password", "CT_BRIDGE_TECHNICAL_PASSWORD")
+	censoredParameters["technical-password"] = true
+
 	// Load and log config.
 	v.SetConfigFile(v.GetString("config-file"))
 	var err = v.ReadInConfig()
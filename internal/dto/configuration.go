@@ -2,14 +2,48 @@ package dto
 
 // RealmConfiguration struct
 type RealmConfiguration struct {
-	DefaultClientID                     *string `json:"default_client_id"`
-	DefaultRedirectURI                  *string `json:"default_redirect_uri"`
-	APISelfAuthenticatorDeletionEnabled *bool   `json:"api_self_authenticator_deletion_enabled"`
-	APISelfPasswordChangeEnabled        *bool   `json:"api_self_password_change_enabled"`
-	APISelfMailEditingEnabled           *bool   `json:"api_self_mail_editing_enabled"`
-	APISelfAccountDeletionEnabled       *bool   `json:"api_self_account_deletion_enabled"`
-	ShowAuthenticatorsTab               *bool   `json:"show_authenticators_tab"`
-	ShowPasswordTab                     *bool   `json:"show_password_tab"`
-	ShowMailEditing                     *bool   `json:"show_mail_editing"`
-	ShowAccountDeletionButton           *bool   `json:"show_account_deletion_button"`
+	DefaultClientID                       *string                `json:"default_client_id"`
+	DefaultRedirectURI                    *string                `json:"default_redirect_uri"`
+	APISelfAuthenticatorDeletionEnabled   *bool                  `json:"api_self_authenticator_deletion_enabled"`
+	APISelfPasswordChangeEnabled          *bool                  `json:"api_self_password_change_enabled"`
+	APISelfMailEditingEnabled             *bool                  `json:"api_self_mail_editing_enabled"`
+	APISelfAccountDeletionEnabled         *bool                  `json:"api_self_account_deletion_enabled"`
+	APISelfActivityLogEnabled             *bool                  `json:"api_self_activity_log_enabled,omitempty"`
+	APISelfSessionsEnabled                *bool                  `json:"api_self_sessions_enabled,omitempty"`
+	APISelfOTPEnrollmentEnabled           *bool                  `json:"api_self_otp_enrollment_enabled,omitempty"`
+	APISelfAuthenticatorListingEnabled    *bool                  `json:"api_self_authenticator_listing_enabled,omitempty"`
+	APISelfAuthenticatorLabellingEnabled  *bool                  `json:"api_self_authenticator_labelling_enabled,omitempty"`
+	APISelfAuthenticatorReorderingEnabled *bool                  `json:"api_self_authenticator_reordering_enabled,omitempty"`
+	APISelfPhoneNumberEditingEnabled      *bool                  `json:"api_self_phone_number_editing_enabled,omitempty"`
+	APISelfNameEditingEnabled             *bool                  `json:"api_self_name_editing_enabled,omitempty"`
+	ShowAuthenticatorsTab                 *bool                  `json:"show_authenticators_tab"`
+	ShowPasswordTab                       *bool                  `json:"show_password_tab"`
+	ShowMailEditing                       *bool                  `json:"show_mail_editing"`
+	ShowAccountDeletionButton             *bool                  `json:"show_account_deletion_button"`
+	UserAttributes                        *[]AttributeDefinition `json:"user_attributes,omitempty"`
+	PasswordPolicy                        *string                `json:"password_policy,omitempty"`
+	BreachedPasswordCheck                 *bool                  `json:"breached_password_check,omitempty"`
+	MFARequired                           *bool                  `json:"mfa_required,omitempty"`
+	DuplicateCheck                        *[]string              `json:"duplicate_check,omitempty"`
+	AllowedPhoneNumberCountries           *[]string              `json:"allowed_phone_number_countries,omitempty"`
+	AccountDeletionGracePeriod            *int                   `json:"account_deletion_grace_period,omitempty"`
+}
+
+// AttributeDefinition describes a custom user attribute allowed in a realm
+type AttributeDefinition struct {
+	Name                  *string `json:"name"`
+	Type                  *string `json:"type"`
+	RegExp                *string `json:"regexp,omitempty"`
+	Required              *bool   `json:"required"`
+	EditableBySelfService *bool   `json:"editable_by_self_service"`
+	EditableByBackOffice  *bool   `json:"editable_by_back_office"`
+}
+
+// RealmConfigurationVersion is a stored version of the custom configuration of a realm
+type RealmConfigurationVersion struct {
+	Version       int                `json:"version"`
+	Configuration RealmConfiguration `json:"configuration"`
+	Author        string             `json:"author"`
+	Comment       string             `json:"comment"`
+	Time          int64              `json:"time"`
 }
@@ -0,0 +1,105 @@
+package keycloakb
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	scheduleDeletionStmt = `INSERT INTO account_deletion (realm_id, user_id, username, requested_at, deletion_time)
+	  VALUES (?, ?, ?, ?, ?)
+	  ON DUPLICATE KEY UPDATE username = ?, requested_at = ?, deletion_time = ?;`
+	cancelDeletionStmt     = `DELETE FROM account_deletion WHERE (realm_id = ?) AND (user_id = ?)`
+	selectDueDeletionsStmt = `SELECT realm_id, user_id, username, requested_at, deletion_time FROM account_deletion
+	  WHERE (deletion_time <= ?) ORDER BY deletion_time`
+	// the lock is taken when it is free or expired, and renewed by its owner. Nothing changes when another instance holds it.
+	lockSchedulerStmt = `INSERT INTO scheduler_lock (name, owner, expires_at)
+	  VALUES (?, ?, ?)
+	  ON DUPLICATE KEY UPDATE owner = IF(expires_at < ? OR owner = VALUES(owner), VALUES(owner), owner),
+	    expires_at = IF(owner = VALUES(owner), VALUES(expires_at), expires_at);`
+)
+
+// accountDeletionLock is the name of the lock held by the instance running the account deletions
+const accountDeletionLock = "account_deletion"
+
+// AccountDeletion is an account whose deletion has been requested by its user and which is kept until the end of the
+// grace period of its realm. Times are in milliseconds since epoch.
+type AccountDeletion struct {
+	RealmName    string
+	UserID       string
+	Username     string
+	RequestedAt  int64
+	DeletionTime int64
+}
+
+// AccountDeletionDBModule stores the accounts waiting for their deletion
+type AccountDeletionDBModule interface {
+	ScheduleDeletion(ctx context.Context, deletion AccountDeletion) error
+	CancelDeletion(ctx context.Context, realmName, userID string) (bool, error)
+	GetDueDeletions(ctx context.Context, now time.Time) ([]AccountDeletion, error)
+	LockScheduler(ctx context.Context, owner string, now time.Time, lease time.Duration) (bool, error)
+}
+
+type accountDeletionDBModule struct {
+	db DBConfiguration
+}
+
+// NewAccountDeletionDBModule returns an AccountDeletionDB module.
+func NewAccountDeletionDBModule(db DBConfiguration) AccountDeletionDBModule {
+	return &accountDeletionDBModule{
+		db: db,
+	}
+}
+
+// ScheduleDeletion stores the deletion of an account. A new request of the same account replaces the previous one.
+func (c *accountDeletionDBModule) ScheduleDeletion(ctx context.Context, deletion AccountDeletion) error {
+	_, err := c.db.Exec(scheduleDeletionStmt, deletion.RealmName, deletion.UserID, deletion.Username, deletion.RequestedAt, deletion.DeletionTime,
+		deletion.Username, deletion.RequestedAt, deletion.DeletionTime)
+	return err
+}
+
+// CancelDeletion removes the deletion of an account. It returns false if no deletion was scheduled for the account.
+func (c *accountDeletionDBModule) CancelDeletion(ctx context.Context, realmName, userID string) (bool, error) {
+	res, err := c.db.Exec(cancelDeletionStmt, realmName, userID)
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	return count > 0, err
+}
+
+// GetDueDeletions returns the accounts whose grace period is over, the oldest first
+func (c *accountDeletionDBModule) GetDueDeletions(ctx context.Context, now time.Time) ([]AccountDeletion, error) {
+	rows, err := c.db.Query(selectDueDeletionsStmt, now.UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deletions = []AccountDeletion{}
+	for rows.Next() {
+		var deletion AccountDeletion
+		if err = rows.Scan(&deletion.RealmName, &deletion.UserID, &deletion.Username, &deletion.RequestedAt, &deletion.DeletionTime); err != nil {
+			return nil, err
+		}
+		deletions = append(deletions, deletion)
+	}
+
+	// Return an error from rows if any error was encountered by Rows.Scan
+	return deletions, rows.Err()
+}
+
+// LockScheduler takes or renews the lock of the account deletions for the given instance of the bridge, so that a single
+// instance deletes the accounts. It returns false if another instance holds the lock. The lock is released when the lease
+// expires without being renewed.
+func (c *accountDeletionDBModule) LockScheduler(ctx context.Context, owner string, now time.Time, lease time.Duration) (bool, error) {
+	var nowMillis = now.UnixNano() / int64(time.Millisecond)
+	var expiresAt = now.Add(lease).UnixNano() / int64(time.Millisecond)
+	res, err := c.db.Exec(lockSchedulerStmt, accountDeletionLock, owner, expiresAt, nowMillis)
+	if err != nil {
+		return false, err
+	}
+	// 1 when the lock is created, 2 when it is taken or renewed, 0 when another instance holds it
+	count, err := res.RowsAffected()
+	return count > 0, err
+}
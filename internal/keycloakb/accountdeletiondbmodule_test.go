@@ -0,0 +1,74 @@
+package keycloakb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type rowsAffected int64
+
+func (r rowsAffected) LastInsertId() (int64, error) { return 0, nil }
+func (r rowsAffected) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestAccountDeletionDBModule(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewDBConfiguration(mockCtrl)
+
+	var module = NewAccountDeletionDBModule(mockDB)
+	var ctx = context.Background()
+
+	t.Run("Schedule a deletion", func(t *testing.T) {
+		var deletion = AccountDeletion{RealmName: "realm", UserID: "userId", Username: "username", RequestedAt: 1000, DeletionTime: 2000}
+		mockDB.EXPECT().Exec(scheduleDeletionStmt, "realm", "userId", "username", int64(1000), int64(2000), "username", int64(1000), int64(2000)).Return(rowsAffected(1), nil).Times(1)
+		assert.Nil(t, module.ScheduleDeletion(ctx, deletion))
+	})
+
+	t.Run("Cancel a deletion", func(t *testing.T) {
+		mockDB.EXPECT().Exec(cancelDeletionStmt, "realm", "userId").Return(rowsAffected(1), nil).Times(1)
+		var canceled, err = module.CancelDeletion(ctx, "realm", "userId")
+		assert.Nil(t, err)
+		assert.True(t, canceled)
+
+		mockDB.EXPECT().Exec(cancelDeletionStmt, "realm", "other").Return(rowsAffected(0), nil).Times(1)
+		canceled, err = module.CancelDeletion(ctx, "realm", "other")
+		assert.Nil(t, err)
+		assert.False(t, canceled)
+
+		mockDB.EXPECT().Exec(cancelDeletionStmt, "realm", "userId").Return(nil, errors.New("db error")).Times(1)
+		_, err = module.CancelDeletion(ctx, "realm", "userId")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Lock the scheduler", func(t *testing.T) {
+		var now = time.Unix(1000, 0)
+		mockDB.EXPECT().Exec(lockSchedulerStmt, accountDeletionLock, "instance", int64(1060000), int64(1000000)).Return(rowsAffected(2), nil).Times(1)
+		var locked, err = module.LockScheduler(ctx, "instance", now, time.Minute)
+		assert.Nil(t, err)
+		assert.True(t, locked)
+
+		mockDB.EXPECT().Exec(lockSchedulerStmt, accountDeletionLock, "other", int64(1060000), int64(1000000)).Return(rowsAffected(0), nil).Times(1)
+		locked, err = module.LockScheduler(ctx, "other", now, time.Minute)
+		assert.Nil(t, err)
+		assert.False(t, locked)
+
+		mockDB.EXPECT().Exec(lockSchedulerStmt, accountDeletionLock, "instance", int64(1060000), int64(1000000)).Return(nil, errors.New("db error")).Times(1)
+		_, err = module.LockScheduler(ctx, "instance", now, time.Minute)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Get due deletions fails", func(t *testing.T) {
+		var now = time.Now()
+		var rows sql.Rows
+		mockDB.EXPECT().Query(selectDueDeletionsStmt, now.UnixNano()/int64(time.Millisecond)).Return(&rows, errors.New("db error")).Times(1)
+		var _, err = module.GetDueDeletions(ctx, now)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,163 @@
+package keycloakb
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+)
+
+// Types of custom user attributes
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeDate    = "date"
+)
+
+// Editors of custom user attributes
+const (
+	AttributeEditorSelfService = "selfService"
+	AttributeEditorBackOffice  = "backOffice"
+)
+
+// reservedUserAttributes are the Keycloak attributes already handled by the bridge through dedicated fields
+var reservedUserAttributes = map[string]bool{
+	"phoneNumber":         true,
+	"phoneNumberVerified": true,
+	"label":               true,
+	"gender":              true,
+	"birthDate":           true,
+	"locale":              true,
+}
+
+// IsReservedUserAttribute returns true if the attribute is managed through a dedicated field and can't be used as a custom attribute
+func IsReservedUserAttribute(name string) bool {
+	return reservedUserAttributes[name]
+}
+
+// IsValidAttributeType returns true if the given type is a supported custom attribute type
+func IsValidAttributeType(attrType string) bool {
+	switch attrType {
+	case AttributeTypeString, AttributeTypeNumber, AttributeTypeBoolean, AttributeTypeDate:
+		return true
+	}
+	return false
+}
+
+// ValidateUserAttributes checks custom attributes and attribute deletions against the attribute schema of a realm.
+// Attributes must be defined in the schema and editable by the given editor, their values must match the declared type
+// and regular expression. Required attributes can't be deleted and must be provided when creating a user.
+func ValidateUserAttributes(schema []dto.AttributeDefinition, editor string, attributes map[string][]string, deleted []string, creation bool) error {
+	var definitions = make(map[string]dto.AttributeDefinition)
+	for _, def := range schema {
+		if def.Name != nil {
+			definitions[*def.Name] = def
+		}
+	}
+
+	for name, values := range attributes {
+		def, ok := definitions[name]
+		if !ok || !isEditableBy(def, editor) {
+			return errors.New(MsgErrInvalidParam + "." + Attributes + "." + name)
+		}
+		if isTrue(def.Required) && len(values) == 0 {
+			return errors.New(MsgErrMissingParam + "." + Attributes + "." + name)
+		}
+		for _, value := range values {
+			if !matchesAttributeDefinition(def, value) {
+				return errors.New(MsgErrInvalidParam + "." + Attributes + "." + name)
+			}
+		}
+	}
+
+	for _, name := range deleted {
+		def, ok := definitions[name]
+		if !ok || !isEditableBy(def, editor) || isTrue(def.Required) {
+			return errors.New(MsgErrInvalidParam + "." + DeletedAttributes + "." + name)
+		}
+	}
+
+	if creation {
+		for name, def := range definitions {
+			if _, ok := attributes[name]; isTrue(def.Required) && !ok {
+				return errors.New(MsgErrMissingParam + "." + Attributes + "." + name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isEditableBy(def dto.AttributeDefinition, editor string) bool {
+	switch editor {
+	case AttributeEditorSelfService:
+		return isTrue(def.EditableBySelfService)
+	case AttributeEditorBackOffice:
+		return isTrue(def.EditableByBackOffice)
+	}
+	return false
+}
+
+func matchesAttributeDefinition(def dto.AttributeDefinition, value string) bool {
+	var attrType = AttributeTypeString
+	if def.Type != nil {
+		attrType = *def.Type
+	}
+
+	var err error
+	switch attrType {
+	case AttributeTypeString:
+	case AttributeTypeNumber:
+		_, err = strconv.ParseFloat(value, 64)
+	case AttributeTypeBoolean:
+		_, err = strconv.ParseBool(value)
+	case AttributeTypeDate:
+		_, err = time.Parse("2006-01-02", value)
+	default:
+		return false
+	}
+	if err != nil {
+		return false
+	}
+
+	if def.RegExp != nil && *def.RegExp != "" {
+		res, _ := regexp.MatchString(*def.RegExp, value)
+		return res
+	}
+	return true
+}
+
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
+
+// PhoneNumberAttribute returns the phone number stored in the attributes of a user
+func PhoneNumberAttribute(attributes *map[string][]string) (string, bool) {
+	if attributes == nil {
+		return "", false
+	}
+	var values, ok = (*attributes)["phoneNumber"]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// ChangedAttributes returns the attributes whose values differ from the current ones
+func ChangedAttributes(attributes *map[string][]string, current *map[string][]string) *map[string][]string {
+	if attributes == nil {
+		return nil
+	}
+	var res = make(map[string][]string)
+	for key, values := range *attributes {
+		if current != nil && reflect.DeepEqual((*current)[key], values) {
+			continue
+		}
+		res[key] = values
+	}
+	return &res
+}
@@ -0,0 +1,80 @@
+package keycloakb
+
+import (
+	"testing"
+
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReservedUserAttribute(t *testing.T) {
+	assert.True(t, IsReservedUserAttribute("phoneNumber"))
+	assert.True(t, IsReservedUserAttribute("locale"))
+	assert.False(t, IsReservedUserAttribute("department"))
+}
+
+func TestValidateUserAttributes(t *testing.T) {
+	var trueBool = true
+	var department, level, active, hired = "department", "level", "active", "hired"
+	var typeNumber, typeBoolean, typeDate = AttributeTypeNumber, AttributeTypeBoolean, AttributeTypeDate
+	var regExp = "^[A-Z]+$"
+	var schema = []dto.AttributeDefinition{
+		{Name: &department, RegExp: &regExp, Required: &trueBool, EditableByBackOffice: &trueBool},
+		{Name: &level, Type: &typeNumber, EditableByBackOffice: &trueBool, EditableBySelfService: &trueBool},
+		{Name: &active, Type: &typeBoolean, EditableByBackOffice: &trueBool},
+		{Name: &hired, Type: &typeDate, EditableByBackOffice: &trueBool},
+	}
+
+	t.Run("Valid attributes", func(t *testing.T) {
+		var attributes = map[string][]string{department: {"IT"}, level: {"3.5"}, active: {"true"}, hired: {"2019-10-01"}}
+		assert.Nil(t, ValidateUserAttributes(schema, AttributeEditorBackOffice, attributes, nil, true))
+	})
+	t.Run("Valid deletion", func(t *testing.T) {
+		assert.Nil(t, ValidateUserAttributes(schema, AttributeEditorBackOffice, nil, []string{level}, false))
+	})
+	t.Run("Missing required attribute on creation", func(t *testing.T) {
+		var err = ValidateUserAttributes(schema, AttributeEditorBackOffice, map[string][]string{level: {"1"}}, nil, true)
+		assert.Equal(t, MsgErrMissingParam+"."+Attributes+"."+department, err.Error())
+	})
+	t.Run("Required attribute is not needed on update", func(t *testing.T) {
+		assert.Nil(t, ValidateUserAttributes(schema, AttributeEditorBackOffice, map[string][]string{level: {"1"}}, nil, false))
+	})
+	t.Run("Required attribute can't be deleted", func(t *testing.T) {
+		var err = ValidateUserAttributes(schema, AttributeEditorBackOffice, nil, []string{department}, false)
+		assert.Equal(t, MsgErrInvalidParam+"."+DeletedAttributes+"."+department, err.Error())
+	})
+	t.Run("Unknown attribute", func(t *testing.T) {
+		assert.NotNil(t, ValidateUserAttributes(schema, AttributeEditorBackOffice, map[string][]string{"unknown": {"x"}}, nil, false))
+		assert.NotNil(t, ValidateUserAttributes(schema, AttributeEditorBackOffice, nil, []string{"unknown"}, false))
+	})
+	t.Run("Attribute not editable by self-service", func(t *testing.T) {
+		assert.Nil(t, ValidateUserAttributes(schema, AttributeEditorSelfService, map[string][]string{level: {"2"}}, nil, false))
+		assert.NotNil(t, ValidateUserAttributes(schema, AttributeEditorSelfService, map[string][]string{active: {"true"}}, nil, false))
+	})
+	t.Run("Invalid values", func(t *testing.T) {
+		for name, value := range map[string]string{department: "it", level: "three", active: "yes", hired: "01.10.2019"} {
+			var err = ValidateUserAttributes(schema, AttributeEditorBackOffice, map[string][]string{name: {value}}, nil, false)
+			assert.Equal(t, MsgErrInvalidParam+"."+Attributes+"."+name, err.Error())
+		}
+	})
+}
+
+func TestPhoneNumberAttribute(t *testing.T) {
+	var _, ok = PhoneNumberAttribute(nil)
+	assert.False(t, ok)
+	_, ok = PhoneNumberAttribute(&map[string][]string{"phoneNumber": {}})
+	assert.False(t, ok)
+
+	phoneNumber, ok := PhoneNumberAttribute(&map[string][]string{"phoneNumber": {"+41791234567"}})
+	assert.True(t, ok)
+	assert.Equal(t, "+41791234567", phoneNumber)
+}
+
+func TestChangedAttributes(t *testing.T) {
+	var current = map[string][]string{"department": {"IT"}, "level": {"3"}}
+	var attributes = map[string][]string{"department": {"IT"}, "level": {"4"}, "office": {"Geneva"}}
+
+	assert.Nil(t, ChangedAttributes(nil, &current))
+	assert.Equal(t, &attributes, ChangedAttributes(&attributes, nil))
+	assert.Equal(t, &map[string][]string{"level": {"4"}, "office": {"Geneva"}}, ChangedAttributes(&attributes, &current))
+}
@@ -0,0 +1,88 @@
+package keycloakb
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hashPrefixLength is the number of hexadecimal characters of the SHA-1 hash used to select a range of the database
+const hashPrefixLength = 5
+
+// BreachedPasswordChecker checks whether a password is known to have been compromised
+type BreachedPasswordChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+type offlineBreachedPasswordChecker struct {
+	dbPath string
+}
+
+// NewOfflineBreachedPasswordChecker returns a checker backed by a local copy of a breached passwords database stored in the
+// k-anonymity format: the directory contains one file per SHA-1 prefix of 5 hexadecimal characters (e.g. 21BD1 or 21BD1.txt),
+// each line of the file being the suffix of a breached password hash followed by its number of occurrences (SUFFIX:COUNT).
+func NewOfflineBreachedPasswordChecker(dbPath string) (BreachedPasswordChecker, error) {
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New(dbPath + " is not a directory")
+	}
+	return &offlineBreachedPasswordChecker{
+		dbPath: dbPath,
+	}, nil
+}
+
+func (c *offlineBreachedPasswordChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	var hash = sha1.Sum([]byte(password))
+	var hexHash = strings.ToUpper(hex.EncodeToString(hash[:]))
+	var prefix, suffix = hexHash[:hashPrefixLength], hexHash[hashPrefixLength:]
+
+	file, err := c.openRange(prefix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// the range is not part of the database
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	var scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line = strings.TrimSpace(scanner.Text())
+		var parts = strings.SplitN(line, ":", 2)
+		if !strings.EqualFold(parts[0], suffix) {
+			continue
+		}
+		// ranges may be padded with entries having no occurrence
+		return len(parts) == 1 || strings.TrimSpace(parts[1]) != "0", nil
+	}
+	return false, scanner.Err()
+}
+
+func (c *offlineBreachedPasswordChecker) openRange(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(c.dbPath, prefix+".txt"))
+	if os.IsNotExist(err) {
+		return os.Open(filepath.Join(c.dbPath, prefix))
+	}
+	return file, err
+}
+
+type disabledBreachedPasswordChecker struct{}
+
+// NewDisabledBreachedPasswordChecker returns a checker considering that no password has been breached. It is used when
+// no breached passwords database is configured.
+func NewDisabledBreachedPasswordChecker() BreachedPasswordChecker {
+	return &disabledBreachedPasswordChecker{}
+}
+
+func (c *disabledBreachedPasswordChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,55 @@
+package keycloakb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfflineBreachedPasswordChecker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "breached-passwords")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	// SHA-1 of "P@ssw0rd" is 21BD12DC183F740EE76F27B78EB39C8AD972A757
+	// SHA-1 of "unknown" starts with a prefix which is not in the database
+	ioutil.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte("003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\r\n"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "21BD1"), []byte("2DC183F740EE76F27B78EB39C8AD972A757:0\n"), 0600)
+
+	var ctx = context.Background()
+	var checker, _ = NewOfflineBreachedPasswordChecker(dir)
+
+	t.Run("Breached password", func(t *testing.T) {
+		breached, err := checker.IsBreached(ctx, "password")
+		assert.Nil(t, err)
+		assert.True(t, breached)
+	})
+	t.Run("Padding entry", func(t *testing.T) {
+		breached, err := checker.IsBreached(ctx, "P@ssw0rd")
+		assert.Nil(t, err)
+		assert.False(t, breached)
+	})
+	t.Run("Range not in the database", func(t *testing.T) {
+		breached, err := checker.IsBreached(ctx, "unknown")
+		assert.Nil(t, err)
+		assert.False(t, breached)
+	})
+	t.Run("Invalid database path", func(t *testing.T) {
+		_, err := NewOfflineBreachedPasswordChecker(filepath.Join(dir, "unknown"))
+		assert.NotNil(t, err)
+
+		_, err = NewOfflineBreachedPasswordChecker(filepath.Join(dir, "5BAA6.txt"))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestDisabledBreachedPasswordChecker(t *testing.T) {
+	breached, err := NewDisabledBreachedPasswordChecker().IsBreached(context.Background(), "password")
+	assert.Nil(t, err)
+	assert.False(t, breached)
+}
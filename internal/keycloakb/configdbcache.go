@@ -0,0 +1,182 @@
+package keycloakb
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cm "github.com/cloudtrust/common-service/metrics"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+)
+
+// ConfigurationVersionsReader returns, for each realm, the version number of its current configuration
+type ConfigurationVersionsReader interface {
+	GetCurrentVersions(context.Context) (map[string]int, error)
+}
+
+// ConfigurationDBModuleCache is a ConfigurationDBModule keeping the configurations of the realms in memory
+type ConfigurationDBModuleCache interface {
+	ConfigurationDBModule
+	Invalidate(realmID string)
+	PollVersions(reader ConfigurationVersionsReader, c <-chan time.Time)
+}
+
+type cachedConfiguration struct {
+	config    dto.RealmConfiguration
+	expiresAt time.Time
+}
+
+// Caching middleware at module level.
+type configDBModuleCacheMW struct {
+	next     ConfigurationDBModule
+	ttl      time.Duration
+	hits     cm.Counter
+	misses   cm.Counter
+	logger   Logger
+	now      func() time.Time
+	mutex    sync.RWMutex
+	entries  map[string]cachedConfiguration
+	versions map[string]int
+}
+
+// MakeConfigurationDBModuleCacheMW makes a caching middleware at module level. The configurations are kept for the given
+// time to live and dropped as soon as they are stored through this middleware. Changes made by other instances are detected
+// by PollVersions. The returned configurations are shared and must not be modified.
+func MakeConfigurationDBModuleCacheMW(ttl time.Duration, hits, misses cm.Counter, logger Logger) func(ConfigurationDBModule) ConfigurationDBModuleCache {
+	return func(next ConfigurationDBModule) ConfigurationDBModuleCache {
+		return &configDBModuleCacheMW{
+			next:    next,
+			ttl:     ttl,
+			hits:    hits,
+			misses:  misses,
+			logger:  logger,
+			now:     time.Now,
+			entries: make(map[string]cachedConfiguration),
+		}
+	}
+}
+
+// configDBModuleCacheMW implements Module.
+func (m *configDBModuleCacheMW) StoreOrUpdate(ctx context.Context, realmID string, config dto.RealmConfiguration, author string, comment string) error {
+	defer m.Invalidate(realmID)
+	return m.next.StoreOrUpdate(ctx, realmID, config, author, comment)
+}
+
+// configDBModuleCacheMW implements Module.
+func (m *configDBModuleCacheMW) GetConfiguration(ctx context.Context, realmID string) (dto.RealmConfiguration, error) {
+	m.mutex.RLock()
+	entry, ok := m.entries[realmID]
+	m.mutex.RUnlock()
+
+	if ok && m.now().Before(entry.expiresAt) {
+		m.hits.Add(1)
+		return entry.config, nil
+	}
+	m.misses.Add(1)
+
+	// errors, including missing configurations, are not cached
+	config, err := m.next.GetConfiguration(ctx, realmID)
+	if err != nil {
+		return config, err
+	}
+
+	m.mutex.Lock()
+	m.entries[realmID] = cachedConfiguration{
+		config:    config,
+		expiresAt: m.now().Add(m.ttl),
+	}
+	m.mutex.Unlock()
+
+	return config, nil
+}
+
+// configDBModuleCacheMW implements Module.
+func (m *configDBModuleCacheMW) GetConfigurationHistory(ctx context.Context, realmID string) ([]dto.RealmConfigurationVersion, error) {
+	return m.next.GetConfigurationHistory(ctx, realmID)
+}
+
+// configDBModuleCacheMW implements Module.
+func (m *configDBModuleCacheMW) GetConfigurationVersion(ctx context.Context, realmID string, version int) (dto.RealmConfigurationVersion, error) {
+	return m.next.GetConfigurationVersion(ctx, realmID, version)
+}
+
+// Invalidate drops the cached configuration of a realm
+func (m *configDBModuleCacheMW) Invalidate(realmID string) {
+	m.mutex.Lock()
+	delete(m.entries, realmID)
+	m.mutex.Unlock()
+}
+
+// Middleware dropping the cached configurations when they are stored through another module
+type configDBModuleInvalidationMW struct {
+	next  ConfigurationDBModule
+	cache ConfigurationDBModuleCache
+}
+
+// MakeConfigurationDBModuleInvalidationMW makes a middleware dropping the configurations kept by the given cache once they
+// are stored through the wrapped module. It is used when the configurations are not written through the cache itself.
+func MakeConfigurationDBModuleInvalidationMW(cache ConfigurationDBModuleCache) func(ConfigurationDBModule) ConfigurationDBModule {
+	return func(next ConfigurationDBModule) ConfigurationDBModule {
+		return &configDBModuleInvalidationMW{
+			next:  next,
+			cache: cache,
+		}
+	}
+}
+
+// configDBModuleInvalidationMW implements Module.
+func (m *configDBModuleInvalidationMW) StoreOrUpdate(ctx context.Context, realmID string, config dto.RealmConfiguration, author string, comment string) error {
+	defer m.cache.Invalidate(realmID)
+	return m.next.StoreOrUpdate(ctx, realmID, config, author, comment)
+}
+
+// configDBModuleInvalidationMW implements Module.
+func (m *configDBModuleInvalidationMW) GetConfiguration(ctx context.Context, realmID string) (dto.RealmConfiguration, error) {
+	return m.next.GetConfiguration(ctx, realmID)
+}
+
+// configDBModuleInvalidationMW implements Module.
+func (m *configDBModuleInvalidationMW) GetConfigurationHistory(ctx context.Context, realmID string) ([]dto.RealmConfigurationVersion, error) {
+	return m.next.GetConfigurationHistory(ctx, realmID)
+}
+
+// configDBModuleInvalidationMW implements Module.
+func (m *configDBModuleInvalidationMW) GetConfigurationVersion(ctx context.Context, realmID string, version int) (dto.RealmConfigurationVersion, error) {
+	return m.next.GetConfigurationVersion(ctx, realmID, version)
+}
+
+// PollVersions reads the versions of the configurations each time a tick is received on the channel and drops the cached
+// configurations of the realms whose version changed since the previous tick. It returns when the channel is closed.
+func (m *configDBModuleCacheMW) PollVersions(reader ConfigurationVersionsReader, c <-chan time.Time) {
+	for range c {
+		m.refreshVersions(reader)
+	}
+}
+
+func (m *configDBModuleCacheMW) refreshVersions(reader ConfigurationVersionsReader) {
+	versions, err := reader.GetCurrentVersions(context.Background())
+	if err != nil {
+		m.logger.Warn("msg", "can't read the versions of the realm configurations", "err", err.Error())
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.versions != nil {
+		for realmID, version := range versions {
+			if previous, ok := m.versions[realmID]; !ok || previous != version {
+				delete(m.entries, realmID)
+			}
+		}
+		for realmID := range m.versions {
+			if _, ok := versions[realmID]; !ok {
+				delete(m.entries, realmID)
+			}
+		}
+	} else {
+		// the configurations cached before the first poll may be older than the versions read now
+		m.entries = make(map[string]cachedConfiguration)
+	}
+	m.versions = versions
+}
@@ -0,0 +1,168 @@
+package keycloakb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigurationDBModuleCacheMW(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockHits = mock.NewCounter(mockCtrl)
+	var mockMisses = mock.NewCounter(mockCtrl)
+
+	var now = time.Now()
+	var m = MakeConfigurationDBModuleCacheMW(time.Minute, mockHits, mockMisses, log.NewNopLogger())(mockModule)
+	m.(*configDBModuleCacheMW).now = func() time.Time { return now }
+
+	var ctx = context.Background()
+	var realmID = "realmID"
+	var enabled = true
+	var config = dto.RealmConfiguration{MFARequired: &enabled}
+
+	t.Run("Miss then hit", func(t *testing.T) {
+		mockMisses.EXPECT().Add(float64(1)).Times(1)
+		mockModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		var res, err = m.GetConfiguration(ctx, realmID)
+		assert.Nil(t, err)
+		assert.Equal(t, config, res)
+
+		mockHits.EXPECT().Add(float64(1)).Times(1)
+		res, err = m.GetConfiguration(ctx, realmID)
+		assert.Nil(t, err)
+		assert.Equal(t, config, res)
+	})
+
+	t.Run("Expired entry", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+		mockMisses.EXPECT().Add(float64(1)).Times(1)
+		mockModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		var _, err = m.GetConfiguration(ctx, realmID)
+		assert.Nil(t, err)
+	})
+
+	t.Run("StoreOrUpdate invalidates the entry", func(t *testing.T) {
+		mockModule.EXPECT().StoreOrUpdate(ctx, realmID, config, "author", "comment").Return(nil).Times(1)
+		assert.Nil(t, m.StoreOrUpdate(ctx, realmID, config, "author", "comment"))
+
+		mockMisses.EXPECT().Add(float64(1)).Times(1)
+		mockModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		var _, err = m.GetConfiguration(ctx, realmID)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Errors are not cached", func(t *testing.T) {
+		mockMisses.EXPECT().Add(float64(1)).Times(2)
+		mockModule.EXPECT().GetConfiguration(ctx, "other").Return(dto.RealmConfiguration{}, MissingRealmConfigurationErr{}).Times(2)
+		var _, err = m.GetConfiguration(ctx, "other")
+		assert.NotNil(t, err)
+		_, err = m.GetConfiguration(ctx, "other")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("History is not cached", func(t *testing.T) {
+		mockModule.EXPECT().GetConfigurationHistory(ctx, realmID).Return([]dto.RealmConfigurationVersion{}, nil).Times(1)
+		m.GetConfigurationHistory(ctx, realmID)
+
+		mockModule.EXPECT().GetConfigurationVersion(ctx, realmID, 2).Return(dto.RealmConfigurationVersion{}, nil).Times(1)
+		m.GetConfigurationVersion(ctx, realmID, 2)
+	})
+}
+
+func TestConfigurationDBModuleCachePollVersions(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockReader = mock.NewConfigurationVersionsReader(mockCtrl)
+	var mockHits = mock.NewCounter(mockCtrl)
+	var mockMisses = mock.NewCounter(mockCtrl)
+
+	var m = MakeConfigurationDBModuleCacheMW(time.Hour, mockHits, mockMisses, log.NewNopLogger())(mockModule)
+
+	var ctx = context.Background()
+	var config = dto.RealmConfiguration{}
+	mockHits.EXPECT().Add(gomock.Any()).AnyTimes()
+	mockMisses.EXPECT().Add(gomock.Any()).AnyTimes()
+
+	var poll = func(versions map[string]int, err error) {
+		var c = make(chan time.Time, 1)
+		mockReader.EXPECT().GetCurrentVersions(gomock.Any()).Return(versions, err).Times(1)
+		c <- time.Now()
+		close(c)
+		m.PollVersions(mockReader, c)
+	}
+
+	// First poll
+	mockModule.EXPECT().GetConfiguration(ctx, "realm1").Return(config, nil).Times(2)
+	m.GetConfiguration(ctx, "realm1")
+	poll(map[string]int{"realm1": 1, "realm2": 4}, nil)
+	m.GetConfiguration(ctx, "realm1")
+
+	// Load realm2 and realm3
+	mockModule.EXPECT().GetConfiguration(ctx, "realm2").Return(config, nil).Times(1)
+	mockModule.EXPECT().GetConfiguration(ctx, "realm3").Return(config, nil).Times(1)
+	m.GetConfiguration(ctx, "realm2")
+	m.GetConfiguration(ctx, "realm3")
+
+	// Reading the versions fails: nothing is invalidated
+	poll(nil, errors.New("db error"))
+	m.GetConfiguration(ctx, "realm1")
+	m.GetConfiguration(ctx, "realm2")
+
+	// realm1 is unchanged, realm2 has been updated and realm3 created by another instance
+	poll(map[string]int{"realm1": 1, "realm2": 5, "realm3": 1}, nil)
+	mockModule.EXPECT().GetConfiguration(ctx, "realm2").Return(config, nil).Times(1)
+	mockModule.EXPECT().GetConfiguration(ctx, "realm3").Return(config, nil).Times(1)
+	m.GetConfiguration(ctx, "realm1")
+	m.GetConfiguration(ctx, "realm2")
+	m.GetConfiguration(ctx, "realm3")
+}
+
+func TestConfigurationDBModuleInvalidationMW(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockReader = mock.NewConfigurationDBModule(mockCtrl)
+	var mockWriter = mock.NewConfigurationDBModule(mockCtrl)
+	var mockHits = mock.NewCounter(mockCtrl)
+	var mockMisses = mock.NewCounter(mockCtrl)
+
+	var cache = MakeConfigurationDBModuleCacheMW(time.Hour, mockHits, mockMisses, log.NewNopLogger())(mockReader)
+	var m = MakeConfigurationDBModuleInvalidationMW(cache)(mockWriter)
+
+	var ctx = context.Background()
+	var realmID = "realmID"
+	var config = dto.RealmConfiguration{}
+	mockHits.EXPECT().Add(gomock.Any()).AnyTimes()
+	mockMisses.EXPECT().Add(gomock.Any()).AnyTimes()
+
+	t.Run("StoreOrUpdate invalidates the entry of the cache", func(t *testing.T) {
+		mockReader.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(2)
+		cache.GetConfiguration(ctx, realmID)
+		cache.GetConfiguration(ctx, realmID)
+
+		mockWriter.EXPECT().StoreOrUpdate(ctx, realmID, config, "author", "comment").Return(nil).Times(1)
+		assert.Nil(t, m.StoreOrUpdate(ctx, realmID, config, "author", "comment"))
+
+		cache.GetConfiguration(ctx, realmID)
+	})
+
+	t.Run("Reads are not cached", func(t *testing.T) {
+		mockWriter.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		m.GetConfiguration(ctx, realmID)
+
+		mockWriter.EXPECT().GetConfigurationHistory(ctx, realmID).Return([]dto.RealmConfigurationVersion{}, nil).Times(1)
+		m.GetConfigurationHistory(ctx, realmID)
+
+		mockWriter.EXPECT().GetConfigurationVersion(ctx, realmID, 2).Return(dto.RealmConfigurationVersion{}, nil).Times(1)
+		m.GetConfigurationVersion(ctx, realmID, 2)
+	})
+}
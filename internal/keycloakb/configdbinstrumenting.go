@@ -17,8 +17,10 @@ type configDBModuleInstrumentingMW struct {
 
 // ConfigurationDBModule is the interface of the configuration module.
 type ConfigurationDBModule interface {
-	StoreOrUpdate(context.Context, string, dto.RealmConfiguration) error
+	StoreOrUpdate(context.Context, string, dto.RealmConfiguration, string, string) error
 	GetConfiguration(context.Context, string) (dto.RealmConfiguration, error)
+	GetConfigurationHistory(context.Context, string) ([]dto.RealmConfigurationVersion, error)
+	GetConfigurationVersion(context.Context, string, int) (dto.RealmConfigurationVersion, error)
 }
 
 // MakeConfigurationDBModuleInstrumentingMW makes an instrumenting middleware at module level.
@@ -32,11 +34,11 @@ func MakeConfigurationDBModuleInstrumentingMW(h cm.Histogram) func(Configuration
 }
 
 // configDBModuleInstrumentingMW implements Module.
-func (m *configDBModuleInstrumentingMW) StoreOrUpdate(ctx context.Context, realmName string, config dto.RealmConfiguration) error {
+func (m *configDBModuleInstrumentingMW) StoreOrUpdate(ctx context.Context, realmName string, config dto.RealmConfiguration, author string, comment string) error {
 	defer func(begin time.Time) {
 		m.h.With("correlation_id", ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
 	}(time.Now())
-	return m.next.StoreOrUpdate(ctx, realmName, config)
+	return m.next.StoreOrUpdate(ctx, realmName, config, author, comment)
 }
 
 // configDBModuleInstrumentingMW implements Module.
@@ -46,3 +48,19 @@ func (m *configDBModuleInstrumentingMW) GetConfiguration(ctx context.Context, re
 	}(time.Now())
 	return m.next.GetConfiguration(ctx, realmName)
 }
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetConfigurationHistory(ctx context.Context, realmName string) ([]dto.RealmConfigurationVersion, error) {
+	defer func(begin time.Time) {
+		m.h.With("correlation_id", ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetConfigurationHistory(ctx, realmName)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetConfigurationVersion(ctx context.Context, realmName string, version int) (dto.RealmConfigurationVersion, error) {
+	defer func(begin time.Time) {
+		m.h.With("correlation_id", ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetConfigurationVersion(ctx, realmName, version)
+}
@@ -42,15 +42,27 @@ func TestComponentInstrumentingMW(t *testing.T) {
 	assert.Panics(t, f)
 
 	// Update configuration.
-	mockComponent.EXPECT().StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{}).Return(nil).Times(1)
+	mockComponent.EXPECT().StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{}, "author", "comment").Return(nil).Times(1)
 	mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
 	mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
-	m.StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{})
+	m.StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{}, "author", "comment")
 
 	// Update configuration without correlation ID.
-	mockComponent.EXPECT().StoreOrUpdate(context.Background(), "realmID", dto.RealmConfiguration{}).Return(nil).Times(1)
+	mockComponent.EXPECT().StoreOrUpdate(context.Background(), "realmID", dto.RealmConfiguration{}, "author", "comment").Return(nil).Times(1)
 	f = func() {
-		m.StoreOrUpdate(context.Background(), "realmID", dto.RealmConfiguration{})
+		m.StoreOrUpdate(context.Background(), "realmID", dto.RealmConfiguration{}, "author", "comment")
 	}
 	assert.Panics(t, f)
+
+	// Get configuration history.
+	mockComponent.EXPECT().GetConfigurationHistory(ctx, "realmID").Return([]dto.RealmConfigurationVersion{}, nil).Times(1)
+	mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+	mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+	m.GetConfigurationHistory(ctx, "realmID")
+
+	// Get configuration version.
+	mockComponent.EXPECT().GetConfigurationVersion(ctx, "realmID", 3).Return(dto.RealmConfigurationVersion{}, nil).Times(1)
+	mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+	mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+	m.GetConfigurationVersion(ctx, "realmID", 3)
 }
@@ -4,21 +4,78 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"strconv"
+	"time"
 
+	"github.com/cloudtrust/common-service/database"
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/sqltypes"
 )
 
 const (
-	updateConfigStmt = `INSERT INTO realm_configuration (realm_id, configuration) 
-	  VALUES (?, ?) 
-	  ON DUPLICATE KEY UPDATE configuration = ?;`
-	selectConfigStmt = `SELECT configuration FROM realm_configuration WHERE (realm_id = ?)`
+	updateConfigStmt = `INSERT INTO realm_configuration (realm_id, configuration, version) 
+	  VALUES (?, ?, 1) 
+	  ON DUPLICATE KEY UPDATE configuration = ?, version = version + 1;`
+	selectConfigStmt  = `SELECT configuration FROM realm_configuration WHERE (realm_id = ?)`
+	insertVersionStmt = `INSERT INTO realm_configuration_history (realm_id, version, configuration, author, comment, created_at)
+	  SELECT ?, IFNULL(MAX(version), 0) + 1, ?, ?, ?, ? FROM realm_configuration_history WHERE (realm_id = ?);`
+	selectVersionsStmt = `SELECT version, configuration, author, comment, created_at FROM realm_configuration_history
+	  WHERE (realm_id = ?) ORDER BY version DESC`
+	selectCurrentVersionsStmt = `SELECT realm_id, version FROM realm_configuration`
+	selectVersionStmt         = `SELECT version, configuration, author, comment, created_at FROM realm_configuration_history
+	  WHERE (realm_id = ?) AND (version = ?)`
 )
 
 // DBConfiguration interface
 type DBConfiguration interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(query string, args ...interface{}) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (sqltypes.Transaction, error)
+}
+
+type configurationDB struct {
+	database.CloudtrustDB
+}
+
+// NewConfigurationDB returns a DBConfiguration on the given database connection
+func NewConfigurationDB(db database.CloudtrustDB) DBConfiguration {
+	return &configurationDB{
+		CloudtrustDB: db,
+	}
+}
+
+// BeginTx starts a transaction. The statements of the connections which don't support transactions (i.e. the no-op
+// database) are executed directly.
+func (db *configurationDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (sqltypes.Transaction, error) {
+	var sqlDB, ok = db.CloudtrustDB.(interface {
+		BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return &directTransaction{db: db.CloudtrustDB}, nil
+	}
+
+	tx, err := sqlDB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+type directTransaction struct {
+	db database.CloudtrustDB
+}
+
+func (t *directTransaction) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.db.Exec(query, args...)
+}
+
+func (t *directTransaction) Commit() error {
+	return nil
+}
+
+func (t *directTransaction) Rollback() error {
+	return nil
 }
 
 type configurationDBModule struct {
@@ -34,6 +91,16 @@ func (e MissingRealmConfigurationErr) Error() string {
 	return "RealmConfiguration is not configured for " + e.realmID
 }
 
+// MissingRealmConfigurationVersionErr is the error thrown if the requested version of a configuration is not found in DB
+type MissingRealmConfigurationVersionErr struct {
+	realmID string
+	version int
+}
+
+func (e MissingRealmConfigurationVersionErr) Error() string {
+	return "RealmConfiguration version " + strconv.Itoa(e.version) + " does not exist for " + e.realmID
+}
+
 // NewConfigurationDBModule returns a ConfigurationDB module.
 func NewConfigurationDBModule(db DBConfiguration) *configurationDBModule {
 	return &configurationDBModule{
@@ -41,16 +108,37 @@ func NewConfigurationDBModule(db DBConfiguration) *configurationDBModule {
 	}
 }
 
-func (c *configurationDBModule) StoreOrUpdate(context context.Context, realmID string, config dto.RealmConfiguration) error {
+// StoreOrUpdate stores a new version of the configuration of a realm and makes it the current one. Previous versions
+// are kept in the history together with their author and change comment. Both are written in the same transaction: the
+// unique key on the realm and the version of the history makes concurrent updates of a realm fail instead of sharing a version.
+func (c *configurationDBModule) StoreOrUpdate(context context.Context, realmID string, config dto.RealmConfiguration, author string, comment string) error {
 	// transform customConfig object into JSON string
 	configJSON, err := json.Marshal(config)
 	if err != nil {
 		return err
 	}
 
+	tx, err := c.db.BeginTx(context, nil)
+	if err != nil {
+		return err
+	}
+	// no effect once the transaction is committed
+	defer tx.Rollback()
+
+	// keep track of the new version
+	var now = time.Now().UnixNano() / int64(time.Millisecond)
+	_, err = tx.Exec(insertVersionStmt, realmID, string(configJSON), author, comment, now, realmID)
+	if err != nil {
+		return err
+	}
+
 	// update value in DB
-	_, err = c.db.Exec(updateConfigStmt, realmID, string(configJSON), string(configJSON))
-	return err
+	_, err = tx.Exec(updateConfigStmt, realmID, string(configJSON), string(configJSON))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (c *configurationDBModule) GetConfiguration(context context.Context, realmID string) (dto.RealmConfiguration, error) {
@@ -72,3 +160,78 @@ func (c *configurationDBModule) GetConfiguration(context context.Context, realmI
 		return config, err
 	}
 }
+
+// GetConfigurationHistory returns all the stored versions of the configuration of a realm, the most recent first
+func (c *configurationDBModule) GetConfigurationHistory(context context.Context, realmID string) ([]dto.RealmConfigurationVersion, error) {
+	rows, err := c.db.Query(selectVersionsStmt, realmID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions = []dto.RealmConfigurationVersion{}
+	for rows.Next() {
+		var version dto.RealmConfigurationVersion
+		if version, err = scanConfigurationVersion(rows); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	// Return an error from rows if any error was encountered by Rows.Scan
+	return versions, rows.Err()
+}
+
+// GetConfigurationVersion returns the given version of the configuration of a realm
+func (c *configurationDBModule) GetConfigurationVersion(context context.Context, realmID string, version int) (dto.RealmConfigurationVersion, error) {
+	row := c.db.QueryRow(selectVersionStmt, realmID, version)
+
+	var res, err = scanConfigurationVersion(row)
+	if err == sql.ErrNoRows {
+		return dto.RealmConfigurationVersion{}, MissingRealmConfigurationVersionErr{
+			realmID: realmID,
+			version: version,
+		}
+	}
+	return res, err
+}
+
+// GetCurrentVersions returns, for each realm, the version number of its current configuration. The number is incremented
+// each time the configuration of the realm is stored.
+func (c *configurationDBModule) GetCurrentVersions(context context.Context) (map[string]int, error) {
+	rows, err := c.db.Query(selectCurrentVersionsStmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions = make(map[string]int)
+	for rows.Next() {
+		var realmID string
+		var version int
+		if err = rows.Scan(&realmID, &version); err != nil {
+			return nil, err
+		}
+		versions[realmID] = version
+	}
+
+	// Return an error from rows if any error was encountered by Rows.Scan
+	return versions, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanConfigurationVersion(row rowScanner) (dto.RealmConfigurationVersion, error) {
+	var configJSON string
+	var res dto.RealmConfigurationVersion
+
+	if err := row.Scan(&res.Version, &configJSON, &res.Author, &res.Comment, &res.Time); err != nil {
+		return dto.RealmConfigurationVersion{}, err
+	}
+	if err := json.Unmarshal([]byte(configJSON), &res.Configuration); err != nil {
+		return dto.RealmConfigurationVersion{}, err
+	}
+	return res, nil
+}
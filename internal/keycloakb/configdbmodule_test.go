@@ -2,6 +2,8 @@ package keycloakb
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"testing"
 
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
@@ -14,9 +16,60 @@ func TestConfigurationDBModule(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
 	var mockDB = mock.NewDBConfiguration(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
 
-	mockDB.EXPECT().Exec(gomock.Any(), "realmId", gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
 	var configDBModule = NewConfigurationDBModule(mockDB)
-	var err = configDBModule.StoreOrUpdate(context.Background(), "realmId", dto.RealmConfiguration{})
-	assert.Nil(t, err)
+	var ctx = context.Background()
+
+	t.Run("Store a new version", func(t *testing.T) {
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(mockTx, nil).Times(1)
+		mockTx.EXPECT().Exec(insertVersionStmt, "realmId", gomock.Any(), "author", "comment", gomock.Any(), "realmId").Return(nil, nil).Times(1)
+		mockTx.EXPECT().Exec(updateConfigStmt, "realmId", gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+		mockTx.EXPECT().Commit().Return(nil).Times(1)
+		mockTx.EXPECT().Rollback().Return(nil).Times(1)
+		var err = configDBModule.StoreOrUpdate(ctx, "realmId", dto.RealmConfiguration{}, "author", "comment")
+		assert.Nil(t, err)
+	})
+
+	t.Run("Transaction can't be started", func(t *testing.T) {
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(nil, errors.New("db error")).Times(1)
+		var err = configDBModule.StoreOrUpdate(ctx, "realmId", dto.RealmConfiguration{}, "author", "comment")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Current configuration is not updated if the version can't be stored", func(t *testing.T) {
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(mockTx, nil).Times(1)
+		mockTx.EXPECT().Exec(insertVersionStmt, "realmId", gomock.Any(), "author", "comment", gomock.Any(), "realmId").Return(nil, errors.New("db error")).Times(1)
+		mockTx.EXPECT().Rollback().Return(nil).Times(1)
+		var err = configDBModule.StoreOrUpdate(ctx, "realmId", dto.RealmConfiguration{}, "author", "comment")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Version is rolled back if the current configuration can't be updated", func(t *testing.T) {
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(mockTx, nil).Times(1)
+		mockTx.EXPECT().Exec(insertVersionStmt, "realmId", gomock.Any(), "author", "comment", gomock.Any(), "realmId").Return(nil, nil).Times(1)
+		mockTx.EXPECT().Exec(updateConfigStmt, "realmId", gomock.Any(), gomock.Any()).Return(nil, errors.New("db error")).Times(1)
+		mockTx.EXPECT().Rollback().Return(nil).Times(1)
+		var err = configDBModule.StoreOrUpdate(ctx, "realmId", dto.RealmConfiguration{}, "author", "comment")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Get current versions fails", func(t *testing.T) {
+		var rows sql.Rows
+		mockDB.EXPECT().Query(selectCurrentVersionsStmt).Return(&rows, errors.New("db error")).Times(1)
+		var _, err = configDBModule.GetCurrentVersions(context.Background())
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Get history fails", func(t *testing.T) {
+		var rows sql.Rows
+		mockDB.EXPECT().Query(selectVersionsStmt, "realmId").Return(&rows, errors.New("db error")).Times(1)
+		var _, err = configDBModule.GetConfigurationHistory(context.Background(), "realmId")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestMissingRealmConfigurationVersionErr(t *testing.T) {
+	var err = MissingRealmConfigurationVersionErr{realmID: "realmId", version: 4}
+	assert.Equal(t, "RealmConfiguration version 4 does not exist for realmId", err.Error())
 }
@@ -0,0 +1,40 @@
+package keycloakb
+
+// Categories of credentials
+const (
+	CredentialCategoryPassword = "password"
+	CredentialCategoryOTP      = "otp"
+	CredentialCategoryWebAuthn = "webauthn"
+	CredentialCategorySMS      = "sms"
+	CredentialCategoryOther    = "other"
+)
+
+// SecondFactorCategories are the categories of credentials used as second factor
+var SecondFactorCategories = []string{CredentialCategoryOTP, CredentialCategoryWebAuthn, CredentialCategorySMS}
+
+// credentialCategories maps the Keycloak credential types to their category
+var credentialCategories = map[string]string{
+	"password":              CredentialCategoryPassword,
+	"password-history":      CredentialCategoryPassword,
+	"otp":                   CredentialCategoryOTP,
+	"totp":                  CredentialCategoryOTP,
+	"hotp":                  CredentialCategoryOTP,
+	"webauthn":              CredentialCategoryWebAuthn,
+	"webauthn-passwordless": CredentialCategoryWebAuthn,
+	"sms":                   CredentialCategorySMS,
+	"ctsms":                 CredentialCategorySMS,
+}
+
+// GetCredentialCategory returns the category of a Keycloak credential type. Unknown types are considered as second factors
+// of the category other.
+func GetCredentialCategory(credentialType string) string {
+	if category, ok := credentialCategories[credentialType]; ok {
+		return category
+	}
+	return CredentialCategoryOther
+}
+
+// IsSecondFactor returns true if the Keycloak credential type is a second factor
+func IsSecondFactor(credentialType string) bool {
+	return GetCredentialCategory(credentialType) != CredentialCategoryPassword
+}
@@ -0,0 +1,22 @@
+package keycloakb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCredentialCategory(t *testing.T) {
+	assert.Equal(t, CredentialCategoryPassword, GetCredentialCategory("password"))
+	assert.Equal(t, CredentialCategoryOTP, GetCredentialCategory("totp"))
+	assert.Equal(t, CredentialCategoryOTP, GetCredentialCategory("hotp"))
+	assert.Equal(t, CredentialCategoryWebAuthn, GetCredentialCategory("webauthn-passwordless"))
+	assert.Equal(t, CredentialCategorySMS, GetCredentialCategory("ctsms"))
+	assert.Equal(t, CredentialCategoryOther, GetCredentialCategory("ctpapercard"))
+}
+
+func TestIsSecondFactor(t *testing.T) {
+	assert.False(t, IsSecondFactor("password"))
+	assert.True(t, IsSecondFactor("totp"))
+	assert.True(t, IsSecondFactor("ctpapercard"))
+}
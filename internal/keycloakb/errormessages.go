@@ -13,42 +13,84 @@ const (
 	MsgErrCannotSaveConfigInDB = "cannotSaveConfigInDB"
 	MsgErrCannotUpdate         = "cannotUpdate"
 	MsgErrUnknown              = "unknowError"
+	MsgErrPreconditionFailed   = "preconditionFailed"
+	MsgErrBreachedPassword     = "breachedPassword"
+	MsgErrCannotDelete         = "cannotDelete"
+	MsgErrDuplicateUser        = "duplicateUser"
+	MsgErrNoPendingChange      = "noPendingChange"
+	MsgErrExpiredCode          = "expiredCode"
+	MsgErrNotEditable          = "notEditable"
+	MsgErrTooManyResults       = "tooManyResults"
 
-	CurrentPassword    = "currentPassword"
-	NewPassword        = "newPassword"
-	ConfirmPassword    = "confirmPassword"
-	Password           = "password"
-	Type               = "type"
-	ID                 = "id"
-	Label              = "label"
-	UserID             = "userId"
-	Username           = "username"
-	User               = "user"
-	Email              = "email"
-	Firstname          = "firstname"
-	Lastname           = "lastname"
-	PhoneNumber        = "phoneNumber"
-	Gender             = "gender"
-	Birthdate          = "birthdate"
-	GroudID            = "groupId"
-	GroudIDs           = "groupIds"
-	RoleID             = "roleId"
-	Locale             = "locale"
-	Description        = "description"
-	ContainerID        = "containerId"
-	DefaultClientID    = "defaultClientId"
-	DefaultRedirectURI = "defaultRedirectURI"
-	RequiredAction     = "requiredAction"
-	DurationLabel      = "durationLabel"
-	Body               = "body"
-	Flatbuffer         = "flatbuffer"
-	Realm              = "realm"
-	KeycloakRealms     = "keycloakRealms"
-	Config             = "config"
-	Response           = "response"
-	ListOfRealms       = "listOfRealms"
-	Groups             = "groups"
-	ClientID           = "clientId"
-	RedirectURI        = "redirectURI"
-	Exclude            = "exclude"
+	CurrentPassword             = "currentPassword"
+	NewPassword                 = "newPassword"
+	ConfirmPassword             = "confirmPassword"
+	Password                    = "password"
+	Type                        = "type"
+	ID                          = "id"
+	Label                       = "label"
+	UserID                      = "userId"
+	Username                    = "username"
+	User                        = "user"
+	Email                       = "email"
+	Firstname                   = "firstname"
+	Lastname                    = "lastname"
+	PhoneNumber                 = "phoneNumber"
+	Gender                      = "gender"
+	Birthdate                   = "birthdate"
+	GroudID                     = "groupId"
+	GroudIDs                    = "groupIds"
+	RoleID                      = "roleId"
+	Locale                      = "locale"
+	Description                 = "description"
+	ContainerID                 = "containerId"
+	DefaultClientID             = "defaultClientId"
+	DefaultRedirectURI          = "defaultRedirectURI"
+	RequiredAction              = "requiredAction"
+	DurationLabel               = "durationLabel"
+	Body                        = "body"
+	Flatbuffer                  = "flatbuffer"
+	Realm                       = "realm"
+	KeycloakRealms              = "keycloakRealms"
+	Config                      = "config"
+	Response                    = "response"
+	ListOfRealms                = "listOfRealms"
+	Groups                      = "groups"
+	ClientID                    = "clientId"
+	RedirectURI                 = "redirectURI"
+	Exclude                     = "exclude"
+	UserIDs                     = "userIds"
+	Search                      = "search"
+	SearchCriteria              = "searchCriteria"
+	Max                         = "max"
+	BulkAction                  = "bulkAction"
+	IfMatch                     = "ifMatch"
+	Attributes                  = "attributes"
+	DeletedAttributes           = "deletedAttributes"
+	UserAttributes              = "userAttributes"
+	Name                        = "name"
+	RegExp                      = "regexp"
+	SessionID                   = "sessionId"
+	PwdPolicy                   = "passwordPolicy"
+	LastSecondFactor            = "lastSecondFactor"
+	BaseURL                     = "baseUrl"
+	Protocol                    = "protocol"
+	AccessType                  = "accessType"
+	RedirectURIs                = "redirectUris"
+	WebOrigins                  = "webOrigins"
+	OTPPolicy                   = "otpPolicy"
+	NotificationGateway         = "notificationGateway"
+	BruteForceDetection         = "bruteForceDetection"
+	SMTPFrom                    = "smtpFrom"
+	LoginTheme                  = "loginTheme"
+	SupportedLocales            = "supportedLocales"
+	ConfigVersion               = "version"
+	From                        = "from"
+	To                          = "to"
+	DuplicateCheck              = "duplicateCheck"
+	PhoneNumberCountry          = "phoneNumberCountry"
+	AllowedPhoneNumberCountries = "allowedPhoneNumberCountries"
+	Code                        = "code"
+	AccountDeletionGracePeriod  = "accountDeletionGracePeriod"
+	OTP                         = "otp"
 )
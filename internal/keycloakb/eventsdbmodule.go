@@ -18,6 +18,7 @@ type EventsDBModule interface {
 	GetEvents(context.Context, map[string]string) ([]api.AuditRepresentation, error)
 	GetEventsSummary(context.Context) (api.EventSummaryRepresentation, error)
 	GetLastConnection(context.Context, string) (int64, error)
+	GetLastUserConnection(context.Context, string, string) (int64, error)
 	GetTotalConnectionsCount(context.Context, string, string) (int64, error)
 }
 
@@ -45,11 +46,12 @@ type selectAuditEventsParameters struct {
 }
 
 const (
+	// ctEventType can be a comma separated list of event types
 	whereAuditEvents = `
 	WHERE origin = IFNULL(?, origin)
 	AND realm_name = IFNULL(?, realm_name)
 	AND user_id = IFNULL(?, user_id)
-	AND ct_event_type = IFNULL(?, ct_event_type)
+	AND IFNULL(FIND_IN_SET(ct_event_type, ?), ct_event_type IS NOT NULL) > 0
 	AND unix_timestamp(audit_time) between IFNULL(?, unix_timestamp(audit_time)) and IFNULL(?, unix_timestamp(audit_time))
 	AND ct_event_type <> IFNULL(?, 'not-a-ct-event-type')
 	`
@@ -62,6 +64,7 @@ const (
 		`
 	selectCountAuditEventsStmt        = `SELECT count(1) FROM audit ` + whereAuditEvents
 	selectLastConnectionTimeStmt      = `SELECT ifnull(unix_timestamp(max(audit_time)), 0) FROM audit WHERE realm_name=? AND ct_event_type='LOGON_OK'`
+	selectLastUserConnectionTimeStmt  = `SELECT ifnull(unix_timestamp(max(audit_time)), 0) FROM audit WHERE realm_name=? AND user_id=? AND ct_event_type='LOGON_OK'`
 	selectConnectionsCount            = `SELECT count(1) FROM audit WHERE realm_name=? AND ct_event_type='LOGON_OK' AND date_add(audit_time, INTERVAL ##INTERVAL##)>now()`
 	selectAuditSummaryRealmStmt       = `SELECT distinct realm_name FROM audit;`
 	selectAuditSummaryOriginStmt      = `SELECT distinct origin FROM audit;`
@@ -103,7 +106,8 @@ func (cm *eventsDBModule) GetEventsCount(_ context.Context, m map[string]string)
 	return count, nil
 }
 
-// GetEvents gets the events matching some criterias (dateFrom, dateTo, realm, ...)
+// GetEvents gets the events matching some criterias (dateFrom, dateTo, realm, ...). Several event types can be
+// requested at once, separated by commas.
 func (cm *eventsDBModule) GetEvents(_ context.Context, m map[string]string) ([]api.AuditRepresentation, error) {
 	var res = []api.AuditRepresentation{}
 	params, errParams := createAuditEventsParametersFromMap(m)
@@ -157,6 +161,14 @@ func (cm *eventsDBModule) GetLastConnection(_ context.Context, realmName string)
 	return res, err
 }
 
+// GetLastUserConnection gets the time of last connection of the given user
+func (cm *eventsDBModule) GetLastUserConnection(_ context.Context, realmName string, userID string) (int64, error) {
+	var res = int64(0)
+	var row = cm.db.QueryRow(selectLastUserConnectionTimeStmt, realmName, userID)
+	var err = row.Scan(&res)
+	return res, err
+}
+
 // GetTotalConnectionsCount gets the number of connection for the given realm during the specified duration
 func (cm *eventsDBModule) GetTotalConnectionsCount(_ context.Context, realmName string, durationLabel string) (int64, error) {
 	var matched, err = regexp.MatchString(`^\d+ [A-Za-z]+$`, durationLabel)
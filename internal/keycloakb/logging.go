@@ -1,5 +1,9 @@
 package keycloakb
 
+import (
+	"encoding/json"
+)
+
 // Logger interface for logging with level
 type Logger interface {
 	Debug(keyvals ...interface{}) error
@@ -7,3 +11,18 @@ type Logger interface {
 	Warn(keyvals ...interface{}) error
 	Error(keyvals ...interface{}) error
 }
+
+// LogUnstoredEvent stores in the logs the event that failed to be stored in the DB. The values are the key/value pairs
+// given to the events DB module.
+func LogUnstoredEvent(logger Logger, err error, eventName string, values ...string) {
+	var m = map[string]interface{}{"event_name": eventName}
+	for i := 0; i+1 < len(values); i += 2 {
+		m[values[i]] = values[i+1]
+	}
+	eventJSON, errMarshal := json.Marshal(m)
+	if errMarshal == nil {
+		logger.Error("err", err.Error(), "event", string(eventJSON))
+	} else {
+		logger.Error("err", err.Error())
+	}
+}
@@ -0,0 +1,39 @@
+package keycloakb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	var document = `{"a":"b","c":{"d":"e","f":"g"},"h":["i","j"]}`
+
+	t.Run("Replace, add and remove members", func(t *testing.T) {
+		var res, err = ApplyMergePatch([]byte(document), []byte(`{"a":"z","c":{"f":null},"k":true}`))
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"a":"z","c":{"d":"e"},"h":["i","j"],"k":true}`, string(res))
+	})
+
+	t.Run("Arrays are replaced", func(t *testing.T) {
+		var res, err = ApplyMergePatch([]byte(document), []byte(`{"h":["x"]}`))
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"a":"b","c":{"d":"e","f":"g"},"h":["x"]}`, string(res))
+	})
+
+	t.Run("Empty patch", func(t *testing.T) {
+		var res, err = ApplyMergePatch([]byte(document), []byte(`{}`))
+		assert.Nil(t, err)
+		assert.JSONEq(t, document, string(res))
+	})
+
+	t.Run("Invalid patch", func(t *testing.T) {
+		var _, err = ApplyMergePatch([]byte(document), []byte(`{"a":`))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Invalid document", func(t *testing.T) {
+		var _, err = ApplyMergePatch([]byte(`[`), []byte(`{}`))
+		assert.NotNil(t, err)
+	})
+}
@@ -1,6 +1,9 @@
 package keycloakb
 
-//go:generate mockgen -destination=./mock/instrumenting.go -package=mock -mock_names=Histogram=Histogram github.com/cloudtrust/common-service/metrics Histogram
+//go:generate mockgen -destination=./mock/instrumenting.go -package=mock -mock_names=Histogram=Histogram,Counter=Counter github.com/cloudtrust/common-service/metrics Histogram,Counter
 //go:generate mockgen -destination=./mock/configdbinstrumenting.go -package=mock -mock_names=ConfigurationDBModule=ConfigurationDBModule github.com/cloudtrust/keycloak-bridge/internal/keycloakb ConfigurationDBModule
 //go:generate mockgen -destination=./mock/configdbmodule.go -package=mock -mock_names=DBConfiguration=DBConfiguration github.com/cloudtrust/keycloak-bridge/internal/keycloakb DBConfiguration
+//go:generate mockgen -destination=./mock/sqltypes.go -package=mock -mock_names=Transaction=Transaction github.com/cloudtrust/keycloak-bridge/internal/sqltypes Transaction
+//go:generate mockgen -destination=./mock/configdbcache.go -package=mock -mock_names=ConfigurationVersionsReader=ConfigurationVersionsReader github.com/cloudtrust/keycloak-bridge/internal/keycloakb ConfigurationVersionsReader
 //go:generate mockgen -destination=./mock/keycloak_client.go -package=mock -mock_names=KeycloakClient=KeycloakClient github.com/cloudtrust/keycloak-bridge/internal/keycloakb KeycloakClient
+//go:generate mockgen -destination=./mock/technicaltoken.go -package=mock -mock_names=KeycloakTokenClient=KeycloakTokenClient github.com/cloudtrust/keycloak-bridge/internal/keycloakb KeycloakTokenClient
@@ -0,0 +1,102 @@
+package keycloakb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/pkg/errors"
+)
+
+// Channels through which the verification codes are sent
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Notifier sends to the users the codes verifying their new email or phone number. Enabled returns false when no
+// notification gateway is configured: the changes are then applied without verification.
+type Notifier interface {
+	Enabled() bool
+	SendVerificationCode(ctx context.Context, realm, channel, recipient, code string, lifespan time.Duration) error
+}
+
+// verificationCodeNotification is the message posted to the notification gateway
+type verificationCodeNotification struct {
+	Realm     string `json:"realm"`
+	Channel   string `json:"channel"`
+	Recipient string `json:"recipient"`
+	Code      string `json:"code"`
+	Lifespan  int    `json:"lifespan"`
+}
+
+type httpNotifier struct {
+	uri        string
+	httpClient *http.Client
+}
+
+// NewHTTPNotifier returns a notifier posting the verification codes to a notification gateway, which renders the message
+// of the realm and sends it by email or SMS. The lifespan of the code is given in seconds.
+func NewHTTPNotifier(uri string, timeout time.Duration) Notifier {
+	return &httpNotifier{
+		uri:        uri,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (n *httpNotifier) Enabled() bool {
+	return true
+}
+
+func (n *httpNotifier) SendVerificationCode(ctx context.Context, realm, channel, recipient, code string, lifespan time.Duration) error {
+	body, err := json.Marshal(verificationCodeNotification{
+		Realm:     realm,
+		Channel:   channel,
+		Recipient: recipient,
+		Code:      code,
+		Lifespan:  int(lifespan.Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.uri, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "could not reach the notification gateway")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("notification gateway answered with status " + strconv.Itoa(resp.StatusCode))
+	}
+	return nil
+}
+
+type disabledNotifier struct{}
+
+// NewDisabledNotifier returns a notifier refusing to send the verification codes. It is used when no notification
+// gateway is configured: the new email or phone number of the users is then applied directly and marked as not verified.
+func NewDisabledNotifier() Notifier {
+	return &disabledNotifier{}
+}
+
+func (n *disabledNotifier) Enabled() bool {
+	return false
+}
+
+func (n *disabledNotifier) SendVerificationCode(ctx context.Context, realm, channel, recipient, code string, lifespan time.Duration) error {
+	return errorhandler.Error{
+		Status:  http.StatusPreconditionFailed,
+		Message: ComponentName + "." + MsgErrPreconditionFailed + "." + NotificationGateway,
+	}
+}
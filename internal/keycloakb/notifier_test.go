@@ -0,0 +1,51 @@
+package keycloakb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPNotifier(t *testing.T) {
+	var received verificationCodeNotification
+	var status = http.StatusAccepted
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	var notifier = NewHTTPNotifier(server.URL, time.Second)
+	assert.True(t, notifier.Enabled())
+
+	t.Run("Code sent", func(t *testing.T) {
+		var err = notifier.SendVerificationCode(context.Background(), "realm", ChannelSMS, "+41791234567", "123456", time.Hour)
+		assert.Nil(t, err)
+		assert.Equal(t, verificationCodeNotification{Realm: "realm", Channel: ChannelSMS, Recipient: "+41791234567", Code: "123456", Lifespan: 3600}, received)
+	})
+
+	t.Run("Gateway error", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		var err = notifier.SendVerificationCode(context.Background(), "realm", ChannelEmail, "john@company.com", "123456", time.Hour)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Gateway unreachable", func(t *testing.T) {
+		var err = NewHTTPNotifier("http://127.0.0.1:1", time.Second).SendVerificationCode(context.Background(), "realm", ChannelEmail, "john@company.com", "123456", time.Hour)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestDisabledNotifier(t *testing.T) {
+	assert.False(t, NewDisabledNotifier().Enabled())
+	var err = NewDisabledNotifier().SendVerificationCode(context.Background(), "realm", ChannelEmail, "john@company.com", "123456", time.Hour)
+	assert.Equal(t, http.StatusPreconditionFailed, err.(errorhandler.Error).Status)
+}
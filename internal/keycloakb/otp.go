@@ -0,0 +1,125 @@
+package keycloakb
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTP algorithms supported by Keycloak
+const (
+	OTPAlgorithmSHA1   = "HmacSHA1"
+	OTPAlgorithmSHA256 = "HmacSHA256"
+	OTPAlgorithmSHA512 = "HmacSHA512"
+)
+
+// otpSecretChars are the characters of the OTP secrets, as generated by Keycloak
+const otpSecretChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// TOTPPolicy is the time-based OTP policy of a realm
+type TOTPPolicy struct {
+	Algorithm       string
+	Digits          int
+	Period          int
+	LookAheadWindow int
+}
+
+// DefaultTOTPPolicy is the Keycloak default OTP policy
+var DefaultTOTPPolicy = TOTPPolicy{Algorithm: OTPAlgorithmSHA1, Digits: 6, Period: 30, LookAheadWindow: 1}
+
+// GenerateOTPSecret returns a new random OTP secret of the given length. Like Keycloak, the secret is kept as text and
+// its bytes are used as HMAC key.
+func GenerateOTPSecret(length int) (string, error) {
+	var secret = make([]byte, length)
+	var max = big.NewInt(int64(len(otpSecretChars)))
+	for i := range secret {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		secret[i] = otpSecretChars[n.Int64()]
+	}
+	return string(secret), nil
+}
+
+// EncodedSecret returns the secret in the base32 form typed by the users in their OTP application
+func (p TOTPPolicy) EncodedSecret(secret string) string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(secret))
+}
+
+// KeyURI returns the otpauth URI rendered as QR code for the OTP applications
+func (p TOTPPolicy) KeyURI(issuer, accountName, secret string) string {
+	var params = url.Values{}
+	params.Set("secret", p.EncodedSecret(secret))
+	params.Set("digits", strconv.Itoa(p.Digits))
+	params.Set("algorithm", strings.TrimPrefix(p.Algorithm, "Hmac"))
+	params.Set("issuer", issuer)
+	params.Set("period", strconv.Itoa(p.Period))
+	var label = url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// ValidateTOTP checks a code against the secret. Codes of the previous and next periods, up to the look ahead window,
+// are accepted to cope with the clock drift of the devices.
+func (p TOTPPolicy) ValidateTOTP(secret, code string, now time.Time) bool {
+	if len(code) != p.Digits || p.Period <= 0 {
+		return false
+	}
+	var counter = now.Unix() / int64(p.Period)
+	for i := -p.LookAheadWindow; i <= p.LookAheadWindow; i++ {
+		var expected, err = p.generateCode(secret, uint64(counter+int64(i)))
+		if err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateTOTP returns the code of the secret for the period including the given time
+func (p TOTPPolicy) GenerateTOTP(secret string, now time.Time) (string, error) {
+	if p.Period <= 0 {
+		return "", fmt.Errorf("invalid OTP period %d", p.Period)
+	}
+	return p.generateCode(secret, uint64(now.Unix()/int64(p.Period)))
+}
+
+// generateCode computes the HOTP value (RFC 4226) of the counter
+func (p TOTPPolicy) generateCode(secret string, counter uint64) (string, error) {
+	var hashFunc func() hash.Hash
+	switch p.Algorithm {
+	case OTPAlgorithmSHA1:
+		hashFunc = sha1.New
+	case OTPAlgorithmSHA256:
+		hashFunc = sha256.New
+	case OTPAlgorithmSHA512:
+		hashFunc = sha512.New
+	default:
+		return "", fmt.Errorf("unsupported OTP algorithm %s", p.Algorithm)
+	}
+
+	var message = make([]byte, 8)
+	binary.BigEndian.PutUint64(message, counter)
+	var mac = hmac.New(hashFunc, []byte(secret))
+	mac.Write(message)
+	var sum = mac.Sum(nil)
+
+	var offset = sum[len(sum)-1] & 0x0f
+	var value = int64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+	var modulo int64 = 1
+	for i := 0; i < p.Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", p.Digits, value%modulo), nil
+}
@@ -0,0 +1,62 @@
+package keycloakb
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	// Test vectors of RFC 6238
+	var policy = TOTPPolicy{Algorithm: OTPAlgorithmSHA1, Digits: 8, Period: 30}
+	var secretSHA1 = "12345678901234567890"
+	assert.True(t, policy.ValidateTOTP(secretSHA1, "94287082", time.Unix(59, 0)))
+	assert.True(t, policy.ValidateTOTP(secretSHA1, "07081804", time.Unix(1111111109, 0)))
+	assert.False(t, policy.ValidateTOTP(secretSHA1, "07081804", time.Unix(1111111109+30, 0)))
+	assert.False(t, policy.ValidateTOTP(secretSHA1, "0708180", time.Unix(1111111109, 0)))
+
+	policy.Algorithm = OTPAlgorithmSHA256
+	assert.True(t, policy.ValidateTOTP(secretSHA1+"123456789012", "46119246", time.Unix(59, 0)))
+
+	policy.Algorithm = OTPAlgorithmSHA512
+	assert.True(t, policy.ValidateTOTP(strings.Repeat(secretSHA1, 3)+"1234", "90693936", time.Unix(59, 0)))
+
+	policy.Algorithm = "unknown"
+	assert.False(t, policy.ValidateTOTP(secretSHA1, "94287082", time.Unix(59, 0)))
+
+	t.Run("Look ahead window", func(t *testing.T) {
+		var policy = TOTPPolicy{Algorithm: OTPAlgorithmSHA1, Digits: 8, Period: 30, LookAheadWindow: 1}
+		assert.True(t, policy.ValidateTOTP(secretSHA1, "07081804", time.Unix(1111111109+30, 0)))
+		assert.True(t, policy.ValidateTOTP(secretSHA1, "07081804", time.Unix(1111111109-30, 0)))
+		assert.False(t, policy.ValidateTOTP(secretSHA1, "07081804", time.Unix(1111111109+60, 0)))
+	})
+}
+
+func TestGenerateTOTP(t *testing.T) {
+	var policy = TOTPPolicy{Algorithm: OTPAlgorithmSHA1, Digits: 8, Period: 30}
+	var code, err = policy.GenerateTOTP("12345678901234567890", time.Unix(1111111109, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "07081804", code)
+
+	policy.Period = 0
+	_, err = policy.GenerateTOTP("12345678901234567890", time.Unix(1111111109, 0))
+	assert.NotNil(t, err)
+}
+
+func TestGenerateOTPSecret(t *testing.T) {
+	var secret, err = GenerateOTPSecret(20)
+	assert.Nil(t, err)
+	assert.Len(t, secret, 20)
+	assert.Equal(t, "", strings.Trim(secret, otpSecretChars))
+
+	other, _ := GenerateOTPSecret(20)
+	assert.NotEqual(t, secret, other)
+}
+
+func TestKeyURI(t *testing.T) {
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", DefaultTOTPPolicy.EncodedSecret("12345678901234567890"))
+	assert.Equal(t, "otpauth://totp/my%20realm:john?algorithm=SHA1&digits=6&issuer=my+realm&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		DefaultTOTPPolicy.KeyURI("my realm", "john", "12345678901234567890"))
+}
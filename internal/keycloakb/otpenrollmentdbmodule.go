@@ -0,0 +1,84 @@
+package keycloakb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	storeOTPEnrollmentStmt = `INSERT INTO otp_enrollment (realm_id, user_id, secret, expires_at, attempts)
+	  VALUES (?, ?, ?, ?, 0)
+	  ON DUPLICATE KEY UPDATE secret = ?, expires_at = ?, attempts = 0;`
+	deleteExpiredOTPEnrollmentsStmt = `DELETE FROM otp_enrollment WHERE (expires_at < ?)`
+	selectOTPEnrollmentStmt         = `SELECT secret, expires_at, attempts FROM otp_enrollment WHERE (realm_id = ?) AND (user_id = ?)`
+	incrementOTPEnrollmentStmt      = `UPDATE otp_enrollment SET attempts = attempts + 1 WHERE (realm_id = ?) AND (user_id = ?)`
+	deleteOTPEnrollmentStmt         = `DELETE FROM otp_enrollment WHERE (realm_id = ?) AND (user_id = ?)`
+)
+
+// OTPEnrollment is an OTP secret generated for a user and waiting for a first code generated with it. The secret is only
+// stored in the DB of the bridge until it is registered in Keycloak. ExpiresAt is in milliseconds since epoch.
+type OTPEnrollment struct {
+	RealmName string
+	UserID    string
+	Secret    string
+	ExpiresAt int64
+	Attempts  int
+}
+
+// OTPEnrollmentDBModule stores the pending OTP enrollments
+type OTPEnrollmentDBModule interface {
+	StoreOTPEnrollment(ctx context.Context, enrollment OTPEnrollment) error
+	GetOTPEnrollment(ctx context.Context, realmName, userID string) (*OTPEnrollment, error)
+	IncrementOTPEnrollmentAttempts(ctx context.Context, realmName, userID string) error
+	DeleteOTPEnrollment(ctx context.Context, realmName, userID string) error
+}
+
+type otpEnrollmentDBModule struct {
+	db DBConfiguration
+}
+
+// NewOTPEnrollmentDBModule returns an OTPEnrollmentDB module.
+func NewOTPEnrollmentDBModule(db DBConfiguration) OTPEnrollmentDBModule {
+	return &otpEnrollmentDBModule{
+		db: db,
+	}
+}
+
+// StoreOTPEnrollment stores the enrollment of a user, replacing their previous one. The expired enrollments of the
+// other users are removed at the same time so that abandoned secrets are not kept.
+func (c *otpEnrollmentDBModule) StoreOTPEnrollment(ctx context.Context, enrollment OTPEnrollment) error {
+	if _, err := c.db.Exec(deleteExpiredOTPEnrollmentsStmt, time.Now().UnixNano()/int64(time.Millisecond)); err != nil {
+		return err
+	}
+	_, err := c.db.Exec(storeOTPEnrollmentStmt, enrollment.RealmName, enrollment.UserID, enrollment.Secret, enrollment.ExpiresAt,
+		enrollment.Secret, enrollment.ExpiresAt)
+	return err
+}
+
+// GetOTPEnrollment returns the pending enrollment of a user, nil if there is none
+func (c *otpEnrollmentDBModule) GetOTPEnrollment(ctx context.Context, realmName, userID string) (*OTPEnrollment, error) {
+	var enrollment = OTPEnrollment{RealmName: realmName, UserID: userID}
+	row := c.db.QueryRow(selectOTPEnrollmentStmt, realmName, userID)
+
+	switch err := row.Scan(&enrollment.Secret, &enrollment.ExpiresAt, &enrollment.Attempts); err {
+	case sql.ErrNoRows:
+		return nil, nil
+	case nil:
+		return &enrollment, nil
+	default:
+		return nil, err
+	}
+}
+
+// IncrementOTPEnrollmentAttempts counts a wrong code sent for the pending enrollment of a user
+func (c *otpEnrollmentDBModule) IncrementOTPEnrollmentAttempts(ctx context.Context, realmName, userID string) error {
+	_, err := c.db.Exec(incrementOTPEnrollmentStmt, realmName, userID)
+	return err
+}
+
+// DeleteOTPEnrollment removes the pending enrollment of a user
+func (c *otpEnrollmentDBModule) DeleteOTPEnrollment(ctx context.Context, realmName, userID string) error {
+	_, err := c.db.Exec(deleteOTPEnrollmentStmt, realmName, userID)
+	return err
+}
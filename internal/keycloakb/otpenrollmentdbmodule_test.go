@@ -0,0 +1,42 @@
+package keycloakb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOTPEnrollmentDBModule(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewDBConfiguration(mockCtrl)
+
+	var module = NewOTPEnrollmentDBModule(mockDB)
+	var ctx = context.Background()
+	var enrollment = OTPEnrollment{RealmName: "realm", UserID: "userId", Secret: "secret", ExpiresAt: 2000}
+
+	t.Run("Store an enrollment", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deleteExpiredOTPEnrollmentsStmt, gomock.Any()).Return(rowsAffected(3), nil).Times(1)
+		mockDB.EXPECT().Exec(storeOTPEnrollmentStmt, "realm", "userId", "secret", int64(2000), "secret", int64(2000)).Return(rowsAffected(1), nil).Times(1)
+		assert.Nil(t, module.StoreOTPEnrollment(ctx, enrollment))
+	})
+
+	t.Run("Expired enrollments can't be removed", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deleteExpiredOTPEnrollmentsStmt, gomock.Any()).Return(nil, errors.New("db error")).Times(1)
+		assert.NotNil(t, module.StoreOTPEnrollment(ctx, enrollment))
+	})
+
+	t.Run("Count a wrong code", func(t *testing.T) {
+		mockDB.EXPECT().Exec(incrementOTPEnrollmentStmt, "realm", "userId").Return(rowsAffected(1), nil).Times(1)
+		assert.Nil(t, module.IncrementOTPEnrollmentAttempts(ctx, "realm", "userId"))
+	})
+
+	t.Run("Delete an enrollment", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deleteOTPEnrollmentStmt, "realm", "userId").Return(nil, errors.New("db error")).Times(1)
+		assert.NotNil(t, module.DeleteOTPEnrollment(ctx, "realm", "userId"))
+	})
+}
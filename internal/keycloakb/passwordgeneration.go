@@ -1,10 +1,9 @@
 package keycloakb
 
 import (
-	"math/rand"
-	"strconv"
+	"crypto/rand"
+	"math/big"
 	"strings"
-	"unicode"
 )
 
 const (
@@ -15,11 +14,29 @@ const (
 	alphabet     = "abcdefghjkmnpqrtuvwxyzABCDEFGHJKLMNPQRTUVWXYZ2346789"
 )
 
+// randomInt returns a uniform random number in [0, max) drawn from a cryptographically secure source
+func randomInt(max int) int {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		// the system random source is broken, generated passwords can't be trusted anymore
+		panic(err)
+	}
+	return int(n.Int64())
+}
+
+// shuffle randomly permutes the elements of a string array
+func shuffle(elems []string) {
+	for i := len(elems) - 1; i > 0; i-- {
+		j := randomInt(i + 1)
+		elems[i], elems[j] = elems[j], elems[i]
+	}
+}
+
 // appendCharacters appends a number of characters from a certain alphabet to a string array
 func appendCharacters(pwdElems []string, alphabet string, length int) []string {
 
 	for j := 0; j < length; j++ {
-		pwdElems = append(pwdElems, string(alphabet[rand.Intn(len(alphabet))]))
+		pwdElems = append(pwdElems, string(alphabet[randomInt(len(alphabet))]))
 	}
 	return pwdElems
 }
@@ -54,58 +71,11 @@ func GeneratePasswordNoKeycloakPolicy(minLength int) string {
 
 // GeneratePasswordFromKeycloakPolicy generates a random password respecting the keycloak password policy
 func GeneratePasswordFromKeycloakPolicy(policy string) (string, error) {
-	// Keycloak password policy is a string of the form
-	// "forceExpiredPasswordChange(365) and specialChars(1) and upperCase(1) and lowerCase(1) and length(4) and digits(1) and notUsername(undefined)"
-	var pwdElems = make([]string, 0)
-	policyItems := strings.Split(policy, "and")
-
-	// generate a random password that corresponds to the password policy
-	//reg := regexp.MustCompile(`[a-zA-z]+[(]{1}[0-9]+[)]{1}`)
-	//pwdReq := string(reg.Find([]byte()))
-
-	f := func(c rune) bool {
-		return !unicode.IsLetter(c) && !unicode.IsNumber(c)
-	}
-
-	for i := 0; i < len(policyItems); i++ {
-		keyValueItem := strings.FieldsFunc(policyItems[i], f)
-		switch keyValueItem[0] {
-		case "length", "lowerCase":
-			minRequired, err := strconv.Atoi(keyValueItem[1])
-			if err == nil {
-				// make sure that the password has the minimum length required by choosing random lower case letters
-				pwdElems = appendCharacters(pwdElems, lowerCase, minRequired)
-			} else {
-				return "", err
-			}
-		case "specialChars":
-			// pick randomly special characters from ?!#%$
-			minRequired, err := strconv.Atoi(keyValueItem[1])
-			if err == nil {
-				pwdElems = appendCharacters(pwdElems, specialChars, minRequired)
-			} else {
-				return "", err
-			}
-		case "upperCase":
-			minRequired, err := strconv.Atoi(keyValueItem[1])
-			if err == nil {
-				pwdElems = appendCharacters(pwdElems, upperCase, minRequired)
-			} else {
-				return "", err
-			}
-		case "digits":
-			minRequired, err := strconv.Atoi(keyValueItem[1])
-			if err == nil {
-				pwdElems = appendCharacters(pwdElems, digits, minRequired)
-			} else {
-				return "", err
-			}
-		}
+	parsedPolicy, err := ParsePasswordPolicy(policy)
+	if err != nil {
+		return "", err
 	}
-	rand.Shuffle(len(pwdElems), func(i, j int) { pwdElems[i], pwdElems[j] = pwdElems[j], pwdElems[i] })
-	pwd := strings.Join(pwdElems, "")
-	return pwd, nil
-
+	return GeneratePasswordFromPolicy(parsedPolicy, "")
 }
 
 // GenerateInitialCode generates a code of the format UpperCase +  digits + LowerCase
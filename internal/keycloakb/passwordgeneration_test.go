@@ -57,13 +57,22 @@ func TestGeneratePasswordFromKeycloakPolicy(t *testing.T) {
 	regLowerCase := regexp.MustCompile("[a-z]")
 
 	pwd, err := GeneratePasswordFromKeycloakPolicy(policy)
-	assert.Equal(t, len(regDigits.FindAllStringIndex(pwd, -1)), nodigits)
-	assert.Equal(t, len(regLowerCase.FindAllStringIndex(pwd, -1)), nolowerCase+length)
-	assert.Equal(t, len(regUpperCase.FindAllStringIndex(pwd, -1)), noupperCase)
+	assert.True(t, len(regDigits.FindAllStringIndex(pwd, -1)) >= nodigits)
+	assert.True(t, len(regLowerCase.FindAllStringIndex(pwd, -1)) >= nolowerCase)
+	assert.True(t, len(regUpperCase.FindAllStringIndex(pwd, -1)) >= noupperCase)
 	assert.Equal(t, len(regSpecialChars.FindAllStringIndex(pwd, -1)), nospecialChars)
-	assert.True(t, len(pwd) >= length)
-	assert.Equal(t, len(pwd), nodigits+nolowerCase+nospecialChars+noupperCase+length)
+	assert.Equal(t, len(pwd), length)
 	assert.Nil(t, err)
+
+	// policy requirements exceeding the minimum length
+	policy = fmt.Sprintf("specialChars(%d) and upperCase(%d) and length(%d)", nospecialChars, noupperCase, 4)
+	pwd, err = GeneratePasswordFromKeycloakPolicy(policy)
+	assert.Nil(t, err)
+	assert.Equal(t, len(pwd), minGeneratedPasswordLength)
+
+	// invalid policy
+	_, err = GeneratePasswordFromKeycloakPolicy("length(abc)")
+	assert.NotNil(t, err)
 }
 
 func TestGeneratePassword(t *testing.T) {
@@ -78,7 +87,7 @@ func TestGeneratePassword(t *testing.T) {
 
 	pwd, err := GeneratePassword(&policy, minLength, userID)
 	assert.Nil(t, err)
-	assert.Equal(t, len(pwd), nodigits+nolowerCase+nospecialChars+noupperCase+length)
+	assert.Equal(t, len(pwd), length)
 
 	pwd, err = GeneratePassword(nil, minLength, userID)
 	assert.Nil(t, err)
@@ -0,0 +1,307 @@
+package keycloakb
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Keycloak built-in password policy items
+const (
+	PolicyLength                     = "length"
+	PolicyMaxLength                  = "maxLength"
+	PolicyDigits                     = "digits"
+	PolicySpecialChars               = "specialChars"
+	PolicyUpperCase                  = "upperCase"
+	PolicyLowerCase                  = "lowerCase"
+	PolicyNotUsername                = "notUsername"
+	PolicyNotEmail                   = "notEmail"
+	PolicyRegexPattern               = "regexPattern"
+	PolicyPasswordHistory            = "passwordHistory"
+	PolicyForceExpiredPasswordChange = "forceExpiredPasswordChange"
+	PolicyHashIterations             = "hashIterations"
+	PolicyHashAlgorithm              = "hashAlgorithm"
+	PolicyPasswordBlacklist          = "passwordBlacklist"
+)
+
+// Default values used by Keycloak when an integer policy item is declared without value
+var policyDefaultValues = map[string]int{
+	PolicyLength:                     8,
+	PolicyMaxLength:                  64,
+	PolicyDigits:                     1,
+	PolicySpecialChars:               1,
+	PolicyUpperCase:                  1,
+	PolicyLowerCase:                  1,
+	PolicyPasswordHistory:            3,
+	PolicyForceExpiredPasswordChange: 365,
+	PolicyHashIterations:             27500,
+}
+
+// minGeneratedPasswordLength is the length of the generated passwords when the policy does not impose a longer one
+const minGeneratedPasswordLength = 8
+
+// maxGenerationAttempts bounds the number of generated candidates when looking for a password complying with the policy
+const maxGenerationAttempts = 100
+
+// PasswordPolicy is a parsed Keycloak password policy. Integer items which are not part of the policy are 0.
+// PasswordHistory, ForceExpiredPasswordChange, the hashing parameters and PasswordBlacklist can't be checked
+// by the bridge as it has no access to the stored credentials nor to the blacklist files, Keycloak enforces them.
+type PasswordPolicy struct {
+	Length                     int
+	MaxLength                  int
+	Digits                     int
+	SpecialChars               int
+	UpperCase                  int
+	LowerCase                  int
+	NotUsername                bool
+	NotEmail                   bool
+	RegexPatterns              []*regexp.Regexp
+	PasswordHistory            int
+	ForceExpiredPasswordChange int
+	HashIterations             int
+	HashAlgorithm              string
+	PasswordBlacklist          string
+}
+
+// PasswordPolicyViolation is returned when a password does not comply with a password policy.
+// Param is the value of the violated policy item (e.g. the minimum length).
+type PasswordPolicyViolation struct {
+	Policy string
+	Param  string
+}
+
+func (v PasswordPolicyViolation) Error() string {
+	return MsgErrInvalidParam + "." + Password + "." + v.Policy
+}
+
+// ParsePasswordPolicy parses a Keycloak password policy, i.e. a string of the form
+// "specialChars(1) and upperCase(1) and lowerCase(1) and length(8) and digits(1) and notUsername(undefined)".
+// As Keycloak does, items are separated by " and " and the configuration of an item is everything between the first
+// opening parenthesis and the last character. Items provided by custom Keycloak extensions are ignored.
+func ParsePasswordPolicy(policy string) (PasswordPolicy, error) {
+	var res PasswordPolicy
+
+	for _, item := range strings.Split(policy, " and ") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var name, value = item, ""
+		if idx := strings.Index(item, "("); idx >= 0 {
+			if !strings.HasSuffix(item, ")") {
+				return PasswordPolicy{}, errors.New(MsgErrInvalidParam + "." + PwdPolicy + "." + item)
+			}
+			name, value = strings.TrimSpace(item[:idx]), item[idx+1:len(item)-1]
+		}
+
+		var target *int
+		switch name {
+		case PolicyLength:
+			target = &res.Length
+		case PolicyMaxLength:
+			target = &res.MaxLength
+		case PolicyDigits:
+			target = &res.Digits
+		case PolicySpecialChars:
+			target = &res.SpecialChars
+		case PolicyUpperCase:
+			target = &res.UpperCase
+		case PolicyLowerCase:
+			target = &res.LowerCase
+		case PolicyPasswordHistory:
+			target = &res.PasswordHistory
+		case PolicyForceExpiredPasswordChange:
+			target = &res.ForceExpiredPasswordChange
+		case PolicyHashIterations:
+			target = &res.HashIterations
+		case PolicyNotUsername:
+			res.NotUsername = true
+		case PolicyNotEmail:
+			res.NotEmail = true
+		case PolicyHashAlgorithm:
+			res.HashAlgorithm = value
+		case PolicyPasswordBlacklist:
+			res.PasswordBlacklist = value
+		case PolicyRegexPattern:
+			// Keycloak requires the whole password to match the pattern
+			var re, err = regexp.Compile("^(?:" + value + ")$")
+			if err != nil || value == "" {
+				return PasswordPolicy{}, errors.New(MsgErrInvalidParam + "." + PwdPolicy + "." + name)
+			}
+			res.RegexPatterns = append(res.RegexPatterns, re)
+		}
+
+		if target == nil {
+			continue
+		}
+		if value == "" {
+			*target = policyDefaultValues[name]
+			continue
+		}
+		var intValue, err = strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || intValue < 0 {
+			return PasswordPolicy{}, errors.New(MsgErrInvalidParam + "." + PwdPolicy + "." + name)
+		}
+		*target = intValue
+	}
+
+	if res.MaxLength > 0 && res.MaxLength < res.Length {
+		return PasswordPolicy{}, errors.New(MsgErrInvalidParam + "." + PwdPolicy + "." + PolicyMaxLength)
+	}
+
+	return res, nil
+}
+
+// Validate checks that a password complies with the policy. The returned error is a PasswordPolicyViolation
+// naming the first violated item.
+func (policy PasswordPolicy) Validate(password, username, email string) error {
+	var nbDigits, nbSpecialChars, nbUpperCase, nbLowerCase int
+	for _, c := range password {
+		switch {
+		case unicode.IsDigit(c):
+			nbDigits++
+		case unicode.IsUpper(c):
+			nbUpperCase++
+		case unicode.IsLower(c):
+			nbLowerCase++
+		case !unicode.IsLetter(c):
+			nbSpecialChars++
+		}
+	}
+	var length = utf8.RuneCountInString(password)
+
+	switch {
+	case length < policy.Length:
+		return PasswordPolicyViolation{Policy: PolicyLength, Param: strconv.Itoa(policy.Length)}
+	case policy.MaxLength > 0 && length > policy.MaxLength:
+		return PasswordPolicyViolation{Policy: PolicyMaxLength, Param: strconv.Itoa(policy.MaxLength)}
+	case nbDigits < policy.Digits:
+		return PasswordPolicyViolation{Policy: PolicyDigits, Param: strconv.Itoa(policy.Digits)}
+	case nbSpecialChars < policy.SpecialChars:
+		return PasswordPolicyViolation{Policy: PolicySpecialChars, Param: strconv.Itoa(policy.SpecialChars)}
+	case nbUpperCase < policy.UpperCase:
+		return PasswordPolicyViolation{Policy: PolicyUpperCase, Param: strconv.Itoa(policy.UpperCase)}
+	case nbLowerCase < policy.LowerCase:
+		return PasswordPolicyViolation{Policy: PolicyLowerCase, Param: strconv.Itoa(policy.LowerCase)}
+	case policy.NotUsername && username != "" && strings.EqualFold(password, username):
+		return PasswordPolicyViolation{Policy: PolicyNotUsername}
+	case policy.NotEmail && email != "" && strings.EqualFold(password, email):
+		return PasswordPolicyViolation{Policy: PolicyNotEmail}
+	}
+
+	for _, re := range policy.RegexPatterns {
+		if !re.MatchString(password) {
+			var pattern = strings.TrimSuffix(strings.TrimPrefix(re.String(), "^(?:"), ")$")
+			return PasswordPolicyViolation{Policy: PolicyRegexPattern, Param: pattern}
+		}
+	}
+
+	return nil
+}
+
+// GeneratePasswordFromPolicy generates a random password complying with the policy. Regular expression patterns
+// can't drive the generation: candidates are generated until one matches, an error is returned if none does.
+func GeneratePasswordFromPolicy(policy PasswordPolicy, username string) (string, error) {
+	var required = policy.Digits + policy.SpecialChars + policy.UpperCase + policy.LowerCase
+	var length = policy.Length
+	if length < minGeneratedPasswordLength {
+		length = minGeneratedPasswordLength
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		length = policy.MaxLength
+	}
+	if length < required {
+		length = required
+	}
+
+	var err error
+	for i := 0; i < maxGenerationAttempts; i++ {
+		var pwdElems []string
+		pwdElems = appendCharacters(pwdElems, digits, policy.Digits)
+		pwdElems = appendCharacters(pwdElems, specialChars, policy.SpecialChars)
+		pwdElems = appendCharacters(pwdElems, upperCase, policy.UpperCase)
+		pwdElems = appendCharacters(pwdElems, lowerCase, policy.LowerCase)
+		pwdElems = appendCharacters(pwdElems, alphabet, length-len(pwdElems))
+		shuffle(pwdElems)
+
+		var pwd = strings.Join(pwdElems, "")
+		if err = policy.Validate(pwd, username, ""); err == nil {
+			return pwd, nil
+		}
+	}
+	return "", fmt.Errorf("can't generate a password complying with the policy: %s", err.Error())
+}
+
+// passwordPolicyMessages are the localized descriptions of the password policy violations
+var passwordPolicyMessages = map[string]map[string]string{
+	"en": {
+		PolicyLength:       "Invalid password: minimum length %s.",
+		PolicyMaxLength:    "Invalid password: maximum length %s.",
+		PolicyDigits:       "Invalid password: must contain at least %s numerical digits.",
+		PolicySpecialChars: "Invalid password: must contain at least %s special characters.",
+		PolicyUpperCase:    "Invalid password: must contain at least %s upper case characters.",
+		PolicyLowerCase:    "Invalid password: must contain at least %s lower case characters.",
+		PolicyNotUsername:  "Invalid password: must not be equal to the username.",
+		PolicyNotEmail:     "Invalid password: must not be equal to the email.",
+		PolicyRegexPattern: "Invalid password: fails to match regex pattern(s).",
+	},
+	"fr": {
+		PolicyLength:       "Mot de passe invalide : longueur minimale requise de %s.",
+		PolicyMaxLength:    "Mot de passe invalide : longueur maximale de %s.",
+		PolicyDigits:       "Mot de passe invalide : doit contenir au moins %s chiffre(s).",
+		PolicySpecialChars: "Mot de passe invalide : doit contenir au moins %s caractère(s) spéciaux.",
+		PolicyUpperCase:    "Mot de passe invalide : doit contenir au moins %s lettre(s) en majuscule.",
+		PolicyLowerCase:    "Mot de passe invalide : doit contenir au moins %s lettre(s) en minuscule.",
+		PolicyNotUsername:  "Mot de passe invalide : ne doit pas être identique au nom d'utilisateur.",
+		PolicyNotEmail:     "Mot de passe invalide : ne doit pas être identique au courriel.",
+		PolicyRegexPattern: "Mot de passe invalide : ne correspond pas au(x) motif(s) d'expression régulière.",
+	},
+	"de": {
+		PolicyLength:       "Ungültiges Passwort: Minimallänge %s.",
+		PolicyMaxLength:    "Ungültiges Passwort: Maximallänge %s.",
+		PolicyDigits:       "Ungültiges Passwort: muss mindestens %s Ziffern beinhalten.",
+		PolicySpecialChars: "Ungültiges Passwort: muss mindestens %s Sonderzeichen beinhalten.",
+		PolicyUpperCase:    "Ungültiges Passwort: muss mindestens %s Großbuchstaben beinhalten.",
+		PolicyLowerCase:    "Ungültiges Passwort: muss mindestens %s Kleinbuchstaben beinhalten.",
+		PolicyNotUsername:  "Ungültiges Passwort: darf nicht identisch mit dem Benutzernamen sein.",
+		PolicyNotEmail:     "Ungültiges Passwort: darf nicht identisch mit der E-Mail-Adresse sein.",
+		PolicyRegexPattern: "Ungültiges Passwort: entspricht nicht dem Regex-Muster.",
+	},
+	"it": {
+		PolicyLength:       "Password non valida: lunghezza minima %s.",
+		PolicyMaxLength:    "Password non valida: lunghezza massima %s.",
+		PolicyDigits:       "Password non valida: deve contenere almeno %s cifre numeriche.",
+		PolicySpecialChars: "Password non valida: deve contenere almeno %s caratteri speciali.",
+		PolicyUpperCase:    "Password non valida: deve contenere almeno %s lettere maiuscole.",
+		PolicyLowerCase:    "Password non valida: deve contenere almeno %s lettere minuscole.",
+		PolicyNotUsername:  "Password non valida: non deve essere uguale al nome utente.",
+		PolicyNotEmail:     "Password non valida: non deve essere uguale all'indirizzo email.",
+		PolicyRegexPattern: "Password non valida: non corrisponde al pattern dell'espressione regolare.",
+	},
+}
+
+// defaultMessageLocale is used when the locale of the user is not supported
+const defaultMessageLocale = "en"
+
+// LocalizedMessage returns the description of the violation in the given locale (e.g. "fr" or "de-CH").
+// English is used for unsupported locales.
+func (v PasswordPolicyViolation) LocalizedMessage(locale string) string {
+	var lang = strings.ToLower(strings.SplitN(strings.Replace(locale, "_", "-", -1), "-", 2)[0])
+	var messages, ok = passwordPolicyMessages[lang]
+	if !ok {
+		messages = passwordPolicyMessages[defaultMessageLocale]
+	}
+	var message, found = messages[v.Policy]
+	if !found {
+		return v.Error()
+	}
+	if strings.Contains(message, "%s") {
+		return fmt.Sprintf(message, v.Param)
+	}
+	return message
+}
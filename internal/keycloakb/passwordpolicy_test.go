@@ -0,0 +1,155 @@
+package keycloakb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePasswordPolicy(t *testing.T) {
+	t.Run("Built-in policies", func(t *testing.T) {
+		var policy, err = ParsePasswordPolicy("forceExpiredPasswordChange(365) and specialChars(1) and upperCase(2) and lowerCase(3) and length(8) and " +
+			"maxLength(20) and digits(4) and notUsername(undefined) and notEmail(undefined) and passwordHistory(3) and hashIterations(27500) and " +
+			"hashAlgorithm(pbkdf2-sha256) and passwordBlacklist(blacklist.txt) and regexPattern([a-z]+[0-9]+)")
+		assert.Nil(t, err)
+		assert.Equal(t, 8, policy.Length)
+		assert.Equal(t, 20, policy.MaxLength)
+		assert.Equal(t, 4, policy.Digits)
+		assert.Equal(t, 1, policy.SpecialChars)
+		assert.Equal(t, 2, policy.UpperCase)
+		assert.Equal(t, 3, policy.LowerCase)
+		assert.True(t, policy.NotUsername)
+		assert.True(t, policy.NotEmail)
+		assert.Equal(t, 3, policy.PasswordHistory)
+		assert.Equal(t, 365, policy.ForceExpiredPasswordChange)
+		assert.Equal(t, 27500, policy.HashIterations)
+		assert.Equal(t, "pbkdf2-sha256", policy.HashAlgorithm)
+		assert.Equal(t, "blacklist.txt", policy.PasswordBlacklist)
+		assert.Len(t, policy.RegexPatterns, 1)
+	})
+	t.Run("Default values", func(t *testing.T) {
+		var policy, err = ParsePasswordPolicy("length and digits() and passwordHistory")
+		assert.Nil(t, err)
+		assert.Equal(t, 8, policy.Length)
+		assert.Equal(t, 1, policy.Digits)
+		assert.Equal(t, 3, policy.PasswordHistory)
+	})
+	t.Run("Empty policy", func(t *testing.T) {
+		var policy, err = ParsePasswordPolicy("")
+		assert.Nil(t, err)
+		assert.Equal(t, PasswordPolicy{}, policy)
+	})
+	t.Run("Custom policies are ignored", func(t *testing.T) {
+		var policy, err = ParsePasswordPolicy("customPolicy(abc) and length(10)")
+		assert.Nil(t, err)
+		assert.Equal(t, PasswordPolicy{Length: 10}, policy)
+	})
+	t.Run("Invalid value", func(t *testing.T) {
+		var _, err = ParsePasswordPolicy("length(abc)")
+		assert.Equal(t, MsgErrInvalidParam+"."+PwdPolicy+"."+PolicyLength, err.Error())
+	})
+	t.Run("Negative value", func(t *testing.T) {
+		var _, err = ParsePasswordPolicy("digits(-1)")
+		assert.NotNil(t, err)
+	})
+	t.Run("Missing closing parenthesis", func(t *testing.T) {
+		var _, err = ParsePasswordPolicy("length(8")
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid regular expression", func(t *testing.T) {
+		var _, err = ParsePasswordPolicy("regexPattern(^(abc$)")
+		assert.Equal(t, MsgErrInvalidParam+"."+PwdPolicy+"."+PolicyRegexPattern, err.Error())
+	})
+	t.Run("Maximum length lower than the minimum one", func(t *testing.T) {
+		var _, err = ParsePasswordPolicy("length(10) and maxLength(8)")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	var policy, _ = ParsePasswordPolicy("length(8) and maxLength(12) and digits(1) and specialChars(1) and upperCase(1) and lowerCase(1) and " +
+		"notUsername(undefined) and notEmail(undefined) and regexPattern(.*[a-z]{3}.*)")
+
+	assert.Nil(t, policy.Validate("P@sswo0rd", "username", "john@doe.ch"))
+	assert.Nil(t, PasswordPolicy{}.Validate("", "username", "john@doe.ch"))
+
+	var testCases = map[string]PasswordPolicyViolation{
+		"P@ssw0r":       {Policy: PolicyLength, Param: "8"},
+		"P@ssw0rd12345": {Policy: PolicyMaxLength, Param: "12"},
+		"P@ssword":      {Policy: PolicyDigits, Param: "1"},
+		"Passw0rdd":     {Policy: PolicySpecialChars, Param: "1"},
+		"p@ssw0rd":      {Policy: PolicyUpperCase, Param: "1"},
+		"P@SSW0RD":      {Policy: PolicyLowerCase, Param: "1"},
+		"P@sSw0rD":      {Policy: PolicyRegexPattern, Param: ".*[a-z]{3}.*"},
+	}
+	for password, violation := range testCases {
+		t.Run(violation.Policy, func(t *testing.T) {
+			var err = policy.Validate(password, "username", "john@doe.ch")
+			assert.Equal(t, violation, err)
+			assert.Equal(t, MsgErrInvalidParam+"."+Password+"."+violation.Policy, err.Error())
+		})
+	}
+
+	t.Run("Username", func(t *testing.T) {
+		var err = PasswordPolicy{NotUsername: true}.Validate("UserName", "username", "john@doe.ch")
+		assert.Equal(t, PasswordPolicyViolation{Policy: PolicyNotUsername}, err)
+	})
+	t.Run("Email", func(t *testing.T) {
+		var err = PasswordPolicy{NotEmail: true}.Validate("John@Doe.ch", "username", "john@doe.ch")
+		assert.Equal(t, PasswordPolicyViolation{Policy: PolicyNotEmail}, err)
+	})
+}
+
+func TestLocalizedMessage(t *testing.T) {
+	var violation = PasswordPolicyViolation{Policy: PolicyLength, Param: "8"}
+	assert.Equal(t, "Invalid password: minimum length 8.", violation.LocalizedMessage("en"))
+	assert.Equal(t, "Mot de passe invalide : longueur minimale requise de 8.", violation.LocalizedMessage("fr-CH"))
+	assert.Equal(t, "Ungültiges Passwort: Minimallänge 8.", violation.LocalizedMessage("de_CH"))
+	assert.Equal(t, "Invalid password: minimum length 8.", violation.LocalizedMessage("ja"))
+	assert.Equal(t, "Invalid password: minimum length 8.", violation.LocalizedMessage(""))
+
+	violation = PasswordPolicyViolation{Policy: PolicyNotUsername}
+	assert.Equal(t, "Password non valida: non deve essere uguale al nome utente.", violation.LocalizedMessage("it"))
+
+	violation = PasswordPolicyViolation{Policy: PolicyPasswordHistory}
+	assert.Equal(t, violation.Error(), violation.LocalizedMessage("en"))
+}
+
+func TestGeneratePasswordFromPolicy(t *testing.T) {
+	t.Run("Policy requirements", func(t *testing.T) {
+		var policy = PasswordPolicy{Length: 10, Digits: 2, SpecialChars: 2, UpperCase: 2, LowerCase: 2, NotUsername: true}
+		for i := 0; i < 20; i++ {
+			var pwd, err = GeneratePasswordFromPolicy(policy, "username")
+			assert.Nil(t, err)
+			assert.Len(t, pwd, 10)
+			assert.Nil(t, policy.Validate(pwd, "username", ""))
+		}
+	})
+	t.Run("Minimum length", func(t *testing.T) {
+		var pwd, _ = GeneratePasswordFromPolicy(PasswordPolicy{}, "username")
+		assert.Len(t, pwd, minGeneratedPasswordLength)
+	})
+	t.Run("Maximum length", func(t *testing.T) {
+		var pwd, _ = GeneratePasswordFromPolicy(PasswordPolicy{MaxLength: 6}, "username")
+		assert.Len(t, pwd, 6)
+	})
+	t.Run("Requirements longer than length", func(t *testing.T) {
+		var pwd, _ = GeneratePasswordFromPolicy(PasswordPolicy{Length: 4, Digits: 5, UpperCase: 5}, "username")
+		assert.Len(t, pwd, 10)
+	})
+	t.Run("Requirements longer than maximum length", func(t *testing.T) {
+		var _, err = GeneratePasswordFromPolicy(PasswordPolicy{MaxLength: 8, Digits: 5, UpperCase: 5}, "username")
+		assert.NotNil(t, err)
+	})
+	t.Run("Regular expression", func(t *testing.T) {
+		var policy, _ = ParsePasswordPolicy("regexPattern([^0-9]*[0-9][^0-9]*)")
+		var pwd, err = GeneratePasswordFromPolicy(policy, "username")
+		assert.Nil(t, err)
+		assert.Nil(t, policy.Validate(pwd, "username", ""))
+	})
+	t.Run("Regular expression which can't be matched", func(t *testing.T) {
+		var policy, _ = ParsePasswordPolicy("regexPattern(^$)")
+		var _, err = GeneratePasswordFromPolicy(policy, "username")
+		assert.NotNil(t, err)
+	})
+}
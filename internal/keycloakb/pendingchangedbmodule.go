@@ -0,0 +1,110 @@
+package keycloakb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	storePendingChangeStmt = `INSERT INTO pending_change (realm_id, user_id, field, value, code_hash, expires_at, attempts)
+	  VALUES (?, ?, ?, ?, ?, ?, 0)
+	  ON DUPLICATE KEY UPDATE value = ?, code_hash = ?, expires_at = ?, attempts = 0;`
+	deleteExpiredPendingChangesStmt = `DELETE FROM pending_change WHERE (expires_at < ?)`
+	selectPendingChangeStmt         = `SELECT value, code_hash, expires_at, attempts FROM pending_change WHERE (realm_id = ?) AND (user_id = ?) AND (field = ?)`
+	selectPendingChangesStmt        = `SELECT field, value, code_hash, expires_at, attempts FROM pending_change WHERE (realm_id = ?) AND (user_id = ?) AND (expires_at >= ?)`
+	incrementPendingChangeStmt      = `UPDATE pending_change SET attempts = attempts + 1 WHERE (realm_id = ?) AND (user_id = ?) AND (field = ?)`
+	deletePendingChangeStmt         = `DELETE FROM pending_change WHERE (realm_id = ?) AND (user_id = ?) AND (field = ?)`
+)
+
+// PendingChange is a new email or phone number waiting for the confirmation of the user. The value and the salted hash of
+// the verification code sent to it are only stored in the DB of the bridge, where the users can't read nor write them.
+// ExpiresAt is in milliseconds since epoch.
+type PendingChange struct {
+	RealmName string
+	UserID    string
+	Field     string
+	Value     string
+	CodeHash  string
+	ExpiresAt int64
+	Attempts  int
+}
+
+// PendingChangeDBModule stores the changes of email and phone number waiting for a confirmation
+type PendingChangeDBModule interface {
+	StorePendingChange(ctx context.Context, change PendingChange) error
+	GetPendingChange(ctx context.Context, realmName, userID, field string) (*PendingChange, error)
+	GetPendingChanges(ctx context.Context, realmName, userID string) ([]PendingChange, error)
+	IncrementPendingChangeAttempts(ctx context.Context, realmName, userID, field string) error
+	DeletePendingChange(ctx context.Context, realmName, userID, field string) error
+}
+
+type pendingChangeDBModule struct {
+	db DBConfiguration
+}
+
+// NewPendingChangeDBModule returns a PendingChangeDB module.
+func NewPendingChangeDBModule(db DBConfiguration) PendingChangeDBModule {
+	return &pendingChangeDBModule{
+		db: db,
+	}
+}
+
+// StorePendingChange stores the change of a field of a user, replacing the previous one. The expired changes of the other
+// users are removed at the same time so that abandoned values are not kept.
+func (c *pendingChangeDBModule) StorePendingChange(ctx context.Context, change PendingChange) error {
+	if _, err := c.db.Exec(deleteExpiredPendingChangesStmt, time.Now().UnixNano()/int64(time.Millisecond)); err != nil {
+		return err
+	}
+	_, err := c.db.Exec(storePendingChangeStmt, change.RealmName, change.UserID, change.Field, change.Value, change.CodeHash, change.ExpiresAt,
+		change.Value, change.CodeHash, change.ExpiresAt)
+	return err
+}
+
+// GetPendingChange returns the pending change of a field of a user, nil if there is none
+func (c *pendingChangeDBModule) GetPendingChange(ctx context.Context, realmName, userID, field string) (*PendingChange, error) {
+	var change = PendingChange{RealmName: realmName, UserID: userID, Field: field}
+	row := c.db.QueryRow(selectPendingChangeStmt, realmName, userID, field)
+
+	switch err := row.Scan(&change.Value, &change.CodeHash, &change.ExpiresAt, &change.Attempts); err {
+	case sql.ErrNoRows:
+		return nil, nil
+	case nil:
+		return &change, nil
+	default:
+		return nil, err
+	}
+}
+
+// GetPendingChanges returns the changes of a user which are not expired
+func (c *pendingChangeDBModule) GetPendingChanges(ctx context.Context, realmName, userID string) ([]PendingChange, error) {
+	rows, err := c.db.Query(selectPendingChangesStmt, realmName, userID, time.Now().UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes = []PendingChange{}
+	for rows.Next() {
+		var change = PendingChange{RealmName: realmName, UserID: userID}
+		if err = rows.Scan(&change.Field, &change.Value, &change.CodeHash, &change.ExpiresAt, &change.Attempts); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	// Return an error from rows if any error was encountered by Rows.Scan
+	return changes, rows.Err()
+}
+
+// IncrementPendingChangeAttempts counts a wrong code sent for the pending change of a field of a user
+func (c *pendingChangeDBModule) IncrementPendingChangeAttempts(ctx context.Context, realmName, userID, field string) error {
+	_, err := c.db.Exec(incrementPendingChangeStmt, realmName, userID, field)
+	return err
+}
+
+// DeletePendingChange removes the pending change of a field of a user
+func (c *pendingChangeDBModule) DeletePendingChange(ctx context.Context, realmName, userID, field string) error {
+	_, err := c.db.Exec(deletePendingChangeStmt, realmName, userID, field)
+	return err
+}
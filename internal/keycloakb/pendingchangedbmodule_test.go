@@ -0,0 +1,49 @@
+package keycloakb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPendingChangeDBModule(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewDBConfiguration(mockCtrl)
+
+	var module = NewPendingChangeDBModule(mockDB)
+	var ctx = context.Background()
+	var change = PendingChange{RealmName: "realm", UserID: "userId", Field: Email, Value: "new@example.com", CodeHash: "salt:hash", ExpiresAt: 2000}
+
+	t.Run("Store a change", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deleteExpiredPendingChangesStmt, gomock.Any()).Return(rowsAffected(3), nil).Times(1)
+		mockDB.EXPECT().Exec(storePendingChangeStmt, "realm", "userId", Email, "new@example.com", "salt:hash", int64(2000),
+			"new@example.com", "salt:hash", int64(2000)).Return(rowsAffected(1), nil).Times(1)
+		assert.Nil(t, module.StorePendingChange(ctx, change))
+	})
+
+	t.Run("Expired changes can't be removed", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deleteExpiredPendingChangesStmt, gomock.Any()).Return(nil, errors.New("db error")).Times(1)
+		assert.NotNil(t, module.StorePendingChange(ctx, change))
+	})
+
+	t.Run("Changes can't be read", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectPendingChangesStmt, "realm", "userId", gomock.Any()).Return(nil, errors.New("db error")).Times(1)
+		var _, err = module.GetPendingChanges(ctx, "realm", "userId")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Count a wrong code", func(t *testing.T) {
+		mockDB.EXPECT().Exec(incrementPendingChangeStmt, "realm", "userId", PhoneNumber).Return(rowsAffected(1), nil).Times(1)
+		assert.Nil(t, module.IncrementPendingChangeAttempts(ctx, "realm", "userId", PhoneNumber))
+	})
+
+	t.Run("Delete a change", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deletePendingChangeStmt, "realm", "userId", Email).Return(nil, errors.New("db error")).Times(1)
+		assert.NotNil(t, module.DeletePendingChange(ctx, "realm", "userId", Email))
+	})
+}
@@ -0,0 +1,202 @@
+package keycloakb
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// phoneNumberMetadata describes the numbering plan of a country
+type phoneNumberMetadata struct {
+	callingCode string
+	// trunkPrefix is dialed before the national number inside the country and dropped in the international format
+	trunkPrefix string
+	// minLength and maxLength bound the number of digits of the national significant number
+	minLength int
+	maxLength int
+}
+
+// phoneNumberCountries is the offline metadata of the supported countries, by ISO 3166-1 alpha-2 code. Countries sharing a
+// calling code (e.g. US and CA) can't be told apart without area code metadata.
+var phoneNumberCountries = map[string]phoneNumberMetadata{
+	"AT": {callingCode: "43", trunkPrefix: "0", minLength: 4, maxLength: 13},
+	"BE": {callingCode: "32", trunkPrefix: "0", minLength: 8, maxLength: 9},
+	"CA": {callingCode: "1", trunkPrefix: "1", minLength: 10, maxLength: 10},
+	"CH": {callingCode: "41", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"CZ": {callingCode: "420", minLength: 9, maxLength: 9},
+	"DE": {callingCode: "49", trunkPrefix: "0", minLength: 6, maxLength: 13},
+	"DK": {callingCode: "45", minLength: 8, maxLength: 8},
+	"ES": {callingCode: "34", minLength: 9, maxLength: 9},
+	"FI": {callingCode: "358", trunkPrefix: "0", minLength: 5, maxLength: 12},
+	"FR": {callingCode: "33", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"GB": {callingCode: "44", trunkPrefix: "0", minLength: 9, maxLength: 10},
+	"GR": {callingCode: "30", minLength: 10, maxLength: 10},
+	"HU": {callingCode: "36", trunkPrefix: "06", minLength: 8, maxLength: 9},
+	"IE": {callingCode: "353", trunkPrefix: "0", minLength: 7, maxLength: 9},
+	"IT": {callingCode: "39", minLength: 6, maxLength: 11},
+	"LI": {callingCode: "423", minLength: 7, maxLength: 9},
+	"LU": {callingCode: "352", minLength: 4, maxLength: 11},
+	"MC": {callingCode: "377", minLength: 8, maxLength: 9},
+	"NL": {callingCode: "31", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"NO": {callingCode: "47", minLength: 8, maxLength: 8},
+	"PL": {callingCode: "48", minLength: 9, maxLength: 9},
+	"PT": {callingCode: "351", minLength: 9, maxLength: 9},
+	"RO": {callingCode: "40", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"SE": {callingCode: "46", trunkPrefix: "0", minLength: 7, maxLength: 10},
+	"SK": {callingCode: "421", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"US": {callingCode: "1", trunkPrefix: "1", minLength: 10, maxLength: 10},
+}
+
+// E.164 limits the number of digits of a phone number, calling code included
+const (
+	minE164Digits = 8
+	maxE164Digits = 15
+)
+
+// ParsedPhoneNumber is a phone number split into its calling code and its national number
+type ParsedPhoneNumber struct {
+	CallingCode    string
+	NationalNumber string
+	// Countries using the calling code whose numbering plan accepts the national number. It is empty when the calling code
+	// is not part of the metadata.
+	Countries []string
+}
+
+// E164 formats the phone number to E.164 (e.g. +41791234567)
+func (p ParsedPhoneNumber) E164() string {
+	return "+" + p.CallingCode + p.NationalNumber
+}
+
+// InCountries checks whether the phone number belongs to one of the given countries
+func (p ParsedPhoneNumber) InCountries(countries []string) bool {
+	for _, country := range countries {
+		for _, candidate := range p.Countries {
+			if strings.EqualFold(country, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsValidPhoneNumberCountry checks whether the metadata of the country are known
+func IsValidPhoneNumberCountry(country string) bool {
+	_, ok := phoneNumberCountries[country]
+	return ok
+}
+
+// ParsePhoneNumber parses a phone number. International numbers start with + or 00, national ones are interpreted
+// with the numbering plan of the default country. Spaces, dots, dashes, slashes and parentheses are ignored.
+func ParsePhoneNumber(value string, defaultCountry string) (ParsedPhoneNumber, error) {
+	var invalid = errors.New(MsgErrInvalidParam + "." + PhoneNumber)
+
+	var digits, international, ok = phoneNumberDigits(value)
+	if !ok || digits == "" {
+		return ParsedPhoneNumber{}, invalid
+	}
+
+	if !international {
+		metadata, ok := phoneNumberCountries[defaultCountry]
+		if !ok {
+			return ParsedPhoneNumber{}, invalid
+		}
+		if metadata.trunkPrefix != "" {
+			digits = strings.TrimPrefix(digits, metadata.trunkPrefix)
+		}
+		digits = metadata.callingCode + digits
+	}
+
+	if len(digits) < minE164Digits || len(digits) > maxE164Digits || digits[0] == '0' {
+		return ParsedPhoneNumber{}, invalid
+	}
+
+	// calling codes are prefix free: at most one of the prefixes of 1 to 3 digits is a known calling code
+	for length := 1; length <= 3; length++ {
+		var callingCode, nationalNumber = digits[:length], digits[length:]
+		var countries = countriesOfCallingCode(callingCode)
+		if len(countries) == 0 {
+			continue
+		}
+		var matching []string
+		for _, country := range countries {
+			var metadata = phoneNumberCountries[country]
+			if len(nationalNumber) >= metadata.minLength && len(nationalNumber) <= metadata.maxLength {
+				matching = append(matching, country)
+			}
+		}
+		if len(matching) == 0 {
+			return ParsedPhoneNumber{}, invalid
+		}
+		return ParsedPhoneNumber{CallingCode: callingCode, NationalNumber: nationalNumber, Countries: matching}, nil
+	}
+
+	// unknown calling code: the number is only checked against the E.164 limits
+	return ParsedPhoneNumber{CallingCode: digits[:1], NationalNumber: digits[1:]}, nil
+}
+
+// NormalizePhoneNumber parses the phone number and formats it to E.164. National numbers are interpreted with the numbering
+// plan of the first allowed country. When allowedCountries is not empty, the number must belong to one of them.
+func NormalizePhoneNumber(value string, allowedCountries []string) (string, error) {
+	var defaultCountry = ""
+	if len(allowedCountries) > 0 {
+		defaultCountry = allowedCountries[0]
+	}
+
+	phoneNumber, err := ParsePhoneNumber(value, defaultCountry)
+	if err != nil {
+		return "", err
+	}
+	if len(allowedCountries) > 0 && !phoneNumber.InCountries(allowedCountries) {
+		return "", errors.New(MsgErrInvalidParam + "." + PhoneNumberCountry)
+	}
+	return phoneNumber.E164(), nil
+}
+
+// SamePhoneNumber checks whether two phone numbers are equivalent. Numbers which can't be parsed are compared as is.
+func SamePhoneNumber(a, b string, allowedCountries []string) bool {
+	if a == b {
+		return true
+	}
+	normalizedA, errA := NormalizePhoneNumber(a, allowedCountries)
+	normalizedB, errB := NormalizePhoneNumber(b, allowedCountries)
+	return errA == nil && errB == nil && normalizedA == normalizedB
+}
+
+func phoneNumberDigits(value string) (string, bool, bool) {
+	value = strings.TrimSpace(value)
+	var international = false
+	if strings.HasPrefix(value, "+") {
+		international = true
+		value = value[1:]
+	}
+
+	var digits strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" .-/()", r):
+		default:
+			return "", false, false
+		}
+	}
+
+	var res = digits.String()
+	if !international && strings.HasPrefix(res, "00") {
+		international = true
+		res = res[2:]
+	}
+	return res, international, true
+}
+
+func countriesOfCallingCode(callingCode string) []string {
+	var res []string
+	for country, metadata := range phoneNumberCountries {
+		if metadata.callingCode == callingCode {
+			res = append(res, country)
+		}
+	}
+	// map iteration order is random
+	sort.Strings(res)
+	return res
+}
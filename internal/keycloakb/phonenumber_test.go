@@ -0,0 +1,84 @@
+package keycloakb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePhoneNumber(t *testing.T) {
+	t.Run("International formats", func(t *testing.T) {
+		for _, value := range []string{"+41791234567", "+41 79 123 45 67", "0041 79 123 45 67"} {
+			var phoneNumber, err = ParsePhoneNumber(value, "")
+			assert.Nil(t, err, value)
+			assert.Equal(t, "41", phoneNumber.CallingCode)
+			assert.Equal(t, "791234567", phoneNumber.NationalNumber)
+			assert.Equal(t, []string{"CH"}, phoneNumber.Countries)
+			assert.Equal(t, "+41791234567", phoneNumber.E164())
+		}
+	})
+	t.Run("National formats", func(t *testing.T) {
+		var phoneNumber, err = ParsePhoneNumber("079/123.45.67", "CH")
+		assert.Nil(t, err)
+		assert.Equal(t, "+41791234567", phoneNumber.E164())
+
+		phoneNumber, err = ParsePhoneNumber("06 12 34 56 78", "FR")
+		assert.Nil(t, err)
+		assert.Equal(t, "+33612345678", phoneNumber.E164())
+
+		_, err = ParsePhoneNumber("079 123 45 67", "")
+		assert.NotNil(t, err)
+	})
+	t.Run("Shared calling code", func(t *testing.T) {
+		var phoneNumber, err = ParsePhoneNumber("+1 212 555 0100", "")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"CA", "US"}, phoneNumber.Countries)
+	})
+	t.Run("Unknown calling code", func(t *testing.T) {
+		var phoneNumber, err = ParsePhoneNumber("+86 138 0013 8000", "")
+		assert.Nil(t, err)
+		assert.Equal(t, "+8613800138000", phoneNumber.E164())
+		assert.Len(t, phoneNumber.Countries, 0)
+	})
+	t.Run("Invalid numbers", func(t *testing.T) {
+		for _, value := range []string{"", "+", "abc", "+41 79 123", "+41 79 123 45 67 89", "+41 (0)79 123 45 67", "+0041791234567", "+1234567890123456", "+41-79-123-45-67x"} {
+			var _, err = ParsePhoneNumber(value, "CH")
+			assert.NotNil(t, err, value)
+		}
+	})
+}
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	t.Run("Any country", func(t *testing.T) {
+		var res, err = NormalizePhoneNumber("+33 6 12 34 56 78", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "+33612345678", res)
+	})
+	t.Run("National number in the first allowed country", func(t *testing.T) {
+		var res, err = NormalizePhoneNumber("079 123 45 67", []string{"CH", "FR"})
+		assert.Nil(t, err)
+		assert.Equal(t, "+41791234567", res)
+	})
+	t.Run("Country not allowed", func(t *testing.T) {
+		var _, err = NormalizePhoneNumber("+33 6 12 34 56 78", []string{"CH"})
+		assert.Equal(t, MsgErrInvalidParam+"."+PhoneNumberCountry, err.Error())
+
+		_, err = NormalizePhoneNumber("+86 138 0013 8000", []string{"CH"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSamePhoneNumber(t *testing.T) {
+	assert.True(t, SamePhoneNumber("+41791234567", "+41791234567", nil))
+	assert.True(t, SamePhoneNumber("+41791234567", "0041 79 123 45 67", nil))
+	assert.True(t, SamePhoneNumber("+41791234567", "079 123 45 67", []string{"CH"}))
+	assert.False(t, SamePhoneNumber("+41791234567", "079 123 45 67", nil))
+	assert.False(t, SamePhoneNumber("+41791234567", "+41791234568", nil))
+	assert.False(t, SamePhoneNumber("invalid", "+41791234567", nil))
+}
+
+func TestIsValidPhoneNumberCountry(t *testing.T) {
+	assert.True(t, IsValidPhoneNumberCountry("CH"))
+	assert.False(t, IsValidPhoneNumberCountry("ch"))
+	assert.False(t, IsValidPhoneNumberCountry("XX"))
+}
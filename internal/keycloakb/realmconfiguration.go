@@ -0,0 +1,46 @@
+package keycloakb
+
+import (
+	"context"
+
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/pkg/errors"
+)
+
+// RealmConfigurationReader is the interface of the modules reading the custom configuration of the realms
+type RealmConfigurationReader interface {
+	GetConfiguration(context.Context, string) (dto.RealmConfiguration, error)
+}
+
+// GetRealmConfiguration returns the custom configuration of the realm. An empty configuration is returned when the realm
+// has none.
+func GetRealmConfiguration(ctx context.Context, reader RealmConfigurationReader, realmID string, logger Logger) (dto.RealmConfiguration, error) {
+	config, err := reader.GetConfiguration(ctx, realmID)
+	if err != nil {
+		switch e := errors.Cause(err).(type) {
+		case MissingRealmConfigurationErr:
+			return dto.RealmConfiguration{}, nil
+		default:
+			logger.Error("err", e.Error())
+			return dto.RealmConfiguration{}, err
+		}
+	}
+	return config, nil
+}
+
+// UserAttributeSchema returns the custom user attributes of a realm configuration
+func UserAttributeSchema(config dto.RealmConfiguration) []dto.AttributeDefinition {
+	if config.UserAttributes == nil {
+		// no configuration means no custom attribute
+		return []dto.AttributeDefinition{}
+	}
+	return *config.UserAttributes
+}
+
+// PhoneNumberCountries returns the countries allowed for the phone numbers by the realm configuration
+func PhoneNumberCountries(config dto.RealmConfiguration) []string {
+	if config.AllowedPhoneNumberCountries == nil {
+		return nil
+	}
+	return *config.AllowedPhoneNumberCountries
+}
@@ -0,0 +1,56 @@
+package keycloakb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRealmConfiguration(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var ctx = context.Background()
+	var realmID = "realmID"
+	var enabled = true
+	var logger = log.NewNopLogger()
+
+	t.Run("Configuration found", func(t *testing.T) {
+		var config = dto.RealmConfiguration{MFARequired: &enabled}
+		mockModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		var res, err = GetRealmConfiguration(ctx, mockModule, realmID, logger)
+		assert.Nil(t, err)
+		assert.Equal(t, config, res)
+	})
+
+	t.Run("No configuration", func(t *testing.T) {
+		mockModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{}, MissingRealmConfigurationErr{}).Times(1)
+		var res, err = GetRealmConfiguration(ctx, mockModule, realmID, logger)
+		assert.Nil(t, err)
+		assert.Equal(t, dto.RealmConfiguration{}, res)
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		mockModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{}, errors.New("error")).Times(1)
+		var _, err = GetRealmConfiguration(ctx, mockModule, realmID, logger)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRealmConfigurationValues(t *testing.T) {
+	var name = "department"
+	var schema = []dto.AttributeDefinition{{Name: &name}}
+	var countries = []string{"CH", "FR"}
+
+	assert.Equal(t, []dto.AttributeDefinition{}, UserAttributeSchema(dto.RealmConfiguration{}))
+	assert.Equal(t, schema, UserAttributeSchema(dto.RealmConfiguration{UserAttributes: &schema}))
+	assert.Nil(t, PhoneNumberCountries(dto.RealmConfiguration{}))
+	assert.Equal(t, countries, PhoneNumberCountries(dto.RealmConfiguration{AllowedPhoneNumberCountries: &countries}))
+}
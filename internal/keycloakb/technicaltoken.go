@@ -0,0 +1,64 @@
+package keycloakb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// KeycloakTokenClient is the method of keycloak-client used to get an access token
+type KeycloakTokenClient interface {
+	GetToken(realm string, username string, password string) (string, error)
+}
+
+// TokenProvider provides the access token of the technical user of the bridge. It is used for the calls the connected user
+// is not allowed to make.
+type TokenProvider interface {
+	ProvideToken(ctx context.Context) (string, error)
+}
+
+type technicalTokenProvider struct {
+	keycloakClient KeycloakTokenClient
+	realm          string
+	username       string
+	password       string
+	validity       time.Duration
+	logger         Logger
+	now            func() time.Time
+	mutex          sync.Mutex
+	token          string
+	expiresAt      time.Time
+}
+
+// NewTechnicalTokenProvider creates a token provider for the technical user. The token is reused for the given validity,
+// which must be shorter than the lifespan of the access tokens of the realm.
+func NewTechnicalTokenProvider(keycloakClient KeycloakTokenClient, realm, username, password string, validity time.Duration, logger Logger) TokenProvider {
+	return &technicalTokenProvider{
+		keycloakClient: keycloakClient,
+		realm:          realm,
+		username:       username,
+		password:       password,
+		validity:       validity,
+		logger:         logger,
+		now:            time.Now,
+	}
+}
+
+func (p *technicalTokenProvider) ProvideToken(ctx context.Context) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var now = p.now()
+	if p.token != "" && now.Before(p.expiresAt) {
+		return p.token, nil
+	}
+
+	token, err := p.keycloakClient.GetToken(p.realm, p.username, p.password)
+	if err != nil {
+		p.logger.Warn("msg", "could not get the token of the technical user", "err", err.Error())
+		return "", err
+	}
+	p.token = token
+	p.expiresAt = now.Add(p.validity)
+	return token, nil
+}
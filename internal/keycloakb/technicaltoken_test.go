@@ -0,0 +1,48 @@
+package keycloakb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTechnicalTokenProvider(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloak = mock.NewKeycloakTokenClient(mockCtrl)
+
+	var now = time.Now()
+	var provider = NewTechnicalTokenProvider(mockKeycloak, "master", "technical", "secret", time.Minute, log.NewNopLogger())
+	provider.(*technicalTokenProvider).now = func() time.Time { return now }
+	var ctx = context.Background()
+
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloak.EXPECT().GetToken("master", "technical", "secret").Return("", errors.New("error")).Times(1)
+		var _, err = provider.ProvideToken(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Token is reused until it expires", func(t *testing.T) {
+		mockKeycloak.EXPECT().GetToken("master", "technical", "secret").Return("TOKEN-1", nil).Times(1)
+		var token, err = provider.ProvideToken(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "TOKEN-1", token)
+
+		now = now.Add(30 * time.Second)
+		token, err = provider.ProvideToken(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "TOKEN-1", token)
+
+		now = now.Add(time.Minute)
+		mockKeycloak.EXPECT().GetToken("master", "technical", "secret").Return("TOKEN-2", nil).Times(1)
+		token, err = provider.ProvideToken(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "TOKEN-2", token)
+	})
+}
@@ -0,0 +1,32 @@
+package keycloakb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// SessionIDFromToken returns the ID of the Keycloak session an access token was issued for. The token must already have
+// been validated: its signature is not checked here. An empty string is returned when the token has no session.
+func SessionIDFromToken(accessToken string) string {
+	var parts = strings.Split(accessToken, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return ""
+	}
+
+	var claims struct {
+		SessionState string `json:"session_state"`
+		SID          string `json:"sid"`
+	}
+	if json.Unmarshal(payload, &claims) != nil {
+		return ""
+	}
+	if claims.SessionState != "" {
+		return claims.SessionState
+	}
+	return claims.SID
+}
@@ -0,0 +1,21 @@
+package keycloakb
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionIDFromToken(t *testing.T) {
+	var token = func(payload string) string {
+		return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+	}
+
+	assert.Equal(t, "4cd7-session", SessionIDFromToken(token(`{"sub":"1234","session_state":"4cd7-session"}`)))
+	assert.Equal(t, "8f2a-session", SessionIDFromToken(token(`{"sub":"1234","sid":"8f2a-session"}`)))
+	assert.Equal(t, "", SessionIDFromToken(token(`{"sub":"1234"}`)))
+	assert.Equal(t, "", SessionIDFromToken(token(`not json`)))
+	assert.Equal(t, "", SessionIDFromToken("TOKEN=="))
+	assert.Equal(t, "", SessionIDFromToken("a.%%%.c"))
+}
@@ -0,0 +1,52 @@
+package keycloakb
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// verificationCodeLength is the number of digits of the codes sent to verify a new email or phone number
+	verificationCodeLength = 6
+	// verificationSaltLength is the number of random bytes of the salt of the verification code hashes
+	verificationSaltLength = 16
+)
+
+// GenerateVerificationCode returns a random numeric code sent to the users to verify a new email or phone number
+func GenerateVerificationCode() string {
+	var code strings.Builder
+	for i := 0; i < verificationCodeLength; i++ {
+		code.WriteByte(byte('0' + randomInt(10)))
+	}
+	return code.String()
+}
+
+// HashVerificationCode returns the salted hash of a verification code, formatted as salt:hash in hexadecimal
+func HashVerificationCode(code string) (string, error) {
+	var salt = make([]byte, verificationSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt) + ":" + verificationCodeHash(salt, code), nil
+}
+
+// CheckVerificationCode checks a verification code against a salted hash returned by HashVerificationCode
+func CheckVerificationCode(code, saltedHash string) bool {
+	var parts = strings.SplitN(saltedHash, ":", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[0])
+	if err != nil || len(salt) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(verificationCodeHash(salt, code)), []byte(parts[1])) == 1
+}
+
+func verificationCodeHash(salt []byte, code string) string {
+	var hash = sha256.Sum256(append(append([]byte{}, salt...), code...))
+	return hex.EncodeToString(hash[:])
+}
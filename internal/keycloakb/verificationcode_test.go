@@ -0,0 +1,31 @@
+package keycloakb
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateVerificationCode(t *testing.T) {
+	var code = GenerateVerificationCode()
+	assert.Regexp(t, regexp.MustCompile(`^[0-9]{6}$`), code)
+}
+
+func TestVerificationCodeHash(t *testing.T) {
+	var code = "123456"
+
+	saltedHash, err := HashVerificationCode(code)
+	assert.Nil(t, err)
+	assert.NotContains(t, saltedHash, code)
+	assert.True(t, CheckVerificationCode(code, saltedHash))
+	assert.False(t, CheckVerificationCode("654321", saltedHash))
+
+	// the same code gets a different hash each time
+	otherHash, _ := HashVerificationCode(code)
+	assert.NotEqual(t, saltedHash, otherHash)
+
+	assert.False(t, CheckVerificationCode(code, ""))
+	assert.False(t, CheckVerificationCode(code, "not-hex:abc"))
+	assert.False(t, CheckVerificationCode(code, ":abc"))
+}
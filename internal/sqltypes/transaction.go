@@ -0,0 +1,12 @@
+package sqltypes
+
+import (
+	"database/sql"
+)
+
+// Transaction is a database transaction
+type Transaction interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Commit() error
+	Rollback() error
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/cloudtrust/common-service/log"
 	api "github.com/cloudtrust/keycloak-bridge/api/account"
+	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	"github.com/pkg/errors"
 )
 
 // Creates constants for API method names
@@ -23,10 +25,118 @@ const (
 	MoveCredential            = "MoveCredential"
 	GetAccount                = "GetAccount"
 	UpdateAccount             = "UpdateAccount"
+	ConfirmEmailChange        = "ConfirmEmailChange"
+	ConfirmPhoneNumberChange  = "ConfirmPhoneNumberChange"
 	DeleteAccount             = "DeleteAccount"
 	GetConfiguration          = "GetConfiguration"
+	ExportPersonalData        = "ExportPersonalData"
+	GetActivity               = "GetActivity"
+	GetSessions               = "GetSessions"
+	DeleteSession             = "DeleteSession"
+	DeleteOtherSessions       = "DeleteOtherSessions"
+	StartOTPEnrollment        = "StartOTPEnrollment"
+	VerifyOTPEnrollment       = "VerifyOTPEnrollment"
 )
 
+// accountPolicy tells whether the configuration of a realm allows an operation of the account API
+type accountPolicy struct {
+	// feature is the name of the feature logged when the operation is denied
+	feature string
+	// allowed checks the configuration of the realm. Operations without it are not restricted.
+	allowed func(config dto.RealmConfiguration) bool
+}
+
+// unrestricted is the policy of the operations always allowed, without reading the configuration of the realm
+var unrestricted = accountPolicy{}
+
+// accountPolicies are the policies of all the operations of the account API. Operations without policy are denied.
+// Users can always read their account and get the data held about them.
+var accountPolicies = map[string]accountPolicy{
+	UpdatePassword:            {"Password change", passwordChangeEnabled},
+	GetCredentials:            {"Authenticator listing", authenticatorListingEnabled},
+	GetCredentialRegistrators: {"Authenticator listing", authenticatorListingEnabled},
+	UpdateLabelCredential:     {"Authenticator labelling", authenticatorLabellingEnabled},
+	DeleteCredential:          {"Authenticator deletion", authenticatorDeletionEnabled},
+	MoveCredential:            {"Authenticator reordering", authenticatorReorderingEnabled},
+	GetAccount:                unrestricted,
+	UpdateAccount:             {"Profile edition", profileEditingEnabled},
+	ConfirmEmailChange:        {"Mail edition", mailEditingEnabled},
+	ConfirmPhoneNumberChange:  {"Phone number edition", phoneNumberEditingEnabled},
+	DeleteAccount:             {"Account deletion", accountDeletionEnabled},
+	GetConfiguration:          unrestricted,
+	ExportPersonalData:        unrestricted,
+	GetActivity:               {"Activity log", activityLogEnabled},
+	GetSessions:               {"Sessions management", sessionsEnabled},
+	DeleteSession:             {"Sessions management", sessionsEnabled},
+	DeleteOtherSessions:       {"Sessions management", sessionsEnabled},
+	StartOTPEnrollment:        {"OTP enrollment", otpEnrollmentEnabled},
+	VerifyOTPEnrollment:       {"OTP enrollment", otpEnrollmentEnabled},
+}
+
+func passwordChangeEnabled(config dto.RealmConfiguration) bool {
+	return isEnabled(config.APISelfPasswordChangeEnabled)
+}
+
+// Listing, labelling and reordering the authenticators were allowed before they could be configured: they remain
+// allowed in the realms which don't configure them
+func authenticatorListingEnabled(config dto.RealmConfiguration) bool {
+	return isEnabledByDefault(config.APISelfAuthenticatorListingEnabled)
+}
+
+func authenticatorLabellingEnabled(config dto.RealmConfiguration) bool {
+	return isEnabledByDefault(config.APISelfAuthenticatorLabellingEnabled)
+}
+
+func authenticatorReorderingEnabled(config dto.RealmConfiguration) bool {
+	return isEnabledByDefault(config.APISelfAuthenticatorReorderingEnabled)
+}
+
+func authenticatorDeletionEnabled(config dto.RealmConfiguration) bool {
+	return isEnabled(config.APISelfAuthenticatorDeletionEnabled)
+}
+
+func mailEditingEnabled(config dto.RealmConfiguration) bool {
+	return isEnabled(config.APISelfMailEditingEnabled)
+}
+
+// The phone number and the names were editable with the mail before they could be configured: the realms which don't
+// configure them keep the mail setting
+func phoneNumberEditingEnabled(config dto.RealmConfiguration) bool {
+	if config.APISelfPhoneNumberEditingEnabled == nil {
+		return mailEditingEnabled(config)
+	}
+	return *config.APISelfPhoneNumberEditingEnabled
+}
+
+func nameEditingEnabled(config dto.RealmConfiguration) bool {
+	if config.APISelfNameEditingEnabled == nil {
+		return mailEditingEnabled(config)
+	}
+	return *config.APISelfNameEditingEnabled
+}
+
+// profileEditingEnabled allows to update the account when at least one of its fields is editable. The component
+// checks that only the editable fields are changed.
+func profileEditingEnabled(config dto.RealmConfiguration) bool {
+	return mailEditingEnabled(config) || phoneNumberEditingEnabled(config) || nameEditingEnabled(config)
+}
+
+func accountDeletionEnabled(config dto.RealmConfiguration) bool {
+	return isEnabled(config.APISelfAccountDeletionEnabled)
+}
+
+func activityLogEnabled(config dto.RealmConfiguration) bool {
+	return isEnabled(config.APISelfActivityLogEnabled)
+}
+
+func sessionsEnabled(config dto.RealmConfiguration) bool {
+	return isEnabled(config.APISelfSessionsEnabled)
+}
+
+func otpEnrollmentEnabled(config dto.RealmConfiguration) bool {
+	return isEnabled(config.APISelfOTPEnrollmentEnabled)
+}
+
 // Tracking middleware at component level.
 type authorizationComponentMW struct {
 	logger         log.Logger
@@ -45,15 +155,30 @@ func MakeAuthorizationAccountComponentMW(logger log.Logger, configDBModule Confi
 	}
 }
 
-// authorizationComponentMW implements Component.
-func (c *authorizationComponentMW) UpdatePassword(ctx context.Context, currentPassword, newPassword, confirmPassword string) error {
-	var action = UpdatePassword
+// checkAuthorization checks the policy of the action against the configuration of the current realm
+func (c *authorizationComponentMW) checkAuthorization(ctx context.Context, action string) error {
 	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
 
-	var config = dto.RealmConfiguration{}
-	var err error
+	policy, ok := accountPolicies[action]
+	if !ok {
+		infos, _ := json.Marshal(map[string]string{
+			"Action":       action,
+			"currentRealm": currentRealm,
+		})
+		c.logger.Warn("ForbiddenError", "No policy for the action", "infos", string(infos))
+		return security.ForbiddenError{}
+	}
+
+	if policy.allowed == nil {
+		return nil
+	}
 
-	if config, err = c.configDBModule.GetConfiguration(ctx, currentRealm); err != nil {
+	config, err := c.configDBModule.GetConfiguration(ctx, currentRealm)
+	if _, ok := errors.Cause(err).(internal.MissingRealmConfigurationErr); ok {
+		// a realm without configuration only allows the operations allowed by default
+		config, err = dto.RealmConfiguration{}, nil
+	}
+	if err != nil {
 		infos, _ := json.Marshal(map[string]string{
 			"currentRealm": currentRealm,
 		})
@@ -61,129 +186,157 @@ func (c *authorizationComponentMW) UpdatePassword(ctx context.Context, currentPa
 		return err
 	}
 
-	if !isEnabled(config.APISelfPasswordChangeEnabled) {
+	if !policy.allowed(config) {
 		infos, _ := json.Marshal(map[string]string{
 			"Action":       action,
 			"currentRealm": currentRealm,
 		})
-		c.logger.Debug("ForbiddenError", "Password change disabled", "infos", string(infos))
+		c.logger.Debug("ForbiddenError", policy.feature+" disabled", "infos", string(infos))
 		return security.ForbiddenError{}
 	}
 
-	return c.next.UpdatePassword(ctx, currentPassword, newPassword, confirmPassword)
+	return nil
 }
 
 // authorizationComponentMW implements Component.
+func (c *authorizationComponentMW) UpdatePassword(ctx context.Context, currentPassword, newPassword, confirmPassword string) error {
+	if err := c.checkAuthorization(ctx, UpdatePassword); err != nil {
+		return err
+	}
+	return c.next.UpdatePassword(ctx, currentPassword, newPassword, confirmPassword)
+}
+
 func (c *authorizationComponentMW) GetCredentials(ctx context.Context) ([]api.CredentialRepresentation, error) {
-	// No restriction for this call
+	if err := c.checkAuthorization(ctx, GetCredentials); err != nil {
+		return nil, err
+	}
 	return c.next.GetCredentials(ctx)
 }
 
 func (c *authorizationComponentMW) GetCredentialRegistrators(ctx context.Context) ([]string, error) {
-	// No restriction for this call
+	if err := c.checkAuthorization(ctx, GetCredentialRegistrators); err != nil {
+		return nil, err
+	}
 	return c.next.GetCredentialRegistrators(ctx)
 }
 
 func (c *authorizationComponentMW) UpdateLabelCredential(ctx context.Context, credentialID string, label string) error {
-	// No restriction for this call
+	if err := c.checkAuthorization(ctx, UpdateLabelCredential); err != nil {
+		return err
+	}
 	return c.next.UpdateLabelCredential(ctx, credentialID, label)
 }
 
 func (c *authorizationComponentMW) MoveCredential(ctx context.Context, credentialID string, previousCredentialID string) error {
-	// No restriction for this call
+	if err := c.checkAuthorization(ctx, MoveCredential); err != nil {
+		return err
+	}
 	return c.next.MoveCredential(ctx, credentialID, previousCredentialID)
 }
 
 func (c *authorizationComponentMW) DeleteCredential(ctx context.Context, credentialID string) error {
-	var action = DeleteCredential
-	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
-
-	var config = dto.RealmConfiguration{}
-	var err error
-
-	if config, err = c.configDBModule.GetConfiguration(ctx, currentRealm); err != nil {
-		infos, _ := json.Marshal(map[string]string{
-			"currentRealm": currentRealm,
-		})
-		c.logger.Error("Error", "Configuration not found", "infos", string(infos))
+	if err := c.checkAuthorization(ctx, DeleteCredential); err != nil {
 		return err
 	}
-
-	if !isEnabled(config.APISelfAuthenticatorDeletionEnabled) {
-		infos, _ := json.Marshal(map[string]string{
-			"Action":       action,
-			"currentRealm": currentRealm,
-		})
-		c.logger.Debug("ForbiddenError", "Authenticator deletion disabled", "infos", string(infos))
-		return security.ForbiddenError{}
-	}
-
 	return c.next.DeleteCredential(ctx, credentialID)
 }
 
 func (c *authorizationComponentMW) GetAccount(ctx context.Context) (api.AccountRepresentation, error) {
-	// No restriction for this call
+	if err := c.checkAuthorization(ctx, GetAccount); err != nil {
+		return api.AccountRepresentation{}, err
+	}
 	return c.next.GetAccount(ctx)
 }
 
 func (c *authorizationComponentMW) UpdateAccount(ctx context.Context, account api.AccountRepresentation) error {
-	var action = UpdateAccount
-	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
+	if err := c.checkAuthorization(ctx, UpdateAccount); err != nil {
+		return err
+	}
+	return c.next.UpdateAccount(ctx, account)
+}
 
-	var config = dto.RealmConfiguration{}
-	var err error
+func (c *authorizationComponentMW) ConfirmEmailChange(ctx context.Context, code string) error {
+	if err := c.checkAuthorization(ctx, ConfirmEmailChange); err != nil {
+		return err
+	}
+	return c.next.ConfirmEmailChange(ctx, code)
+}
 
-	if config, err = c.configDBModule.GetConfiguration(ctx, currentRealm); err != nil {
-		infos, _ := json.Marshal(map[string]string{
-			"currentRealm": currentRealm,
-		})
-		c.logger.Error("Error", "Configuration not found", "infos", string(infos))
+func (c *authorizationComponentMW) ConfirmPhoneNumberChange(ctx context.Context, code string) error {
+	if err := c.checkAuthorization(ctx, ConfirmPhoneNumberChange); err != nil {
 		return err
 	}
+	return c.next.ConfirmPhoneNumberChange(ctx, code)
+}
 
-	if !isEnabled(config.APISelfMailEditingEnabled) {
-		infos, _ := json.Marshal(map[string]string{
-			"Action":       action,
-			"currentRealm": currentRealm,
-		})
-		c.logger.Debug("ForbiddenError", "Mail edition disabled", "infos", string(infos))
-		return security.ForbiddenError{}
+func (c *authorizationComponentMW) DeleteAccount(ctx context.Context) error {
+	if err := c.checkAuthorization(ctx, DeleteAccount); err != nil {
+		return err
 	}
+	return c.next.DeleteAccount(ctx)
+}
 
-	return c.next.UpdateAccount(ctx, account)
+func (c *authorizationComponentMW) GetConfiguration(ctx context.Context) (api.Configuration, error) {
+	if err := c.checkAuthorization(ctx, GetConfiguration); err != nil {
+		return api.Configuration{}, err
+	}
+	return c.next.GetConfiguration(ctx)
 }
 
-func (c *authorizationComponentMW) DeleteAccount(ctx context.Context) error {
-	var action = DeleteAccount
-	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
+func (c *authorizationComponentMW) ExportPersonalData(ctx context.Context) (api.PersonalDataRepresentation, error) {
+	if err := c.checkAuthorization(ctx, ExportPersonalData); err != nil {
+		return api.PersonalDataRepresentation{}, err
+	}
+	return c.next.ExportPersonalData(ctx)
+}
+
+func (c *authorizationComponentMW) GetActivity(ctx context.Context, paramKV ...string) ([]api.ActivityRepresentation, error) {
+	if err := c.checkAuthorization(ctx, GetActivity); err != nil {
+		return nil, err
+	}
+	return c.next.GetActivity(ctx, paramKV...)
+}
 
-	var err error
-	var config = dto.RealmConfiguration{}
+func (c *authorizationComponentMW) GetSessions(ctx context.Context) ([]api.SessionRepresentation, error) {
+	if err := c.checkAuthorization(ctx, GetSessions); err != nil {
+		return nil, err
+	}
+	return c.next.GetSessions(ctx)
+}
 
-	if config, err = c.configDBModule.GetConfiguration(ctx, currentRealm); err != nil {
-		infos, _ := json.Marshal(map[string]string{
-			"currentRealm": currentRealm,
-		})
-		c.logger.Error("Error", "Configuration not found", "infos", string(infos))
+func (c *authorizationComponentMW) DeleteSession(ctx context.Context, sessionID string) error {
+	if err := c.checkAuthorization(ctx, DeleteSession); err != nil {
+		return err
+	}
+	return c.next.DeleteSession(ctx, sessionID)
+}
+
+func (c *authorizationComponentMW) DeleteOtherSessions(ctx context.Context) error {
+	if err := c.checkAuthorization(ctx, DeleteOtherSessions); err != nil {
 		return err
 	}
+	return c.next.DeleteOtherSessions(ctx)
+}
 
-	if !isEnabled(config.APISelfAccountDeletionEnabled) {
-		infos, _ := json.Marshal(map[string]string{
-			"Action":       action,
-			"currentRealm": currentRealm,
-		})
-		c.logger.Debug("ForbiddenError", "Account deletion disabled", "infos", string(infos))
-		return security.ForbiddenError{}
+func (c *authorizationComponentMW) StartOTPEnrollment(ctx context.Context) (api.OTPEnrollmentRepresentation, error) {
+	if err := c.checkAuthorization(ctx, StartOTPEnrollment); err != nil {
+		return api.OTPEnrollmentRepresentation{}, err
 	}
-	return c.next.DeleteAccount(ctx)
+	return c.next.StartOTPEnrollment(ctx)
 }
 
-func (c *authorizationComponentMW) GetConfiguration(ctx context.Context) (api.Configuration, error) {
-	//No restriction for this call
-	return c.next.GetConfiguration(ctx)
+func (c *authorizationComponentMW) VerifyOTPEnrollment(ctx context.Context, code string, userLabel *string) error {
+	if err := c.checkAuthorization(ctx, VerifyOTPEnrollment); err != nil {
+		return err
+	}
+	return c.next.VerifyOTPEnrollment(ctx, code, userLabel)
 }
 
 func isEnabled(booleanPtr *bool) bool {
 	return booleanPtr != nil && *booleanPtr
 }
+
+// isEnabledByDefault is used by the flags of the operations allowed before they could be configured
+func isEnabledByDefault(booleanPtr *bool) bool {
+	return booleanPtr == nil || *booleanPtr
+}
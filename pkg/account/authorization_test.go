@@ -3,6 +3,7 @@ package account
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
 
 	cs "github.com/cloudtrust/common-service"
@@ -10,6 +11,7 @@ import (
 	"github.com/cloudtrust/common-service/security"
 	api "github.com/cloudtrust/keycloak-bridge/api/account"
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	"github.com/cloudtrust/keycloak-bridge/pkg/account/mock"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -25,7 +27,6 @@ func TestNoRestrictions(t *testing.T) {
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
-	var credentialID = "786-5684-6464"
 
 	var err error
 
@@ -36,22 +37,6 @@ func TestNoRestrictions(t *testing.T) {
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
 
-		mockAccountComponent.EXPECT().GetCredentials(ctx).Return([]api.CredentialRepresentation{}, nil).Times(1)
-		_, err = authorizationMW.GetCredentials(ctx)
-		assert.Nil(t, err)
-
-		mockAccountComponent.EXPECT().GetCredentialRegistrators(ctx).Return([]string{}, nil).Times(1)
-		_, err = authorizationMW.GetCredentialRegistrators(ctx)
-		assert.Nil(t, err)
-
-		mockAccountComponent.EXPECT().UpdateLabelCredential(ctx, credentialID, "newLabel").Return(nil).Times(1)
-		err = authorizationMW.UpdateLabelCredential(ctx, credentialID, "newLabel")
-		assert.Nil(t, err)
-
-		mockAccountComponent.EXPECT().MoveCredential(ctx, credentialID, credentialID).Return(nil).Times(1)
-		err = authorizationMW.MoveCredential(ctx, credentialID, credentialID)
-		assert.Nil(t, err)
-
 		mockAccountComponent.EXPECT().GetAccount(ctx).Return(api.AccountRepresentation{}, nil).Times(1)
 		_, err = authorizationMW.GetAccount(ctx)
 		assert.Nil(t, err)
@@ -59,6 +44,10 @@ func TestNoRestrictions(t *testing.T) {
 		mockAccountComponent.EXPECT().GetConfiguration(ctx).Return(api.Configuration{}, nil).Times(1)
 		_, err = authorizationMW.GetConfiguration(ctx)
 		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().ExportPersonalData(ctx).Return(api.PersonalDataRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.ExportPersonalData(ctx)
+		assert.Nil(t, err)
 	}
 }
 
@@ -77,12 +66,15 @@ func TestDeny(t *testing.T) {
 	var credentialID = "786-5684-6464"
 
 	var realmConfig = dto.RealmConfiguration{
-		DefaultClientID:                     new(string),
-		DefaultRedirectURI:                  new(string),
-		APISelfAuthenticatorDeletionEnabled: &falseBool,
-		APISelfAccountDeletionEnabled:       &falseBool,
-		APISelfMailEditingEnabled:           &falseBool,
-		APISelfPasswordChangeEnabled:        &falseBool,
+		DefaultClientID:                       new(string),
+		DefaultRedirectURI:                    new(string),
+		APISelfAuthenticatorDeletionEnabled:   &falseBool,
+		APISelfAccountDeletionEnabled:         &falseBool,
+		APISelfMailEditingEnabled:             &falseBool,
+		APISelfPasswordChangeEnabled:          &falseBool,
+		APISelfAuthenticatorListingEnabled:    &falseBool,
+		APISelfAuthenticatorLabellingEnabled:  &falseBool,
+		APISelfAuthenticatorReorderingEnabled: &falseBool,
 	}
 
 	mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmName).Return(realmConfig, nil).AnyTimes()
@@ -99,14 +91,50 @@ func TestDeny(t *testing.T) {
 		err = authorizationMW.UpdatePassword(ctx, "currentPassword", "newPassword", "newPAssword")
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		_, err = authorizationMW.GetCredentials(ctx)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetCredentialRegistrators(ctx)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.UpdateLabelCredential(ctx, credentialID, "newLabel")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.MoveCredential(ctx, credentialID, credentialID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		err = authorizationMW.DeleteCredential(ctx, credentialID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
 		err = authorizationMW.UpdateAccount(ctx, api.AccountRepresentation{})
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		err = authorizationMW.ConfirmEmailChange(ctx, "code")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.ConfirmPhoneNumberChange(ctx, "code")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		err = authorizationMW.DeleteAccount(ctx)
 		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetActivity(ctx)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetSessions(ctx)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.DeleteSession(ctx, "sessionID")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.DeleteOtherSessions(ctx)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.StartOTPEnrollment(ctx)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.VerifyOTPEnrollment(ctx, "123456", nil)
+		assert.Equal(t, security.ForbiddenError{}, err)
 	}
 }
 
@@ -125,12 +153,18 @@ func TestAllowed(t *testing.T) {
 	var credentialID = "786-5684-6464"
 
 	var realmConfig = dto.RealmConfiguration{
-		DefaultClientID:                     new(string),
-		DefaultRedirectURI:                  new(string),
-		APISelfAuthenticatorDeletionEnabled: &trueBool,
-		APISelfAccountDeletionEnabled:       &trueBool,
-		APISelfMailEditingEnabled:           &trueBool,
-		APISelfPasswordChangeEnabled:        &trueBool,
+		DefaultClientID:                       new(string),
+		DefaultRedirectURI:                    new(string),
+		APISelfAuthenticatorDeletionEnabled:   &trueBool,
+		APISelfAccountDeletionEnabled:         &trueBool,
+		APISelfMailEditingEnabled:             &trueBool,
+		APISelfPasswordChangeEnabled:          &trueBool,
+		APISelfActivityLogEnabled:             &trueBool,
+		APISelfSessionsEnabled:                &trueBool,
+		APISelfOTPEnrollmentEnabled:           &trueBool,
+		APISelfAuthenticatorListingEnabled:    &trueBool,
+		APISelfAuthenticatorLabellingEnabled:  &trueBool,
+		APISelfAuthenticatorReorderingEnabled: &trueBool,
 	}
 
 	mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmName).Return(realmConfig, nil).AnyTimes()
@@ -148,6 +182,22 @@ func TestAllowed(t *testing.T) {
 		err = authorizationMW.UpdatePassword(ctx, "currentPassword", "newPassword", "newPAssword")
 		assert.Nil(t, err)
 
+		mockAccountComponent.EXPECT().GetCredentials(ctx).Return([]api.CredentialRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetCredentials(ctx)
+		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().GetCredentialRegistrators(ctx).Return([]string{}, nil).Times(1)
+		_, err = authorizationMW.GetCredentialRegistrators(ctx)
+		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().UpdateLabelCredential(ctx, credentialID, "newLabel").Return(nil).Times(1)
+		err = authorizationMW.UpdateLabelCredential(ctx, credentialID, "newLabel")
+		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().MoveCredential(ctx, credentialID, credentialID).Return(nil).Times(1)
+		err = authorizationMW.MoveCredential(ctx, credentialID, credentialID)
+		assert.Nil(t, err)
+
 		mockAccountComponent.EXPECT().DeleteCredential(ctx, credentialID).Return(nil).Times(1)
 		err = authorizationMW.DeleteCredential(ctx, credentialID)
 		assert.Nil(t, err)
@@ -156,10 +206,42 @@ func TestAllowed(t *testing.T) {
 		err = authorizationMW.UpdateAccount(ctx, api.AccountRepresentation{})
 		assert.Nil(t, err)
 
+		mockAccountComponent.EXPECT().ConfirmEmailChange(ctx, "code").Return(nil).Times(1)
+		err = authorizationMW.ConfirmEmailChange(ctx, "code")
+		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().ConfirmPhoneNumberChange(ctx, "code").Return(nil).Times(1)
+		err = authorizationMW.ConfirmPhoneNumberChange(ctx, "code")
+		assert.Nil(t, err)
+
 		mockAccountComponent.EXPECT().DeleteAccount(ctx).Return(nil).Times(1)
 		err = authorizationMW.DeleteAccount(ctx)
 		assert.Nil(t, err)
 
+		mockAccountComponent.EXPECT().GetActivity(ctx, "max", "10").Return([]api.ActivityRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetActivity(ctx, "max", "10")
+		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().GetSessions(ctx).Return([]api.SessionRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetSessions(ctx)
+		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().DeleteSession(ctx, "sessionID").Return(nil).Times(1)
+		err = authorizationMW.DeleteSession(ctx, "sessionID")
+		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().DeleteOtherSessions(ctx).Return(nil).Times(1)
+		err = authorizationMW.DeleteOtherSessions(ctx)
+		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().StartOTPEnrollment(ctx).Return(api.OTPEnrollmentRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.StartOTPEnrollment(ctx)
+		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().VerifyOTPEnrollment(ctx, "123456", nil).Return(nil).Times(1)
+		err = authorizationMW.VerifyOTPEnrollment(ctx, "123456", nil)
+		assert.Nil(t, err)
+
 	}
 }
 
@@ -192,13 +274,117 @@ func TestError(t *testing.T) {
 		err = authorizationMW.UpdatePassword(ctx, "currentPassword", "newPassword", "newPAssword")
 		assert.NotNil(t, err)
 
+		_, err = authorizationMW.GetCredentials(ctx)
+		assert.NotNil(t, err)
+
+		_, err = authorizationMW.GetCredentialRegistrators(ctx)
+		assert.NotNil(t, err)
+
+		err = authorizationMW.UpdateLabelCredential(ctx, credentialID, "newLabel")
+		assert.NotNil(t, err)
+
+		err = authorizationMW.MoveCredential(ctx, credentialID, credentialID)
+		assert.NotNil(t, err)
+
 		err = authorizationMW.DeleteCredential(ctx, credentialID)
 		assert.NotNil(t, err)
 
 		err = authorizationMW.UpdateAccount(ctx, api.AccountRepresentation{})
 		assert.NotNil(t, err)
 
+		err = authorizationMW.ConfirmEmailChange(ctx, "code")
+		assert.NotNil(t, err)
+
+		err = authorizationMW.ConfirmPhoneNumberChange(ctx, "code")
+		assert.NotNil(t, err)
+
 		err = authorizationMW.DeleteAccount(ctx)
 		assert.NotNil(t, err)
+
+		_, err = authorizationMW.GetActivity(ctx)
+		assert.NotNil(t, err)
+
+		_, err = authorizationMW.GetSessions(ctx)
+		assert.NotNil(t, err)
+
+		err = authorizationMW.DeleteSession(ctx, "sessionID")
+		assert.NotNil(t, err)
+
+		err = authorizationMW.DeleteOtherSessions(ctx)
+		assert.NotNil(t, err)
+
+		_, err = authorizationMW.StartOTPEnrollment(ctx)
+		assert.NotNil(t, err)
+
+		err = authorizationMW.VerifyOTPEnrollment(ctx, "123456", nil)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestDefaultPolicies(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockAccountComponent = mock.NewAccountComponent(mockCtrl)
+	var authorizationMW = MakeAuthorizationAccountComponentMW(log.NewNopLogger(), mockConfigurationDBModule)(mockAccountComponent)
+
+	var realmName = "master"
+	var credentialID = "786-5684-6464"
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, realmName)
+
+	t.Run("Realm without configuration", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(dto.RealmConfiguration{}, keycloakb.MissingRealmConfigurationErr{}).Times(3)
+
+		mockAccountComponent.EXPECT().GetCredentials(ctx).Return([]api.CredentialRepresentation{}, nil).Times(1)
+		var _, err = authorizationMW.GetCredentials(ctx)
+		assert.Nil(t, err)
+
+		mockAccountComponent.EXPECT().MoveCredential(ctx, credentialID, credentialID).Return(nil).Times(1)
+		err = authorizationMW.MoveCredential(ctx, credentialID, credentialID)
+		assert.Nil(t, err)
+
+		err = authorizationMW.UpdatePassword(ctx, "currentPassword", "newPassword", "newPassword")
+		assert.Equal(t, security.ForbiddenError{}, err)
+	})
+
+	t.Run("Phone number and names follow the mail editing when not configured", func(t *testing.T) {
+		var trueBool = true
+		var falseBool = false
+
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(dto.RealmConfiguration{APISelfMailEditingEnabled: &trueBool}, nil).Times(2)
+		mockAccountComponent.EXPECT().ConfirmPhoneNumberChange(ctx, "code").Return(nil).Times(1)
+		var err = authorizationMW.ConfirmPhoneNumberChange(ctx, "code")
+		assert.Nil(t, err)
+		mockAccountComponent.EXPECT().UpdateAccount(ctx, api.AccountRepresentation{}).Return(nil).Times(1)
+		err = authorizationMW.UpdateAccount(ctx, api.AccountRepresentation{})
+		assert.Nil(t, err)
+
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(dto.RealmConfiguration{APISelfMailEditingEnabled: &trueBool, APISelfPhoneNumberEditingEnabled: &falseBool}, nil).Times(1)
+		err = authorizationMW.ConfirmPhoneNumberChange(ctx, "code")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		// the account can be updated when a single field is editable
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(dto.RealmConfiguration{APISelfNameEditingEnabled: &trueBool}, nil).Times(2)
+		mockAccountComponent.EXPECT().UpdateAccount(ctx, api.AccountRepresentation{}).Return(nil).Times(1)
+		err = authorizationMW.UpdateAccount(ctx, api.AccountRepresentation{})
+		assert.Nil(t, err)
+		err = authorizationMW.ConfirmEmailChange(ctx, "code")
+		assert.Equal(t, security.ForbiddenError{}, err)
+	})
+}
+
+func TestAccountPolicies(t *testing.T) {
+	// every operation of the component has a policy: operations without policy are denied
+	var componentType = reflect.TypeOf((*Component)(nil)).Elem()
+	for i := 0; i < componentType.NumMethod(); i++ {
+		var method = componentType.Method(i).Name
+		_, ok := accountPolicies[method]
+		assert.True(t, ok, "no policy for "+method)
 	}
+	assert.Len(t, accountPolicies, componentType.NumMethod())
+
+	var authorizationMW = &authorizationComponentMW{logger: log.NewNopLogger()}
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, "master")
+	assert.Equal(t, security.ForbiddenError{}, authorizationMW.checkAuthorization(ctx, "UnknownAction"))
 }
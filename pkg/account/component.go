@@ -5,14 +5,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	cs "github.com/cloudtrust/common-service"
 	"github.com/cloudtrust/common-service/database"
 	errorhandler "github.com/cloudtrust/common-service/errors"
 	api "github.com/cloudtrust/keycloak-bridge/api/account"
+	events_api "github.com/cloudtrust/keycloak-bridge/api/events"
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
 	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/pkg/errors"
 )
 
 // KeycloakAccountClient interface exposes methods we need to call to send requests to Keycloak API of Account
@@ -29,6 +33,17 @@ type KeycloakAccountClient interface {
 	DeleteAccount(accessToken, realm string) error
 }
 
+// KeycloakTechnicalClient interface exposes the methods of Keycloak called with the token of the technical user
+type KeycloakTechnicalClient interface {
+	GetUser(accessToken string, realmName, userID string) (kc.UserRepresentation, error)
+	UpdateUser(accessToken string, realmName, userID string, user kc.UserRepresentation) error
+	LogoutUser(accessToken string, realmName, userID string) error
+	DeleteUser(accessToken string, realmName, userID string) error
+	GetSessionsForUser(accessToken string, realmName, userID string) ([]kc.UserSessionRepresentation, error)
+	DeleteSession(accessToken string, realmName, sessionID string) error
+	GetRealm(accessToken string, realmName string) (kc.RealmRepresentation, error)
+}
+
 // Component interface exposes methods used by the bridge API
 type Component interface {
 	UpdatePassword(ctx context.Context, currentPassword, newPassword, confirmPassword string) error
@@ -39,31 +54,83 @@ type Component interface {
 	MoveCredential(ctx context.Context, credentialID string, previousCredentialID string) error
 	GetAccount(ctx context.Context) (api.AccountRepresentation, error)
 	UpdateAccount(context.Context, api.AccountRepresentation) error
+	ConfirmEmailChange(ctx context.Context, code string) error
+	ConfirmPhoneNumberChange(ctx context.Context, code string) error
 	DeleteAccount(context.Context) error
 	GetConfiguration(context.Context) (api.Configuration, error)
+	ExportPersonalData(context.Context) (api.PersonalDataRepresentation, error)
+	GetActivity(ctx context.Context, paramKV ...string) ([]api.ActivityRepresentation, error)
+	GetSessions(ctx context.Context) ([]api.SessionRepresentation, error)
+	DeleteSession(ctx context.Context, sessionID string) error
+	DeleteOtherSessions(ctx context.Context) error
+	StartOTPEnrollment(ctx context.Context) (api.OTPEnrollmentRepresentation, error)
+	VerifyOTPEnrollment(ctx context.Context, code string, userLabel *string) error
 }
 
 // ConfigurationDBModule is the interface of the configuration module.
 type ConfigurationDBModule interface {
 	GetConfiguration(context.Context, string) (dto.RealmConfiguration, error)
-	StoreOrUpdate(context.Context, string, dto.RealmConfiguration) error
+	GetConfigurationHistory(context.Context, string) ([]dto.RealmConfigurationVersion, error)
+	GetConfigurationVersion(context.Context, string, int) (dto.RealmConfigurationVersion, error)
+	StoreOrUpdate(context.Context, string, dto.RealmConfiguration, string, string) error
+}
+
+// activityEventTypes are the audit events shown to the users in their activity: logins, password and credential changes
+var activityEventTypes = []string{"LOGON_OK", "LOGON_ERROR", "LOGOUT", "PASSWORD_RESET", "SELF_UPDATE_CREDENTIAL", "SELF_ADD_CREDENTIAL", "SELF_DELETE_CREDENTIAL", "SELF_MOVE_CREDENTIAL"}
+
+// maxActivityEvents is the default number of events returned by GetActivity
+const maxActivityEvents = 100
+
+// exportEventsPageSize is the number of audit events read at once when exporting the personal data
+const exportEventsPageSize = 500
+
+// AuditEventsReaderModule is the interface of the module reading the audit events.
+type AuditEventsReaderModule interface {
+	GetEvents(context.Context, map[string]string) ([]events_api.AuditRepresentation, error)
+}
+
+// PasswordPolicyError is returned when a new password does not comply with the password policy of the realm.
+// It is rendered as a bad request including the description of the violation in the locale of the user.
+type PasswordPolicyError struct {
+	Violation internal.PasswordPolicyViolation
+	Locale    string
+}
+
+func (e PasswordPolicyError) Error() string {
+	return internal.ComponentName + "." + internal.MsgErrInvalidParam + "." + internal.NewPassword + "." + e.Violation.Policy
 }
 
 // Component is the management component.
 type component struct {
-	keycloakAccountClient KeycloakAccountClient
-	eventDBModule         database.EventsDBModule
-	configDBModule        ConfigurationDBModule
-	logger                internal.Logger
+	keycloakAccountClient   KeycloakAccountClient
+	keycloakTechnicalClient KeycloakTechnicalClient
+	tokenProvider           internal.TokenProvider
+	eventDBModule           database.EventsDBModule
+	configDBModule          ConfigurationDBModule
+	accountDeletionDBModule internal.AccountDeletionDBModule
+	otpEnrollmentDBModule   internal.OTPEnrollmentDBModule
+	pendingChangeDBModule   internal.PendingChangeDBModule
+	auditEventsReader       AuditEventsReaderModule
+	breachedPasswordChecker internal.BreachedPasswordChecker
+	notifier                internal.Notifier
+	logger                  internal.Logger
 }
 
 // NewComponent returns the self-service component.
-func NewComponent(keycloakAccountClient KeycloakAccountClient, eventDBModule database.EventsDBModule, configDBModule ConfigurationDBModule, logger internal.Logger) Component {
+func NewComponent(keycloakAccountClient KeycloakAccountClient, keycloakTechnicalClient KeycloakTechnicalClient, tokenProvider internal.TokenProvider, eventDBModule database.EventsDBModule, configDBModule ConfigurationDBModule, accountDeletionDBModule internal.AccountDeletionDBModule, otpEnrollmentDBModule internal.OTPEnrollmentDBModule, pendingChangeDBModule internal.PendingChangeDBModule, auditEventsReader AuditEventsReaderModule, breachedPasswordChecker internal.BreachedPasswordChecker, notifier internal.Notifier, logger internal.Logger) Component {
 	return &component{
-		keycloakAccountClient: keycloakAccountClient,
-		eventDBModule:         eventDBModule,
-		configDBModule:        configDBModule,
-		logger:                logger,
+		keycloakAccountClient:   keycloakAccountClient,
+		keycloakTechnicalClient: keycloakTechnicalClient,
+		tokenProvider:           tokenProvider,
+		eventDBModule:           eventDBModule,
+		configDBModule:          configDBModule,
+		accountDeletionDBModule: accountDeletionDBModule,
+		otpEnrollmentDBModule:   otpEnrollmentDBModule,
+		pendingChangeDBModule:   pendingChangeDBModule,
+		auditEventsReader:       auditEventsReader,
+		breachedPasswordChecker: breachedPasswordChecker,
+		notifier:                notifier,
+		logger:                  logger,
 	}
 }
 
@@ -71,6 +138,19 @@ func (c *component) reportEvent(ctx context.Context, apiCall string, values ...s
 	return c.eventDBModule.ReportEvent(ctx, apiCall, "self-service", values...)
 }
 
+// reportAccountEvent stores an event of the account in the DB, or in the logs if it can't be stored
+func (c *component) reportAccountEvent(ctx context.Context, eventName, realm, userID, username string, details map[string]string) {
+	var values = []string{database.CtEventRealmName, realm, database.CtEventUserID, userID, database.CtEventUsername, username}
+	if details != nil {
+		additionalInfo, _ := json.Marshal(details)
+		values = append(values, database.CtEventAdditionalInfo, string(additionalInfo))
+	}
+
+	if err := c.reportEvent(ctx, eventName, values...); err != nil {
+		internal.LogUnstoredEvent(c.logger, err, eventName, values...)
+	}
+}
+
 func (c *component) UpdatePassword(ctx context.Context, currentPassword, newPassword, confirmPassword string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var realm = ctx.Value(cs.CtContextRealm).(string)
@@ -84,6 +164,10 @@ func (c *component) UpdatePassword(ctx context.Context, currentPassword, newPass
 		}
 	}
 
+	if err := c.checkNewPassword(ctx, accessToken, realm, username, newPassword); err != nil {
+		return err
+	}
+
 	_, err := c.keycloakAccountClient.UpdatePassword(accessToken, realm, currentPassword, newPassword, confirmPassword)
 	if err != nil {
 		c.logger.Warn("err", err.Error())
@@ -110,6 +194,7 @@ func (c *component) UpdatePassword(ctx context.Context, currentPassword, newPass
 func (c *component) GetAccount(ctx context.Context) (api.AccountRepresentation, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var realm = ctx.Value(cs.CtContextRealm).(string)
+	var userID = ctx.Value(cs.CtContextUserID).(string)
 
 	var userRep api.AccountRepresentation
 	userKc, err := c.keycloakAccountClient.GetAccount(accessToken, realm)
@@ -121,6 +206,22 @@ func (c *component) GetAccount(ctx context.Context) (api.AccountRepresentation,
 
 	userRep = api.ConvertToAPIAccount(userKc)
 
+	// the changes waiting for a confirmation are only stored in the DB of the bridge
+	changes, err := c.pendingChangeDBModule.GetPendingChanges(ctx, realm, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return api.AccountRepresentation{}, err
+	}
+	for _, change := range changes {
+		var value = change.Value
+		switch change.Field {
+		case internal.Email:
+			userRep.PendingEmail = &value
+		case internal.PhoneNumber:
+			userRep.PendingPhoneNumber = &value
+		}
+	}
+
 	return userRep, nil
 }
 
@@ -138,32 +239,77 @@ func (c *component) UpdateAccount(ctx context.Context, user api.AccountRepresent
 		return err
 	}
 
-	var emailVerified, phoneNumberVerified *bool
+	// custom attributes are checked against the attribute schema of the realm
+	if user.Attributes != nil || user.DeletedAttributes != nil {
+		schema, err := c.getUserAttributeSchema(ctx, realm)
+		if err != nil {
+			return err
+		}
+		// attributes sent back unchanged are not checked again
+		var changed = api.AccountRepresentation{
+			Attributes:        internal.ChangedAttributes(user.Attributes, oldUserKc.Attributes),
+			DeletedAttributes: user.DeletedAttributes,
+		}
+		if err = changed.ValidateAttributes(schema); err != nil {
+			c.logger.Warn("err", err.Error())
+			return errorhandler.CreateBadRequestError(err.Error())
+		}
+	}
+
+	// a new email or phone number is kept pending until the user confirms it with the code sent to it: the current value
+	// remains in use meanwhile. Without notification gateway, the new value is applied directly.
+	var newEmail, newPhoneNumber *string
 
-	// when the email changes, set the EmailVerified to false
-	if user.Email != nil && oldUserKc.Email != nil && *oldUserKc.Email != *user.Email {
-		var verified = false
-		emailVerified = &verified
+	if user.Email != nil && (oldUserKc.Email == nil || *oldUserKc.Email != *user.Email) {
+		newEmail = user.Email
+		user.Email = oldUserKc.Email
 	}
 
-	// when the phone number changes, set the PhoneNumberVerified to false
+	// phone numbers are stored in the E.164 format and an equivalent number is not a change
 	if user.PhoneNumber != nil {
-		if oldUserKc.Attributes != nil {
-			var m = *oldUserKc.Attributes
-			if _, ok := m["phoneNumber"]; !ok || m["phoneNumber"][0] != *user.PhoneNumber {
-				var verified = false
-				phoneNumberVerified = &verified
+		var oldPhoneNumber, hasPhoneNumber = internal.PhoneNumberAttribute(oldUserKc.Attributes)
+		if hasPhoneNumber && oldPhoneNumber == *user.PhoneNumber {
+			user.PhoneNumber = nil
+		} else {
+			config, err := internal.GetRealmConfiguration(ctx, c.configDBModule, realm, c.logger)
+			if err != nil {
+				return err
+			}
+			var countries = internal.PhoneNumberCountries(config)
+			phoneNumber, err := internal.NormalizePhoneNumber(*user.PhoneNumber, countries)
+			if err != nil {
+				c.logger.Warn("err", err.Error())
+				return errorhandler.CreateBadRequestError(err.Error())
+			}
+			if hasPhoneNumber && internal.SamePhoneNumber(oldPhoneNumber, phoneNumber, countries) {
+				user.PhoneNumber = &phoneNumber
+			} else {
+				newPhoneNumber = &phoneNumber
+				user.PhoneNumber = nil
 			}
-		} else { // the user has no attributes until now, i.e. he has not set yet his phone number
-			var verified = false
-			phoneNumberVerified = &verified
 		}
 	}
 
+	if err = c.checkEditableFields(ctx, realm, oldUserKc, user, newEmail, newPhoneNumber); err != nil {
+		return err
+	}
+
 	userRep = api.ConvertToKCUser(user)
 
-	if emailVerified != nil {
-		userRep.EmailVerified = emailVerified
+	// without notification gateway, a new email or phone number is applied directly and marked as not verified
+	var phoneNumberUnverified bool
+	if (newEmail != nil || newPhoneNumber != nil) && !c.notifier.Enabled() {
+		if newEmail != nil {
+			var verified = false
+			userRep.Email = newEmail
+			userRep.EmailVerified = &verified
+			newEmail = nil
+		}
+		if newPhoneNumber != nil {
+			user.PhoneNumber = newPhoneNumber
+			phoneNumberUnverified = true
+			newPhoneNumber = nil
+		}
 	}
 
 	// Merge the attributes coming from the old user representation and the updated user representation in order not to lose anything
@@ -176,12 +322,24 @@ func (c *component) UpdateAccount(ctx context.Context, user api.AccountRepresent
 		}
 	}
 
+	if user.Attributes != nil {
+		for key, attribute := range *user.Attributes {
+			mergedAttributes[key] = attribute
+		}
+	}
+
+	if user.DeletedAttributes != nil {
+		for _, key := range *user.DeletedAttributes {
+			delete(mergedAttributes, key)
+		}
+	}
+
 	if user.PhoneNumber != nil {
 		mergedAttributes["phoneNumber"] = []string{*user.PhoneNumber}
 	}
 
-	if phoneNumberVerified != nil {
-		mergedAttributes["phoneNumberVerified"] = []string{strconv.FormatBool(*phoneNumberVerified)}
+	if phoneNumberUnverified {
+		mergedAttributes["phoneNumberVerified"] = []string{"false"}
 	}
 
 	userRep.Attributes = &mergedAttributes
@@ -193,23 +351,245 @@ func (c *component) UpdateAccount(ctx context.Context, user api.AccountRepresent
 		return err
 	}
 
+	if newEmail != nil {
+		if err = c.requestConfirmation(ctx, realm, userID, username, pendingEmailChange, *newEmail); err != nil {
+			return err
+		}
+	}
+
+	if newPhoneNumber != nil {
+		if err = c.requestConfirmation(ctx, realm, userID, username, pendingPhoneNumberChange, *newPhoneNumber); err != nil {
+			return err
+		}
+	}
+
 	//store the API call into the DB
 	_ = c.reportEvent(ctx, "UPDATE_ACCOUNT", database.CtEventRealmName, realm, database.CtEventUserID, userID, database.CtEventUsername, username)
 
 	return nil
 }
 
+// checkEditableFields checks that the fields changed by the user are editable in the realm. The authorization middleware
+// only checks that some fields are editable as it can't tell which fields are changed.
+func (c *component) checkEditableFields(ctx context.Context, realm string, oldUserKc kc.UserRepresentation, user api.AccountRepresentation, newEmail, newPhoneNumber *string) error {
+	var nameChanged = isChanged(oldUserKc.FirstName, user.FirstName) || isChanged(oldUserKc.LastName, user.LastName)
+	if newEmail == nil && newPhoneNumber == nil && !nameChanged {
+		return nil
+	}
+
+	config, err := internal.GetRealmConfiguration(ctx, c.configDBModule, realm, c.logger)
+	if err != nil {
+		return err
+	}
+
+	var field string
+	switch {
+	case newEmail != nil && !mailEditingEnabled(config):
+		field = internal.Email
+	case newPhoneNumber != nil && !phoneNumberEditingEnabled(config):
+		field = internal.PhoneNumber
+	case isChanged(oldUserKc.FirstName, user.FirstName) && !nameEditingEnabled(config):
+		field = internal.Firstname
+	case isChanged(oldUserKc.LastName, user.LastName) && !nameEditingEnabled(config):
+		field = internal.Lastname
+	default:
+		return nil
+	}
+	return errorhandler.Error{
+		Status:  http.StatusForbidden,
+		Message: internal.ComponentName + "." + internal.MsgErrNotEditable + "." + field,
+	}
+}
+
+// isChanged tells whether a new value is sent for a field. An empty value is the same as no value.
+func isChanged(oldValue, newValue *string) bool {
+	if newValue == nil {
+		return false
+	}
+	if oldValue == nil {
+		return *newValue != ""
+	}
+	return *oldValue != *newValue
+}
+
+// checkNewPassword checks the new password against the password policy defined in the configuration of the realm, or
+// the one of the Keycloak realm if it is not overridden, and, if enabled for the realm, against the breached passwords
+// database. Policy violations are reported in the locale of the user. Keycloak remains in charge of enforcing its own policy.
+func (c *component) checkNewPassword(ctx context.Context, accessToken, realm, username, newPassword string) error {
+	config, err := c.configDBModule.GetConfiguration(ctx, realm)
+	if err != nil {
+		switch e := errors.Cause(err).(type) {
+		case internal.MissingRealmConfigurationErr:
+			// no configuration means only the password policy of the Keycloak realm is checked
+			config = dto.RealmConfiguration{}
+		default:
+			c.logger.Error("err", e.Error())
+			return err
+		}
+	}
+
+	passwordPolicy, err := c.getPasswordPolicy(ctx, realm, config)
+	if err != nil {
+		return err
+	}
+
+	if passwordPolicy != "" {
+		if err = c.checkPasswordPolicy(accessToken, realm, username, newPassword, passwordPolicy); err != nil {
+			return err
+		}
+	}
+
+	if config.BreachedPasswordCheck != nil && *config.BreachedPasswordCheck {
+		breached, err := c.breachedPasswordChecker.IsBreached(ctx, newPassword)
+		if err != nil {
+			c.logger.Warn("err", err.Error())
+			return err
+		}
+		if breached {
+			return errorhandler.Error{
+				Status:  http.StatusBadRequest,
+				Message: internal.ComponentName + "." + internal.MsgErrBreachedPassword + "." + internal.NewPassword,
+			}
+		}
+	}
+
+	return nil
+}
+
+// getPasswordPolicy returns the password policy overridden in the configuration of the realm or, by default, the password
+// policy of the Keycloak realm
+func (c *component) getPasswordPolicy(ctx context.Context, realm string, config dto.RealmConfiguration) (string, error) {
+	if config.PasswordPolicy != nil && *config.PasswordPolicy != "" {
+		return *config.PasswordPolicy, nil
+	}
+
+	// the realm can only be read with the token of the technical user
+	technicalToken, err := c.tokenProvider.ProvideToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	realmKc, err := c.keycloakTechnicalClient.GetRealm(technicalToken, realm)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return "", err
+	}
+
+	if realmKc.PasswordPolicy == nil {
+		return "", nil
+	}
+	return *realmKc.PasswordPolicy, nil
+}
+
+func (c *component) checkPasswordPolicy(accessToken, realm, username, newPassword, passwordPolicy string) error {
+	policy, err := internal.ParsePasswordPolicy(passwordPolicy)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	userKc, err := c.keycloakAccountClient.GetAccount(accessToken, realm)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+	var email, locale string
+	if userKc.Email != nil {
+		email = *userKc.Email
+	}
+	if userKc.Attributes != nil && len((*userKc.Attributes)["locale"]) > 0 {
+		locale = (*userKc.Attributes)["locale"][0]
+	}
+
+	err = policy.Validate(newPassword, username, email)
+	if violation, ok := err.(internal.PasswordPolicyViolation); ok {
+		return PasswordPolicyError{Violation: violation, Locale: locale}
+	}
+	return err
+}
+
+// getUserAttributeSchema returns the custom user attributes defined in the configuration of the realm
+func (c *component) getUserAttributeSchema(ctx context.Context, realm string) ([]dto.AttributeDefinition, error) {
+	config, err := internal.GetRealmConfiguration(ctx, c.configDBModule, realm, c.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return internal.UserAttributeSchema(config), nil
+}
+
+// DeleteAccount deletes the account of the user. When the realm defines a grace period, the account is only marked
+// for deletion and disabled: its sessions are closed and it is deleted by the AccountDeletionScheduler unless the user
+// tries to log in again with their password.
 func (c *component) DeleteAccount(ctx context.Context) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var realm = ctx.Value(cs.CtContextRealm).(string)
+	var userID = ctx.Value(cs.CtContextUserID).(string)
+	var username = ctx.Value(cs.CtContextUsername).(string)
 
-	err := c.keycloakAccountClient.DeleteAccount(accessToken, realm)
-
+	config, err := internal.GetRealmConfiguration(ctx, c.configDBModule, realm, c.logger)
 	if err != nil {
+		return err
+	}
+
+	if config.AccountDeletionGracePeriod == nil || *config.AccountDeletionGracePeriod <= 0 {
+		err = c.keycloakAccountClient.DeleteAccount(accessToken, realm)
+		if err != nil {
+			c.logger.Warn("err", err.Error())
+			return err
+		}
+		c.reportAccountEvent(ctx, "SELF_DELETE_ACCOUNT", realm, userID, username, nil)
+		return nil
+	}
+
+	var now = time.Now()
+	var deletionTime = now.AddDate(0, 0, *config.AccountDeletionGracePeriod)
+	var deletion = internal.AccountDeletion{
+		RealmName:    realm,
+		UserID:       userID,
+		Username:     username,
+		RequestedAt:  now.UnixNano() / int64(time.Millisecond),
+		DeletionTime: deletionTime.UnixNano() / int64(time.Millisecond),
+	}
+	if err = c.accountDeletionDBModule.ScheduleDeletion(ctx, deletion); err != nil {
 		c.logger.Warn("err", err.Error())
 		return err
 	}
 
+	technicalToken, err := c.tokenProvider.ProvideToken(ctx)
+	if err == nil {
+		err = c.disableUser(technicalToken, realm, userID)
+	}
+	if err != nil {
+		// the account stays usable: the deletion is canceled so that it can be requested again
+		if _, errCancel := c.accountDeletionDBModule.CancelDeletion(ctx, realm, userID); errCancel != nil {
+			c.logger.Warn("msg", "can't cancel the deletion of the account", "err", errCancel.Error())
+		}
+		return err
+	}
+
+	// the sessions of a disabled account can't be refreshed anyway: a failure to close them does not fail the request
+	if err = c.keycloakTechnicalClient.LogoutUser(technicalToken, realm, userID); err != nil {
+		c.logger.Warn("msg", "can't close the sessions of the disabled account", "err", err.Error())
+	}
+
+	c.reportAccountEvent(ctx, "SELF_DELETE_ACCOUNT_REQUESTED", realm, userID, username, map[string]string{"deletion_time": deletionTime.UTC().Format(time.RFC3339)})
+	return nil
+}
+
+// disableUser disables the account with the token of the technical user as users can't disable their own account
+func (c *component) disableUser(technicalToken, realm, userID string) error {
+	userKc, err := c.keycloakTechnicalClient.GetUser(technicalToken, realm, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+	var enabled = false
+	userKc.Enabled = &enabled
+	if err = c.keycloakTechnicalClient.UpdateUser(technicalToken, realm, userID, userKc); err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
 	return nil
 }
 
@@ -324,9 +704,88 @@ func (c *component) GetConfiguration(ctx context.Context) (api.Configuration, er
 	}
 
 	return api.Configuration{
-		ShowAuthenticatorsTab:     config.ShowAuthenticatorsTab,
-		ShowAccountDeletionButton: config.ShowAccountDeletionButton,
-		ShowMailEditing:           config.ShowMailEditing,
-		ShowPasswordTab:           config.ShowPasswordTab,
+		ShowAuthenticatorsTab:      config.ShowAuthenticatorsTab,
+		ShowAccountDeletionButton:  config.ShowAccountDeletionButton,
+		ShowMailEditing:            config.ShowMailEditing,
+		ShowPasswordTab:            config.ShowPasswordTab,
+		AccountDeletionGracePeriod: config.AccountDeletionGracePeriod,
 	}, nil
 }
+
+// ExportPersonalData returns the profile, the credentials and the audit trail of the current user
+func (c *component) ExportPersonalData(ctx context.Context) (api.PersonalDataRepresentation, error) {
+	var realm = ctx.Value(cs.CtContextRealm).(string)
+	var userID = ctx.Value(cs.CtContextUserID).(string)
+	var username = ctx.Value(cs.CtContextUsername).(string)
+
+	account, err := c.GetAccount(ctx)
+	if err != nil {
+		return api.PersonalDataRepresentation{}, err
+	}
+
+	credentials, err := c.GetCredentials(ctx)
+	if err != nil {
+		return api.PersonalDataRepresentation{}, err
+	}
+
+	auditEvents, err := c.getUserEvents(ctx, realm, userID)
+	if err != nil {
+		return api.PersonalDataRepresentation{}, err
+	}
+
+	c.reportAccountEvent(ctx, "SELF_EXPORT_PERSONAL_DATA", realm, userID, username, nil)
+
+	return api.PersonalDataRepresentation{
+		ExportedAt:  time.Now().Unix(),
+		Account:     account,
+		Credentials: credentials,
+		Events:      auditEvents,
+	}, nil
+}
+
+// GetActivity returns the logins, password and credential changes of the current user, the most recent first
+func (c *component) GetActivity(ctx context.Context, paramKV ...string) ([]api.ActivityRepresentation, error) {
+	var params = map[string]string{"max": strconv.Itoa(maxActivityEvents)}
+	for i := 0; i+1 < len(paramKV); i += 2 {
+		params[paramKV[i]] = paramKV[i+1]
+	}
+	// the user can only read their own events
+	params["realm"] = ctx.Value(cs.CtContextRealm).(string)
+	params["userID"] = ctx.Value(cs.CtContextUserID).(string)
+	params["ctEventType"] = strings.Join(activityEventTypes, ",")
+
+	auditEvents, err := c.auditEventsReader.GetEvents(ctx, params)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return nil, err
+	}
+
+	var activity = []api.ActivityRepresentation{}
+	for _, auditEvent := range auditEvents {
+		activity = append(activity, api.ConvertToActivity(auditEvent))
+	}
+	return activity, nil
+}
+
+// getUserEvents returns all the audit events of a user, the most recent first. The events are read by pages as the
+// events DB module limits the number of events returned by a query.
+func (c *component) getUserEvents(ctx context.Context, realm, userID string) ([]events_api.AuditRepresentation, error) {
+	var res = []events_api.AuditRepresentation{}
+	for first := 0; ; first += exportEventsPageSize {
+		var params = map[string]string{
+			"realm":  realm,
+			"userID": userID,
+			"first":  strconv.Itoa(first),
+			"max":    strconv.Itoa(exportEventsPageSize),
+		}
+		auditEvents, err := c.auditEventsReader.GetEvents(ctx, params)
+		if err != nil {
+			c.logger.Warn("err", err.Error())
+			return nil, err
+		}
+		res = append(res, auditEvents...)
+		if len(auditEvents) < exportEventsPageSize {
+			return res, nil
+		}
+	}
+}
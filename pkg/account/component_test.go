@@ -10,12 +10,15 @@ import (
 	"time"
 
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 
 	cs "github.com/cloudtrust/common-service"
 	"github.com/cloudtrust/common-service/database"
+	commonhttp "github.com/cloudtrust/common-service/errors"
 	"github.com/cloudtrust/common-service/log"
 	account_api "github.com/cloudtrust/keycloak-bridge/api/account"
 	api "github.com/cloudtrust/keycloak-bridge/api/account"
+	events_api "github.com/cloudtrust/keycloak-bridge/api/events"
 	"github.com/cloudtrust/keycloak-bridge/pkg/account/mock"
 	kc "github.com/cloudtrust/keycloak-client"
 	"github.com/golang/mock/gomock"
@@ -30,9 +33,12 @@ func genericUpdatePasswordTest(t *testing.T, oldPasswd, newPasswd, confirmPasswo
 	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
 	mockLogger := log.NewNopLogger()
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
-	component := NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	component := NewComponent(mockKeycloakAccountClient, mockKeycloakTechnicalClient, mockTokenProvider, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, mockLogger)
 
 	accessToken := "access token"
+	technicalToken := "technical token"
 	realm := "sample realm"
 	userID := "123-456-789"
 	username := "username"
@@ -41,6 +47,9 @@ func genericUpdatePasswordTest(t *testing.T, oldPasswd, newPasswd, confirmPasswo
 	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
 	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
+	mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realm).Return(dto.RealmConfiguration{}, keycloakb.MissingRealmConfigurationErr{}).Times(kcCalls)
+	mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return(technicalToken, nil).Times(kcCalls)
+	mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realm).Return(kc.RealmRepresentation{}, nil).Times(kcCalls)
 	mockKeycloakAccountClient.EXPECT().UpdatePassword(accessToken, realm, oldPasswd, newPasswd, confirmPassword).Return("", nil).Times(kcCalls)
 	mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "PASSWORD_RESET", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(kcCalls)
 
@@ -78,9 +87,12 @@ func TestUpdatePasswordWrongPwd(t *testing.T) {
 	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
 	mockLogger := mock.NewLogger(mockCtrl)
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
-	component := NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	component := NewComponent(mockKeycloakAccountClient, mockKeycloakTechnicalClient, mockTokenProvider, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, mockLogger)
 
 	accessToken := "access token"
+	technicalToken := "technical token"
 	realm := "sample realm"
 	userID := "123-456-789"
 	username := "username"
@@ -89,6 +101,9 @@ func TestUpdatePasswordWrongPwd(t *testing.T) {
 	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
 	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
+	mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realm).Return(dto.RealmConfiguration{}, keycloakb.MissingRealmConfigurationErr{}).AnyTimes()
+	mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return(technicalToken, nil).AnyTimes()
+	mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realm).Return(kc.RealmRepresentation{}, nil).AnyTimes()
 	mockKeycloakAccountClient.EXPECT().UpdatePassword(accessToken, realm, oldPasswd, newPasswd, newPasswd).Return("", fmt.Errorf("invalidPasswordExistingMessage")).Times(1)
 	mockLogger.EXPECT().Warn("err", "invalidPasswordExistingMessage")
 
@@ -117,6 +132,222 @@ func TestUpdatePasswordWrongPwd(t *testing.T) {
 
 }
 
+func TestUpdatePasswordPolicy(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockKeycloakAccountClient := mock.NewKeycloakAccountClient(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+	mockLogger := mock.NewLogger(mockCtrl)
+	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	component := NewComponent(mockKeycloakAccountClient, mockKeycloakTechnicalClient, mockTokenProvider, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, mockLogger)
+
+	accessToken := "access token"
+	technicalToken := "technical token"
+	realm := "sample realm"
+	userID := "123-456-789"
+	username := "username"
+	email := "john.doe@domain.ch"
+	oldPasswd := "prev10u5"
+	policy := "length(10) and digits(1) and notEmail(undefined)"
+	ctx := context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realm)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	var attributes = map[string][]string{"locale": {"fr"}}
+	var account = kc.UserRepresentation{Email: &email, Attributes: &attributes}
+	var config = dto.RealmConfiguration{PasswordPolicy: &policy}
+
+	t.Run("Password complies with the policy", func(t *testing.T) {
+		newPasswd := "l0ng password"
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(config, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realm).Return(account, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().UpdatePassword(accessToken, realm, oldPasswd, newPasswd, newPasswd).Return("", nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "PASSWORD_RESET", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Password too short", func(t *testing.T) {
+		newPasswd := "sh0rt"
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(config, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realm).Return(account, nil).Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.Equal(t, PasswordPolicyError{Violation: keycloakb.PasswordPolicyViolation{Policy: keycloakb.PolicyLength, Param: "10"}, Locale: "fr"}, err)
+		assert.Equal(t, keycloakb.ComponentName+"."+keycloakb.MsgErrInvalidParam+"."+keycloakb.NewPassword+"."+keycloakb.PolicyLength, err.Error())
+	})
+
+	t.Run("Password is the email", func(t *testing.T) {
+		newPasswd := "John.Doe1@domain.ch"
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(config, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realm).Return(kc.UserRepresentation{Email: &newPasswd}, nil).Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.Equal(t, PasswordPolicyError{Violation: keycloakb.PasswordPolicyViolation{Policy: keycloakb.PolicyNotEmail}}, err)
+	})
+
+	t.Run("Password policy of the Keycloak realm", func(t *testing.T) {
+		newPasswd := "sh0rt"
+		var realmPolicy = "length(8)"
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(dto.RealmConfiguration{}, keycloakb.MissingRealmConfigurationErr{}).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realm).Return(kc.RealmRepresentation{PasswordPolicy: &realmPolicy}, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realm).Return(account, nil).Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.Equal(t, PasswordPolicyError{Violation: keycloakb.PasswordPolicyViolation{Policy: keycloakb.PolicyLength, Param: "8"}, Locale: "fr"}, err)
+	})
+
+	t.Run("Keycloak realm can't be read", func(t *testing.T) {
+		newPasswd := "l0ng password"
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(dto.RealmConfiguration{}, nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realm).Return(kc.RealmRepresentation{}, errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Technical token can't be obtained", func(t *testing.T) {
+		newPasswd := "l0ng password"
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(dto.RealmConfiguration{}, nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return("", errors.New("token error")).Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("No password policy", func(t *testing.T) {
+		newPasswd := "sh0rt"
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(dto.RealmConfiguration{}, nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realm).Return(kc.RealmRepresentation{}, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().UpdatePassword(accessToken, realm, oldPasswd, newPasswd, newPasswd).Return("", nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "PASSWORD_RESET", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Configuration can't be read", func(t *testing.T) {
+		newPasswd := "l0ng password"
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(dto.RealmConfiguration{}, errors.New("DB error")).Times(1)
+		mockLogger.EXPECT().Error("err", "DB error").Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Invalid password policy", func(t *testing.T) {
+		newPasswd := "l0ng password"
+		invalidPolicy := "length(abc)"
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(dto.RealmConfiguration{PasswordPolicy: &invalidPolicy}, nil).Times(1)
+		mockLogger.EXPECT().Warn("err", gomock.Any()).Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Account can't be read", func(t *testing.T) {
+		newPasswd := "l0ng password"
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(config, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realm).Return(kc.UserRepresentation{}, errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestUpdatePasswordBreached(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockKeycloakAccountClient := mock.NewKeycloakAccountClient(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+	mockLogger := mock.NewLogger(mockCtrl)
+	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
+	mockBreachedPasswordChecker := mock.NewBreachedPasswordChecker(mockCtrl)
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	component := NewComponent(mockKeycloakAccountClient, mockKeycloakTechnicalClient, mockTokenProvider, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, mockBreachedPasswordChecker, nil, mockLogger)
+
+	accessToken := "access token"
+	technicalToken := "technical token"
+	realm := "sample realm"
+	userID := "123-456-789"
+	username := "username"
+	oldPasswd := "prev10u5"
+	newPasswd := "a p@55w0rd"
+	breachedCheck := true
+	ctx := context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realm)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	var config = dto.RealmConfiguration{BreachedPasswordCheck: &breachedCheck}
+
+	// no password policy for the realm
+	mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).AnyTimes()
+	mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realm).Return(kc.RealmRepresentation{}, nil).AnyTimes()
+
+	t.Run("Breached password", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(config, nil).Times(1)
+		mockBreachedPasswordChecker.EXPECT().IsBreached(ctx, newPasswd).Return(true, nil).Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.Equal(t, keycloakb.ComponentName+"."+keycloakb.MsgErrBreachedPassword+"."+keycloakb.NewPassword, err.Error())
+	})
+
+	t.Run("Password not breached", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(config, nil).Times(1)
+		mockBreachedPasswordChecker.EXPECT().IsBreached(ctx, newPasswd).Return(false, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().UpdatePassword(accessToken, realm, oldPasswd, newPasswd, newPasswd).Return("", nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "PASSWORD_RESET", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Check disabled for the realm", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(dto.RealmConfiguration{}, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().UpdatePassword(accessToken, realm, oldPasswd, newPasswd, newPasswd).Return("", nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "PASSWORD_RESET", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Breached passwords database can't be read", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realm).Return(config, nil).Times(1)
+		mockBreachedPasswordChecker.EXPECT().IsBreached(ctx, newPasswd).Return(false, errors.New("IO error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "IO error").Times(1)
+
+		err := component.UpdatePassword(ctx, oldPasswd, newPasswd, newPasswd)
+
+		assert.NotNil(t, err)
+	})
+}
+
 func TestUpdateAccount(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -124,8 +355,12 @@ func TestUpdateAccount(t *testing.T) {
 	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	mockNotifier := mock.NewNotifier(mockCtrl)
+	mockPendingChangeDBModule := mock.NewPendingChangeDBModule(mockCtrl)
 
-	var accountComponent = NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var accountComponent = NewComponent(mockKeycloakAccountClient, mockKeycloakTechnicalClient, mockTokenProvider, mockEventDBModule, mockConfigurationDBModule, nil, nil, mockPendingChangeDBModule, nil, nil, mockNotifier, mockLogger)
 
 	accessToken := "access token"
 	realmName := "master"
@@ -143,7 +378,7 @@ func TestUpdateAccount(t *testing.T) {
 	var emailVerified = true
 	var firstName = "Titi"
 	var lastName = "Tutu"
-	var phoneNumber = "+41789456"
+	var phoneNumber = "+41789456123"
 	var phoneNumberVerified = true
 	var label = "Label"
 	var gender = "M"
@@ -179,6 +414,10 @@ func TestUpdateAccount(t *testing.T) {
 		PhoneNumber: &phoneNumber,
 	}
 
+	var trueBool = true
+
+	mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(dto.RealmConfiguration{APISelfMailEditingEnabled: &trueBool}, nil).AnyTimes()
+
 	// Update account with succces
 	{
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "UPDATE_ACCOUNT", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
@@ -200,49 +439,133 @@ func TestUpdateAccount(t *testing.T) {
 		assert.Nil(t, err)
 	}
 
-	// update by changing the email address
+	// update by changing the email address: the new one is pending until it is confirmed
 	{
 		var oldEmail = "toti@elca.ch"
 		var oldkcUserRep = kc.UserRepresentation{
 			Id:            &id,
 			Email:         &oldEmail,
 			EmailVerified: &emailVerified,
+			Attributes:    &attributes,
 		}
 		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(oldkcUserRep, nil).Times(1)
 		mockKeycloakAccountClient.EXPECT().UpdateAccount(accessToken, realmName, gomock.Any()).DoAndReturn(
 			func(accessToken, realmName string, kcUserRep kc.UserRepresentation) error {
-				assert.Equal(t, email, *kcUserRep.Email)
-				assert.Equal(t, false, *kcUserRep.EmailVerified)
+				assert.Equal(t, oldEmail, *kcUserRep.Email)
+				assert.Nil(t, kcUserRep.EmailVerified)
 				return nil
 			}).Times(1)
+		var codeHash string
+		mockNotifier.EXPECT().Enabled().Return(true).Times(1)
+		mockPendingChangeDBModule.EXPECT().StorePendingChange(ctx, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, change keycloakb.PendingChange) error {
+				assert.Equal(t, keycloakb.PendingChange{RealmName: realmName, UserID: userID, Field: keycloakb.Email, Value: email,
+					CodeHash: change.CodeHash, ExpiresAt: change.ExpiresAt}, change)
+				assert.True(t, change.ExpiresAt > time.Now().UnixNano()/int64(time.Millisecond))
+				codeHash = change.CodeHash
+				return nil
+			}).Times(1)
+		mockNotifier.EXPECT().SendVerificationCode(ctx, realmName, keycloakb.ChannelEmail, email, gomock.Any(), time.Hour).DoAndReturn(
+			func(ctx context.Context, realm, channel, recipient, code string, lifespan time.Duration) error {
+				// the code is sent to the new email and only its hash is stored
+				assert.True(t, keycloakb.CheckVerificationCode(code, codeHash))
+				return nil
+			}).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "EMAIL_CHANGE_REQUESTED", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 
 		err := accountComponent.UpdateAccount(ctx, userRep)
 
 		assert.Nil(t, err)
 	}
 
-	var oldNumber = "+41789467"
+	// the verification code can't be sent
+	{
+		var oldEmail = "toti@elca.ch"
+		var oldkcUserRep = kc.UserRepresentation{
+			Id:         &id,
+			Email:      &oldEmail,
+			Attributes: &attributes,
+		}
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(oldkcUserRep, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().UpdateAccount(accessToken, realmName, gomock.Any()).Return(nil).Times(1)
+		mockNotifier.EXPECT().Enabled().Return(true).Times(1)
+		mockPendingChangeDBModule.EXPECT().StorePendingChange(ctx, gomock.Any()).Return(nil).Times(1)
+		mockNotifier.EXPECT().SendVerificationCode(ctx, realmName, keycloakb.ChannelEmail, email, gomock.Any(), time.Hour).Return(errors.New("error")).Times(1)
+
+		err := accountComponent.UpdateAccount(ctx, userRep)
+
+		assert.NotNil(t, err)
+	}
+
+	var oldNumber = "+41789467123"
 	var oldAttributes = make(map[string][]string)
 	oldAttributes["phoneNumber"] = []string{oldNumber}
 	oldAttributes["phoneNumberVerified"] = []string{strconv.FormatBool(phoneNumberVerified)}
 	var oldkcUserRep2 = kc.UserRepresentation{
 		Id:         &id,
+		Email:      &email,
 		Attributes: &oldAttributes,
 	}
 
-	// update by changing the phone number
+	// update by changing the phone number: the new one is pending until it is confirmed
 	{
+		var newNumber = "+41 78 945 61 23"
+		var userRepNewNumber = api.AccountRepresentation{
+			PhoneNumber: &newNumber,
+		}
 
 		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(oldkcUserRep2, nil).Times(1)
 		mockKeycloakAccountClient.EXPECT().UpdateAccount(accessToken, realmName, gomock.Any()).DoAndReturn(
 			func(accessToken, realmName string, kcUserRep kc.UserRepresentation) error {
 				verified, _ := strconv.ParseBool(((*kcUserRep.Attributes)["phoneNumberVerified"][0]))
-				assert.Equal(t, phoneNumber, (*kcUserRep.Attributes)["phoneNumber"][0])
-				assert.Equal(t, false, verified)
+				assert.Equal(t, oldNumber, (*kcUserRep.Attributes)["phoneNumber"][0])
+				assert.Equal(t, true, verified)
+				return nil
+			}).Times(1)
+		mockNotifier.EXPECT().Enabled().Return(true).Times(1)
+		mockPendingChangeDBModule.EXPECT().StorePendingChange(ctx, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, change keycloakb.PendingChange) error {
+				assert.Equal(t, keycloakb.PhoneNumber, change.Field)
+				assert.Equal(t, phoneNumber, change.Value)
 				return nil
 			}).Times(1)
+		mockNotifier.EXPECT().SendVerificationCode(ctx, realmName, keycloakb.ChannelSMS, phoneNumber, gomock.Any(), time.Hour).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "PHONE_NUMBER_CHANGE_REQUESTED", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 
-		err := accountComponent.UpdateAccount(ctx, userRep)
+		err := accountComponent.UpdateAccount(ctx, userRepNewNumber)
+
+		assert.Nil(t, err)
+	}
+
+	// the pending change can't be stored
+	{
+		var newNumber = "+41 78 945 61 23"
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(oldkcUserRep2, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().UpdateAccount(accessToken, realmName, gomock.Any()).Return(nil).Times(1)
+		mockNotifier.EXPECT().Enabled().Return(true).Times(1)
+		mockPendingChangeDBModule.EXPECT().StorePendingChange(ctx, gomock.Any()).Return(errors.New("db error")).Times(1)
+
+		err := accountComponent.UpdateAccount(ctx, api.AccountRepresentation{PhoneNumber: &newNumber})
+
+		assert.NotNil(t, err)
+	}
+
+	// without notification gateway, the new email and phone number are applied directly and are not verified
+	{
+		var newNumber = "+41 78 945 61 23"
+		var newEmail = "tata@elca.ch"
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(oldkcUserRep2, nil).Times(1)
+		mockNotifier.EXPECT().Enabled().Return(false).Times(1)
+		mockKeycloakAccountClient.EXPECT().UpdateAccount(accessToken, realmName, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName string, kcUserRep kc.UserRepresentation) error {
+				assert.Equal(t, newEmail, *kcUserRep.Email)
+				assert.False(t, *kcUserRep.EmailVerified)
+				assert.Equal(t, []string{phoneNumber}, (*kcUserRep.Attributes)["phoneNumber"])
+				assert.Equal(t, []string{"false"}, (*kcUserRep.Attributes)["phoneNumberVerified"])
+				return nil
+			}).Times(1)
+
+		err := accountComponent.UpdateAccount(ctx, api.AccountRepresentation{Email: &newEmail, PhoneNumber: &newNumber})
 
 		assert.Nil(t, err)
 	}
@@ -270,6 +593,42 @@ func TestUpdateAccount(t *testing.T) {
 		assert.Nil(t, err)
 	}
 
+	// update with an equivalent phone number
+	{
+		var equivalentNumber = "0041 78 946 71 23"
+		var userRepEquivalentNumber = api.AccountRepresentation{
+			PhoneNumber: &equivalentNumber,
+		}
+
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(oldkcUserRep2, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().UpdateAccount(accessToken, realmName, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName string, kcUserRep kc.UserRepresentation) error {
+				verified, _ := strconv.ParseBool(((*kcUserRep.Attributes)["phoneNumberVerified"][0]))
+				assert.Equal(t, oldNumber, (*kcUserRep.Attributes)["phoneNumber"][0])
+				assert.Equal(t, true, verified)
+				return nil
+			}).Times(1)
+
+		err := accountComponent.UpdateAccount(ctx, userRepEquivalentNumber)
+
+		assert.Nil(t, err)
+	}
+
+	// update with an invalid phone number
+	{
+		var invalidNumber = "+41 78 946"
+		var userRepInvalidNumber = api.AccountRepresentation{
+			PhoneNumber: &invalidNumber,
+		}
+
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(oldkcUserRep2, nil).Times(1)
+
+		err := accountComponent.UpdateAccount(ctx, userRepInvalidNumber)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	}
+
 	//Error - get user
 	{
 		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kc.UserRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
@@ -293,6 +652,169 @@ func TestUpdateAccount(t *testing.T) {
 	}
 }
 
+func TestUpdateAccountEditableFields(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakAccountClient := mock.NewKeycloakAccountClient(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
+
+	var accountComponent = NewComponent(mockKeycloakAccountClient, nil, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "access token"
+	var realmName = "master"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, "123-456-789")
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "username")
+
+	var email = "toto@elca.ch"
+	var newEmail = "titi@elca.ch"
+	var firstName = "Titi"
+	var newFirstName = "Toto"
+	var empty = ""
+	var phoneNumber = "+41789456123"
+	var newPhoneNumber = "+41789467123"
+	var trueBool = true
+	var falseBool = false
+	var oldUserKc = kc.UserRepresentation{
+		Email:      &email,
+		FirstName:  &firstName,
+		Attributes: &map[string][]string{"phoneNumber": {phoneNumber}},
+	}
+	// only the names are editable
+	var config = dto.RealmConfiguration{APISelfMailEditingEnabled: &falseBool, APISelfNameEditingEnabled: &trueBool}
+	mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(config, nil).AnyTimes()
+
+	t.Run("Unchanged fields are not checked", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(oldUserKc, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().UpdateAccount(accessToken, realmName, gomock.Any()).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "UPDATE_ACCOUNT", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		var err = accountComponent.UpdateAccount(ctx, api.AccountRepresentation{Email: &email, PhoneNumber: &phoneNumber, FirstName: &newFirstName, LastName: &empty})
+		assert.Nil(t, err)
+	})
+
+	t.Run("Email is not editable", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(oldUserKc, nil).Times(1)
+
+		var err = accountComponent.UpdateAccount(ctx, api.AccountRepresentation{Email: &newEmail})
+		assert.Equal(t, commonhttp.Error{Status: 403, Message: "keycloak-bridge.notEditable.email"}, err)
+	})
+
+	t.Run("Phone number is not editable", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(oldUserKc, nil).Times(1)
+
+		var err = accountComponent.UpdateAccount(ctx, api.AccountRepresentation{PhoneNumber: &newPhoneNumber})
+		assert.Equal(t, commonhttp.Error{Status: 403, Message: "keycloak-bridge.notEditable.phoneNumber"}, err)
+	})
+
+	t.Run("Names are not editable", func(t *testing.T) {
+		var mailOnly = dto.RealmConfiguration{APISelfMailEditingEnabled: &trueBool, APISelfNameEditingEnabled: &falseBool}
+		var ctx = context.WithValue(ctx, cs.CtContextRealm, "other")
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, "other").Return(mailOnly, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, "other").Return(oldUserKc, nil).Times(1)
+
+		var err = accountComponent.UpdateAccount(ctx, api.AccountRepresentation{FirstName: &firstName, LastName: &newFirstName})
+		assert.Equal(t, commonhttp.Error{Status: 403, Message: "keycloak-bridge.notEditable.lastname"}, err)
+	})
+}
+
+func TestUpdateAccountAttributes(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakAccountClient := mock.NewKeycloakAccountClient(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
+
+	var accountComponent = NewComponent(mockKeycloakAccountClient, nil, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, mockLogger)
+
+	accessToken := "access token"
+	realmName := "master"
+	userID := "123-456-789"
+	username := "username"
+	ctx := context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	var trueBool = true
+	var nickname = "nickname"
+	var department = "department"
+	var config = dto.RealmConfiguration{
+		UserAttributes: &[]dto.AttributeDefinition{
+			{Name: &nickname, EditableBySelfService: &trueBool},
+			{Name: &department, EditableByBackOffice: &trueBool},
+		},
+	}
+	var kcUserRep = kc.UserRepresentation{
+		Username: &username,
+		Attributes: &map[string][]string{
+			nickname:   {"bob"},
+			department: {"IT"},
+		},
+	}
+
+	t.Run("Update and delete self-service attributes", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kcUserRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(config, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().UpdateAccount(accessToken, realmName, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName string, kcUserRep kc.UserRepresentation) error {
+				var attributes = *kcUserRep.Attributes
+				_, ok := attributes[nickname]
+				assert.False(t, ok)
+				assert.Equal(t, []string{"IT"}, attributes[department])
+				return nil
+			}).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "UPDATE_ACCOUNT", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		var userRep = api.AccountRepresentation{
+			Attributes:        &map[string][]string{department: {"IT"}},
+			DeletedAttributes: &[]string{nickname},
+		}
+		err := accountComponent.UpdateAccount(ctx, userRep)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Attribute not editable by the user", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kcUserRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(config, nil).Times(1)
+
+		var userRep = api.AccountRepresentation{
+			Attributes: &map[string][]string{department: {"HR"}},
+		}
+		err := accountComponent.UpdateAccount(ctx, userRep)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("No configuration for the realm", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kcUserRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(dto.RealmConfiguration{}, keycloakb.MissingRealmConfigurationErr{}).Times(1)
+
+		var userRep = api.AccountRepresentation{
+			DeletedAttributes: &[]string{nickname},
+		}
+		err := accountComponent.UpdateAccount(ctx, userRep)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Error while loading the configuration", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kcUserRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(dto.RealmConfiguration{}, errors.New("error")).Times(1)
+
+		var userRep = api.AccountRepresentation{
+			Attributes: &map[string][]string{nickname: {"alice"}},
+		}
+		err := accountComponent.UpdateAccount(ctx, userRep)
+
+		assert.NotNil(t, err)
+	})
+}
+
 func TestGetUser(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -300,15 +822,18 @@ func TestGetUser(t *testing.T) {
 	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
+	mockPendingChangeDBModule := mock.NewPendingChangeDBModule(mockCtrl)
 
-	var accountComponent = NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var accountComponent = NewComponent(mockKeycloakAccountClient, nil, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, mockPendingChangeDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
+	var userID = "123-456-789"
 	var username = "username"
 
 	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
 	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
 	// Get user with succces
@@ -348,6 +873,7 @@ func TestGetUser(t *testing.T) {
 		}
 
 		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kcUserRep, nil).Times(1)
+		mockPendingChangeDBModule.EXPECT().GetPendingChanges(ctx, realmName, userID).Return([]keycloakb.PendingChange{{Field: keycloakb.Email, Value: "new@elca.ch"}}, nil).Times(1)
 
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "GET_DETAILS", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
@@ -359,6 +885,8 @@ func TestGetUser(t *testing.T) {
 		assert.Equal(t, firstName, *apiUserRep.FirstName)
 		assert.Equal(t, lastName, *apiUserRep.LastName)
 		assert.Equal(t, phoneNumber, *apiUserRep.PhoneNumber)
+		assert.Equal(t, "new@elca.ch", *apiUserRep.PendingEmail)
+		assert.Nil(t, apiUserRep.PendingPhoneNumber)
 	}
 
 	//Error
@@ -368,42 +896,136 @@ func TestGetUser(t *testing.T) {
 
 		assert.NotNil(t, err)
 	}
+
+	//Error - pending changes
+	{
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kc.UserRepresentation{}, nil).Times(1)
+		mockPendingChangeDBModule.EXPECT().GetPendingChanges(ctx, realmName, userID).Return(nil, errors.New("db error")).Times(1)
+		_, err := accountComponent.GetAccount(ctx)
+
+		assert.NotNil(t, err)
+	}
 }
 
 func TestDeleteUser(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
 	mockKeycloakAccountClient := mock.NewKeycloakAccountClient(mockCtrl)
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
 	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+	mockAccountDeletionDBModule := mock.NewAccountDeletionDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
 
-	var accountComponent = NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var accountComponent = NewComponent(mockKeycloakAccountClient, mockKeycloakTechnicalClient, mockTokenProvider, mockEventDBModule, mockConfigurationDBModule, mockAccountDeletionDBModule, nil, nil, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
+	var technicalToken = "technical token"
 	var realmName = "master"
+	var userID = "1234-789"
 	var username = "username"
+	var gracePeriod = 30
 
 	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
 	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
-	// Delete user with succces
-	{
+	t.Run("Configuration can't be read", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(dto.RealmConfiguration{}, errors.New("db error")).Times(1)
+		err := accountComponent.DeleteAccount(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Delete user immediately when the realm has no grace period", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(dto.RealmConfiguration{}, nil).Times(1)
 		mockKeycloakAccountClient.EXPECT().DeleteAccount(accessToken, realmName).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "SELF_DELETE_ACCOUNT", "self-service", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username).Return(nil).Times(1)
 
 		err := accountComponent.DeleteAccount(ctx)
-
 		assert.Nil(t, err)
-	}
+	})
 
-	//Error
-	{
+	t.Run("Immediate deletion fails", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(dto.RealmConfiguration{}, nil).Times(1)
 		mockKeycloakAccountClient.EXPECT().DeleteAccount(accessToken, realmName).Return(fmt.Errorf("Unexpected error")).Times(1)
+
 		err := accountComponent.DeleteAccount(ctx)
+		assert.NotNil(t, err)
+	})
+
+	var config = dto.RealmConfiguration{AccountDeletionGracePeriod: &gracePeriod}
+
+	t.Run("Deletion is scheduled", func(t *testing.T) {
+		var before = time.Now()
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(config, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().ScheduleDeletion(ctx, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, deletion keycloakb.AccountDeletion) error {
+				assert.Equal(t, realmName, deletion.RealmName)
+				assert.Equal(t, userID, deletion.UserID)
+				assert.Equal(t, username, deletion.Username)
+				assert.True(t, deletion.RequestedAt >= before.UnixNano()/int64(time.Millisecond))
+				assert.Equal(t, deletion.RequestedAt, time.Unix(0, deletion.DeletionTime*int64(time.Millisecond)).AddDate(0, 0, -gracePeriod).UnixNano()/int64(time.Millisecond))
+				return nil
+			}).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetUser(technicalToken, realmName, userID).Return(kc.UserRepresentation{Username: &username}, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().UpdateUser(technicalToken, realmName, userID, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName, userID string, user kc.UserRepresentation) error {
+				assert.False(t, *user.Enabled)
+				assert.Equal(t, username, *user.Username)
+				return nil
+			}).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().LogoutUser(technicalToken, realmName, userID).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "SELF_DELETE_ACCOUNT_REQUESTED", "self-service", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username, database.CtEventAdditionalInfo, gomock.Any()).Return(errors.New("db error")).Times(1)
 
+		err := accountComponent.DeleteAccount(ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Deletion can't be scheduled", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(config, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().ScheduleDeletion(ctx, gomock.Any()).Return(errors.New("db error")).Times(1)
+
+		err := accountComponent.DeleteAccount(ctx)
 		assert.NotNil(t, err)
-	}
+	})
+
+	t.Run("Account can't be disabled", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(config, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().ScheduleDeletion(ctx, gomock.Any()).Return(nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetUser(technicalToken, realmName, userID).Return(kc.UserRepresentation{}, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().UpdateUser(technicalToken, realmName, userID, gomock.Any()).Return(errors.New("error")).Times(1)
+		mockAccountDeletionDBModule.EXPECT().CancelDeletion(ctx, realmName, userID).Return(true, nil).Times(1)
+
+		err := accountComponent.DeleteAccount(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Token of the technical user can't be obtained", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(config, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().ScheduleDeletion(ctx, gomock.Any()).Return(nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return("", errors.New("error")).Times(1)
+		mockAccountDeletionDBModule.EXPECT().CancelDeletion(ctx, realmName, userID).Return(false, errors.New("db error")).Times(1)
+
+		err := accountComponent.DeleteAccount(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Sessions can't be closed", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmName).Return(config, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().ScheduleDeletion(ctx, gomock.Any()).Return(nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetUser(technicalToken, realmName, userID).Return(kc.UserRepresentation{}, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().UpdateUser(technicalToken, realmName, userID, gomock.Any()).Return(nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().LogoutUser(technicalToken, realmName, userID).Return(errors.New("error")).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "SELF_DELETE_ACCOUNT_REQUESTED", "self-service", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username, database.CtEventAdditionalInfo, gomock.Any()).Return(nil).Times(1)
+
+		err := accountComponent.DeleteAccount(ctx)
+		assert.Nil(t, err)
+	})
 }
 
 func TestGetCredentials(t *testing.T) {
@@ -415,7 +1037,7 @@ func TestGetCredentials(t *testing.T) {
 	mockLogger := log.NewNopLogger()
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
 
-	component := NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	component := NewComponent(mockKeycloakAccountClient, nil, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var currentRealm = "master"
@@ -474,7 +1096,7 @@ func TestGetCredentialRegistrators(t *testing.T) {
 	mockLogger := log.NewNopLogger()
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
 
-	component := NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	component := NewComponent(mockKeycloakAccountClient, nil, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var currentRealm = "master"
@@ -520,7 +1142,7 @@ func TestUpdateLabelCredential(t *testing.T) {
 	mockLogger := log.NewNopLogger()
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
 
-	component := NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	component := NewComponent(mockKeycloakAccountClient, nil, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, mockLogger)
 
 	accessToken := "access token"
 	realm := "sample realm"
@@ -561,7 +1183,7 @@ func TestDeleteCredential(t *testing.T) {
 	mockLogger := log.NewNopLogger()
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
 
-	component := NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	component := NewComponent(mockKeycloakAccountClient, nil, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, mockLogger)
 
 	accessToken := "access token"
 	realm := "sample realm"
@@ -600,7 +1222,7 @@ func TestMoveCredential(t *testing.T) {
 	mockLogger := log.NewNopLogger()
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
 
-	component := NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	component := NewComponent(mockKeycloakAccountClient, nil, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, mockLogger)
 
 	accessToken := "access token"
 	realm := "sample realm"
@@ -656,7 +1278,7 @@ func TestGetConfiguration(t *testing.T) {
 	mockLogger := log.NewNopLogger()
 	mockConfigurationDBModule := mock.NewConfigurationDBModule(mockCtrl)
 
-	component := NewComponent(mockKeycloakAccountClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	component := NewComponent(mockKeycloakAccountClient, nil, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var currentRealm = "master"
@@ -677,6 +1299,7 @@ func TestGetConfiguration(t *testing.T) {
 			ShowAccountDeletionButton:           &trueBool,
 			ShowMailEditing:                     &trueBool,
 			ShowPasswordTab:                     &trueBool,
+			AccountDeletionGracePeriod:          new(int),
 		}
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
@@ -692,6 +1315,7 @@ func TestGetConfiguration(t *testing.T) {
 		assert.Equal(t, *config.ShowAccountDeletionButton, *resConfig.ShowAccountDeletionButton)
 		assert.Equal(t, *config.ShowMailEditing, *resConfig.ShowMailEditing)
 		assert.Equal(t, *config.ShowPasswordTab, *resConfig.ShowPasswordTab)
+		assert.Equal(t, *config.AccountDeletionGracePeriod, *resConfig.AccountDeletionGracePeriod)
 	}
 
 	//Error
@@ -707,3 +1331,119 @@ func TestGetConfiguration(t *testing.T) {
 		assert.NotNil(t, err)
 	}
 }
+
+func TestExportPersonalData(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakAccountClient := mock.NewKeycloakAccountClient(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+	mockAuditEventsReader := mock.NewAuditEventsReaderModule(mockCtrl)
+	mockPendingChangeDBModule := mock.NewPendingChangeDBModule(mockCtrl)
+
+	var accountComponent = NewComponent(mockKeycloakAccountClient, nil, nil, mockEventDBModule, nil, nil, nil, mockPendingChangeDBModule, mockAuditEventsReader, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var userID = "1234-789"
+	var username = "username"
+	var credentialID = "5678"
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	var eventsPage = func(first, count int) ([]events_api.AuditRepresentation, error) {
+		var res = make([]events_api.AuditRepresentation, count)
+		for i := range res {
+			res[i] = events_api.AuditRepresentation{AuditID: int64(first + i)}
+		}
+		return res, nil
+	}
+	var eventsParams = func(first int) map[string]string {
+		return map[string]string{"realm": realmName, "userID": userID, "first": strconv.Itoa(first), "max": strconv.Itoa(exportEventsPageSize)}
+	}
+
+	t.Run("Account can't be read", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kc.UserRepresentation{}, errors.New("error")).Times(1)
+		_, err := accountComponent.ExportPersonalData(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Credentials can't be read", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kc.UserRepresentation{Username: &username}, nil).Times(1)
+		mockPendingChangeDBModule.EXPECT().GetPendingChanges(ctx, realmName, userID).Return(nil, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().GetCredentials(accessToken, realmName).Return(nil, errors.New("error")).Times(1)
+		_, err := accountComponent.ExportPersonalData(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Events can't be read", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kc.UserRepresentation{Username: &username}, nil).Times(1)
+		mockPendingChangeDBModule.EXPECT().GetPendingChanges(ctx, realmName, userID).Return(nil, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().GetCredentials(accessToken, realmName).Return([]kc.CredentialRepresentation{}, nil).Times(1)
+		mockAuditEventsReader.EXPECT().GetEvents(ctx, eventsParams(0)).Return(nil, errors.New("db error")).Times(1)
+		_, err := accountComponent.ExportPersonalData(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Personal data are exported", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kc.UserRepresentation{Username: &username}, nil).Times(1)
+		mockPendingChangeDBModule.EXPECT().GetPendingChanges(ctx, realmName, userID).Return(nil, nil).Times(1)
+		mockKeycloakAccountClient.EXPECT().GetCredentials(accessToken, realmName).Return([]kc.CredentialRepresentation{{Id: &credentialID}}, nil).Times(1)
+		mockAuditEventsReader.EXPECT().GetEvents(ctx, eventsParams(0)).Return(eventsPage(0, exportEventsPageSize)).Times(1)
+		mockAuditEventsReader.EXPECT().GetEvents(ctx, eventsParams(exportEventsPageSize)).Return(eventsPage(exportEventsPageSize, 3)).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "SELF_EXPORT_PERSONAL_DATA", "self-service", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username).Return(nil).Times(1)
+
+		data, err := accountComponent.ExportPersonalData(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, username, *data.Account.Username)
+		assert.Equal(t, credentialID, *data.Credentials[0].ID)
+		assert.Len(t, data.Events, exportEventsPageSize+3)
+		assert.Equal(t, int64(exportEventsPageSize+2), data.Events[exportEventsPageSize+2].AuditID)
+	})
+}
+
+func TestGetActivity(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockAuditEventsReader := mock.NewAuditEventsReaderModule(mockCtrl)
+
+	var accountComponent = NewComponent(nil, nil, nil, nil, nil, nil, nil, nil, mockAuditEventsReader, nil, nil, log.NewNopLogger())
+
+	var realmName = "master"
+	var userID = "1234-789"
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+
+	var expectedParams = map[string]string{
+		"realm":       realmName,
+		"userID":      userID,
+		"ctEventType": "LOGON_OK,LOGON_ERROR,LOGOUT,PASSWORD_RESET,SELF_UPDATE_CREDENTIAL,SELF_ADD_CREDENTIAL,SELF_DELETE_CREDENTIAL,SELF_MOVE_CREDENTIAL",
+		"max":         "10",
+	}
+
+	t.Run("DB error", func(t *testing.T) {
+		mockAuditEventsReader.EXPECT().GetEvents(ctx, expectedParams).Return(nil, errors.New("db error")).Times(1)
+		_, err := accountComponent.GetActivity(ctx, "max", "10")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Default number of events", func(t *testing.T) {
+		var params = map[string]string{"realm": realmName, "userID": userID, "ctEventType": expectedParams["ctEventType"], "max": "100"}
+		mockAuditEventsReader.EXPECT().GetEvents(ctx, params).Return(nil, nil).Times(1)
+		activity, err := accountComponent.GetActivity(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, activity, 0)
+	})
+
+	t.Run("Users can't read the events of other users", func(t *testing.T) {
+		var auditEvents = []events_api.AuditRepresentation{
+			{AuditTime: 1234, CtEventType: "LOGON_OK", AdditionalInfo: `{"ip_address":"192.168.10.23"}`},
+		}
+		mockAuditEventsReader.EXPECT().GetEvents(ctx, expectedParams).Return(auditEvents, nil).Times(1)
+		activity, err := accountComponent.GetActivity(ctx, "max", "10", "userID", "other", "ctEventType", "ADMIN")
+		assert.Nil(t, err)
+		assert.Equal(t, []api.ActivityRepresentation{{Time: 1234, Type: "LOGON_OK", IPAddress: "192.168.*.*"}}, activity)
+	})
+}
@@ -0,0 +1,176 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	"github.com/cloudtrust/common-service/database"
+	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/pkg/errors"
+)
+
+// kcErrorUserDisabled is the error of the Keycloak login events of disabled users. Keycloak checks the password
+// before the enabled flag: the event is only emitted for users who proved their identity.
+const kcErrorUserDisabled = "user_disabled"
+
+// AccountDeletionScheduler deletes the accounts whose deletion was requested by their users once the grace period of
+// their realm is over. The accounts are disabled during the grace period: a login attempt of the user with the right
+// password cancels the deletion and enables the account again. When several instances of the bridge run, only the one
+// holding the lock of the scheduler deletes the accounts.
+type AccountDeletionScheduler interface {
+	CancelOnLogin(ctx context.Context, event map[string]string) error
+	DeleteDueAccounts(ctx context.Context)
+	Run(c <-chan time.Time)
+}
+
+type accountDeletionScheduler struct {
+	keycloakTechnicalClient KeycloakTechnicalClient
+	tokenProvider           internal.TokenProvider
+	accountDeletionDBModule internal.AccountDeletionDBModule
+	eventDBModule           database.EventsDBModule
+	instanceID              string
+	lockLease               time.Duration
+	logger                  internal.Logger
+	now                     func() time.Time
+}
+
+// NewAccountDeletionScheduler returns the scheduler of the account deletions. The instanceID identifies this instance of
+// the bridge as the owner of the lock of the scheduler, which is held for lockLease unless it is renewed.
+func NewAccountDeletionScheduler(keycloakTechnicalClient KeycloakTechnicalClient, tokenProvider internal.TokenProvider, accountDeletionDBModule internal.AccountDeletionDBModule, eventDBModule database.EventsDBModule, instanceID string, lockLease time.Duration, logger internal.Logger) AccountDeletionScheduler {
+	return &accountDeletionScheduler{
+		keycloakTechnicalClient: keycloakTechnicalClient,
+		tokenProvider:           tokenProvider,
+		accountDeletionDBModule: accountDeletionDBModule,
+		eventDBModule:           eventDBModule,
+		instanceID:              instanceID,
+		lockLease:               lockLease,
+		logger:                  logger,
+		now:                     time.Now,
+	}
+}
+
+// CancelOnLogin is called with the Keycloak events. A successful login, or the login of a disabled user with the right
+// password, cancels the deletion of the account which is then enabled again.
+func (s *accountDeletionScheduler) CancelOnLogin(ctx context.Context, event map[string]string) error {
+	if !isLoginOfUser(event) {
+		return nil
+	}
+
+	var realm = event[database.CtEventRealmName]
+	var userID = event[database.CtEventUserID]
+	canceled, err := s.accountDeletionDBModule.CancelDeletion(ctx, realm, userID)
+	if err != nil {
+		s.logger.Warn("msg", "can't cancel the deletion of the account", "realm", realm, "userID", userID, "err", err.Error())
+		return err
+	}
+	if !canceled {
+		return nil
+	}
+	s.reportEvent("SELF_DELETE_ACCOUNT_CANCELED", realm, userID, event[database.CtEventUsername])
+
+	if err = s.enableUser(ctx, realm, userID); err != nil {
+		s.logger.Warn("msg", "can't enable the account whose deletion is canceled", "realm", realm, "userID", userID, "err", err.Error())
+		return err
+	}
+	return nil
+}
+
+// isLoginOfUser returns true for the events proving the user knows their password
+func isLoginOfUser(event map[string]string) bool {
+	if event[database.CtEventUserID] == "" {
+		return false
+	}
+	switch event[database.CtEventType] {
+	case "LOGON_OK":
+		return true
+	case "LOGON_ERROR":
+		var additionalInfo map[string]string
+		_ = json.Unmarshal([]byte(event[database.CtEventAdditionalInfo]), &additionalInfo)
+		return additionalInfo["error"] == kcErrorUserDisabled
+	}
+	return false
+}
+
+func (s *accountDeletionScheduler) enableUser(ctx context.Context, realm, userID string) error {
+	accessToken, err := s.tokenProvider.ProvideToken(ctx)
+	if err != nil {
+		return err
+	}
+	userKc, err := s.keycloakTechnicalClient.GetUser(accessToken, realm, userID)
+	if err != nil {
+		return err
+	}
+	var enabled = true
+	userKc.Enabled = &enabled
+	return s.keycloakTechnicalClient.UpdateUser(accessToken, realm, userID, userKc)
+}
+
+// DeleteDueAccounts deletes the accounts whose grace period is over
+func (s *accountDeletionScheduler) DeleteDueAccounts(ctx context.Context) {
+	var now = s.now()
+	locked, err := s.accountDeletionDBModule.LockScheduler(ctx, s.instanceID, now, s.lockLease)
+	if err != nil {
+		s.logger.Warn("msg", "can't lock the scheduler of the account deletions", "err", err.Error())
+		return
+	}
+	if !locked {
+		// another instance of the bridge deletes the accounts
+		return
+	}
+
+	deletions, err := s.accountDeletionDBModule.GetDueDeletions(ctx, now)
+	if err != nil {
+		s.logger.Warn("msg", "can't read the accounts to delete", "err", err.Error())
+		return
+	}
+	if len(deletions) == 0 {
+		return
+	}
+
+	accessToken, err := s.tokenProvider.ProvideToken(ctx)
+	if err != nil {
+		s.logger.Warn("msg", "can't get the token of the technical user", "err", err.Error())
+		return
+	}
+
+	for _, deletion := range deletions {
+		if err = s.keycloakTechnicalClient.DeleteUser(accessToken, deletion.RealmName, deletion.UserID); err != nil && !isNotFound(err) {
+			// the deletion is kept and retried at the next run
+			s.logger.Warn("msg", "can't delete the account", "realm", deletion.RealmName, "userID", deletion.UserID, "err", err.Error())
+			continue
+		}
+		if _, err = s.accountDeletionDBModule.CancelDeletion(ctx, deletion.RealmName, deletion.UserID); err != nil {
+			s.logger.Warn("msg", "can't remove the deleted account from the scheduled deletions", "realm", deletion.RealmName, "userID", deletion.UserID, "err", err.Error())
+		}
+		s.reportEvent("SELF_DELETE_ACCOUNT", deletion.RealmName, deletion.UserID, deletion.Username)
+	}
+}
+
+// isNotFound returns true when Keycloak answered 404: the account is already deleted
+func isNotFound(err error) bool {
+	kcError, ok := errors.Cause(err).(kc.HTTPError)
+	return ok && kcError.HTTPStatus == http.StatusNotFound
+}
+
+// Run deletes the due accounts each time a tick is received on the channel
+func (s *accountDeletionScheduler) Run(c <-chan time.Time) {
+	for range c {
+		s.DeleteDueAccounts(context.Background())
+	}
+}
+
+// reportEvent stores the event in the DB. The user is the agent of the events as they requested the deletion.
+func (s *accountDeletionScheduler) reportEvent(eventName, realm, userID, username string) {
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, realm)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	var values = []string{database.CtEventRealmName, realm, database.CtEventUserID, userID, database.CtEventUsername, username}
+	if err := s.eventDBModule.ReportEvent(ctx, eventName, "self-service", values...); err != nil {
+		internal.LogUnstoredEvent(s.logger, err, eventName, values...)
+	}
+}
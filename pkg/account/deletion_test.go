@@ -0,0 +1,186 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/common-service/database"
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	"github.com/cloudtrust/keycloak-bridge/pkg/account/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancelOnLogin(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	mockAccountDeletionDBModule := mock.NewAccountDeletionDBModule(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+
+	var scheduler = NewAccountDeletionScheduler(mockKeycloakTechnicalClient, mockTokenProvider, mockAccountDeletionDBModule, mockEventDBModule, "instance", time.Minute, log.NewNopLogger())
+
+	var ctx = context.Background()
+	var technicalToken = "technical token"
+	var realmName = "master"
+	var userID = "1234-789"
+	var username = "username"
+	var event = map[string]string{
+		database.CtEventType:      "LOGON_OK",
+		database.CtEventRealmName: realmName,
+		database.CtEventUserID:    userID,
+		database.CtEventUsername:  username,
+	}
+
+	t.Run("Other events are ignored", func(t *testing.T) {
+		var err = scheduler.CancelOnLogin(ctx, map[string]string{database.CtEventType: "LOGOUT", database.CtEventUserID: userID})
+		assert.Nil(t, err)
+	})
+
+	t.Run("Failed logins are ignored", func(t *testing.T) {
+		var err = scheduler.CancelOnLogin(ctx, map[string]string{
+			database.CtEventType:           "LOGON_ERROR",
+			database.CtEventUserID:         userID,
+			database.CtEventAdditionalInfo: `{"error":"invalid_user_credentials"}`,
+		})
+		assert.Nil(t, err)
+	})
+
+	t.Run("Login of a disabled user cancels the deletion", func(t *testing.T) {
+		var disabledEvent = map[string]string{
+			database.CtEventType:           "LOGON_ERROR",
+			database.CtEventRealmName:      realmName,
+			database.CtEventUserID:         userID,
+			database.CtEventUsername:       username,
+			database.CtEventAdditionalInfo: `{"error":"user_disabled","ip_address":"127.0.0.1"}`,
+		}
+		var disabled = false
+		mockAccountDeletionDBModule.EXPECT().CancelDeletion(ctx, realmName, userID).Return(true, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "SELF_DELETE_ACCOUNT_CANCELED", "self-service", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username).Return(nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetUser(technicalToken, realmName, userID).Return(kc.UserRepresentation{Enabled: &disabled}, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().UpdateUser(technicalToken, realmName, userID, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName, userID string, user kc.UserRepresentation) error {
+				assert.True(t, *user.Enabled)
+				return nil
+			}).Times(1)
+		var err = scheduler.CancelOnLogin(ctx, disabledEvent)
+		assert.Nil(t, err)
+	})
+
+	t.Run("No deletion scheduled", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().CancelDeletion(ctx, realmName, userID).Return(false, nil).Times(1)
+		var err = scheduler.CancelOnLogin(ctx, event)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Deletion is canceled", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().CancelDeletion(ctx, realmName, userID).Return(true, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "SELF_DELETE_ACCOUNT_CANCELED", "self-service", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username).Return(nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetUser(technicalToken, realmName, userID).Return(kc.UserRepresentation{}, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().UpdateUser(technicalToken, realmName, userID, gomock.Any()).Return(nil).Times(1)
+		var err = scheduler.CancelOnLogin(ctx, event)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Account can't be enabled", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().CancelDeletion(ctx, realmName, userID).Return(true, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "SELF_DELETE_ACCOUNT_CANCELED", "self-service", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username).Return(nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetUser(technicalToken, realmName, userID).Return(kc.UserRepresentation{}, errors.New("error")).Times(1)
+		var err = scheduler.CancelOnLogin(ctx, event)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().CancelDeletion(ctx, realmName, userID).Return(false, errors.New("db error")).Times(1)
+		var err = scheduler.CancelOnLogin(ctx, event)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestDeleteDueAccounts(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	mockAccountDeletionDBModule := mock.NewAccountDeletionDBModule(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+
+	var scheduler = NewAccountDeletionScheduler(mockKeycloakTechnicalClient, mockTokenProvider, mockAccountDeletionDBModule, mockEventDBModule, "instance", time.Minute, log.NewNopLogger())
+	var now = time.Now()
+	scheduler.(*accountDeletionScheduler).now = func() time.Time { return now }
+
+	var ctx = context.Background()
+	var technicalToken = "technical token"
+	var deletions = []keycloakb.AccountDeletion{
+		{RealmName: "master", UserID: "user-1", Username: "user1"},
+		{RealmName: "other", UserID: "user-2", Username: "user2"},
+	}
+
+	t.Run("Lock can't be taken", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().LockScheduler(ctx, "instance", now, time.Minute).Return(false, errors.New("db error")).Times(1)
+		scheduler.DeleteDueAccounts(ctx)
+	})
+
+	t.Run("Another instance holds the lock", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().LockScheduler(ctx, "instance", now, time.Minute).Return(false, nil).Times(1)
+		scheduler.DeleteDueAccounts(ctx)
+	})
+
+	t.Run("Deletions can't be read", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().LockScheduler(ctx, "instance", now, time.Minute).Return(true, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().GetDueDeletions(ctx, now).Return(nil, errors.New("db error")).Times(1)
+		scheduler.DeleteDueAccounts(ctx)
+	})
+
+	t.Run("Nothing to delete", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().LockScheduler(ctx, "instance", now, time.Minute).Return(true, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().GetDueDeletions(ctx, now).Return([]keycloakb.AccountDeletion{}, nil).Times(1)
+		scheduler.DeleteDueAccounts(ctx)
+	})
+
+	t.Run("Token of the technical user can't be obtained", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().LockScheduler(ctx, "instance", now, time.Minute).Return(true, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().GetDueDeletions(ctx, now).Return(deletions, nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return("", errors.New("error")).Times(1)
+		scheduler.DeleteDueAccounts(ctx)
+	})
+
+	t.Run("Accounts are deleted", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().LockScheduler(ctx, "instance", now, time.Minute).Return(true, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().GetDueDeletions(ctx, now).Return(deletions, nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		// the failed deletion is kept for the next run
+		mockKeycloakTechnicalClient.EXPECT().DeleteUser(technicalToken, "master", "user-1").Return(errors.New("error")).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().DeleteUser(technicalToken, "other", "user-2").Return(nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().CancelDeletion(ctx, "other", "user-2").Return(true, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "SELF_DELETE_ACCOUNT", "self-service", database.CtEventRealmName, "other", database.CtEventUserID, "user-2", database.CtEventUsername, "user2").Return(errors.New("db error")).Times(1)
+		scheduler.DeleteDueAccounts(ctx)
+	})
+
+	t.Run("Account already deleted", func(t *testing.T) {
+		mockAccountDeletionDBModule.EXPECT().LockScheduler(ctx, "instance", now, time.Minute).Return(true, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().GetDueDeletions(ctx, now).Return(deletions[1:], nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().DeleteUser(technicalToken, "other", "user-2").Return(kc.HTTPError{HTTPStatus: 404, Message: "not found"}).Times(1)
+		mockAccountDeletionDBModule.EXPECT().CancelDeletion(ctx, "other", "user-2").Return(true, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "SELF_DELETE_ACCOUNT", "self-service", database.CtEventRealmName, "other", database.CtEventUserID, "user-2", database.CtEventUsername, "user2").Return(nil).Times(1)
+		scheduler.DeleteDueAccounts(ctx)
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		var c = make(chan time.Time, 1)
+		mockAccountDeletionDBModule.EXPECT().LockScheduler(gomock.Any(), "instance", now, time.Minute).Return(true, nil).Times(1)
+		mockAccountDeletionDBModule.EXPECT().GetDueDeletions(gomock.Any(), now).Return([]keycloakb.AccountDeletion{}, nil).Times(1)
+		c <- now
+		close(c)
+		scheduler.Run(c)
+	})
+}
@@ -21,8 +21,17 @@ type Endpoints struct {
 	MoveCredential            endpoint.Endpoint
 	GetAccount                endpoint.Endpoint
 	UpdateAccount             endpoint.Endpoint
+	ConfirmEmailChange        endpoint.Endpoint
+	ConfirmPhoneNumberChange  endpoint.Endpoint
 	DeleteAccount             endpoint.Endpoint
 	GetConfiguration          endpoint.Endpoint
+	ExportPersonalData        endpoint.Endpoint
+	GetActivity               endpoint.Endpoint
+	GetSessions               endpoint.Endpoint
+	DeleteSession             endpoint.Endpoint
+	DeleteOtherSessions       endpoint.Endpoint
+	StartOTPEnrollment        endpoint.Endpoint
+	VerifyOTPEnrollment       endpoint.Endpoint
 }
 
 // UpdatePasswordBody is the definition of the expected body content of UpdatePassword method
@@ -42,8 +51,17 @@ type AccountComponent interface {
 	MoveCredential(ctx context.Context, credentialID string, previousCredentialID string) error
 	GetAccount(ctx context.Context) (api.AccountRepresentation, error)
 	UpdateAccount(ctx context.Context, account api.AccountRepresentation) error
+	ConfirmEmailChange(ctx context.Context, code string) error
+	ConfirmPhoneNumberChange(ctx context.Context, code string) error
 	DeleteAccount(ctx context.Context) error
 	GetConfiguration(ctx context.Context) (api.Configuration, error)
+	ExportPersonalData(ctx context.Context) (api.PersonalDataRepresentation, error)
+	GetActivity(ctx context.Context, paramKV ...string) ([]api.ActivityRepresentation, error)
+	GetSessions(ctx context.Context) ([]api.SessionRepresentation, error)
+	DeleteSession(ctx context.Context, sessionID string) error
+	DeleteOtherSessions(ctx context.Context) error
+	StartOTPEnrollment(ctx context.Context) (api.OTPEnrollmentRepresentation, error)
+	VerifyOTPEnrollment(ctx context.Context, code string, userLabel *string) error
 }
 
 // MakeUpdatePasswordEndpoint makes the UpdatePassword endpoint to update connected user's own password.
@@ -147,6 +165,46 @@ func MakeUpdateAccountEndpoint(component AccountComponent) cs.Endpoint {
 	}
 }
 
+// MakeConfirmEmailChangeEndpoint makes the ConfirmEmailChange endpoint to confirm the new email of the connected user.
+func MakeConfirmEmailChangeEndpoint(component AccountComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		code, err := decodeConfirmationCode(req)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, component.ConfirmEmailChange(ctx, code)
+	}
+}
+
+// MakeConfirmPhoneNumberChangeEndpoint makes the ConfirmPhoneNumberChange endpoint to confirm the new phone number of the connected user.
+func MakeConfirmPhoneNumberChangeEndpoint(component AccountComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		code, err := decodeConfirmationCode(req)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, component.ConfirmPhoneNumberChange(ctx, code)
+	}
+}
+
+func decodeConfirmationCode(req interface{}) (string, error) {
+	var m = req.(map[string]string)
+	var body api.ConfirmationCodeBody
+
+	err := json.Unmarshal([]byte(m["body"]), &body)
+	if err != nil {
+		return "", errrorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Body)
+	}
+
+	if err = body.Validate(); err != nil {
+		return "", errrorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Code)
+	}
+
+	return body.Code, nil
+}
+
 // MakeDeleteAccountEndpoint makes the DeleteAccount endpoint to delete connected user.
 func MakeDeleteAccountEndpoint(component AccountComponent) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -160,3 +218,89 @@ func MakeGetConfigurationEndpoint(component AccountComponent) cs.Endpoint {
 		return component.GetConfiguration(ctx)
 	}
 }
+
+// PersonalDataExport is the reply of the ExportPersonalData endpoint. It is sent as a file in the requested format.
+type PersonalDataExport struct {
+	Format string
+	Data   api.PersonalDataRepresentation
+}
+
+// MakeExportPersonalDataEndpoint makes the ExportPersonalData endpoint to download the data held about the connected user.
+func MakeExportPersonalDataEndpoint(component AccountComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var format = "json"
+		if value, ok := m["format"]; ok {
+			format = value
+		}
+
+		data, err := component.ExportPersonalData(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return PersonalDataExport{Format: format, Data: data}, nil
+	}
+}
+
+// MakeGetActivityEndpoint makes the GetActivity endpoint to read the activity of the connected user.
+func MakeGetActivityEndpoint(component AccountComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var paramKV []string
+		for _, key := range []string{"first", "max"} {
+			if value, ok := m[key]; ok {
+				paramKV = append(paramKV, key, value)
+			}
+		}
+		return component.GetActivity(ctx, paramKV...)
+	}
+}
+
+// MakeGetSessionsEndpoint makes the GetSessions endpoint to list the sessions of the connected user.
+func MakeGetSessionsEndpoint(component AccountComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return component.GetSessions(ctx)
+	}
+}
+
+// MakeDeleteSessionEndpoint makes the DeleteSession endpoint to end a session of the connected user.
+func MakeDeleteSessionEndpoint(component AccountComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return nil, component.DeleteSession(ctx, m["sessionID"])
+	}
+}
+
+// MakeDeleteOtherSessionsEndpoint makes the DeleteOtherSessions endpoint to end all the sessions of the connected user but the current one.
+func MakeDeleteOtherSessionsEndpoint(component AccountComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, component.DeleteOtherSessions(ctx)
+	}
+}
+
+// MakeStartOTPEnrollmentEndpoint makes the StartOTPEnrollment endpoint to generate an OTP secret for the connected user.
+func MakeStartOTPEnrollmentEndpoint(component AccountComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return component.StartOTPEnrollment(ctx)
+	}
+}
+
+// MakeVerifyOTPEnrollmentEndpoint makes the VerifyOTPEnrollment endpoint to register the OTP secret of the connected user.
+func MakeVerifyOTPEnrollmentEndpoint(component AccountComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var body api.OTPVerificationBody
+
+		err := json.Unmarshal([]byte(m["body"]), &body)
+		if err != nil {
+			return nil, errrorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Body)
+		}
+
+		if err = body.Validate(); err != nil {
+			return nil, errrorhandler.CreateBadRequestError(err.Error())
+		}
+
+		return nil, component.VerifyOTPEnrollment(ctx, body.Code, body.UserLabel)
+	}
+}
@@ -2,6 +2,7 @@ package account
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	account_api "github.com/cloudtrust/keycloak-bridge/api/account"
@@ -108,3 +109,156 @@ func TestMakeMoveCredentialEndpoint(t *testing.T) {
 	_, err := MakeMoveCredentialEndpoint(mockAccountComponent)(context.Background(), m)
 	assert.Nil(t, err)
 }
+
+func TestMakeConfirmEmailChangeEndpoint(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockAccountComponent := mock.NewAccountComponent(mockCtrl)
+	mockAccountComponent.EXPECT().ConfirmEmailChange(gomock.Any(), "123456").Return(nil).Times(1)
+
+	m := map[string]string{}
+
+	{
+		m["body"] = "{\"code\":\"123456\"}"
+		_, err := MakeConfirmEmailChangeEndpoint(mockAccountComponent)(context.Background(), m)
+		assert.Nil(t, err)
+	}
+
+	{
+		m["body"] = "{"
+		_, err := MakeConfirmEmailChangeEndpoint(mockAccountComponent)(context.Background(), m)
+		assert.NotNil(t, err)
+	}
+
+	{
+		m["body"] = "{\"code\":\"\"}"
+		_, err := MakeConfirmEmailChangeEndpoint(mockAccountComponent)(context.Background(), m)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestMakeConfirmPhoneNumberChangeEndpoint(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockAccountComponent := mock.NewAccountComponent(mockCtrl)
+	mockAccountComponent.EXPECT().ConfirmPhoneNumberChange(gomock.Any(), "123456").Return(nil).Times(1)
+
+	m := map[string]string{}
+
+	{
+		m["body"] = "{\"code\":\"123456\"}"
+		_, err := MakeConfirmPhoneNumberChangeEndpoint(mockAccountComponent)(context.Background(), m)
+		assert.Nil(t, err)
+	}
+
+	{
+		m["body"] = "{\"code\":\"12 34\"}"
+		_, err := MakeConfirmPhoneNumberChangeEndpoint(mockAccountComponent)(context.Background(), m)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestMakeExportPersonalDataEndpoint(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockAccountComponent := mock.NewAccountComponent(mockCtrl)
+
+	{
+		mockAccountComponent.EXPECT().ExportPersonalData(gomock.Any()).Return(account_api.PersonalDataRepresentation{ExportedAt: 1234}, nil).Times(1)
+		res, err := MakeExportPersonalDataEndpoint(mockAccountComponent)(context.Background(), map[string]string{})
+		assert.Nil(t, err)
+		assert.Equal(t, PersonalDataExport{Format: "json", Data: account_api.PersonalDataRepresentation{ExportedAt: 1234}}, res)
+	}
+
+	{
+		mockAccountComponent.EXPECT().ExportPersonalData(gomock.Any()).Return(account_api.PersonalDataRepresentation{}, nil).Times(1)
+		res, err := MakeExportPersonalDataEndpoint(mockAccountComponent)(context.Background(), map[string]string{"format": "zip"})
+		assert.Nil(t, err)
+		assert.Equal(t, "zip", res.(PersonalDataExport).Format)
+	}
+
+	{
+		mockAccountComponent.EXPECT().ExportPersonalData(gomock.Any()).Return(account_api.PersonalDataRepresentation{}, errors.New("error")).Times(1)
+		_, err := MakeExportPersonalDataEndpoint(mockAccountComponent)(context.Background(), map[string]string{})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestMakeGetActivityEndpoint(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockAccountComponent := mock.NewAccountComponent(mockCtrl)
+
+	{
+		mockAccountComponent.EXPECT().GetActivity(gomock.Any()).Return([]account_api.ActivityRepresentation{}, nil).Times(1)
+		_, err := MakeGetActivityEndpoint(mockAccountComponent)(context.Background(), map[string]string{})
+		assert.Nil(t, err)
+	}
+
+	{
+		mockAccountComponent.EXPECT().GetActivity(gomock.Any(), "first", "20", "max", "10").Return([]account_api.ActivityRepresentation{}, nil).Times(1)
+		_, err := MakeGetActivityEndpoint(mockAccountComponent)(context.Background(), map[string]string{"first": "20", "max": "10", "realm": "master"})
+		assert.Nil(t, err)
+	}
+}
+
+func TestMakeSessionsEndpoints(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockAccountComponent := mock.NewAccountComponent(mockCtrl)
+	var ctx = context.Background()
+
+	{
+		mockAccountComponent.EXPECT().GetSessions(ctx).Return([]account_api.SessionRepresentation{}, nil).Times(1)
+		_, err := MakeGetSessionsEndpoint(mockAccountComponent)(ctx, map[string]string{})
+		assert.Nil(t, err)
+	}
+
+	{
+		mockAccountComponent.EXPECT().DeleteSession(ctx, "sessionID").Return(nil).Times(1)
+		_, err := MakeDeleteSessionEndpoint(mockAccountComponent)(ctx, map[string]string{"sessionID": "sessionID"})
+		assert.Nil(t, err)
+	}
+
+	{
+		mockAccountComponent.EXPECT().DeleteOtherSessions(ctx).Return(errors.New("error")).Times(1)
+		_, err := MakeDeleteOtherSessionsEndpoint(mockAccountComponent)(ctx, map[string]string{})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestMakeOTPEnrollmentEndpoints(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockAccountComponent := mock.NewAccountComponent(mockCtrl)
+	var ctx = context.Background()
+
+	{
+		mockAccountComponent.EXPECT().StartOTPEnrollment(ctx).Return(account_api.OTPEnrollmentRepresentation{}, nil).Times(1)
+		_, err := MakeStartOTPEnrollmentEndpoint(mockAccountComponent)(ctx, map[string]string{})
+		assert.Nil(t, err)
+	}
+
+	{
+		var label = "My phone"
+		mockAccountComponent.EXPECT().VerifyOTPEnrollment(ctx, "123456", &label).Return(nil).Times(1)
+		_, err := MakeVerifyOTPEnrollmentEndpoint(mockAccountComponent)(ctx, map[string]string{"body": `{"code":"123456","userLabel":"My phone"}`})
+		assert.Nil(t, err)
+	}
+
+	{
+		_, err := MakeVerifyOTPEnrollmentEndpoint(mockAccountComponent)(ctx, map[string]string{"body": `{"code":"12a"}`})
+		assert.NotNil(t, err)
+	}
+
+	{
+		_, err := MakeVerifyOTPEnrollmentEndpoint(mockAccountComponent)(ctx, map[string]string{"body": "{"})
+		assert.NotNil(t, err)
+	}
+}
@@ -1,7 +1,10 @@
 package account
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 
 	commonhttp "github.com/cloudtrust/common-service/http"
@@ -15,8 +18,8 @@ import (
 func MakeAccountHandler(e endpoint.Endpoint, logger log.Logger) *http_transport.Server {
 	return http_transport.NewServer(e,
 		decodeAccountRequest,
-		commonhttp.EncodeReply,
-		http_transport.ServerErrorEncoder(commonhttp.ErrorHandler(logger)),
+		encodeAccountReply,
+		http_transport.ServerErrorEncoder(accountErrorHandler(logger)),
 	)
 }
 
@@ -25,9 +28,95 @@ func decodeAccountRequest(ctx context.Context, req *http.Request) (interface{},
 	var pathParams = map[string]string{
 		"credentialID":         account_api.RegExpID,
 		"previousCredentialID": account_api.RegExpIDNullable,
+		"sessionID":            account_api.RegExpID,
 	}
 
-	var queryParams = map[string]string{}
+	var queryParams = map[string]string{
+		"format": account_api.RegExpExportFormat,
+		"first":  account_api.RegExpFirst,
+		"max":    account_api.RegExpMax,
+	}
 
 	return commonhttp.DecodeRequest(ctx, req, pathParams, queryParams)
 }
+
+// encodeAccountReply sends the personal data exports as files, the other replies as JSON
+func encodeAccountReply(ctx context.Context, w http.ResponseWriter, rep interface{}) error {
+	var export, ok = rep.(PersonalDataExport)
+	if !ok {
+		return commonhttp.EncodeReply(ctx, w, rep)
+	}
+
+	var contentType, filename = "application/json; charset=utf-8", "personal-data.json"
+	var content, err = json.MarshalIndent(export.Data, "", " ")
+	if err == nil && export.Format == "zip" {
+		contentType, filename = "application/zip", "personal-data.zip"
+		content, err = zipPersonalData(export.Data)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+	return nil
+}
+
+// zipPersonalData creates an archive with one JSON file per kind of data
+func zipPersonalData(data account_api.PersonalDataRepresentation) ([]byte, error) {
+	var files = []struct {
+		name    string
+		content interface{}
+	}{
+		{"account.json", data.Account},
+		{"credentials.json", data.Credentials},
+		{"events.json", data.Events},
+	}
+
+	var buf bytes.Buffer
+	var archive = zip.NewWriter(&buf)
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.content, "", " ")
+		if err != nil {
+			return nil, err
+		}
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = f.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// passwordPolicyErrorBody is the reply sent when a new password does not comply with the password policy
+type passwordPolicyErrorBody struct {
+	Message          string `json:"message"`
+	LocalizedMessage string `json:"localizedMessage"`
+}
+
+// accountErrorHandler encodes the reply when there is an error.
+func accountErrorHandler(logger log.Logger) func(context.Context, error, http.ResponseWriter) {
+	defaultHandler := commonhttp.ErrorHandler(logger)
+	return func(ctx context.Context, err error, w http.ResponseWriter) {
+		switch e := err.(type) {
+		case PasswordPolicyError:
+			var body, _ = json.Marshal(passwordPolicyErrorBody{
+				Message:          e.Error(),
+				LocalizedMessage: e.Violation.LocalizedMessage(e.Locale),
+			})
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(body)
+		default:
+			defaultHandler(ctx, err, w)
+		}
+	}
+}
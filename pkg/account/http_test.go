@@ -1,6 +1,7 @@
 package account
 
 import (
+	"archive/zip"
 	"bytes"
 	"encoding/json"
 	"io/ioutil"
@@ -47,4 +48,84 @@ func TestHTTPAccountHandler(t *testing.T) {
 		buf.ReadFrom(res.Body)
 		assert.Equal(t, "", buf.String())
 	}
+
+	{
+		body := account_api.UpdatePasswordBody{
+			CurrentPassword: "current",
+			NewPassword:     "new",
+			ConfirmPassword: "new",
+		}
+		json, _ := json.MarshalIndent(body, "", " ")
+
+		var policyErr = PasswordPolicyError{Violation: keycloakb.PasswordPolicyViolation{Policy: keycloakb.PolicyLength, Param: "8"}, Locale: "de"}
+		mockAccountComponent.EXPECT().UpdatePassword(gomock.Any(), body.CurrentPassword, body.NewPassword, body.ConfirmPassword).Return(policyErr).Times(1)
+
+		res, err := http.Post(ts.URL+"/path/to/master/password", "application/json", ioutil.NopCloser(bytes.NewBuffer(json)))
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(res.Body)
+		assert.Equal(t, `{"message":"keycloak-bridge.invalidParameter.newPassword.length","localizedMessage":"Ungültiges Passwort: Minimallänge 8."}`, buf.String())
+	}
+}
+
+func TestHTTPExportPersonalData(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockAccountComponent = mock.NewAccountComponent(mockCtrl)
+
+	r := mux.NewRouter()
+	r.Handle("/path/to/{realm}/export", MakeAccountHandler(keycloakb.ToGoKitEndpoint(MakeExportPersonalDataEndpoint(mockAccountComponent)), log.NewNopLogger()))
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var username = "username"
+	var data = account_api.PersonalDataRepresentation{
+		ExportedAt:  1234,
+		Account:     account_api.AccountRepresentation{Username: &username},
+		Credentials: []account_api.CredentialRepresentation{},
+	}
+
+	t.Run("JSON file", func(t *testing.T) {
+		mockAccountComponent.EXPECT().ExportPersonalData(gomock.Any()).Return(data, nil).Times(1)
+
+		res, err := http.Get(ts.URL + "/path/to/master/export")
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, `attachment; filename="personal-data.json"`, res.Header.Get("Content-Disposition"))
+
+		var received account_api.PersonalDataRepresentation
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&received))
+		assert.Equal(t, username, *received.Account.Username)
+	})
+
+	t.Run("ZIP archive", func(t *testing.T) {
+		mockAccountComponent.EXPECT().ExportPersonalData(gomock.Any()).Return(data, nil).Times(1)
+
+		res, err := http.Get(ts.URL + "/path/to/master/export?format=zip")
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/zip", res.Header.Get("Content-Type"))
+
+		content, _ := ioutil.ReadAll(res.Body)
+		archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		assert.Nil(t, err)
+		var names []string
+		for _, f := range archive.File {
+			names = append(names, f.Name)
+		}
+		assert.Equal(t, []string{"account.json", "credentials.json", "events.json"}, names)
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/path/to/master/export?format=xml")
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
 }
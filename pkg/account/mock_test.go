@@ -5,3 +5,11 @@ package account
 //go:generate mockgen -destination=./mock/eventsdbmodule.go -package=mock -mock_names=EventsDBModule=EventsDBModule github.com/cloudtrust/common-service/database EventsDBModule
 //go:generate mockgen -destination=./mock/component.go -package=mock -mock_names=AccountComponent=AccountComponent,Component=Component github.com/cloudtrust/keycloak-bridge/pkg/account AccountComponent,Component
 //go:generate mockgen -destination=./mock/logger.go -package=mock -mock_names=Logger=Logger github.com/cloudtrust/keycloak-bridge/internal/keycloakb Logger
+//go:generate mockgen -destination=./mock/breachedpasswords.go -package=mock -mock_names=BreachedPasswordChecker=BreachedPasswordChecker github.com/cloudtrust/keycloak-bridge/internal/keycloakb BreachedPasswordChecker
+//go:generate mockgen -destination=./mock/notifier.go -package=mock -mock_names=Notifier=Notifier github.com/cloudtrust/keycloak-bridge/internal/keycloakb Notifier
+//go:generate mockgen -destination=./mock/keycloak_technical_client.go -package=mock -mock_names=KeycloakTechnicalClient=KeycloakTechnicalClient github.com/cloudtrust/keycloak-bridge/pkg/account KeycloakTechnicalClient
+//go:generate mockgen -destination=./mock/technicaltoken.go -package=mock -mock_names=TokenProvider=TokenProvider github.com/cloudtrust/keycloak-bridge/internal/keycloakb TokenProvider
+//go:generate mockgen -destination=./mock/accountdeletion.go -package=mock -mock_names=AccountDeletionDBModule=AccountDeletionDBModule github.com/cloudtrust/keycloak-bridge/internal/keycloakb AccountDeletionDBModule
+//go:generate mockgen -destination=./mock/otpenrollment.go -package=mock -mock_names=OTPEnrollmentDBModule=OTPEnrollmentDBModule github.com/cloudtrust/keycloak-bridge/internal/keycloakb OTPEnrollmentDBModule
+//go:generate mockgen -destination=./mock/pendingchange.go -package=mock -mock_names=PendingChangeDBModule=PendingChangeDBModule github.com/cloudtrust/keycloak-bridge/internal/keycloakb PendingChangeDBModule
+//go:generate mockgen -destination=./mock/auditeventsreader.go -package=mock -mock_names=AuditEventsReaderModule=AuditEventsReaderModule github.com/cloudtrust/keycloak-bridge/pkg/account AuditEventsReaderModule
@@ -0,0 +1,216 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	api "github.com/cloudtrust/keycloak-bridge/api/account"
+	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	kc "github.com/cloudtrust/keycloak-client"
+)
+
+const (
+	// otpEnrollmentLifespan is the time given to the user to register the secret in their OTP application
+	otpEnrollmentLifespan = 10 * time.Minute
+	// otpSecretLength is the length of the OTP secrets, the one used by Keycloak
+	otpSecretLength = 20
+	// otpCredentialType is the Keycloak type of the OTP credentials
+	otpCredentialType = "otp"
+)
+
+// otpSecretData is the secret data of the Keycloak OTP credentials
+type otpSecretData struct {
+	Value string `json:"value"`
+}
+
+// otpCredentialData is the credential data of the Keycloak OTP credentials
+type otpCredentialData struct {
+	SubType   string `json:"subType"`
+	Digits    int    `json:"digits"`
+	Counter   int    `json:"counter"`
+	Period    int    `json:"period"`
+	Algorithm string `json:"algorithm"`
+}
+
+// StartOTPEnrollment generates a new OTP secret for the current user. The secret is kept as pending in the DB of the
+// bridge until the user proves it is registered in their OTP application by sending a code generated with it. A previous
+// pending enrollment is replaced.
+func (c *component) StartOTPEnrollment(ctx context.Context) (api.OTPEnrollmentRepresentation, error) {
+	var realm = ctx.Value(cs.CtContextRealm).(string)
+	var userID = ctx.Value(cs.CtContextUserID).(string)
+	var username = ctx.Value(cs.CtContextUsername).(string)
+
+	// the OTP policy of the realm is only readable with the token of the technical user
+	accessToken, err := c.tokenProvider.ProvideToken(ctx)
+	if err != nil {
+		return api.OTPEnrollmentRepresentation{}, err
+	}
+
+	policy, err := c.getTOTPPolicy(accessToken, realm)
+	if err != nil {
+		return api.OTPEnrollmentRepresentation{}, err
+	}
+
+	secret, err := internal.GenerateOTPSecret(otpSecretLength)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return api.OTPEnrollmentRepresentation{}, err
+	}
+
+	var expiry = time.Now().Add(otpEnrollmentLifespan)
+	var enrollment = internal.OTPEnrollment{
+		RealmName: realm,
+		UserID:    userID,
+		Secret:    secret,
+		ExpiresAt: expiry.UnixNano() / int64(time.Millisecond),
+	}
+	if err = c.otpEnrollmentDBModule.StoreOTPEnrollment(ctx, enrollment); err != nil {
+		c.logger.Warn("err", err.Error())
+		return api.OTPEnrollmentRepresentation{}, err
+	}
+
+	return api.OTPEnrollmentRepresentation{
+		Secret:    policy.EncodedSecret(secret),
+		QRPayload: policy.KeyURI(realm, username, secret),
+		Algorithm: policy.Algorithm,
+		Digits:    policy.Digits,
+		Period:    policy.Period,
+		ExpiresAt: expiry.Unix(),
+	}, nil
+}
+
+// VerifyOTPEnrollment checks the code generated by the OTP application of the user with the pending secret and
+// registers the secret as a new OTP credential of the user in Keycloak
+func (c *component) VerifyOTPEnrollment(ctx context.Context, code string, userLabel *string) error {
+	var realm = ctx.Value(cs.CtContextRealm).(string)
+	var userID = ctx.Value(cs.CtContextUserID).(string)
+	var username = ctx.Value(cs.CtContextUsername).(string)
+
+	enrollment, err := c.otpEnrollmentDBModule.GetOTPEnrollment(ctx, realm, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+	if enrollment == nil {
+		return errorhandler.Error{
+			Status:  http.StatusBadRequest,
+			Message: internal.ComponentName + "." + internal.MsgErrNoPendingChange + "." + internal.OTP,
+		}
+	}
+
+	var now = time.Now()
+	if now.UnixNano()/int64(time.Millisecond) > enrollment.ExpiresAt {
+		c.deleteOTPEnrollment(ctx, realm, userID)
+		return errorhandler.Error{
+			Status:  http.StatusBadRequest,
+			Message: internal.ComponentName + "." + internal.MsgErrExpiredCode + "." + internal.OTP,
+		}
+	}
+
+	accessToken, err := c.tokenProvider.ProvideToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the policy is read again as the code must be checked the way Keycloak will check it
+	policy, err := c.getTOTPPolicy(accessToken, realm)
+	if err != nil {
+		return err
+	}
+
+	if !policy.ValidateTOTP(enrollment.Secret, code, now) {
+		if enrollment.Attempts+1 >= maxConfirmationAttempts {
+			c.deleteOTPEnrollment(ctx, realm, userID)
+		} else if err = c.otpEnrollmentDBModule.IncrementOTPEnrollmentAttempts(ctx, realm, userID); err != nil {
+			c.logger.Warn("err", err.Error())
+			return err
+		}
+		return errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Code)
+	}
+
+	credential, err := otpCredential(enrollment.Secret, policy, userLabel)
+	if err != nil {
+		return err
+	}
+	// Keycloak creates the credentials sent with the representation of the user: the other fields are left unchanged
+	var credentials = []kc.CredentialRepresentation{credential}
+	if err = c.keycloakTechnicalClient.UpdateUser(accessToken, realm, userID, kc.UserRepresentation{Credentials: &credentials}); err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	// the credential is created: a failure to remove the pending secret must not be reported as a failed enrollment
+	c.deleteOTPEnrollment(ctx, realm, userID)
+
+	c.reportAccountEvent(ctx, "SELF_ADD_CREDENTIAL", realm, userID, username, map[string]string{"credential_type": otpCredentialType})
+	return nil
+}
+
+// otpCredential returns the Keycloak representation of a time-based OTP credential
+func otpCredential(secret string, policy internal.TOTPPolicy, userLabel *string) (kc.CredentialRepresentation, error) {
+	secretData, err := json.Marshal(otpSecretData{Value: secret})
+	if err != nil {
+		return kc.CredentialRepresentation{}, err
+	}
+	credentialData, err := json.Marshal(otpCredentialData{
+		SubType:   "totp",
+		Digits:    policy.Digits,
+		Counter:   0,
+		Period:    policy.Period,
+		Algorithm: policy.Algorithm,
+	})
+	if err != nil {
+		return kc.CredentialRepresentation{}, err
+	}
+
+	var credentialType = otpCredentialType
+	var secretDataJSON = string(secretData)
+	var credentialDataJSON = string(credentialData)
+	return kc.CredentialRepresentation{
+		Type:           &credentialType,
+		UserLabel:      userLabel,
+		SecretData:     &secretDataJSON,
+		CredentialData: &credentialDataJSON,
+	}, nil
+}
+
+func (c *component) deleteOTPEnrollment(ctx context.Context, realm, userID string) {
+	if err := c.otpEnrollmentDBModule.DeleteOTPEnrollment(ctx, realm, userID); err != nil {
+		c.logger.Warn("msg", "can't remove the pending OTP enrollment", "err", err.Error())
+	}
+}
+
+// getTOTPPolicy returns the OTP policy of the realm. Only time-based OTP can be enrolled through the API.
+func (c *component) getTOTPPolicy(accessToken, realm string) (internal.TOTPPolicy, error) {
+	realmKc, err := c.keycloakTechnicalClient.GetRealm(accessToken, realm)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return internal.TOTPPolicy{}, err
+	}
+
+	if realmKc.OtpPolicyType != nil && *realmKc.OtpPolicyType != "totp" {
+		return internal.TOTPPolicy{}, errorhandler.Error{
+			Status:  http.StatusPreconditionFailed,
+			Message: internal.ComponentName + "." + internal.MsgErrPreconditionFailed + "." + internal.OTPPolicy,
+		}
+	}
+
+	var policy = internal.DefaultTOTPPolicy
+	if realmKc.OtpPolicyAlgorithm != nil {
+		policy.Algorithm = *realmKc.OtpPolicyAlgorithm
+	}
+	if realmKc.OtpPolicyDigits != nil {
+		policy.Digits = int(*realmKc.OtpPolicyDigits)
+	}
+	if realmKc.OtpPolicyPeriod != nil {
+		policy.Period = int(*realmKc.OtpPolicyPeriod)
+	}
+	if realmKc.OtpPolicyLookAheadWindow != nil {
+		policy.LookAheadWindow = int(*realmKc.OtpPolicyLookAheadWindow)
+	}
+	return policy, nil
+}
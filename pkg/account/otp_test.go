@@ -0,0 +1,203 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	"github.com/cloudtrust/common-service/database"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	"github.com/cloudtrust/keycloak-bridge/pkg/account/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartOTPEnrollment(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	mockOTPEnrollmentDBModule := mock.NewOTPEnrollmentDBModule(mockCtrl)
+
+	var accountComponent = NewComponent(nil, mockKeycloakTechnicalClient, mockTokenProvider, nil, nil, nil, mockOTPEnrollmentDBModule, nil, nil, nil, nil, log.NewNopLogger())
+
+	var technicalToken = "technical token"
+	var realmName = "master"
+	var userID = "1234-789"
+	var username = "username"
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	var algorithm = keycloakb.OTPAlgorithmSHA256
+	var digits int32 = 8
+
+	t.Run("Token of the technical user can't be obtained", func(t *testing.T) {
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return("", errors.New("error")).Times(1)
+		var _, err = accountComponent.StartOTPEnrollment(ctx)
+		assert.NotNil(t, err)
+	})
+
+	mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).AnyTimes()
+
+	t.Run("Realm can't be read", func(t *testing.T) {
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realmName).Return(kc.RealmRepresentation{}, errors.New("error")).Times(1)
+		var _, err = accountComponent.StartOTPEnrollment(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Realm uses counter-based OTP", func(t *testing.T) {
+		var otpType = "hotp"
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realmName).Return(kc.RealmRepresentation{OtpPolicyType: &otpType}, nil).Times(1)
+		var _, err = accountComponent.StartOTPEnrollment(ctx)
+		assert.NotNil(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, err.(errorhandler.Error).Status)
+	})
+
+	t.Run("Enrollment can't be stored", func(t *testing.T) {
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realmName).Return(kc.RealmRepresentation{}, nil).Times(1)
+		mockOTPEnrollmentDBModule.EXPECT().StoreOTPEnrollment(ctx, gomock.Any()).Return(errors.New("db error")).Times(1)
+		var _, err = accountComponent.StartOTPEnrollment(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Secret is generated", func(t *testing.T) {
+		var stored keycloakb.OTPEnrollment
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realmName).Return(kc.RealmRepresentation{OtpPolicyAlgorithm: &algorithm, OtpPolicyDigits: &digits}, nil).Times(1)
+		mockOTPEnrollmentDBModule.EXPECT().StoreOTPEnrollment(ctx, gomock.Any()).DoAndReturn(
+			func(_ context.Context, enrollment keycloakb.OTPEnrollment) error {
+				stored = enrollment
+				return nil
+			}).Times(1)
+
+		var enrollment, err = accountComponent.StartOTPEnrollment(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, algorithm, enrollment.Algorithm)
+		assert.Equal(t, 8, enrollment.Digits)
+		assert.Equal(t, 30, enrollment.Period)
+		assert.True(t, strings.HasPrefix(enrollment.QRPayload, "otpauth://totp/master:username?"))
+
+		assert.Equal(t, realmName, stored.RealmName)
+		assert.Equal(t, userID, stored.UserID)
+		assert.Len(t, stored.Secret, otpSecretLength)
+		assert.Equal(t, keycloakb.DefaultTOTPPolicy.EncodedSecret(stored.Secret), enrollment.Secret)
+		assert.Equal(t, enrollment.ExpiresAt, stored.ExpiresAt/1000)
+	})
+}
+
+func TestVerifyOTPEnrollment(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+	mockOTPEnrollmentDBModule := mock.NewOTPEnrollmentDBModule(mockCtrl)
+
+	var accountComponent = NewComponent(nil, mockKeycloakTechnicalClient, mockTokenProvider, mockEventDBModule, nil, nil, mockOTPEnrollmentDBModule, nil, nil, nil, nil, log.NewNopLogger())
+
+	var technicalToken = "technical token"
+	var realmName = "master"
+	var userID = "1234-789"
+	var username = "username"
+	var secret = "12345678901234567890"
+	var label = "My phone"
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	var validCode, _ = keycloakb.DefaultTOTPPolicy.GenerateTOTP(secret, time.Now())
+	var wrongCode = "000000"
+	if validCode == wrongCode {
+		wrongCode = "111111"
+	}
+	var pendingEnrollment = func(expiry time.Time, attempts int) *keycloakb.OTPEnrollment {
+		return &keycloakb.OTPEnrollment{
+			RealmName: realmName,
+			UserID:    userID,
+			Secret:    secret,
+			ExpiresAt: expiry.UnixNano() / int64(time.Millisecond),
+			Attempts:  attempts,
+		}
+	}
+
+	mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).AnyTimes()
+
+	t.Run("Enrollment can't be read", func(t *testing.T) {
+		mockOTPEnrollmentDBModule.EXPECT().GetOTPEnrollment(ctx, realmName, userID).Return(nil, errors.New("db error")).Times(1)
+		var err = accountComponent.VerifyOTPEnrollment(ctx, validCode, nil)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("No pending enrollment", func(t *testing.T) {
+		mockOTPEnrollmentDBModule.EXPECT().GetOTPEnrollment(ctx, realmName, userID).Return(nil, nil).Times(1)
+		var err = accountComponent.VerifyOTPEnrollment(ctx, validCode, nil)
+		assert.NotNil(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(errorhandler.Error).Status)
+	})
+
+	t.Run("Pending enrollment expired", func(t *testing.T) {
+		mockOTPEnrollmentDBModule.EXPECT().GetOTPEnrollment(ctx, realmName, userID).Return(pendingEnrollment(time.Now().Add(-time.Minute), 0), nil).Times(1)
+		mockOTPEnrollmentDBModule.EXPECT().DeleteOTPEnrollment(ctx, realmName, userID).Return(nil).Times(1)
+		var err = accountComponent.VerifyOTPEnrollment(ctx, validCode, nil)
+		assert.NotNil(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(errorhandler.Error).Status)
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		mockOTPEnrollmentDBModule.EXPECT().GetOTPEnrollment(ctx, realmName, userID).Return(pendingEnrollment(time.Now().Add(time.Minute), 1), nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realmName).Return(kc.RealmRepresentation{}, nil).Times(1)
+		mockOTPEnrollmentDBModule.EXPECT().IncrementOTPEnrollmentAttempts(ctx, realmName, userID).Return(nil).Times(1)
+		var err = accountComponent.VerifyOTPEnrollment(ctx, wrongCode, nil)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Too many wrong codes", func(t *testing.T) {
+		mockOTPEnrollmentDBModule.EXPECT().GetOTPEnrollment(ctx, realmName, userID).Return(pendingEnrollment(time.Now().Add(time.Minute), maxConfirmationAttempts-1), nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realmName).Return(kc.RealmRepresentation{}, nil).Times(1)
+		mockOTPEnrollmentDBModule.EXPECT().DeleteOTPEnrollment(ctx, realmName, userID).Return(nil).Times(1)
+		var err = accountComponent.VerifyOTPEnrollment(ctx, wrongCode, nil)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Credential can't be created", func(t *testing.T) {
+		mockOTPEnrollmentDBModule.EXPECT().GetOTPEnrollment(ctx, realmName, userID).Return(pendingEnrollment(time.Now().Add(time.Minute), 0), nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realmName).Return(kc.RealmRepresentation{}, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().UpdateUser(technicalToken, realmName, userID, gomock.Any()).Return(errors.New("error")).Times(1)
+		var err = accountComponent.VerifyOTPEnrollment(ctx, validCode, nil)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("OTP is enrolled", func(t *testing.T) {
+		var user kc.UserRepresentation
+		mockOTPEnrollmentDBModule.EXPECT().GetOTPEnrollment(ctx, realmName, userID).Return(pendingEnrollment(time.Now().Add(time.Minute), 2), nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetRealm(technicalToken, realmName).Return(kc.RealmRepresentation{}, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().UpdateUser(technicalToken, realmName, userID, gomock.Any()).DoAndReturn(
+			func(_, _, _ string, u kc.UserRepresentation) error {
+				user = u
+				return nil
+			}).Times(1)
+		// the pending secret can't be removed: the enrollment succeeds anyway
+		mockOTPEnrollmentDBModule.EXPECT().DeleteOTPEnrollment(ctx, realmName, userID).Return(errors.New("db error")).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "SELF_ADD_CREDENTIAL", "self-service", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username, database.CtEventAdditionalInfo, `{"credential_type":"otp"}`).Return(nil).Times(1)
+
+		var err = accountComponent.VerifyOTPEnrollment(ctx, validCode, &label)
+		assert.Nil(t, err)
+		assert.Nil(t, user.Attributes)
+		assert.Len(t, *user.Credentials, 1)
+		var credential = (*user.Credentials)[0]
+		assert.Equal(t, "otp", *credential.Type)
+		assert.Equal(t, label, *credential.UserLabel)
+		assert.Nil(t, credential.Value)
+		assert.Equal(t, `{"value":"12345678901234567890"}`, *credential.SecretData)
+		assert.Equal(t, `{"subType":"totp","digits":6,"counter":0,"period":30,"algorithm":"HmacSHA1"}`, *credential.CredentialData)
+	})
+}
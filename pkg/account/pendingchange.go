@@ -0,0 +1,174 @@
+package account
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	kc "github.com/cloudtrust/keycloak-client"
+)
+
+const (
+	// pendingChangeLifespan is the time given to the user to confirm a change
+	pendingChangeLifespan = time.Hour
+	// maxConfirmationAttempts is the number of wrong codes after which the pending change is dropped
+	maxConfirmationAttempts = 5
+)
+
+// pendingChange describes how a change of email or phone number is confirmed. The pending value and the salted hash of
+// its verification code are kept in the DB of the bridge: the users can write their own attributes through the account
+// API of Keycloak and must not be able to forge a confirmation.
+type pendingChange struct {
+	field       string
+	eventPrefix string
+	channel     string
+	// apply replaces the current value by the confirmed one
+	apply func(user *kc.UserRepresentation, attributes map[string][]string, value string)
+}
+
+var pendingEmailChange = pendingChange{
+	field:       internal.Email,
+	eventPrefix: "EMAIL_CHANGE",
+	channel:     internal.ChannelEmail,
+	apply: func(user *kc.UserRepresentation, attributes map[string][]string, value string) {
+		var verified = true
+		user.Email = &value
+		user.EmailVerified = &verified
+	},
+}
+
+var pendingPhoneNumberChange = pendingChange{
+	field:       internal.PhoneNumber,
+	eventPrefix: "PHONE_NUMBER_CHANGE",
+	channel:     internal.ChannelSMS,
+	apply: func(user *kc.UserRepresentation, attributes map[string][]string, value string) {
+		attributes["phoneNumber"] = []string{value}
+		attributes["phoneNumberVerified"] = []string{"true"}
+	},
+}
+
+// requestConfirmation stores the new value as pending and sends a verification code to it. Only the salted hash of the
+// code is kept. A previous pending change of the same field and its code are replaced.
+func (c *component) requestConfirmation(ctx context.Context, realm, userID, username string, change pendingChange, value string) error {
+	var code = internal.GenerateVerificationCode()
+	codeHash, err := internal.HashVerificationCode(code)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	var pending = internal.PendingChange{
+		RealmName: realm,
+		UserID:    userID,
+		Field:     change.field,
+		Value:     value,
+		CodeHash:  codeHash,
+		ExpiresAt: time.Now().Add(pendingChangeLifespan).UnixNano() / int64(time.Millisecond),
+	}
+	if err = c.pendingChangeDBModule.StorePendingChange(ctx, pending); err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	if err = c.notifier.SendVerificationCode(ctx, realm, change.channel, value, code, pendingChangeLifespan); err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	c.reportAccountEvent(ctx, change.eventPrefix+"_REQUESTED", realm, userID, username, nil)
+	return nil
+}
+
+func (c *component) ConfirmEmailChange(ctx context.Context, code string) error {
+	return c.confirmChange(ctx, pendingEmailChange, code)
+}
+
+func (c *component) ConfirmPhoneNumberChange(ctx context.Context, code string) error {
+	return c.confirmChange(ctx, pendingPhoneNumberChange, code)
+}
+
+// confirmChange checks the verification code of the pending change and replaces the current value by the pending one
+func (c *component) confirmChange(ctx context.Context, change pendingChange, code string) error {
+	var realm = ctx.Value(cs.CtContextRealm).(string)
+	var userID = ctx.Value(cs.CtContextUserID).(string)
+	var username = ctx.Value(cs.CtContextUsername).(string)
+
+	pending, err := c.pendingChangeDBModule.GetPendingChange(ctx, realm, userID, change.field)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+	if pending == nil {
+		return errorhandler.Error{
+			Status:  http.StatusBadRequest,
+			Message: internal.ComponentName + "." + internal.MsgErrNoPendingChange + "." + change.field,
+		}
+	}
+
+	if time.Now().UnixNano()/int64(time.Millisecond) > pending.ExpiresAt {
+		c.deletePendingChange(ctx, realm, userID, change.field)
+		c.reportAccountEvent(ctx, change.eventPrefix+"_EXPIRED", realm, userID, username, nil)
+		return errorhandler.Error{
+			Status:  http.StatusBadRequest,
+			Message: internal.ComponentName + "." + internal.MsgErrExpiredCode + "." + change.field,
+		}
+	}
+
+	if !internal.CheckVerificationCode(code, pending.CodeHash) {
+		var attempts = pending.Attempts + 1
+		if attempts >= maxConfirmationAttempts {
+			c.deletePendingChange(ctx, realm, userID, change.field)
+		} else if err = c.pendingChangeDBModule.IncrementPendingChangeAttempts(ctx, realm, userID, change.field); err != nil {
+			c.logger.Warn("err", err.Error())
+			return err
+		}
+		c.reportAccountEvent(ctx, change.eventPrefix+"_CONFIRMATION_FAILED", realm, userID, username, map[string]string{"attempts": strconv.Itoa(attempts)})
+		return errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Code)
+	}
+
+	accessToken, err := c.tokenProvider.ProvideToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	userKc, err := c.keycloakTechnicalClient.GetUser(accessToken, realm, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+	var attributes = userAttributes(userKc)
+	change.apply(&userKc, attributes, pending.Value)
+	userKc.Attributes = &attributes
+
+	if err = c.keycloakTechnicalClient.UpdateUser(accessToken, realm, userID, userKc); err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	// the change is applied: a failure to remove it from the DB must not be reported as a failed confirmation
+	c.deletePendingChange(ctx, realm, userID, change.field)
+
+	c.reportAccountEvent(ctx, change.eventPrefix+"_CONFIRMED", realm, userID, username, nil)
+	return nil
+}
+
+func (c *component) deletePendingChange(ctx context.Context, realm, userID, field string) {
+	if err := c.pendingChangeDBModule.DeletePendingChange(ctx, realm, userID, field); err != nil {
+		c.logger.Warn("msg", "can't remove the pending change", "field", field, "err", err.Error())
+	}
+}
+
+// userAttributes returns a copy of the attributes of a user
+func userAttributes(user kc.UserRepresentation) map[string][]string {
+	var res = make(map[string][]string)
+	if user.Attributes != nil {
+		for key, values := range *user.Attributes {
+			res[key] = values
+		}
+	}
+	return res
+}
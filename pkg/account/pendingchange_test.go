@@ -0,0 +1,189 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	"github.com/cloudtrust/common-service/database"
+	commonhttp "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	"github.com/cloudtrust/keycloak-bridge/pkg/account/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func codeHash(code string) string {
+	var hash, _ = keycloakb.HashVerificationCode(code)
+	return hash
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+	mockPendingChangeDBModule := mock.NewPendingChangeDBModule(mockCtrl)
+
+	var accountComponent = NewComponent(nil, mockKeycloakTechnicalClient, mockTokenProvider, mockEventDBModule, nil, nil, nil, mockPendingChangeDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var technicalToken = "technical token"
+	var realmName = "master"
+	var userID = "123-456-789"
+	var username = "username"
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	var email = "old@mail.com"
+	var newEmail = "new@mail.com"
+	var code = "123456"
+	var userKc = kc.UserRepresentation{Email: &email, Attributes: &map[string][]string{"label": {"Label"}}}
+	var pendingChange = func(expiry time.Time, attempts int) *keycloakb.PendingChange {
+		return &keycloakb.PendingChange{
+			RealmName: realmName,
+			UserID:    userID,
+			Field:     keycloakb.Email,
+			Value:     newEmail,
+			CodeHash:  codeHash(code),
+			ExpiresAt: expiry.UnixNano() / int64(time.Millisecond),
+			Attempts:  attempts,
+		}
+	}
+	var validUntil = time.Now().Add(time.Minute)
+
+	t.Run("Pending change can't be read", func(t *testing.T) {
+		mockPendingChangeDBModule.EXPECT().GetPendingChange(ctx, realmName, userID, keycloakb.Email).Return(nil, errors.New("db error")).Times(1)
+		var err = accountComponent.ConfirmEmailChange(ctx, code)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("No pending change", func(t *testing.T) {
+		mockPendingChangeDBModule.EXPECT().GetPendingChange(ctx, realmName, userID, keycloakb.Email).Return(nil, nil).Times(1)
+		var err = accountComponent.ConfirmEmailChange(ctx, code)
+		assert.Equal(t, keycloakb.ComponentName+"."+keycloakb.MsgErrNoPendingChange+"."+keycloakb.Email, err.(commonhttp.Error).Message)
+	})
+
+	t.Run("Expired change is dropped", func(t *testing.T) {
+		mockPendingChangeDBModule.EXPECT().GetPendingChange(ctx, realmName, userID, keycloakb.Email).Return(pendingChange(time.Now().Add(-time.Minute), 0), nil).Times(1)
+		mockPendingChangeDBModule.EXPECT().DeletePendingChange(ctx, realmName, userID, keycloakb.Email).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "EMAIL_CHANGE_EXPIRED", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		var err = accountComponent.ConfirmEmailChange(ctx, code)
+		assert.Equal(t, keycloakb.ComponentName+"."+keycloakb.MsgErrExpiredCode+"."+keycloakb.Email, err.(commonhttp.Error).Message)
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		mockPendingChangeDBModule.EXPECT().GetPendingChange(ctx, realmName, userID, keycloakb.Email).Return(pendingChange(validUntil, 1), nil).Times(1)
+		mockPendingChangeDBModule.EXPECT().IncrementPendingChangeAttempts(ctx, realmName, userID, keycloakb.Email).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "EMAIL_CHANGE_CONFIRMATION_FAILED", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), database.CtEventAdditionalInfo, `{"attempts":"2"}`).Return(nil).Times(1)
+
+		var err = accountComponent.ConfirmEmailChange(ctx, "654321")
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Wrong code can't be counted", func(t *testing.T) {
+		mockPendingChangeDBModule.EXPECT().GetPendingChange(ctx, realmName, userID, keycloakb.Email).Return(pendingChange(validUntil, 1), nil).Times(1)
+		mockPendingChangeDBModule.EXPECT().IncrementPendingChangeAttempts(ctx, realmName, userID, keycloakb.Email).Return(errors.New("db error")).Times(1)
+
+		var err = accountComponent.ConfirmEmailChange(ctx, "654321")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Too many wrong codes", func(t *testing.T) {
+		mockPendingChangeDBModule.EXPECT().GetPendingChange(ctx, realmName, userID, keycloakb.Email).Return(pendingChange(validUntil, maxConfirmationAttempts-1), nil).Times(1)
+		mockPendingChangeDBModule.EXPECT().DeletePendingChange(ctx, realmName, userID, keycloakb.Email).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "EMAIL_CHANGE_CONFIRMATION_FAILED", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		var err = accountComponent.ConfirmEmailChange(ctx, "654321")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Token of the technical user can't be obtained", func(t *testing.T) {
+		mockPendingChangeDBModule.EXPECT().GetPendingChange(ctx, realmName, userID, keycloakb.Email).Return(pendingChange(validUntil, 0), nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return("", errors.New("error")).Times(1)
+
+		var err = accountComponent.ConfirmEmailChange(ctx, code)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Email is replaced", func(t *testing.T) {
+		mockPendingChangeDBModule.EXPECT().GetPendingChange(ctx, realmName, userID, keycloakb.Email).Return(pendingChange(validUntil, 1), nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetUser(technicalToken, realmName, userID).Return(userKc, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().UpdateUser(technicalToken, realmName, userID, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName, userID string, user kc.UserRepresentation) error {
+				assert.Equal(t, newEmail, *user.Email)
+				assert.True(t, *user.EmailVerified)
+				assert.Equal(t, map[string][]string{"label": {"Label"}}, *user.Attributes)
+				return nil
+			}).Times(1)
+		// the change is applied even if it can't be removed from the DB
+		mockPendingChangeDBModule.EXPECT().DeletePendingChange(ctx, realmName, userID, keycloakb.Email).Return(errors.New("db error")).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "EMAIL_CHANGE_CONFIRMED", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("db error")).Times(1)
+
+		var err = accountComponent.ConfirmEmailChange(ctx, code)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Update fails", func(t *testing.T) {
+		mockPendingChangeDBModule.EXPECT().GetPendingChange(ctx, realmName, userID, keycloakb.Email).Return(pendingChange(validUntil, 0), nil).Times(1)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().GetUser(technicalToken, realmName, userID).Return(userKc, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().UpdateUser(technicalToken, realmName, userID, gomock.Any()).Return(errors.New("error")).Times(1)
+
+		var err = accountComponent.ConfirmEmailChange(ctx, code)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestConfirmPhoneNumberChange(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+	mockPendingChangeDBModule := mock.NewPendingChangeDBModule(mockCtrl)
+
+	var accountComponent = NewComponent(nil, mockKeycloakTechnicalClient, mockTokenProvider, mockEventDBModule, nil, nil, nil, mockPendingChangeDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var technicalToken = "technical token"
+	var realmName = "master"
+	var userID = "123-456-789"
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "username")
+
+	var code = "123456"
+	var attributes = map[string][]string{
+		"phoneNumber":         {"+41789467123"},
+		"phoneNumberVerified": {"true"},
+	}
+	var pending = keycloakb.PendingChange{
+		Field:     keycloakb.PhoneNumber,
+		Value:     "+41789456123",
+		CodeHash:  codeHash(code),
+		ExpiresAt: time.Now().Add(time.Minute).UnixNano() / int64(time.Millisecond),
+	}
+
+	mockPendingChangeDBModule.EXPECT().GetPendingChange(ctx, realmName, userID, keycloakb.PhoneNumber).Return(&pending, nil).Times(1)
+	mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).Times(1)
+	mockKeycloakTechnicalClient.EXPECT().GetUser(technicalToken, realmName, userID).Return(kc.UserRepresentation{Attributes: &attributes}, nil).Times(1)
+	mockKeycloakTechnicalClient.EXPECT().UpdateUser(technicalToken, realmName, userID, gomock.Any()).DoAndReturn(
+		func(accessToken, realmName, userID string, user kc.UserRepresentation) error {
+			assert.Equal(t, map[string][]string{"phoneNumber": {"+41789456123"}, "phoneNumberVerified": {"true"}}, *user.Attributes)
+			return nil
+		}).Times(1)
+	mockPendingChangeDBModule.EXPECT().DeletePendingChange(ctx, realmName, userID, keycloakb.PhoneNumber).Return(nil).Times(1)
+	mockEventDBModule.EXPECT().ReportEvent(ctx, "PHONE_NUMBER_CHANGE_CONFIRMED", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	var err = accountComponent.ConfirmPhoneNumberChange(ctx, code)
+	assert.Nil(t, err)
+	// the attributes read from Keycloak are not modified
+	assert.Equal(t, []string{"+41789467123"}, attributes["phoneNumber"])
+}
@@ -0,0 +1,116 @@
+package account
+
+import (
+	"context"
+	"net/http"
+
+	cs "github.com/cloudtrust/common-service"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	api "github.com/cloudtrust/keycloak-bridge/api/account"
+	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	kc "github.com/cloudtrust/keycloak-client"
+)
+
+// GetSessions returns the active sessions of the current user. The session of the access token is flagged as current.
+func (c *component) GetSessions(ctx context.Context) ([]api.SessionRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	sessionsKc, err := c.getUserSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var currentSessionID = internal.SessionIDFromToken(accessToken)
+	var sessionsRep = []api.SessionRepresentation{}
+	for _, sessionKc := range sessionsKc {
+		sessionsRep = append(sessionsRep, api.ConvertToAPISession(sessionKc, currentSessionID))
+	}
+
+	return sessionsRep, nil
+}
+
+// DeleteSession ends a session of the current user
+func (c *component) DeleteSession(ctx context.Context, sessionID string) error {
+	sessionsKc, err := c.getUserSessions(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the session must belong to the user, sessions are deleted at realm level by Keycloak
+	for _, sessionKc := range sessionsKc {
+		if sessionKc.Id != nil && *sessionKc.Id == sessionID {
+			return c.deleteSession(ctx, sessionID)
+		}
+	}
+	return errorhandler.Error{
+		Status:  http.StatusNotFound,
+		Message: internal.ComponentName + "." + internal.MsgErrInvalidParam + "." + internal.SessionID,
+	}
+}
+
+// DeleteOtherSessions ends all the sessions of the current user but the one of the access token
+func (c *component) DeleteOtherSessions(ctx context.Context) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	// without the current session, all the sessions of the user would be ended
+	var currentSessionID = internal.SessionIDFromToken(accessToken)
+	if currentSessionID == "" {
+		return errorhandler.Error{
+			Status:  http.StatusBadRequest,
+			Message: internal.ComponentName + "." + internal.MsgErrMissingParam + "." + internal.SessionID,
+		}
+	}
+
+	sessionsKc, err := c.getUserSessions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, sessionKc := range sessionsKc {
+		if sessionKc.Id == nil || *sessionKc.Id == currentSessionID {
+			continue
+		}
+		if err = c.deleteSession(ctx, *sessionKc.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getUserSessions reads the sessions of the current user. Sessions are only readable through the admin API, with the
+// token of the technical user.
+func (c *component) getUserSessions(ctx context.Context) ([]kc.UserSessionRepresentation, error) {
+	var realm = ctx.Value(cs.CtContextRealm).(string)
+	var userID = ctx.Value(cs.CtContextUserID).(string)
+
+	technicalToken, err := c.tokenProvider.ProvideToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionsKc, err := c.keycloakTechnicalClient.GetSessionsForUser(technicalToken, realm, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return nil, err
+	}
+	return sessionsKc, nil
+}
+
+func (c *component) deleteSession(ctx context.Context, sessionID string) error {
+	var realm = ctx.Value(cs.CtContextRealm).(string)
+	var userID = ctx.Value(cs.CtContextUserID).(string)
+	var username = ctx.Value(cs.CtContextUsername).(string)
+
+	technicalToken, err := c.tokenProvider.ProvideToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = c.keycloakTechnicalClient.DeleteSession(technicalToken, realm, sessionID); err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	c.reportAccountEvent(ctx, "SELF_LOGOUT_SESSION", realm, userID, username, map[string]string{"session_id": sessionID})
+	return nil
+}
@@ -0,0 +1,119 @@
+package account
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service"
+	"github.com/cloudtrust/common-service/database"
+	commonhttp "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/keycloak-bridge/pkg/account/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessions(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakTechnicalClient := mock.NewKeycloakTechnicalClient(mockCtrl)
+	mockTokenProvider := mock.NewTokenProvider(mockCtrl)
+	mockEventDBModule := mock.NewEventsDBModule(mockCtrl)
+
+	var accountComponent = NewComponent(nil, mockKeycloakTechnicalClient, mockTokenProvider, mockEventDBModule, nil, nil, nil, nil, nil, nil, nil, log.NewNopLogger())
+
+	var currentSessionID = "11111111-aaaa-bbbb-cccc-000000000001"
+	var otherSessionID = "11111111-aaaa-bbbb-cccc-000000000002"
+	var accessToken = "header." + base64.RawURLEncoding.EncodeToString([]byte(`{"session_state":"`+currentSessionID+`"}`)) + ".signature"
+	var technicalToken = "technical token"
+	var realmName = "master"
+	var userID = "1234-789"
+	var username = "username"
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, userID)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	var ipAddress = "10.0.0.1"
+	var sessionsKc = []kc.UserSessionRepresentation{
+		{Id: &currentSessionID, IpAddress: &ipAddress, Clients: &map[string]string{"id-2": "self-service", "id-1": "account"}},
+		{Id: &otherSessionID},
+	}
+
+	mockTokenProvider.EXPECT().ProvideToken(ctx).Return(technicalToken, nil).AnyTimes()
+
+	t.Run("Sessions can't be read", func(t *testing.T) {
+		mockKeycloakTechnicalClient.EXPECT().GetSessionsForUser(technicalToken, realmName, userID).Return(nil, errors.New("error")).Times(3)
+
+		var _, err = accountComponent.GetSessions(ctx)
+		assert.NotNil(t, err)
+		err = accountComponent.DeleteSession(ctx, otherSessionID)
+		assert.NotNil(t, err)
+		err = accountComponent.DeleteOtherSessions(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Get sessions", func(t *testing.T) {
+		mockKeycloakTechnicalClient.EXPECT().GetSessionsForUser(technicalToken, realmName, userID).Return(sessionsKc, nil).Times(1)
+
+		var sessions, err = accountComponent.GetSessions(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 2)
+		assert.True(t, sessions[0].Current)
+		assert.Equal(t, ipAddress, *sessions[0].IPAddress)
+		assert.Equal(t, []string{"account", "self-service"}, *sessions[0].Clients)
+		assert.False(t, sessions[1].Current)
+	})
+
+	t.Run("Delete a session", func(t *testing.T) {
+		mockKeycloakTechnicalClient.EXPECT().GetSessionsForUser(technicalToken, realmName, userID).Return(sessionsKc, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().DeleteSession(technicalToken, realmName, otherSessionID).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "SELF_LOGOUT_SESSION", "self-service", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username, database.CtEventAdditionalInfo, `{"session_id":"`+otherSessionID+`"}`).Return(nil).Times(1)
+
+		var err = accountComponent.DeleteSession(ctx, otherSessionID)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Session of another user", func(t *testing.T) {
+		mockKeycloakTechnicalClient.EXPECT().GetSessionsForUser(technicalToken, realmName, userID).Return(sessionsKc, nil).Times(1)
+
+		var err = accountComponent.DeleteSession(ctx, "11111111-aaaa-bbbb-cccc-000000000003")
+		assert.Equal(t, 404, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Session can't be deleted", func(t *testing.T) {
+		mockKeycloakTechnicalClient.EXPECT().GetSessionsForUser(technicalToken, realmName, userID).Return(sessionsKc, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().DeleteSession(technicalToken, realmName, otherSessionID).Return(errors.New("error")).Times(1)
+
+		var err = accountComponent.DeleteSession(ctx, otherSessionID)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Delete the other sessions", func(t *testing.T) {
+		mockKeycloakTechnicalClient.EXPECT().GetSessionsForUser(technicalToken, realmName, userID).Return(sessionsKc, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().DeleteSession(technicalToken, realmName, otherSessionID).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "SELF_LOGOUT_SESSION", "self-service", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("db error")).Times(1)
+
+		var err = accountComponent.DeleteOtherSessions(ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Other sessions can't be deleted", func(t *testing.T) {
+		mockKeycloakTechnicalClient.EXPECT().GetSessionsForUser(technicalToken, realmName, userID).Return(sessionsKc, nil).Times(1)
+		mockKeycloakTechnicalClient.EXPECT().DeleteSession(technicalToken, realmName, otherSessionID).Return(errors.New("error")).Times(1)
+
+		var err = accountComponent.DeleteOtherSessions(ctx)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Current session unknown", func(t *testing.T) {
+		var ctx = context.WithValue(ctx, cs.CtContextAccessToken, "header."+base64.RawURLEncoding.EncodeToString([]byte(`{}`))+".signature")
+
+		var err = accountComponent.DeleteOtherSessions(ctx)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+}
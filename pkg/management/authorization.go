@@ -3,41 +3,59 @@ package management
 import (
 	"context"
 
+	errorhandler "github.com/cloudtrust/common-service/errors"
 	"github.com/cloudtrust/common-service/log"
 	"github.com/cloudtrust/common-service/security"
 	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 )
 
 // Creates constants for API method names
 const (
-	GetRealms                      = "GetRealms"
-	GetRealm                       = "GetRealm"
-	GetClient                      = "GetClient"
-	GetClients                     = "GetClients"
-	DeleteUser                     = "DeleteUser"
-	GetUser                        = "GetUser"
-	UpdateUser                     = "UpdateUser"
-	GetUsers                       = "GetUsers"
-	CreateUser                     = "CreateUser"
-	GetUserAccountStatus           = "GetUserAccountStatus"
-	GetRolesOfUser                 = "GetRolesOfUser"
-	GetGroupsOfUser                = "GetGroupsOfUser"
-	GetClientRolesForUser          = "GetClientRolesForUser"
-	AddClientRolesToUser           = "AddClientRolesToUser"
-	ResetPassword                  = "ResetPassword"
-	SendVerifyEmail                = "SendVerifyEmail"
-	ExecuteActionsEmail            = "ExecuteActionsEmail"
-	SendNewEnrolmentCode           = "SendNewEnrolmentCode"
-	SendReminderEmail              = "SendReminderEmail"
-	GetCredentialsForUser          = "GetCredentialsForUser"
-	DeleteCredentialsForUser       = "DeleteCredentialsForUser"
-	GetRoles                       = "GetRoles"
-	GetRole                        = "GetRole"
-	GetGroups                      = "GetGroups"
-	GetClientRoles                 = "GetClientRoles"
-	CreateClientRole               = "CreateClientRole"
-	GetRealmCustomConfiguration    = "GetRealmCustomConfiguration"
-	UpdateRealmCustomConfiguration = "UpdateRealmCustomConfiguration"
+	GetRealms                          = "GetRealms"
+	GetRealm                           = "GetRealm"
+	GetRealmSettings                   = "GetRealmSettings"
+	UpdateRealmSettings                = "UpdateRealmSettings"
+	GetClient                          = "GetClient"
+	GetClients                         = "GetClients"
+	CreateClient                       = "CreateClient"
+	UpdateClient                       = "UpdateClient"
+	EnableClient                       = "EnableClient"
+	DisableClient                      = "DisableClient"
+	RegenerateClientSecret             = "RegenerateClientSecret"
+	DeleteUser                         = "DeleteUser"
+	GetUser                            = "GetUser"
+	UpdateUser                         = "UpdateUser"
+	GetUsers                           = "GetUsers"
+	CreateUser                         = "CreateUser"
+	GetUserAccountStatus               = "GetUserAccountStatus"
+	GetRolesOfUser                     = "GetRolesOfUser"
+	GetGroupsOfUser                    = "GetGroupsOfUser"
+	GetClientRolesForUser              = "GetClientRolesForUser"
+	AddClientRolesToUser               = "AddClientRolesToUser"
+	ResetPassword                      = "ResetPassword"
+	SendVerifyEmail                    = "SendVerifyEmail"
+	ExecuteActionsEmail                = "ExecuteActionsEmail"
+	SendNewEnrolmentCode               = "SendNewEnrolmentCode"
+	SendReminderEmail                  = "SendReminderEmail"
+	GetCredentialsForUser              = "GetCredentialsForUser"
+	DeleteCredentialsForUser           = "DeleteCredentialsForUser"
+	ResetCredentialsForUser            = "ResetCredentialsForUser"
+	GetRoles                           = "GetRoles"
+	GetRole                            = "GetRole"
+	GetGroups                          = "GetGroups"
+	GetClientRoles                     = "GetClientRoles"
+	CreateClientRole                   = "CreateClientRole"
+	GetRealmCustomConfiguration        = "GetRealmCustomConfiguration"
+	UpdateRealmCustomConfiguration     = "UpdateRealmCustomConfiguration"
+	PatchRealmCustomConfiguration      = "PatchRealmCustomConfiguration"
+	GetRealmCustomConfigurationHistory = "GetRealmCustomConfigurationHistory"
+	GetRealmCustomConfigurationDiff    = "GetRealmCustomConfigurationDiff"
+	RollbackRealmCustomConfiguration   = "RollbackRealmCustomConfiguration"
+	GetUserHistory                     = "GetUserHistory"
+	GetUserSessions                    = "GetUserSessions"
+	LogoutUser                         = "LogoutUser"
+	RevokeSession                      = "RevokeSession"
 )
 
 // Tracking middleware at component level.
@@ -81,6 +99,28 @@ func (c *authorizationComponentMW) GetRealm(ctx context.Context, realm string) (
 	return c.next.GetRealm(ctx, realm)
 }
 
+func (c *authorizationComponentMW) GetRealmSettings(ctx context.Context, realmName string) (api.RealmSettingsRepresentation, error) {
+	var action = GetRealmSettings
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return api.RealmSettingsRepresentation{}, err
+	}
+
+	return c.next.GetRealmSettings(ctx, realmName)
+}
+
+func (c *authorizationComponentMW) UpdateRealmSettings(ctx context.Context, realmName string, settings api.RealmSettingsRepresentation) error {
+	var action = UpdateRealmSettings
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.UpdateRealmSettings(ctx, realmName, settings)
+}
+
 func (c *authorizationComponentMW) GetClient(ctx context.Context, realmName, idClient string) (api.ClientRepresentation, error) {
 	var action = GetClient
 	var targetRealm = realmName
@@ -103,6 +143,61 @@ func (c *authorizationComponentMW) GetClients(ctx context.Context, realmName str
 	return c.next.GetClients(ctx, realmName)
 }
 
+func (c *authorizationComponentMW) CreateClient(ctx context.Context, realmName string, client api.ClientRepresentation) (string, error) {
+	var action = CreateClient
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return "", err
+	}
+
+	return c.next.CreateClient(ctx, realmName, client)
+}
+
+func (c *authorizationComponentMW) UpdateClient(ctx context.Context, realmName, idClient string, client api.ClientRepresentation) error {
+	var action = UpdateClient
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.UpdateClient(ctx, realmName, idClient, client)
+}
+
+func (c *authorizationComponentMW) EnableClient(ctx context.Context, realmName, idClient string) error {
+	var action = EnableClient
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.EnableClient(ctx, realmName, idClient)
+}
+
+func (c *authorizationComponentMW) DisableClient(ctx context.Context, realmName, idClient string) error {
+	var action = DisableClient
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.DisableClient(ctx, realmName, idClient)
+}
+
+func (c *authorizationComponentMW) RegenerateClientSecret(ctx context.Context, realmName, idClient string) (api.ClientSecretRepresentation, error) {
+	var action = RegenerateClientSecret
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return api.ClientSecretRepresentation{}, err
+	}
+
+	return c.next.RegenerateClientSecret(ctx, realmName, idClient)
+}
+
 func (c *authorizationComponentMW) DeleteUser(ctx context.Context, realmName, userID string) error {
 	var action = DeleteUser
 	var targetRealm = realmName
@@ -125,7 +220,7 @@ func (c *authorizationComponentMW) GetUser(ctx context.Context, realmName, userI
 	return c.next.GetUser(ctx, realmName, userID)
 }
 
-func (c *authorizationComponentMW) UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation) error {
+func (c *authorizationComponentMW) UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation, ifMatch string) error {
 	var action = UpdateUser
 	var targetRealm = realmName
 
@@ -133,7 +228,7 @@ func (c *authorizationComponentMW) UpdateUser(ctx context.Context, realmName, us
 		return err
 	}
 
-	return c.next.UpdateUser(ctx, realmName, userID, user)
+	return c.next.UpdateUser(ctx, realmName, userID, user, ifMatch)
 }
 
 func (c *authorizationComponentMW) GetUsers(ctx context.Context, realmName string, groupIDs []string, paramKV ...string) (api.UsersPageRepresentation, error) {
@@ -149,7 +244,7 @@ func (c *authorizationComponentMW) GetUsers(ctx context.Context, realmName strin
 	return c.next.GetUsers(ctx, realmName, groupIDs, paramKV...)
 }
 
-func (c *authorizationComponentMW) CreateUser(ctx context.Context, realmName string, user api.UserRepresentation) (string, error) {
+func (c *authorizationComponentMW) CreateUser(ctx context.Context, realmName string, user api.UserRepresentation, force bool) (string, error) {
 	var action = CreateUser
 	var targetRealm = realmName
 
@@ -159,15 +254,15 @@ func (c *authorizationComponentMW) CreateUser(ctx context.Context, realmName str
 		}
 	}
 
-	return c.next.CreateUser(ctx, realmName, user)
+	return c.next.CreateUser(ctx, realmName, user, force)
 }
 
-func (c *authorizationComponentMW) GetUserAccountStatus(ctx context.Context, realmName, userID string) (map[string]bool, error) {
+func (c *authorizationComponentMW) GetUserAccountStatus(ctx context.Context, realmName, userID string) (api.UserStatusRepresentation, error) {
 	var action = GetUserAccountStatus
 	var targetRealm = realmName
 
 	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
-		return nil, err
+		return api.UserStatusRepresentation{}, err
 	}
 
 	return c.next.GetUserAccountStatus(ctx, realmName, userID)
@@ -294,6 +389,17 @@ func (c *authorizationComponentMW) DeleteCredentialsForUser(ctx context.Context,
 	return c.next.DeleteCredentialsForUser(ctx, realmName, userID, credentialID)
 }
 
+func (c *authorizationComponentMW) ResetCredentialsForUser(ctx context.Context, realmName string, userID string, credentialCategory string) error {
+	var action = ResetCredentialsForUser
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return err
+	}
+
+	return c.next.ResetCredentialsForUser(ctx, realmName, userID, credentialCategory)
+}
+
 func (c *authorizationComponentMW) GetRoles(ctx context.Context, realmName string) ([]api.RoleRepresentation, error) {
 	var action = GetRoles
 	var targetRealm = realmName
@@ -360,7 +466,7 @@ func (c *authorizationComponentMW) GetRealmCustomConfiguration(ctx context.Conte
 	return c.next.GetRealmCustomConfiguration(ctx, realmName)
 }
 
-func (c *authorizationComponentMW) UpdateRealmCustomConfiguration(ctx context.Context, realmName string, customConfig api.RealmCustomConfiguration) error {
+func (c *authorizationComponentMW) UpdateRealmCustomConfiguration(ctx context.Context, realmName string, customConfig api.RealmCustomConfiguration, comment string) error {
 	var action = UpdateRealmCustomConfiguration
 	var targetRealm = realmName
 
@@ -368,5 +474,144 @@ func (c *authorizationComponentMW) UpdateRealmCustomConfiguration(ctx context.Co
 		return err
 	}
 
-	return c.next.UpdateRealmCustomConfiguration(ctx, realmName, customConfig)
+	return c.next.UpdateRealmCustomConfiguration(ctx, realmName, customConfig, comment)
+}
+
+func (c *authorizationComponentMW) PatchRealmCustomConfiguration(ctx context.Context, realmName string, patch []byte, comment string) error {
+	var action = PatchRealmCustomConfiguration
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.PatchRealmCustomConfiguration(ctx, realmName, patch, comment)
+}
+
+func (c *authorizationComponentMW) GetRealmCustomConfigurationHistory(ctx context.Context, realmName string) ([]api.RealmCustomConfigurationVersion, error) {
+	var action = GetRealmCustomConfigurationHistory
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetRealmCustomConfigurationHistory(ctx, realmName)
+}
+
+func (c *authorizationComponentMW) GetRealmCustomConfigurationDiff(ctx context.Context, realmName string, fromVersion, toVersion int) ([]api.FieldChangeRepresentation, error) {
+	var action = GetRealmCustomConfigurationDiff
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetRealmCustomConfigurationDiff(ctx, realmName, fromVersion, toVersion)
+}
+
+func (c *authorizationComponentMW) RollbackRealmCustomConfiguration(ctx context.Context, realmName string, version int) error {
+	var action = RollbackRealmCustomConfiguration
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.RollbackRealmCustomConfiguration(ctx, realmName, version)
+}
+
+// BulkUserAction does not have its own action: each targeted user is checked against the action of the corresponding
+// single-user call. Users that can't be targeted are reported as failures, the others are processed by the next component.
+func (c *authorizationComponentMW) BulkUserAction(ctx context.Context, realmName string, bulk api.BulkUserActionRepresentation, paramKV ...string) ([]api.BulkUserActionResultRepresentation, error) {
+	var targetRealm = realmName
+	var action string
+
+	switch *bulk.Action {
+	case api.BulkActionLock, api.BulkActionUnlock:
+		action = UpdateUser
+	case api.BulkActionDelete:
+		action = DeleteUser
+	case api.BulkActionExecuteActionsEmail:
+		action = ExecuteActionsEmail
+	case api.BulkActionSendReminderEmail:
+		action = SendReminderEmail
+	default:
+		return nil, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.BulkAction)
+	}
+
+	// When users are selected with a filter, GetUsers of this middleware checks that the filter groups can be listed
+	userIDs, err := getBulkUserIDs(ctx, c, realmName, bulk)
+	if err != nil {
+		return nil, err
+	}
+
+	var results = []api.BulkUserActionResultRepresentation{}
+	var allowedUserIDs = []string{}
+	for _, userID := range userIDs {
+		if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+			results = append(results, bulkUserActionResult(userID, err))
+			continue
+		}
+		allowedUserIDs = append(allowedUserIDs, userID)
+	}
+
+	if len(allowedUserIDs) == 0 {
+		return results, nil
+	}
+
+	bulk.UserIDs = &allowedUserIDs
+	bulk.GroupIDs = nil
+	bulk.Search = nil
+
+	allowedResults, err := c.next.BulkUserAction(ctx, realmName, bulk, paramKV...)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(results, allowedResults...), nil
+}
+
+func (c *authorizationComponentMW) GetUserHistory(ctx context.Context, realmName, userID string, paramKV ...string) ([]api.UserChangeRepresentation, error) {
+	var action = GetUserHistory
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetUserHistory(ctx, realmName, userID, paramKV...)
+}
+
+func (c *authorizationComponentMW) GetUserSessions(ctx context.Context, realmName, userID string) ([]api.UserSessionRepresentation, error) {
+	var action = GetUserSessions
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetUserSessions(ctx, realmName, userID)
+}
+
+func (c *authorizationComponentMW) LogoutUser(ctx context.Context, realmName, userID string) error {
+	var action = LogoutUser
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return err
+	}
+
+	return c.next.LogoutUser(ctx, realmName, userID)
+}
+
+func (c *authorizationComponentMW) RevokeSession(ctx context.Context, realmName, userID, sessionID string) error {
+	var action = RevokeSession
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return err
+	}
+
+	return c.next.RevokeSession(ctx, realmName, userID, sessionID)
 }
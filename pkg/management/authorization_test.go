@@ -29,6 +29,7 @@ func TestDeny(t *testing.T) {
 	var clientID = "789-789-741"
 	var roleID = "456-852-785"
 	var credentialID = "741-865-741"
+	var sessionID = "741-865-742"
 	var userUsername = "toto"
 
 	var roleName = "role"
@@ -57,6 +58,10 @@ func TestDeny(t *testing.T) {
 
 	var roles = []api.RoleRepresentation{role}
 
+	var client = api.ClientRepresentation{
+		ClientID: &clientID,
+	}
+
 	var password = api.PasswordRepresentation{
 		Value: &pass,
 	}
@@ -83,19 +88,40 @@ func TestDeny(t *testing.T) {
 		_, err = authorizationMW.GetRealm(ctx, realmName)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		_, err = authorizationMW.GetRealmSettings(ctx, realmName)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.UpdateRealmSettings(ctx, realmName, api.RealmSettingsRepresentation{})
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		_, err = authorizationMW.GetClient(ctx, realmName, clientID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
 		_, err = authorizationMW.GetClients(ctx, realmName)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		_, err = authorizationMW.CreateClient(ctx, realmName, client)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.UpdateClient(ctx, realmName, clientID, client)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.EnableClient(ctx, realmName, clientID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.DisableClient(ctx, realmName, clientID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.RegenerateClientSecret(ctx, realmName, clientID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		err = authorizationMW.DeleteUser(ctx, realmName, userID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
 		_, err = authorizationMW.GetUser(ctx, realmName, userID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
-		err = authorizationMW.UpdateUser(ctx, realmName, userID, user)
+		err = authorizationMW.UpdateUser(ctx, realmName, userID, user, "")
 		assert.Equal(t, security.ForbiddenError{}, err)
 
 		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
@@ -103,7 +129,7 @@ func TestDeny(t *testing.T) {
 		assert.Equal(t, security.ForbiddenError{}, err)
 
 		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
-		_, err = authorizationMW.CreateUser(ctx, realmName, user)
+		_, err = authorizationMW.CreateUser(ctx, realmName, user, false)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
 		_, err = authorizationMW.GetUserAccountStatus(ctx, realmName, userID)
@@ -160,7 +186,46 @@ func TestDeny(t *testing.T) {
 		_, err = authorizationMW.GetRealmCustomConfiguration(ctx, realmName)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
-		err = authorizationMW.UpdateRealmCustomConfiguration(ctx, realmName, customConfig)
+		err = authorizationMW.UpdateRealmCustomConfiguration(ctx, realmName, customConfig, "comment")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.PatchRealmCustomConfiguration(ctx, realmName, []byte(`{}`), "comment")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetRealmCustomConfigurationHistory(ctx, realmName)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetRealmCustomConfigurationDiff(ctx, realmName, 1, 2)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.RollbackRealmCustomConfiguration(ctx, realmName, 1)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		var lock = api.BulkActionLock
+		var userIDs = []string{userID}
+		results, err := authorizationMW.BulkUserAction(ctx, realmName, api.BulkUserActionRepresentation{Action: &lock, UserIDs: &userIDs})
+		assert.Nil(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, api.BulkStatusFailure, *results[0].Status)
+		assert.Equal(t, security.ForbiddenError{}.Error(), *results[0].Error)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		_, err = authorizationMW.BulkUserAction(ctx, realmName, api.BulkUserActionRepresentation{Action: &lock, GroupIDs: &groupIDs})
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetUserHistory(ctx, realmName, userID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetUserSessions(ctx, realmName, userID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.LogoutUser(ctx, realmName, userID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.RevokeSession(ctx, realmName, userID, sessionID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.ResetCredentialsForUser(ctx, realmName, userID, "otp")
 		assert.Equal(t, security.ForbiddenError{}, err)
 	}
 }
@@ -180,6 +245,7 @@ func TestAllowed(t *testing.T) {
 	var clientID = "789-789-741"
 	var roleID = "456-852-785"
 	var credentialID = "7845-785-1545"
+	var sessionID = "7845-785-1546"
 	var userUsername = "toto"
 
 	var roleName = "role"
@@ -206,6 +272,10 @@ func TestAllowed(t *testing.T) {
 
 	var roles = []api.RoleRepresentation{role}
 
+	var client = api.ClientRepresentation{
+		ClientID: &clientID,
+	}
+
 	var password = api.PasswordRepresentation{
 		Value: &pass,
 	}
@@ -222,8 +292,15 @@ func TestAllowed(t *testing.T) {
 				"toe": {
 					"GetRealms": {"*": {}},
 					"GetRealm": {"*": {"*": {} }},
+					"GetRealmSettings": {"*": {"*": {} }},
+					"UpdateRealmSettings": {"*": {"*": {} }},
 					"GetClient": {"*": {"*": {} }},
 					"GetClients": {"*": {"*": {} }},
+					"CreateClient": {"*": {"*": {} }},
+					"UpdateClient": {"*": {"*": {} }},
+					"EnableClient": {"*": {"*": {} }},
+					"DisableClient": {"*": {"*": {} }},
+					"RegenerateClientSecret": {"*": {"*": {} }},
 					"DeleteUser": {"*": {"*": {} }},
 					"GetUser": {"*": {"*": {} }},
 					"UpdateUser": {"*": {"*": {} }},
@@ -247,7 +324,16 @@ func TestAllowed(t *testing.T) {
 					"GetClientRoles": {"*": {"*": {} }},
 					"CreateClientRole": {"*": {"*": {} }},
 					"GetRealmCustomConfiguration": {"*": {"*": {} }},
-					"UpdateRealmCustomConfiguration": {"*": {"*": {} }}
+					"UpdateRealmCustomConfiguration": {"*": {"*": {} }},
+					"PatchRealmCustomConfiguration": {"*": {"*": {} }},
+					"GetRealmCustomConfigurationHistory": {"*": {"*": {} }},
+					"GetRealmCustomConfigurationDiff": {"*": {"*": {} }},
+					"RollbackRealmCustomConfiguration": {"*": {"*": {} }},
+					"GetUserHistory": {"*": {"*": {} }},
+					"GetUserSessions": {"*": {"*": {} }},
+					"LogoutUser": {"*": {"*": {} }},
+					"RevokeSession": {"*": {"*": {} }},
+					"ResetCredentialsForUser": {"*": {"*": {} }}
 				}
 			}
 		}`)
@@ -267,6 +353,14 @@ func TestAllowed(t *testing.T) {
 		_, err = authorizationMW.GetRealm(ctx, realmName)
 		assert.Nil(t, err)
 
+		mockManagementComponent.EXPECT().GetRealmSettings(ctx, realmName).Return(api.RealmSettingsRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetRealmSettings(ctx, realmName)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().UpdateRealmSettings(ctx, realmName, api.RealmSettingsRepresentation{}).Return(nil).Times(1)
+		err = authorizationMW.UpdateRealmSettings(ctx, realmName, api.RealmSettingsRepresentation{})
+		assert.Nil(t, err)
+
 		mockManagementComponent.EXPECT().GetClient(ctx, realmName, clientID).Return(api.ClientRepresentation{}, nil).Times(1)
 		_, err = authorizationMW.GetClient(ctx, realmName, clientID)
 		assert.Nil(t, err)
@@ -275,6 +369,26 @@ func TestAllowed(t *testing.T) {
 		_, err = authorizationMW.GetClients(ctx, realmName)
 		assert.Nil(t, err)
 
+		mockManagementComponent.EXPECT().CreateClient(ctx, realmName, client).Return("", nil).Times(1)
+		_, err = authorizationMW.CreateClient(ctx, realmName, client)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().UpdateClient(ctx, realmName, clientID, client).Return(nil).Times(1)
+		err = authorizationMW.UpdateClient(ctx, realmName, clientID, client)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().EnableClient(ctx, realmName, clientID).Return(nil).Times(1)
+		err = authorizationMW.EnableClient(ctx, realmName, clientID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().DisableClient(ctx, realmName, clientID).Return(nil).Times(1)
+		err = authorizationMW.DisableClient(ctx, realmName, clientID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().RegenerateClientSecret(ctx, realmName, clientID).Return(api.ClientSecretRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.RegenerateClientSecret(ctx, realmName, clientID)
+		assert.Nil(t, err)
+
 		mockManagementComponent.EXPECT().DeleteUser(ctx, realmName, userID).Return(nil).Times(1)
 		err = authorizationMW.DeleteUser(ctx, realmName, userID)
 		assert.Nil(t, err)
@@ -283,8 +397,8 @@ func TestAllowed(t *testing.T) {
 		_, err = authorizationMW.GetUser(ctx, realmName, userID)
 		assert.Nil(t, err)
 
-		mockManagementComponent.EXPECT().UpdateUser(ctx, realmName, userID, user).Return(nil).Times(1)
-		err = authorizationMW.UpdateUser(ctx, realmName, userID, user)
+		mockManagementComponent.EXPECT().UpdateUser(ctx, realmName, userID, user, "").Return(nil).Times(1)
+		err = authorizationMW.UpdateUser(ctx, realmName, userID, user, "")
 		assert.Nil(t, err)
 
 		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
@@ -293,11 +407,11 @@ func TestAllowed(t *testing.T) {
 		assert.Nil(t, err)
 
 		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
-		mockManagementComponent.EXPECT().CreateUser(ctx, realmName, user).Return("", nil).Times(1)
-		_, err = authorizationMW.CreateUser(ctx, realmName, user)
+		mockManagementComponent.EXPECT().CreateUser(ctx, realmName, user, false).Return("", nil).Times(1)
+		_, err = authorizationMW.CreateUser(ctx, realmName, user, false)
 		assert.Nil(t, err)
 
-		mockManagementComponent.EXPECT().GetUserAccountStatus(ctx, realmName, userID).Return(map[string]bool{"enabled": true}, nil).Times(1)
+		mockManagementComponent.EXPECT().GetUserAccountStatus(ctx, realmName, userID).Return(api.UserStatusRepresentation{}, nil).Times(1)
 		_, err = authorizationMW.GetUserAccountStatus(ctx, realmName, userID)
 		assert.Nil(t, err)
 
@@ -369,8 +483,125 @@ func TestAllowed(t *testing.T) {
 		_, err = authorizationMW.GetRealmCustomConfiguration(ctx, realmName)
 		assert.Nil(t, err)
 
-		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, customConfig).Return(nil).Times(1)
-		err = authorizationMW.UpdateRealmCustomConfiguration(ctx, realmName, customConfig)
+		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, customConfig, "comment").Return(nil).Times(1)
+		err = authorizationMW.UpdateRealmCustomConfiguration(ctx, realmName, customConfig, "comment")
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().PatchRealmCustomConfiguration(ctx, realmName, []byte(`{}`), "comment").Return(nil).Times(1)
+		err = authorizationMW.PatchRealmCustomConfiguration(ctx, realmName, []byte(`{}`), "comment")
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetRealmCustomConfigurationHistory(ctx, realmName).Return([]api.RealmCustomConfigurationVersion{}, nil).Times(1)
+		_, err = authorizationMW.GetRealmCustomConfigurationHistory(ctx, realmName)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetRealmCustomConfigurationDiff(ctx, realmName, 1, 2).Return([]api.FieldChangeRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetRealmCustomConfigurationDiff(ctx, realmName, 1, 2)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().RollbackRealmCustomConfiguration(ctx, realmName, 1).Return(nil).Times(1)
+		err = authorizationMW.RollbackRealmCustomConfiguration(ctx, realmName, 1)
+		assert.Nil(t, err)
+
+		var lock = api.BulkActionLock
+		var userIDs = []string{userID}
+		var bulk = api.BulkUserActionRepresentation{Action: &lock, UserIDs: &userIDs}
+		mockManagementComponent.EXPECT().BulkUserAction(ctx, realmName, bulk).Return([]api.BulkUserActionResultRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.BulkUserAction(ctx, realmName, bulk)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetUserHistory(ctx, realmName, userID).Return([]api.UserChangeRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetUserHistory(ctx, realmName, userID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetUserSessions(ctx, realmName, userID).Return([]api.UserSessionRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetUserSessions(ctx, realmName, userID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().LogoutUser(ctx, realmName, userID).Return(nil).Times(1)
+		err = authorizationMW.LogoutUser(ctx, realmName, userID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().RevokeSession(ctx, realmName, userID, sessionID).Return(nil).Times(1)
+		err = authorizationMW.RevokeSession(ctx, realmName, userID, sessionID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().ResetCredentialsForUser(ctx, realmName, userID, "otp").Return(nil).Times(1)
+		err = authorizationMW.ResetCredentialsForUser(ctx, realmName, userID, "otp")
+		assert.Nil(t, err)
+	}
+}
+
+func TestBulkUserActionAuthorization(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockLogger = log.NewNopLogger()
+	var mockKeycloakClient = mock.NewKcClientAuth(mockCtrl)
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var groups = []string{"toe"}
+
+	var allowedUserID = "123-456-789"
+	var deniedUserID = "987-654-321"
+	var groupID = "123-789-454"
+	var groupIDs = []string{groupID}
+	var groupName = "titi"
+	var search = "toto"
+
+	mockKeycloakClient.EXPECT().GetGroupNamesOfUser(accessToken, realmName, allowedUserID).Return([]string{groupName}, nil).AnyTimes()
+	mockKeycloakClient.EXPECT().GetGroupNamesOfUser(accessToken, realmName, deniedUserID).Return([]string{"other"}, nil).AnyTimes()
+	mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), realmName, groupID).Return(groupName, nil).AnyTimes()
+
+	var authorizations, err = security.NewAuthorizationManager(mockKeycloakClient, log.NewNopLogger(), `{"master":
+		{
+			"toe": {
+				"GetUsers": {"*": {"titi": {} }},
+				"UpdateUser": {"*": {"titi": {} }}
+			}
+		}
+	}`)
+	assert.Nil(t, err)
+
+	var authorizationMW = MakeAuthorizationManagementComponentMW(mockLogger, authorizations)(mockManagementComponent)
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, groups)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, "master")
+
+	var lock = api.BulkActionLock
+	var success = api.BulkStatusSuccess
+
+	// Users selected with a filter: only the allowed ones are given to the next component
+	{
+		var bulk = api.BulkUserActionRepresentation{Action: &lock, GroupIDs: &groupIDs, Search: &search}
+		var usersPage = api.UsersPageRepresentation{Users: []api.UserRepresentation{{ID: &allowedUserID}, {ID: &deniedUserID}}}
+		var allowedUserIDs = []string{allowedUserID}
+		var allowedBulk = api.BulkUserActionRepresentation{Action: &lock, UserIDs: &allowedUserIDs}
+		var allowedResults = []api.BulkUserActionResultRepresentation{{UserID: &allowedUserID, Status: &success}}
+
+		mockManagementComponent.EXPECT().GetUsers(ctx, realmName, groupIDs, "search", search, "first", "0", "max", "500").Return(usersPage, nil).Times(1)
+		mockManagementComponent.EXPECT().BulkUserAction(ctx, realmName, allowedBulk).Return(allowedResults, nil).Times(1)
+
+		results, err := authorizationMW.BulkUserAction(ctx, realmName, bulk)
+		assert.Nil(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, deniedUserID, *results[0].UserID)
+		assert.Equal(t, api.BulkStatusFailure, *results[0].Status)
+		assert.Equal(t, allowedResults[0], results[1])
+	}
+
+	// Action not allowed for any user
+	{
+		var deleteAction = api.BulkActionDelete
+		var userIDs = []string{allowedUserID, deniedUserID}
+
+		results, err := authorizationMW.BulkUserAction(ctx, realmName, api.BulkUserActionRepresentation{Action: &deleteAction, UserIDs: &userIDs})
 		assert.Nil(t, err)
+		assert.Len(t, results, 2)
+		for _, result := range results {
+			assert.Equal(t, api.BulkStatusFailure, *result.Status)
+		}
 	}
 }
@@ -1,14 +1,18 @@
 package management
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	cs "github.com/cloudtrust/common-service"
 	"github.com/cloudtrust/common-service/database"
 	errorhandler "github.com/cloudtrust/common-service/errors"
+	events_api "github.com/cloudtrust/keycloak-bridge/api/events"
 	api "github.com/cloudtrust/keycloak-bridge/api/management"
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
 	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
@@ -17,15 +21,20 @@ import (
 )
 
 const (
-	initPasswordAction = "sms-password-set"
+	initPasswordAction    = "sms-password-set"
+	defaultClientProtocol = "openid-connect"
 )
 
 // KeycloakClient are methods from keycloak-client used by this component
 type KeycloakClient interface {
 	GetRealms(accessToken string) ([]kc.RealmRepresentation, error)
 	GetRealm(accessToken string, realmName string) (kc.RealmRepresentation, error)
+	UpdateRealm(accessToken string, realmName string, realm kc.RealmRepresentation) error
 	GetClient(accessToken string, realmName, idClient string) (kc.ClientRepresentation, error)
 	GetClients(accessToken string, realmName string, paramKV ...string) ([]kc.ClientRepresentation, error)
+	CreateClient(accessToken string, realmName string, client kc.ClientRepresentation) (string, error)
+	UpdateClient(accessToken string, realmName, idClient string, client kc.ClientRepresentation) error
+	RegenerateSecret(accessToken string, realmName, idClient string) (kc.CredentialRepresentation, error)
 	DeleteUser(accessToken string, realmName, userID string) error
 	GetUser(accessToken string, realmName, userID string) (kc.UserRepresentation, error)
 	GetGroupsOfUser(accessToken string, realmName, userID string) ([]kc.GroupRepresentation, error)
@@ -49,26 +58,45 @@ type KeycloakClient interface {
 	GetCredentials(accessToken string, realmName string, userID string) ([]kc.CredentialRepresentation, error)
 	UpdateLabelCredential(accessToken string, realmName string, userID string, credentialID string, label string) error
 	DeleteCredential(accessToken string, realmName string, userID string, credentialID string) error
+	GetSessionsForUser(accessToken string, realmName, userID string) ([]kc.UserSessionRepresentation, error)
+	LogoutUser(accessToken string, realmName, userID string) error
+	DeleteSession(accessToken string, realmName, sessionID string) error
+	GetAttackDetectionStatus(accessToken string, realmName, userID string) (map[string]interface{}, error)
 }
 
 // ConfigurationDBModule is the interface of the configuration module.
 type ConfigurationDBModule interface {
-	StoreOrUpdate(context.Context, string, dto.RealmConfiguration) error
+	StoreOrUpdate(context.Context, string, dto.RealmConfiguration, string, string) error
 	GetConfiguration(context.Context, string) (dto.RealmConfiguration, error)
+	GetConfigurationHistory(context.Context, string) ([]dto.RealmConfigurationVersion, error)
+	GetConfigurationVersion(context.Context, string, int) (dto.RealmConfigurationVersion, error)
+}
+
+// AuditEventsReaderModule is the interface of the module reading the audit events.
+type AuditEventsReaderModule interface {
+	GetEvents(context.Context, map[string]string) ([]events_api.AuditRepresentation, error)
+	GetLastUserConnection(context.Context, string, string) (int64, error)
 }
 
 // Component is the management component interface.
 type Component interface {
 	GetRealms(ctx context.Context) ([]api.RealmRepresentation, error)
 	GetRealm(ctx context.Context, realmName string) (api.RealmRepresentation, error)
+	GetRealmSettings(ctx context.Context, realmName string) (api.RealmSettingsRepresentation, error)
+	UpdateRealmSettings(ctx context.Context, realmName string, settings api.RealmSettingsRepresentation) error
 	GetClient(ctx context.Context, realmName, idClient string) (api.ClientRepresentation, error)
 	GetClients(ctx context.Context, realmName string) ([]api.ClientRepresentation, error)
+	CreateClient(ctx context.Context, realmName string, client api.ClientRepresentation) (string, error)
+	UpdateClient(ctx context.Context, realmName, idClient string, client api.ClientRepresentation) error
+	EnableClient(ctx context.Context, realmName, idClient string) error
+	DisableClient(ctx context.Context, realmName, idClient string) error
+	RegenerateClientSecret(ctx context.Context, realmName, idClient string) (api.ClientSecretRepresentation, error)
 	DeleteUser(ctx context.Context, realmName, userID string) error
 	GetUser(ctx context.Context, realmName, userID string) (api.UserRepresentation, error)
-	UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation) error
+	UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation, ifMatch string) error
 	GetUsers(ctx context.Context, realmName string, groupIDs []string, paramKV ...string) (api.UsersPageRepresentation, error)
-	CreateUser(ctx context.Context, realmName string, user api.UserRepresentation) (string, error)
-	GetUserAccountStatus(ctx context.Context, realmName, userID string) (map[string]bool, error)
+	CreateUser(ctx context.Context, realmName string, user api.UserRepresentation, force bool) (string, error)
+	GetUserAccountStatus(ctx context.Context, realmName, userID string) (api.UserStatusRepresentation, error)
 	GetRolesOfUser(ctx context.Context, realmName, userID string) ([]api.RoleRepresentation, error)
 	GetGroupsOfUser(ctx context.Context, realmName, userID string) ([]api.GroupRepresentation, error)
 	GetClientRolesForUser(ctx context.Context, realmName, userID, clientID string) ([]api.RoleRepresentation, error)
@@ -80,30 +108,50 @@ type Component interface {
 	SendReminderEmail(ctx context.Context, realmName string, userID string, paramKV ...string) error
 	GetCredentialsForUser(ctx context.Context, realmName string, userID string) ([]api.CredentialRepresentation, error)
 	DeleteCredentialsForUser(ctx context.Context, realmName string, userID string, credentialID string) error
+	ResetCredentialsForUser(ctx context.Context, realmName string, userID string, credentialCategory string) error
 	GetRoles(ctx context.Context, realmName string) ([]api.RoleRepresentation, error)
 	GetRole(ctx context.Context, realmName string, roleID string) (api.RoleRepresentation, error)
 	GetGroups(ctx context.Context, realmName string) ([]api.GroupRepresentation, error)
 	GetClientRoles(ctx context.Context, realmName, idClient string) ([]api.RoleRepresentation, error)
 	CreateClientRole(ctx context.Context, realmName, clientID string, role api.RoleRepresentation) (string, error)
 	GetRealmCustomConfiguration(ctx context.Context, realmName string) (api.RealmCustomConfiguration, error)
-	UpdateRealmCustomConfiguration(ctx context.Context, realmID string, customConfig api.RealmCustomConfiguration) error
+	UpdateRealmCustomConfiguration(ctx context.Context, realmID string, customConfig api.RealmCustomConfiguration, comment string) error
+	PatchRealmCustomConfiguration(ctx context.Context, realmName string, patch []byte, comment string) error
+	GetRealmCustomConfigurationHistory(ctx context.Context, realmName string) ([]api.RealmCustomConfigurationVersion, error)
+	GetRealmCustomConfigurationDiff(ctx context.Context, realmName string, fromVersion, toVersion int) ([]api.FieldChangeRepresentation, error)
+	RollbackRealmCustomConfiguration(ctx context.Context, realmName string, version int) error
+	BulkUserAction(ctx context.Context, realmName string, bulk api.BulkUserActionRepresentation, paramKV ...string) ([]api.BulkUserActionResultRepresentation, error)
+	GetUserHistory(ctx context.Context, realmName, userID string, paramKV ...string) ([]api.UserChangeRepresentation, error)
+	GetUserSessions(ctx context.Context, realmName, userID string) ([]api.UserSessionRepresentation, error)
+	LogoutUser(ctx context.Context, realmName, userID string) error
+	RevokeSession(ctx context.Context, realmName, userID, sessionID string) error
 }
 
 // Component is the management component.
 type component struct {
-	keycloakClient KeycloakClient
-	eventDBModule  database.EventsDBModule
-	configDBModule ConfigurationDBModule
-	logger         internal.Logger
+	keycloakClient          KeycloakClient
+	eventDBModule           database.EventsDBModule
+	auditEventsReader       AuditEventsReaderModule
+	configDBModule          ConfigurationDBModule
+	breachedPasswordChecker internal.BreachedPasswordChecker
+	historyMaskedFields     []string
+	clientURLSchemes        []string
+	logger                  internal.Logger
 }
 
 // NewComponent returns the management component.
-func NewComponent(keycloakClient KeycloakClient, eventDBModule database.EventsDBModule, configDBModule ConfigurationDBModule, logger internal.Logger) Component {
+// The values of the historyMaskedFields are not stored in the history of the users, only the fact they changed is kept.
+// Besides http(s), the base URL and the redirect URIs of the clients can only use the clientURLSchemes (custom schemes of native applications).
+func NewComponent(keycloakClient KeycloakClient, eventDBModule database.EventsDBModule, auditEventsReader AuditEventsReaderModule, configDBModule ConfigurationDBModule, breachedPasswordChecker internal.BreachedPasswordChecker, historyMaskedFields []string, clientURLSchemes []string, logger internal.Logger) Component {
 	return &component{
-		keycloakClient: keycloakClient,
-		eventDBModule:  eventDBModule,
-		configDBModule: configDBModule,
-		logger:         logger,
+		keycloakClient:          keycloakClient,
+		eventDBModule:           eventDBModule,
+		auditEventsReader:       auditEventsReader,
+		configDBModule:          configDBModule,
+		breachedPasswordChecker: breachedPasswordChecker,
+		historyMaskedFields:     historyMaskedFields,
+		clientURLSchemes:        clientURLSchemes,
+		logger:                  logger,
 	}
 }
 
@@ -156,10 +204,48 @@ func (c *component) GetRealm(ctx context.Context, realm string) (api.RealmRepres
 	return realmRep, nil
 }
 
+// GetRealmSettings gets the subset of the realm settings which can be managed through the bridge
+func (c *component) GetRealmSettings(ctx context.Context, realmName string) (api.RealmSettingsRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	realmKc, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return api.RealmSettingsRepresentation{}, err
+	}
+
+	return api.ConvertToAPIRealmSettings(realmKc), nil
+}
+
+// UpdateRealmSettings updates the realm settings. The settings which are not provided are left unchanged.
+func (c *component) UpdateRealmSettings(ctx context.Context, realmName string, settings api.RealmSettingsRepresentation) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	realmKc, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	err = c.keycloakClient.UpdateRealm(accessToken, realmName, api.ConvertToKCRealmSettings(settings, realmKc.SmtpServer))
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	//store the API call into the DB
+	settingsJSON, _ := json.Marshal(settings)
+	var values = []string{database.CtEventRealmName, realmName, database.CtEventAdditionalInfo, string(settingsJSON)}
+	if err = c.reportEvent(ctx, "REALM_SETTINGS_UPDATE", values...); err != nil {
+		internal.LogUnstoredEvent(c.logger, err, "REALM_SETTINGS_UPDATE", values...)
+	}
+
+	return nil
+}
+
 func (c *component) GetClient(ctx context.Context, realmName, idClient string) (api.ClientRepresentation, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
-	var clientRep api.ClientRepresentation
 	clientKc, err := c.keycloakClient.GetClient(accessToken, realmName, idClient)
 
 	if err != nil {
@@ -167,14 +253,7 @@ func (c *component) GetClient(ctx context.Context, realmName, idClient string) (
 		return api.ClientRepresentation{}, err
 	}
 
-	clientRep.ID = clientKc.Id
-	clientRep.Name = clientKc.Name
-	clientRep.BaseURL = clientKc.BaseUrl
-	clientRep.ClientID = clientKc.ClientId
-	clientRep.Protocol = clientKc.Protocol
-	clientRep.Enabled = clientKc.Enabled
-
-	return clientRep, nil
+	return api.ConvertToAPIClient(clientKc), nil
 }
 
 func (c *component) GetClients(ctx context.Context, realmName string) ([]api.ClientRepresentation, error) {
@@ -189,25 +268,207 @@ func (c *component) GetClients(ctx context.Context, realmName string) ([]api.Cli
 
 	var clientsRep = []api.ClientRepresentation{}
 	for _, clientKc := range clientsKc {
-		var clientRep api.ClientRepresentation
-		clientRep.ID = clientKc.Id
-		clientRep.Name = clientKc.Name
-		clientRep.BaseURL = clientKc.BaseUrl
-		clientRep.ClientID = clientKc.ClientId
-		clientRep.Protocol = clientKc.Protocol
-		clientRep.Enabled = clientKc.Enabled
-		clientsRep = append(clientsRep, clientRep)
+		clientsRep = append(clientsRep, api.ConvertToAPIClient(clientKc))
 	}
 
 	return clientsRep, nil
 }
 
-func (c *component) CreateUser(ctx context.Context, realmName string, user api.UserRepresentation) (string, error) {
+// CreateClient creates a client in the realm. OpenID Connect is used if no protocol is provided.
+func (c *component) CreateClient(ctx context.Context, realmName string, client api.ClientRepresentation) (string, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	if err := c.checkClientURLSchemes(client); err != nil {
+		return "", err
+	}
+
+	var clientKc = api.ConvertToKCClient(client)
+	if clientKc.Protocol == nil {
+		var protocol = defaultClientProtocol
+		clientKc.Protocol = &protocol
+	}
+
+	locationURL, err := c.keycloakClient.CreateClient(accessToken, realmName, clientKc)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return "", err
+	}
+
+	c.reportClientEvent(ctx, "API_CLIENT_CREATION", realmName, clientKc.ClientId)
+
+	return locationURL, nil
+}
+
+// UpdateClient updates the client. The fields which are not provided are left unchanged.
+func (c *component) UpdateClient(ctx context.Context, realmName, idClient string, client api.ClientRepresentation) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	if err := c.checkClientURLSchemes(client); err != nil {
+		return err
+	}
+
+	oldClientKc, err := c.keycloakClient.GetClient(accessToken, realmName, idClient)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	var clientKc = api.ConvertToKCClient(client)
+	clientKc.Id = &idClient
+	if clientKc.ClientId == nil {
+		clientKc.ClientId = oldClientKc.ClientId
+	}
+
+	err = c.keycloakClient.UpdateClient(accessToken, realmName, idClient, clientKc)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	c.reportClientEvent(ctx, "API_CLIENT_UPDATE", realmName, clientKc.ClientId)
+
+	return nil
+}
+
+// checkClientURLSchemes rejects the client URLs which use a custom scheme which is not allowed
+func (c *component) checkClientURLSchemes(client api.ClientRepresentation) error {
+	if client.BaseURL != nil && !c.isAllowedClientURL(*client.BaseURL) {
+		return errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.BaseURL)
+	}
+	if client.RedirectURIs != nil {
+		for _, redirectURI := range *client.RedirectURIs {
+			if !c.isAllowedClientURL(redirectURI) {
+				return errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.RedirectURIs)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *component) isAllowedClientURL(clientURL string) bool {
+	var scheme = api.ClientURLScheme(clientURL)
+	if scheme == "" {
+		return true
+	}
+	for _, allowed := range c.clientURLSchemes {
+		if strings.ToLower(allowed) == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableClient enables the client
+func (c *component) EnableClient(ctx context.Context, realmName, idClient string) error {
+	return c.setClientEnabled(ctx, realmName, idClient, true, "CLIENT_ENABLED")
+}
+
+// DisableClient disables the client: users can't login through it anymore and its tokens are rejected
+func (c *component) DisableClient(ctx context.Context, realmName, idClient string) error {
+	return c.setClientEnabled(ctx, realmName, idClient, false, "CLIENT_DISABLED")
+}
+
+func (c *component) setClientEnabled(ctx context.Context, realmName, idClient string, enabled bool, eventName string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	clientKc, err := c.keycloakClient.GetClient(accessToken, realmName, idClient)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	err = c.keycloakClient.UpdateClient(accessToken, realmName, idClient, kc.ClientRepresentation{
+		Id:       &idClient,
+		ClientId: clientKc.ClientId,
+		Enabled:  &enabled,
+	})
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	c.reportClientEvent(ctx, eventName, realmName, clientKc.ClientId)
+
+	return nil
+}
+
+// RegenerateClientSecret generates a new secret for a confidential client. The previous secret can't be used anymore.
+func (c *component) RegenerateClientSecret(ctx context.Context, realmName, idClient string) (api.ClientSecretRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	clientKc, err := c.keycloakClient.GetClient(accessToken, realmName, idClient)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return api.ClientSecretRepresentation{}, err
+	}
+
+	if clientKc.PublicClient != nil && *clientKc.PublicClient {
+		// public clients don't have any secret
+		return api.ClientSecretRepresentation{}, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.AccessType)
+	}
+
+	secretKc, err := c.keycloakClient.RegenerateSecret(accessToken, realmName, idClient)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return api.ClientSecretRepresentation{}, err
+	}
+
+	c.reportClientEvent(ctx, "CLIENT_SECRET_REGENERATED", realmName, clientKc.ClientId)
+
+	return api.ClientSecretRepresentation{Value: secretKc.Value}, nil
+}
+
+// reportClientEvent stores an event concerning a client in the DB
+func (c *component) reportClientEvent(ctx context.Context, eventName, realmName string, clientID *string) {
+	var values = []string{database.CtEventRealmName, realmName}
+	if clientID != nil {
+		values = append(values, database.CtEventClientID, *clientID)
+	}
+
+	if err := c.reportEvent(ctx, eventName, values...); err != nil {
+		internal.LogUnstoredEvent(c.logger, err, eventName, values...)
+	}
+}
+
+// CreateUser creates a user. When the detection of duplicates is enabled in the configuration of the realm, the creation is
+// refused if existing users look like the new one, unless it is forced.
+func (c *component) CreateUser(ctx context.Context, realmName string, user api.UserRepresentation, force bool) (string, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var ctxRealm = ctx.Value(cs.CtContextRealm).(string)
 
 	var userRep kc.UserRepresentation
 
+	config, err := c.getRealmConfiguration(ctx, accessToken, realmName)
+	if err != nil {
+		return "", err
+	}
+	if err = user.ValidateAttributes(internal.UserAttributeSchema(config), true); err != nil {
+		c.logger.Warn("err", err.Error())
+		return "", errorhandler.CreateBadRequestError(err.Error())
+	}
+
+	// phone numbers are stored in the E.164 format
+	if user.PhoneNumber != nil {
+		phoneNumber, err := internal.NormalizePhoneNumber(*user.PhoneNumber, internal.PhoneNumberCountries(config))
+		if err != nil {
+			c.logger.Warn("err", err.Error())
+			return "", errorhandler.CreateBadRequestError(err.Error())
+		}
+		user.PhoneNumber = &phoneNumber
+	}
+
+	var candidateIDs []string
+	if config.DuplicateCheck != nil && len(*config.DuplicateCheck) > 0 {
+		candidateIDs, err = c.findDuplicateCandidates(accessToken, ctxRealm, realmName, user, *config.DuplicateCheck, internal.PhoneNumberCountries(config))
+		if err != nil {
+			return "", err
+		}
+		if len(candidateIDs) > 0 && !force {
+			c.logger.Info("msg", "user creation refused: duplicate candidates found", "realm", realmName, "candidates", strings.Join(candidateIDs, ","))
+			return "", DuplicateUserError{CandidateIDs: candidateIDs}
+		}
+	}
+
 	userRep = api.ConvertToKCUser(user)
 
 	locationURL, err := c.keycloakClient.CreateUser(accessToken, ctxRealm, realmName, userRep)
@@ -239,6 +500,11 @@ func (c *component) CreateUser(ctx context.Context, realmName string, user api.U
 		}
 	}
 
+	if len(candidateIDs) > 0 {
+		// the creation has been forced despite the duplicate candidates
+		c.reportDuplicateCreationForced(ctx, realmName, userID, username, candidateIDs)
+	}
+
 	return locationURL, nil
 }
 
@@ -302,7 +568,9 @@ func (c *component) GetUser(ctx context.Context, realmName, userID string) (api.
 
 }
 
-func (c *component) UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation) error {
+// UpdateUser updates a user. When ifMatch is not empty, the update is rejected if the user has been modified since the
+// ETag given by GetUser was computed.
+func (c *component) UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation, ifMatch string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var userRep kc.UserRepresentation
 
@@ -313,23 +581,57 @@ func (c *component) UpdateUser(ctx context.Context, realmName, userID string, us
 		return err
 	}
 
+	// the user must not have been modified by someone else since it was read
+	if ifMatch != "" && !api.MatchesETag(ifMatch, api.ComputeETag(api.ConvertToAPIUser(oldUserKc))) {
+		return errorhandler.Error{
+			Status:  412,
+			Message: internal.MsgErrPreconditionFailed + "." + internal.IfMatch,
+		}
+	}
+
+	// custom attributes are checked against the attribute schema of the realm
+	if user.Attributes != nil || user.DeletedAttributes != nil {
+		schema, err := c.getUserAttributeSchema(ctx, accessToken, realmName)
+		if err != nil {
+			return err
+		}
+		// attributes sent back unchanged are not checked again
+		var changed = api.UserRepresentation{
+			Attributes:        internal.ChangedAttributes(user.Attributes, oldUserKc.Attributes),
+			DeletedAttributes: user.DeletedAttributes,
+		}
+		if err = changed.ValidateAttributes(schema, false); err != nil {
+			c.logger.Warn("err", err.Error())
+			return errorhandler.CreateBadRequestError(err.Error())
+		}
+	}
+
 	// when the email changes, set the EmailVerified to false
 	if user.Email != nil && oldUserKc.Email != nil && *oldUserKc.Email != *user.Email {
 		var verified = false
 		user.EmailVerified = &verified
 	}
 
-	// when the phone number changes, set the PhoneNumberVerified to false
+	// when the phone number changes, set the PhoneNumberVerified to false. Phone numbers are stored in the E.164 format and
+	// an equivalent number does not reset the verification.
 	if user.PhoneNumber != nil {
-		if oldUserKc.Attributes != nil {
-			var m = *oldUserKc.Attributes
-			if _, ok := m["phoneNumber"]; !ok || m["phoneNumber"][0] != *user.PhoneNumber {
+		var oldPhoneNumber, hasPhoneNumber = internal.PhoneNumberAttribute(oldUserKc.Attributes)
+		if !hasPhoneNumber || oldPhoneNumber != *user.PhoneNumber {
+			config, err := c.getRealmConfiguration(ctx, accessToken, realmName)
+			if err != nil {
+				return err
+			}
+			var countries = internal.PhoneNumberCountries(config)
+			phoneNumber, err := internal.NormalizePhoneNumber(*user.PhoneNumber, countries)
+			if err != nil {
+				c.logger.Warn("err", err.Error())
+				return errorhandler.CreateBadRequestError(err.Error())
+			}
+			user.PhoneNumber = &phoneNumber
+			if !hasPhoneNumber || !internal.SamePhoneNumber(oldPhoneNumber, phoneNumber, countries) {
 				var verified = false
 				user.PhoneNumberVerified = &verified
 			}
-		} else { // the user has no attributes until now, i.e. he has not set yet his phone number
-			var verified = false
-			user.PhoneNumberVerified = &verified
 		}
 	}
 
@@ -350,8 +652,16 @@ func (c *component) UpdateUser(ctx context.Context, realmName, userID string, us
 			mergedAttributes[key] = attribute
 		}
 	}
+	// Remove the attributes explicitly deleted
+	if user.DeletedAttributes != nil {
+		for _, key := range *user.DeletedAttributes {
+			delete(mergedAttributes, key)
+		}
+	}
 	userRep.Attributes = &mergedAttributes
 
+	var changes = diffUsers(oldUserKc, userRep, c.historyMaskedFields)
+
 	err = c.keycloakClient.UpdateUser(accessToken, realmName, userID, userRep)
 
 	if err != nil {
@@ -359,6 +669,20 @@ func (c *component) UpdateUser(ctx context.Context, realmName, userID string, us
 		return err
 	}
 
+	//store the field-level changes into the DB to build the history of the user
+	if len(changes) > 0 {
+		var username = ""
+		if oldUserKc.Username != nil {
+			username = *oldUserKc.Username
+		}
+		additionalInfo, _ := json.Marshal(userChanges{Changes: changes})
+
+		var values = []string{database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username, database.CtEventAdditionalInfo, string(additionalInfo)}
+		if err = c.reportEvent(ctx, userUpdatedEvent, values...); err != nil {
+			internal.LogUnstoredEvent(c.logger, err, userUpdatedEvent, values...)
+		}
+	}
+
 	//store the API call into the DB in case where user.Enable is present
 	if user.Enabled != nil {
 		var username = ""
@@ -396,42 +720,191 @@ func (c *component) GetUsers(ctx context.Context, realmName string, groupIDs []s
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var ctxRealm = ctx.Value(cs.CtContextRealm).(string)
 
+	var search, kcParams = newUserSearch(paramKV)
 	for _, groupID := range groupIDs {
-		paramKV = append(paramKV, "groupId", groupID)
+		kcParams = append(kcParams, "groupId", groupID)
+	}
+
+	if search == nil {
+		usersKc, err := c.keycloakClient.GetUsers(accessToken, ctxRealm, realmName, kcParams...)
+
+		if err != nil {
+			c.logger.Warn("err", err.Error())
+			return api.UsersPageRepresentation{}, err
+		}
+
+		return api.ConvertToAPIUsersPage(usersKc), nil
+	}
+
+	if search.max > maxSearchPageSize {
+		return api.UsersPageRepresentation{}, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Max)
+	}
+	// the brute force detection state is requested user by user: the users must be selected by another criteria
+	if search.locked != nil && len(kcParams) == 0 && !search.narrowed() {
+		return api.UsersPageRepresentation{}, errorhandler.CreateBadRequestError(internal.MsgErrMissingParam + "." + internal.SearchCriteria)
 	}
 
-	usersKc, err := c.keycloakClient.GetUsers(accessToken, ctxRealm, realmName, paramKV...)
+	if search.phoneNumber != nil {
+		config, err := c.getRealmConfiguration(ctx, accessToken, realmName)
+		if err != nil {
+			return api.UsersPageRepresentation{}, err
+		}
+		search.countries = internal.PhoneNumberCountries(config)
+	}
 
+	// Keycloak can't filter on attributes nor sort the users: get all the users matching the other criteria and
+	// filter, sort and page them here. The scan is bounded so that a search can't load a whole realm.
+	var users = []api.UserRepresentation{}
+	var scanned = 0
+	err := c.forEachUser(accessToken, ctxRealm, realmName, kcParams, func(userKc kc.UserRepresentation) error {
+		if scanned++; scanned > maxSearchedUsers {
+			return errorhandler.CreateBadRequestError(internal.MsgErrTooManyResults)
+		}
+		if user := api.ConvertToAPIUser(userKc); search.matches(user) {
+			users = append(users, user)
+		}
+		return nil
+	})
 	if err != nil {
-		c.logger.Warn("err", err.Error())
 		return api.UsersPageRepresentation{}, err
 	}
 
-	return api.ConvertToAPIUsersPage(usersKc), nil
+	// the brute force detection state is requested user by user: it is only evaluated once the other criteria are applied
+	if search.locked != nil {
+		if len(users) > maxLockChecks {
+			return api.UsersPageRepresentation{}, errorhandler.CreateBadRequestError(internal.MsgErrTooManyResults)
+		}
+		var candidates = users
+		users = []api.UserRepresentation{}
+		for _, user := range candidates {
+			locked, err := c.isTemporarilyLocked(accessToken, realmName, *user.ID)
+			if err != nil {
+				return api.UsersPageRepresentation{}, err
+			}
+			if locked == *search.locked {
+				users = append(users, user)
+			}
+		}
+	}
+
+	search.sort(users)
+	return search.page(users), nil
 }
 
-// GetUserAccountStatus gets the user status : user should be enabled in Keycloak and have multifactor activated
-func (c *component) GetUserAccountStatus(ctx context.Context, realmName, userID string) (map[string]bool, error) {
-	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
-	var res map[string]bool
+// forEachUser calls the given function for each user matching the Keycloak search parameters. The users are requested
+// by batches and the iteration stops at the first error.
+func (c *component) forEachUser(accessToken, ctxRealm, realmName string, kcParams []string, f func(kc.UserRepresentation) error) error {
+	for first := 0; ; first += searchBatchSize {
+		var batchParams = append(kcParams, "first", strconv.Itoa(first), "max", strconv.Itoa(searchBatchSize))
+		usersKc, err := c.keycloakClient.GetUsers(accessToken, ctxRealm, realmName, batchParams...)
+		if err != nil {
+			c.logger.Warn("err", err.Error())
+			return err
+		}
+
+		for _, userKc := range usersKc.Users {
+			if err = f(userKc); err != nil {
+				return err
+			}
+		}
+
+		if len(usersKc.Users) < searchBatchSize || (usersKc.Count != nil && first+searchBatchSize >= *usersKc.Count) {
+			return nil
+		}
+	}
+}
+
+// isTemporarilyLocked checks whether the brute force detection locked the user
+func (c *component) isTemporarilyLocked(accessToken, realmName, userID string) (bool, error) {
+	attackDetection, err := c.keycloakClient.GetAttackDetectionStatus(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return false, err
+	}
+	var locked, _ = attackDetection["disabled"].(bool)
+	return locked, nil
+}
 
-	res = make(map[string]bool)
-	res["enabled"] = false
+// GetUserAccountStatus gets the status of the user account: whether the account can be used (user enabled with a password and
+// a second factor), Keycloak flags, configured credentials, pending required actions, brute force detection state, last login
+// and age of the password
+func (c *component) GetUserAccountStatus(ctx context.Context, realmName, userID string) (api.UserStatusRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	var res api.UserStatusRepresentation
 
 	userKc, err := c.keycloakClient.GetUser(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return api.UserStatusRepresentation{}, err
+	}
+
+	var user = api.ConvertToAPIUser(userKc)
+	var falseBool = false
+	res.UserEnabled = &falseBool
+	if userKc.Enabled != nil {
+		res.UserEnabled = userKc.Enabled
+	}
+	res.EmailVerified = &falseBool
+	if userKc.EmailVerified != nil {
+		res.EmailVerified = userKc.EmailVerified
+	}
+	res.PhoneNumberVerified = &falseBool
+	if user.PhoneNumberVerified != nil {
+		res.PhoneNumberVerified = user.PhoneNumberVerified
+	}
+	var requiredActions = []string{}
+	if userKc.RequiredActions != nil {
+		requiredActions = append(requiredActions, *userKc.RequiredActions...)
+	}
+	res.RequiredActions = &requiredActions
 
+	credsKc, err := c.keycloakClient.GetCredentials(accessToken, realmName, userID)
 	if err != nil {
 		c.logger.Warn("err", err.Error())
-		return res, err
+		return api.UserStatusRepresentation{}, err
 	}
 
-	if !*userKc.Enabled {
-		return res, nil
+	// report which categories of credentials are configured for the user
+	var credentials = make(map[string]bool)
+	for _, category := range append([]string{internal.CredentialCategoryPassword}, internal.SecondFactorCategories...) {
+		credentials[category] = false
+	}
+	var mfaConfigured = false
+	var passwordLastChange *int64
+	for _, credKc := range credsKc {
+		if credKc.Type == nil {
+			continue
+		}
+		var category = internal.GetCredentialCategory(*credKc.Type)
+		credentials[category] = true
+		mfaConfigured = mfaConfigured || internal.IsSecondFactor(*credKc.Type)
+		if *credKc.Type == internal.CredentialCategoryPassword && credKc.CreatedDate != nil {
+			passwordLastChange = credKc.CreatedDate
+		}
+	}
+	res.Credentials = &credentials
+	res.MFAConfigured = &mfaConfigured
+	// the account can be used once the user is enabled and has a password and a second factor
+	var accountEnabled = *res.UserEnabled && credentials[internal.CredentialCategoryPassword] && mfaConfigured
+	res.Enabled = &accountEnabled
+	if passwordLastChange != nil {
+		// Keycloak stores the creation date of the credentials in milliseconds
+		var passwordAge = int64(time.Since(time.Unix(0, *passwordLastChange*int64(time.Millisecond))) / (24 * time.Hour))
+		res.PasswordAge = &passwordAge
 	}
 
-	creds, err := c.GetCredentialsForUser(ctx, realmName, userID)
-	res["enabled"] = len(creds) > 1
-	return res, err
+	// the brute force detection state and the last login are informative: they are left out when they can't be obtained
+	if locked, err := c.isTemporarilyLocked(accessToken, realmName, userID); err == nil {
+		res.TemporarilyLocked = &locked
+	}
+
+	if lastLogin, err := c.auditEventsReader.GetLastUserConnection(ctx, realmName, userID); err != nil {
+		c.logger.Warn("err", err.Error())
+	} else if lastLogin > 0 {
+		res.LastLogin = &lastLogin
+	}
+
+	return res, nil
 }
 
 func (c *component) GetRolesOfUser(ctx context.Context, realmName, userID string) ([]api.RoleRepresentation, error) {
@@ -542,32 +1015,57 @@ func (c *component) ResetPassword(ctx context.Context, realmName string, userID
 	var passwordType = "password"
 	credKc.Type = &passwordType
 
+	policy, breachedPasswordCheck, err := c.getPasswordConfiguration(ctx, accessToken, realmName)
+	if err != nil {
+		return "", err
+	}
+
+	var username, email string
+	if policy != nil && (policy.NotUsername || policy.NotEmail) {
+		userKc, err := c.keycloakClient.GetUser(accessToken, realmName, userID)
+		if err != nil {
+			c.logger.Warn("err", err.Error())
+			return "", err
+		}
+		if userKc.Username != nil {
+			username = *userKc.Username
+		}
+		if userKc.Email != nil {
+			email = *userKc.Email
+		}
+	}
+
 	if password.Value == nil {
-		// the commented code respects the following scenario: if the realm has a pwd policy, the generated pwd respects this policy
-		/*
-			// no password value was provided; a new password, that respects the password policy of the realm, will be generated
-			var minLength int = 8
-
-			//obtain password policy
-			realmKc, err := c.keycloakClient.GetRealm(accessToken, realmName)
-			if err == nil {
-				// generate password according to the policy of the realm
-				pwd, err = internal.GeneratePassword(realmKc.PasswordPolicy, minLength, userID)
-				if err != nil {
-					return pwd, err
-				}
-				credKc.Value = &pwd
-			} else {
+		if policy != nil {
+			// no password value was provided; a new password, that respects the password policy of the realm, is generated
+			if pwd, err = internal.GeneratePasswordFromPolicy(*policy, username); err != nil {
+				c.logger.Warn("err", err.Error())
 				return "", err
 			}
-		*/
-		// generate a password of the format UpperCase + 6 digits + LowerCase
-		var nbUpperCase = 1
-		var nbDigits = 6
-		var nbLowerCase = 1
-		pwd = internal.GenerateInitialCode(nbUpperCase, nbDigits, nbLowerCase)
+		} else {
+			// generate a password of the format UpperCase + 6 digits + LowerCase
+			var nbUpperCase = 1
+			var nbDigits = 6
+			var nbLowerCase = 1
+			pwd = internal.GenerateInitialCode(nbUpperCase, nbDigits, nbLowerCase)
+		}
 		credKc.Value = &pwd
 	} else {
+		if policy != nil {
+			if err = policy.Validate(*password.Value, username, email); err != nil {
+				return "", errorhandler.CreateBadRequestError(err.Error())
+			}
+		}
+		if breachedPasswordCheck {
+			breached, err := c.breachedPasswordChecker.IsBreached(ctx, *password.Value)
+			if err != nil {
+				c.logger.Warn("err", err.Error())
+				return "", err
+			}
+			if breached {
+				return "", errorhandler.CreateBadRequestError(internal.MsgErrBreachedPassword + "." + internal.Password)
+			}
+		}
 		credKc.Value = password.Value
 	}
 
@@ -699,7 +1197,24 @@ func (c *component) DeleteCredentialsForUser(ctx context.Context, realmName stri
 	// get the list of credentails of the user
 	credsKc, err := c.keycloakClient.GetCredentials(accessToken, realmName, userID)
 	if err != nil {
+		// without the credentials, the removal of the last second factor can't be detected
 		c.logger.Warn("msg", "Could not obtain list of credentials", "err", err.Error())
+		return err
+	}
+
+	var deletedCredential *kc.CredentialRepresentation
+	for i, credKc := range credsKc {
+		if credKc.Id != nil && *credKc.Id == credentialID {
+			deletedCredential = &credsKc[i]
+			break
+		}
+	}
+
+	var isSecondFactor = deletedCredential != nil && deletedCredential.Type != nil && internal.IsSecondFactor(*deletedCredential.Type)
+	if isSecondFactor && countSecondFactors(credsKc) == 1 {
+		if err = c.checkSecondFactorRemoval(ctx, accessToken, realmName); err != nil {
+			return err
+		}
 	}
 
 	err = c.keycloakClient.DeleteCredential(accessToken, realmName, userID, credentialID)
@@ -710,25 +1225,178 @@ func (c *component) DeleteCredentialsForUser(ctx context.Context, realmName stri
 	}
 
 	// if a credential other than the password was deleted, record the event 2ND_FACTOR_REMOVED in the audit DB
-	for _, credKc := range credsKc {
-		if *credKc.Id == credentialID && *credKc.Type != "password" {
+	if isSecondFactor {
+		c.reportSecondFactorRemoved(ctx, realmName, userID)
+	}
 
-			errEvent := c.reportEvent(ctx, "2ND_FACTOR_REMOVED", database.CtEventRealmName, realmName, database.CtEventUserID, userID)
-			if errEvent != nil {
-				//store in the logs also the event that failed to be stored in the DB
-				m := map[string]interface{}{"event_name": "2ND_FACTOR_REMOVED", database.CtEventRealmName: realmName, database.CtEventUserID: userID}
-				eventJSON, errMarshal := json.Marshal(m)
-				if errMarshal == nil {
-					c.logger.Error("err", errEvent.Error(), "event", string(eventJSON))
-				} else {
-					c.logger.Error("err", errEvent.Error())
-				}
-			}
+	return err
+}
+
+// ResetCredentialsForUser deletes all the credentials of the given category (otp, webauthn or sms) of the user
+func (c *component) ResetCredentialsForUser(ctx context.Context, realmName string, userID string, credentialCategory string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	var isSecondFactorCategory = false
+	for _, category := range internal.SecondFactorCategories {
+		isSecondFactorCategory = isSecondFactorCategory || category == credentialCategory
+	}
+	if !isSecondFactorCategory {
+		return errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Type)
+	}
+
+	credsKc, err := c.keycloakClient.GetCredentials(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	var deletedIDs []string
+	for _, credKc := range credsKc {
+		if credKc.Id != nil && credKc.Type != nil && internal.GetCredentialCategory(*credKc.Type) == credentialCategory {
+			deletedIDs = append(deletedIDs, *credKc.Id)
+		}
+	}
+	if len(deletedIDs) == 0 {
+		return nil
+	}
+
+	if len(deletedIDs) == countSecondFactors(credsKc) {
+		if err = c.checkSecondFactorRemoval(ctx, accessToken, realmName); err != nil {
+			return err
+		}
+	}
+
+	for _, credentialID := range deletedIDs {
+		if err = c.keycloakClient.DeleteCredential(accessToken, realmName, userID, credentialID); err != nil {
+			c.logger.Warn("err", err.Error())
+			return err
+		}
+		c.reportSecondFactorRemoved(ctx, realmName, userID)
+	}
+
+	return nil
+}
+
+// checkSecondFactorRemoval returns an error if the last second factor of a user is about to be removed while the realm requires
+// multi-factor authentication
+func (c *component) checkSecondFactorRemoval(ctx context.Context, accessToken, realmName string) error {
+	// no configuration means multi-factor authentication is not required
+	config, err := c.getRealmConfiguration(ctx, accessToken, realmName)
+	if err != nil {
+		return err
+	}
+
+	if config.MFARequired != nil && *config.MFARequired {
+		return errorhandler.Error{
+			Status:  409,
+			Message: internal.ComponentName + "." + internal.MsgErrCannotDelete + "." + internal.LastSecondFactor,
+		}
+	}
+	return nil
+}
+
+func (c *component) reportSecondFactorRemoved(ctx context.Context, realmName, userID string) {
+	errEvent := c.reportEvent(ctx, "2ND_FACTOR_REMOVED", database.CtEventRealmName, realmName, database.CtEventUserID, userID)
+	if errEvent != nil {
+		//store in the logs also the event that failed to be stored in the DB
+		m := map[string]interface{}{"event_name": "2ND_FACTOR_REMOVED", database.CtEventRealmName: realmName, database.CtEventUserID: userID}
+		eventJSON, errMarshal := json.Marshal(m)
+		if errMarshal == nil {
+			c.logger.Error("err", errEvent.Error(), "event", string(eventJSON))
+		} else {
+			c.logger.Error("err", errEvent.Error())
+		}
+	}
+}
+
+// countSecondFactors returns the number of credentials used as second factor
+func countSecondFactors(credsKc []kc.CredentialRepresentation) int {
+	var count = 0
+	for _, credKc := range credsKc {
+		if credKc.Type != nil && internal.IsSecondFactor(*credKc.Type) {
+			count++
+		}
+	}
+	return count
+}
+
+// GetUserSessions returns the active sessions of the user
+func (c *component) GetUserSessions(ctx context.Context, realmName, userID string) ([]api.UserSessionRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	sessionsKc, err := c.keycloakClient.GetSessionsForUser(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return nil, err
+	}
+
+	var sessionsRep = []api.UserSessionRepresentation{}
+	for _, sessionKc := range sessionsKc {
+		sessionsRep = append(sessionsRep, api.ConvertToAPIUserSession(sessionKc))
+	}
+
+	return sessionsRep, nil
+}
+
+// LogoutUser terminates all the sessions of the user
+func (c *component) LogoutUser(ctx context.Context, realmName, userID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	err := c.keycloakClient.LogoutUser(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	c.reportSessionsRevoked(ctx, realmName, userID, "*")
+
+	return nil
+}
+
+// RevokeSession terminates a single session of the user
+func (c *component) RevokeSession(ctx context.Context, realmName, userID, sessionID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	// the session must belong to the user, sessions are deleted at realm level by Keycloak
+	sessionsKc, err := c.keycloakClient.GetSessionsForUser(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	var found = false
+	for _, sessionKc := range sessionsKc {
+		if sessionKc.Id != nil && *sessionKc.Id == sessionID {
+			found = true
 			break
 		}
 	}
+	if !found {
+		return errorhandler.Error{
+			Status:  404,
+			Message: internal.MsgErrInvalidParam + "." + internal.SessionID,
+		}
+	}
 
-	return err
+	err = c.keycloakClient.DeleteSession(accessToken, realmName, sessionID)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+
+	c.reportSessionsRevoked(ctx, realmName, userID, sessionID)
+
+	return nil
+}
+
+// reportSessionsRevoked stores the SESSIONS_REVOKED event in the DB. The wildcard is used when all the sessions of the user are revoked.
+func (c *component) reportSessionsRevoked(ctx context.Context, realmName, userID, sessionID string) {
+	additionalInfo, _ := json.Marshal(map[string]string{"sessionId": sessionID})
+
+	var values = []string{database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventAdditionalInfo, string(additionalInfo)}
+	if err := c.reportEvent(ctx, "SESSIONS_REVOKED", values...); err != nil {
+		internal.LogUnstoredEvent(c.logger, err, "SESSIONS_REVOKED", values...)
+	}
 }
 
 func (c *component) GetRoles(ctx context.Context, realmName string) ([]api.RoleRepresentation, error) {
@@ -867,16 +1535,30 @@ func (c *component) GetRealmCustomConfiguration(ctx context.Context, realmName s
 		case internal.MissingRealmConfigurationErr:
 			c.logger.Warn("message", e.Error())
 			return api.RealmCustomConfiguration{
-				DefaultClientID:                     new(string),
-				DefaultRedirectURI:                  new(string),
-				APISelfAuthenticatorDeletionEnabled: &falseBool,
-				APISelfPasswordChangeEnabled:        &falseBool,
-				APISelfMailEditingEnabled:           &falseBool,
-				APISelfAccountDeletionEnabled:       &falseBool,
-				ShowAuthenticatorsTab:               &falseBool,
-				ShowPasswordTab:                     &falseBool,
-				ShowMailEditing:                     &falseBool,
-				ShowAccountDeletionButton:           &falseBool,
+				DefaultClientID:                       new(string),
+				DefaultRedirectURI:                    new(string),
+				APISelfAuthenticatorDeletionEnabled:   &falseBool,
+				APISelfPasswordChangeEnabled:          &falseBool,
+				APISelfMailEditingEnabled:             &falseBool,
+				APISelfAccountDeletionEnabled:         &falseBool,
+				APISelfActivityLogEnabled:             &falseBool,
+				APISelfSessionsEnabled:                &falseBool,
+				APISelfOTPEnrollmentEnabled:           &falseBool,
+				APISelfAuthenticatorListingEnabled:    &falseBool,
+				APISelfAuthenticatorLabellingEnabled:  &falseBool,
+				APISelfAuthenticatorReorderingEnabled: &falseBool,
+				APISelfPhoneNumberEditingEnabled:      &falseBool,
+				APISelfNameEditingEnabled:             &falseBool,
+				ShowAuthenticatorsTab:                 &falseBool,
+				ShowPasswordTab:                       &falseBool,
+				ShowMailEditing:                       &falseBool,
+				ShowAccountDeletionButton:             &falseBool,
+				UserAttributes:                        &[]api.AttributeDefinition{},
+				BreachedPasswordCheck:                 &falseBool,
+				MFARequired:                           &falseBool,
+				DuplicateCheck:                        &[]string{},
+				AllowedPhoneNumberCountries:           &[]string{},
+				AccountDeletionGracePeriod:            new(int),
 			}, nil
 		default:
 			c.logger.Error("err", e.Error())
@@ -884,22 +1566,35 @@ func (c *component) GetRealmCustomConfiguration(ctx context.Context, realmName s
 		}
 	}
 
-	return api.RealmCustomConfiguration{
-		DefaultClientID:                     config.DefaultClientID,
-		DefaultRedirectURI:                  config.DefaultRedirectURI,
-		APISelfAuthenticatorDeletionEnabled: config.APISelfAuthenticatorDeletionEnabled,
-		APISelfPasswordChangeEnabled:        config.APISelfPasswordChangeEnabled,
-		APISelfMailEditingEnabled:           config.APISelfMailEditingEnabled,
-		APISelfAccountDeletionEnabled:       config.APISelfAccountDeletionEnabled,
-		ShowAuthenticatorsTab:               config.ShowAuthenticatorsTab,
-		ShowPasswordTab:                     config.ShowPasswordTab,
-		ShowMailEditing:                     config.ShowMailEditing,
-		ShowAccountDeletionButton:           config.ShowAccountDeletionButton,
-	}, nil
+	return api.ConvertToAPIRealmCustomConfiguration(config), nil
 }
 
 // Update the configuration in the database; verify that the content of the configuration is coherent with Keycloak configuration
-func (c *component) UpdateRealmCustomConfiguration(ctx context.Context, realmName string, customConfig api.RealmCustomConfiguration) error {
+func (c *component) UpdateRealmCustomConfiguration(ctx context.Context, realmName string, customConfig api.RealmCustomConfiguration, comment string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	// get the realm config from Keycloak
+	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Error("err", err.Error())
+		return err
+	}
+	if err = c.checkDefaultRedirection(accessToken, realmName, customConfig); err != nil {
+		return err
+	}
+
+	// transform customConfig object into DTO
+	var config = api.ConvertToDTORealmConfiguration(customConfig)
+
+	// from the realm ID, store a new version of the custom configuration in the DB
+	realmID := realmConfig.Id
+	err = c.configDBModule.StoreOrUpdate(ctx, *realmID, config, configurationAuthor(ctx), comment)
+	return err
+}
+
+// PatchRealmCustomConfiguration applies a JSON Merge Patch (RFC 7386) on the stored configuration of a realm, so that the
+// fields which are not part of the patch are kept
+func (c *component) PatchRealmCustomConfiguration(ctx context.Context, realmName string, patch []byte, comment string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
 	// get the realm config from Keycloak
@@ -908,6 +1603,68 @@ func (c *component) UpdateRealmCustomConfiguration(ctx context.Context, realmNam
 		c.logger.Error("err", err.Error())
 		return err
 	}
+	var realmID = *realmConfig.Id
+
+	currentConfig, err := c.configDBModule.GetConfiguration(ctx, realmID)
+	if err != nil {
+		if _, ok := errors.Cause(err).(internal.MissingRealmConfigurationErr); !ok {
+			c.logger.Error("err", err.Error())
+			return err
+		}
+	}
+
+	// apply the patch on the API representation of the stored configuration
+	currentJSON, err := json.Marshal(api.ConvertToAPIRealmCustomConfiguration(currentConfig))
+	if err != nil {
+		c.logger.Error("err", err.Error())
+		return err
+	}
+	patchedJSON, err := internal.ApplyMergePatch(currentJSON, patch)
+	if err != nil {
+		return errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Body)
+	}
+
+	var customConfig api.RealmCustomConfiguration
+	var decoder = json.NewDecoder(bytes.NewReader(patchedJSON))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&customConfig); err != nil {
+		return errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Body)
+	}
+	if err = customConfig.Validate(); err != nil {
+		return errorhandler.CreateBadRequestError(err.Error())
+	}
+	if err = c.checkDefaultRedirection(accessToken, realmName, customConfig); err != nil {
+		return err
+	}
+
+	var config = api.ConvertToDTORealmConfiguration(customConfig)
+	var changes = diffConfigurations(currentConfig, config)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	err = c.configDBModule.StoreOrUpdate(ctx, realmID, config, configurationAuthor(ctx), comment)
+	if err != nil {
+		c.logger.Error("err", err.Error())
+		return err
+	}
+
+	//store the API call into the DB
+	var changedKeys = []string{}
+	for _, change := range changes {
+		changedKeys = append(changedKeys, change.Field)
+	}
+	changesJSON, _ := json.Marshal(map[string][]string{"changed_keys": changedKeys})
+	var values = []string{database.CtEventRealmName, realmName, database.CtEventAdditionalInfo, string(changesJSON)}
+	if err = c.reportEvent(ctx, "REALM_CONFIGURATION_PATCH", values...); err != nil {
+		internal.LogUnstoredEvent(c.logger, err, "REALM_CONFIGURATION_PATCH", values...)
+	}
+
+	return nil
+}
+
+// checkDefaultRedirection verifies that the default redirect URI of a configuration is allowed for its default client
+func (c *component) checkDefaultRedirection(accessToken, realmName string, customConfig api.RealmCustomConfiguration) error {
 	// get the desired client (from its ID)
 	clients, err := c.keycloakClient.GetClients(accessToken, realmName)
 	if err != nil {
@@ -951,22 +1708,302 @@ func (c *component) UpdateRealmCustomConfiguration(ctx context.Context, realmNam
 		}
 	}
 
-	// transform customConfig object into DTO
-	var config = dto.RealmConfiguration{
-		DefaultClientID:                     customConfig.DefaultClientID,
-		DefaultRedirectURI:                  customConfig.DefaultRedirectURI,
-		APISelfAuthenticatorDeletionEnabled: customConfig.APISelfAuthenticatorDeletionEnabled,
-		APISelfPasswordChangeEnabled:        customConfig.APISelfPasswordChangeEnabled,
-		APISelfMailEditingEnabled:           customConfig.APISelfMailEditingEnabled,
-		APISelfAccountDeletionEnabled:       customConfig.APISelfAccountDeletionEnabled,
-		ShowAuthenticatorsTab:               customConfig.ShowAuthenticatorsTab,
-		ShowPasswordTab:                     customConfig.ShowPasswordTab,
-		ShowMailEditing:                     customConfig.ShowMailEditing,
-		ShowAccountDeletionButton:           customConfig.ShowAccountDeletionButton,
-	}
-
-	// from the realm ID, update the custom configuration in the DB
-	realmID := realmConfig.Id
-	err = c.configDBModule.StoreOrUpdate(ctx, *realmID, config)
+	return nil
+}
+
+// GetRealmCustomConfigurationHistory returns all the versions of the custom configuration of a realm, the most recent first
+func (c *component) GetRealmCustomConfigurationHistory(ctx context.Context, realmName string) ([]api.RealmCustomConfigurationVersion, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return nil, err
+	}
+
+	versions, err := c.configDBModule.GetConfigurationHistory(ctx, *realmConfig.Id)
+	if err != nil {
+		c.logger.Error("err", err.Error())
+		return nil, err
+	}
+
+	var res = []api.RealmCustomConfigurationVersion{}
+	for _, version := range versions {
+		res = append(res, api.ConvertToAPIRealmCustomConfigurationVersion(version))
+	}
+	return res, nil
+}
+
+// GetRealmCustomConfigurationDiff returns the differences between two versions of the custom configuration of a realm.
+// When toVersion is 0, the version is compared with the current configuration.
+func (c *component) GetRealmCustomConfigurationDiff(ctx context.Context, realmName string, fromVersion, toVersion int) ([]api.FieldChangeRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return nil, err
+	}
+	var realmID = *realmConfig.Id
+
+	from, err := c.getConfigurationVersion(ctx, realmID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var to dto.RealmConfiguration
+	if toVersion == 0 {
+		to, err = c.configDBModule.GetConfiguration(ctx, realmID)
+		if err != nil {
+			if _, ok := errors.Cause(err).(internal.MissingRealmConfigurationErr); !ok {
+				c.logger.Error("err", err.Error())
+				return nil, err
+			}
+		}
+	} else {
+		var version dto.RealmConfigurationVersion
+		if version, err = c.getConfigurationVersion(ctx, realmID, toVersion); err != nil {
+			return nil, err
+		}
+		to = version.Configuration
+	}
+
+	return diffConfigurations(from.Configuration, to), nil
+}
+
+// RollbackRealmCustomConfiguration restores a previous version of the custom configuration of a realm. The restored
+// configuration is stored as a new version so that the history is never rewritten.
+func (c *component) RollbackRealmCustomConfiguration(ctx context.Context, realmName string, version int) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return err
+	}
+	var realmID = *realmConfig.Id
+
+	previous, err := c.getConfigurationVersion(ctx, realmID, version)
+	if err != nil {
+		return err
+	}
+
+	// the clients may have changed since the version was stored
+	if err = c.checkDefaultRedirection(accessToken, realmName, api.ConvertToAPIRealmCustomConfiguration(previous.Configuration)); err != nil {
+		return err
+	}
+
+	var comment = "Rollback to version " + strconv.Itoa(version)
+	err = c.configDBModule.StoreOrUpdate(ctx, realmID, previous.Configuration, configurationAuthor(ctx), comment)
+	if err != nil {
+		c.logger.Error("err", err.Error())
+	}
 	return err
 }
+
+func (c *component) getConfigurationVersion(ctx context.Context, realmID string, version int) (dto.RealmConfigurationVersion, error) {
+	res, err := c.configDBModule.GetConfigurationVersion(ctx, realmID, version)
+	if err != nil {
+		if _, ok := errors.Cause(err).(internal.MissingRealmConfigurationVersionErr); ok {
+			return dto.RealmConfigurationVersion{}, errorhandler.Error{
+				Status:  404,
+				Message: internal.MsgErrInvalidParam + "." + internal.ConfigVersion,
+			}
+		}
+		c.logger.Error("err", err.Error())
+	}
+	return res, err
+}
+
+// configurationAuthor returns the name of the agent changing a realm configuration
+func configurationAuthor(ctx context.Context) string {
+	var username, _ = ctx.Value(cs.CtContextUsername).(string)
+	var realm, _ = ctx.Value(cs.CtContextRealm).(string)
+	if realm == "" {
+		return username
+	}
+	return realm + "/" + username
+}
+
+// GetUserHistory returns the changes made to a user, the most recent first
+func (c *component) GetUserHistory(ctx context.Context, realmName, userID string, paramKV ...string) ([]api.UserChangeRepresentation, error) {
+	var params = map[string]string{
+		"realm":       realmName,
+		"userID":      userID,
+		"ctEventType": userUpdatedEvent,
+	}
+	for i := 0; i+1 < len(paramKV); i += 2 {
+		params[paramKV[i]] = paramKV[i+1]
+	}
+
+	auditEvents, err := c.auditEventsReader.GetEvents(ctx, params)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return nil, err
+	}
+
+	var history = []api.UserChangeRepresentation{}
+	for _, auditEvent := range auditEvents {
+		userChange, err := convertToUserChange(auditEvent)
+		if err != nil {
+			c.logger.Warn("err", err.Error(), "auditId", auditEvent.AuditID)
+			continue
+		}
+		history = append(history, userChange)
+	}
+
+	return history, nil
+}
+
+// getPasswordConfiguration returns the password policy of the realm and whether passwords must be checked against the
+// breached passwords database. The password policy is the one defined in the configuration of the realm if any, the Keycloak
+// one otherwise. Nil is returned when the realm has no password policy.
+func (c *component) getPasswordConfiguration(ctx context.Context, accessToken, realmName string) (*internal.PasswordPolicy, bool, error) {
+	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return nil, false, err
+	}
+
+	var policyValue = realmConfig.PasswordPolicy
+	var breachedPasswordCheck = false
+	config, err := c.configDBModule.GetConfiguration(ctx, *realmConfig.Id)
+	if err != nil {
+		switch e := errors.Cause(err).(type) {
+		case internal.MissingRealmConfigurationErr:
+			// no configuration means no override of the Keycloak policy
+		default:
+			c.logger.Error("err", e.Error())
+			return nil, false, err
+		}
+	} else {
+		if config.PasswordPolicy != nil && *config.PasswordPolicy != "" {
+			policyValue = config.PasswordPolicy
+		}
+		breachedPasswordCheck = config.BreachedPasswordCheck != nil && *config.BreachedPasswordCheck
+	}
+
+	if policyValue == nil || *policyValue == "" {
+		return nil, breachedPasswordCheck, nil
+	}
+
+	policy, err := internal.ParsePasswordPolicy(*policyValue)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return nil, false, err
+	}
+	return &policy, breachedPasswordCheck, nil
+}
+
+// getUserAttributeSchema returns the custom user attributes defined in the configuration of the realm
+func (c *component) getUserAttributeSchema(ctx context.Context, accessToken, realmName string) ([]dto.AttributeDefinition, error) {
+	config, err := c.getRealmConfiguration(ctx, accessToken, realmName)
+	if err != nil {
+		return nil, err
+	}
+	return internal.UserAttributeSchema(config), nil
+}
+
+// getRealmConfiguration returns the custom configuration of the realm, stored under the ID of the realm. An empty
+// configuration is returned when the realm has none.
+func (c *component) getRealmConfiguration(ctx context.Context, accessToken, realmName string) (dto.RealmConfiguration, error) {
+	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn("err", err.Error())
+		return dto.RealmConfiguration{}, err
+	}
+
+	return internal.GetRealmConfiguration(ctx, c.configDBModule, *realmConfig.Id, c.logger)
+}
+
+// BulkUserAction applies the same action to a list of users, or to the users matching a GetUsers filter.
+// The action is applied user by user and a failure for one user does not prevent the others from being processed.
+func (c *component) BulkUserAction(ctx context.Context, realmName string, bulk api.BulkUserActionRepresentation, paramKV ...string) ([]api.BulkUserActionResultRepresentation, error) {
+	userIDs, err := getBulkUserIDs(ctx, c, realmName, bulk)
+	if err != nil {
+		return nil, err
+	}
+
+	var results = []api.BulkUserActionResultRepresentation{}
+	for _, userID := range userIDs {
+		var err error
+
+		switch *bulk.Action {
+		case api.BulkActionLock, api.BulkActionUnlock:
+			var enabled = *bulk.Action == api.BulkActionUnlock
+			err = c.UpdateUser(ctx, realmName, userID, api.UserRepresentation{Enabled: &enabled}, "")
+		case api.BulkActionDelete:
+			err = c.DeleteUser(ctx, realmName, userID)
+		case api.BulkActionExecuteActionsEmail:
+			var requiredActions []api.RequiredAction
+			if bulk.RequiredActions != nil {
+				requiredActions = *bulk.RequiredActions
+			}
+			err = c.ExecuteActionsEmail(ctx, realmName, userID, requiredActions, paramKV...)
+		case api.BulkActionSendReminderEmail:
+			err = c.SendReminderEmail(ctx, realmName, userID, paramKV...)
+		default:
+			return nil, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.BulkAction)
+		}
+
+		results = append(results, bulkUserActionResult(userID, err))
+	}
+
+	return results, nil
+}
+
+// getBulkUserIDs returns the IDs of the users targeted by a bulk request: either the explicit list of user IDs or the users
+// returned by GetUsers for the group IDs and search filter of the request. The users are requested page by page, through the
+// given component so that the listing of the filter groups is still authorized.
+func getBulkUserIDs(ctx context.Context, component Component, realmName string, bulk api.BulkUserActionRepresentation) ([]string, error) {
+	if bulk.UserIDs != nil && len(*bulk.UserIDs) > 0 {
+		return *bulk.UserIDs, nil
+	}
+
+	var groupIDs []string
+	if bulk.GroupIDs != nil {
+		groupIDs = *bulk.GroupIDs
+	}
+
+	var paramKV []string
+	if bulk.Search != nil {
+		paramKV = append(paramKV, "search", *bulk.Search)
+	}
+
+	var userIDs = []string{}
+	for first := 0; ; first += searchBatchSize {
+		var pageParamKV = append(paramKV, "first", strconv.Itoa(first), "max", strconv.Itoa(searchBatchSize))
+		usersPage, err := component.GetUsers(ctx, realmName, groupIDs, pageParamKV...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range usersPage.Users {
+			if user.ID != nil {
+				userIDs = append(userIDs, *user.ID)
+			}
+		}
+
+		if len(usersPage.Users) < searchBatchSize || (usersPage.Count != nil && first+searchBatchSize >= *usersPage.Count) {
+			return userIDs, nil
+		}
+	}
+}
+
+func bulkUserActionResult(userID string, err error) api.BulkUserActionResultRepresentation {
+	var id = userID
+	var status = api.BulkStatusSuccess
+	var result = api.BulkUserActionResultRepresentation{
+		UserID: &id,
+		Status: &status,
+	}
+
+	if err != nil {
+		var failure = api.BulkStatusFailure
+		var message = err.Error()
+		result.Status = &failure
+		result.Error = &message
+	}
+
+	return result
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/cloudtrust/common-service/database"
 	commonhttp "github.com/cloudtrust/common-service/errors"
 	"github.com/cloudtrust/common-service/log"
+	events_api "github.com/cloudtrust/keycloak-bridge/api/events"
 	api "github.com/cloudtrust/keycloak-bridge/api/management"
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
 	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
@@ -30,7 +31,7 @@ func TestGetRealms(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 
@@ -94,7 +95,7 @@ func TestGetRealm(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -151,6 +152,124 @@ func TestGetRealm(t *testing.T) {
 	}
 }
 
+func TestGetRealmSettings(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, nil, nil, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var passwordPolicy = "length(10)"
+	var otpType = "totp"
+	var bruteForceProtected = true
+	var failureFactor = int32(5)
+	var smtpServer = map[string]string{"host": "smtp.example.com", "from": "noreply@example.com"}
+	var locales = []string{"en", "fr"}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Get settings with success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{
+			PasswordPolicy:      &passwordPolicy,
+			OtpPolicyType:       &otpType,
+			BruteForceProtected: &bruteForceProtected,
+			FailureFactor:       &failureFactor,
+			SmtpServer:          &smtpServer,
+			SupportedLocales:    &locales,
+		}, nil).Times(1)
+
+		settings, err := managementComponent.GetRealmSettings(ctx, realmName)
+
+		assert.Nil(t, err)
+		assert.Equal(t, passwordPolicy, *settings.PasswordPolicy)
+		assert.Equal(t, otpType, *settings.OTPPolicy.Type)
+		assert.True(t, *settings.BruteForceDetection.Enabled)
+		assert.Equal(t, failureFactor, *settings.BruteForceDetection.MaxLoginFailures)
+		assert.Equal(t, "noreply@example.com", *settings.SMTPFrom)
+		assert.Nil(t, settings.LoginTheme)
+		assert.Equal(t, locales, *settings.SupportedLocales)
+	})
+
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, errors.New("KC error")).Times(1)
+
+		_, err := managementComponent.GetRealmSettings(ctx, realmName)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestUpdateRealmSettings(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, nil, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var smtpFrom = "bridge@example.com"
+	var loginTheme = "cloudtrust"
+	var smtpServer = map[string]string{"host": "smtp.example.com", "from": "noreply@example.com"}
+	var settings = api.RealmSettingsRepresentation{
+		SMTPFrom:   &smtpFrom,
+		LoginTheme: &loginTheme,
+	}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Update settings with success", func(t *testing.T) {
+		var expectedSMTPServer = map[string]string{"host": "smtp.example.com", "from": smtpFrom}
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{SmtpServer: &smtpServer}, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateRealm(accessToken, realmName, kc.RealmRepresentation{
+			SmtpServer: &expectedSMTPServer,
+			LoginTheme: &loginTheme,
+		}).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "REALM_SETTINGS_UPDATE", "back-office", database.CtEventRealmName, realmName, database.CtEventAdditionalInfo, gomock.Any()).Return(nil).Times(1)
+
+		err := managementComponent.UpdateRealmSettings(ctx, realmName, settings)
+
+		assert.Nil(t, err)
+		// the current configuration of the realm is not modified
+		assert.Equal(t, "noreply@example.com", smtpServer["from"])
+	})
+
+	t.Run("Event can't be stored", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateRealm(accessToken, realmName, gomock.Any()).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "REALM_SETTINGS_UPDATE", "back-office", database.CtEventRealmName, realmName, database.CtEventAdditionalInfo, gomock.Any()).Return(errors.New("DB error")).Times(1)
+		mockLogger.EXPECT().Error("err", "DB error", "event", gomock.Any()).Times(1)
+
+		err := managementComponent.UpdateRealmSettings(ctx, realmName, settings)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Error while getting the realm", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		err := managementComponent.UpdateRealmSettings(ctx, realmName, settings)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Error while updating the realm", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateRealm(accessToken, realmName, gomock.Any()).Return(errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		err := managementComponent.UpdateRealmSettings(ctx, realmName, settings)
+
+		assert.NotNil(t, err)
+	})
+}
+
 func TestGetClient(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -159,7 +278,7 @@ func TestGetClient(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -193,13 +312,15 @@ func TestGetClient(t *testing.T) {
 
 		apiClientRep, err := managementComponent.GetClient(ctx, "master", id)
 
+		var accessType = "confidential"
 		var expectedAPIClientRep = api.ClientRepresentation{
-			ID:       &id,
-			Name:     &name,
-			BaseURL:  &baseURL,
-			ClientID: &clientID,
-			Protocol: &protocol,
-			Enabled:  &enabled,
+			ID:         &id,
+			Name:       &name,
+			BaseURL:    &baseURL,
+			ClientID:   &clientID,
+			Protocol:   &protocol,
+			Enabled:    &enabled,
+			AccessType: &accessType,
 		}
 
 		assert.Nil(t, err)
@@ -227,7 +348,7 @@ func TestGetClients(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -259,13 +380,15 @@ func TestGetClients(t *testing.T) {
 
 		apiClientsRep, err := managementComponent.GetClients(ctx, "master")
 
+		var accessType = "confidential"
 		var expectedAPIClientRep = api.ClientRepresentation{
-			ID:       &id,
-			Name:     &name,
-			BaseURL:  &baseURL,
-			ClientID: &clientID,
-			Protocol: &protocol,
-			Enabled:  &enabled,
+			ID:         &id,
+			Name:       &name,
+			BaseURL:    &baseURL,
+			ClientID:   &clientID,
+			Protocol:   &protocol,
+			Enabled:    &enabled,
+			AccessType: &accessType,
 		}
 
 		var expectedAPIClientsRep []api.ClientRepresentation
@@ -287,6 +410,264 @@ func TestGetClients(t *testing.T) {
 	}
 }
 
+func TestCreateClient(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, nil, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var clientID = "my-app"
+	var accessType = "public"
+	var redirectURIs = []string{"https://my-app.com/*"}
+	var locationURL = "http://toto.com/realms/master/clients/1234-7894-58"
+	var client = api.ClientRepresentation{
+		ClientID:     &clientID,
+		AccessType:   &accessType,
+		RedirectURIs: &redirectURIs,
+	}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Create a client with success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().CreateClient(accessToken, realmName, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName string, clientKc kc.ClientRepresentation) (string, error) {
+				assert.Equal(t, clientID, *clientKc.ClientId)
+				assert.Equal(t, "openid-connect", *clientKc.Protocol)
+				assert.True(t, *clientKc.PublicClient)
+				assert.False(t, *clientKc.BearerOnly)
+				assert.Equal(t, redirectURIs, *clientKc.RedirectUris)
+				return locationURL, nil
+			}).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_CLIENT_CREATION", "back-office", database.CtEventRealmName, realmName, database.CtEventClientID, clientID).Return(nil).Times(1)
+
+		location, err := managementComponent.CreateClient(ctx, realmName, client)
+
+		assert.Nil(t, err)
+		assert.Equal(t, locationURL, location)
+	})
+
+	t.Run("Event can't be stored", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().CreateClient(accessToken, realmName, gomock.Any()).Return(locationURL, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_CLIENT_CREATION", "back-office", database.CtEventRealmName, realmName, database.CtEventClientID, clientID).Return(errors.New("DB error")).Times(1)
+		mockLogger.EXPECT().Error("err", "DB error", "event", gomock.Any()).Times(1)
+
+		location, err := managementComponent.CreateClient(ctx, realmName, client)
+
+		assert.Nil(t, err)
+		assert.Equal(t, locationURL, location)
+	})
+
+	t.Run("Keycloak fails to create the client", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().CreateClient(accessToken, realmName, gomock.Any()).Return("", errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		_, err := managementComponent.CreateClient(ctx, realmName, client)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Custom scheme not allowed", func(t *testing.T) {
+		var appRedirectURIs = []string{"https://my-app.com/*", "javascript:alert(1)"}
+		var appClient = api.ClientRepresentation{ClientID: &clientID, RedirectURIs: &appRedirectURIs}
+
+		_, err := managementComponent.CreateClient(ctx, realmName, appClient)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Allowed custom scheme", func(t *testing.T) {
+		var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, nil, nil, nil, []string{"com.my-app"}, mockLogger)
+		var appBaseURL = "com.My-App:/home"
+		var appClient = api.ClientRepresentation{ClientID: &clientID, BaseURL: &appBaseURL}
+
+		mockKeycloakClient.EXPECT().CreateClient(accessToken, realmName, gomock.Any()).Return(locationURL, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_CLIENT_CREATION", "back-office", database.CtEventRealmName, realmName, database.CtEventClientID, clientID).Return(nil).Times(1)
+
+		_, err := managementComponent.CreateClient(ctx, realmName, appClient)
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestUpdateClient(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, nil, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var id = "1234-7894-58"
+	var clientID = "my-app"
+	var webOrigins = []string{"+"}
+	var client = api.ClientRepresentation{WebOrigins: &webOrigins}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Update a client with success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(kc.ClientRepresentation{Id: &id, ClientId: &clientID}, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateClient(accessToken, realmName, id, kc.ClientRepresentation{Id: &id, ClientId: &clientID, WebOrigins: &webOrigins}).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_CLIENT_UPDATE", "back-office", database.CtEventRealmName, realmName, database.CtEventClientID, clientID).Return(nil).Times(1)
+
+		err := managementComponent.UpdateClient(ctx, realmName, id, client)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Client does not exist", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(kc.ClientRepresentation{}, errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		err := managementComponent.UpdateClient(ctx, realmName, id, client)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Keycloak fails to update the client", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(kc.ClientRepresentation{Id: &id, ClientId: &clientID}, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateClient(accessToken, realmName, id, gomock.Any()).Return(errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		err := managementComponent.UpdateClient(ctx, realmName, id, client)
+
+		assert.NotNil(t, err)
+	})
+	t.Run("Custom scheme not allowed", func(t *testing.T) {
+		var baseURL = "data:text/html,hello"
+
+		err := managementComponent.UpdateClient(ctx, realmName, id, api.ClientRepresentation{BaseURL: &baseURL})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+}
+
+func TestEnableDisableClient(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, nil, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var id = "1234-7894-58"
+	var clientID = "my-app"
+	var enabled = true
+	var disabled = false
+	var clientKc = kc.ClientRepresentation{Id: &id, ClientId: &clientID}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Enable a client", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(clientKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateClient(accessToken, realmName, id, kc.ClientRepresentation{Id: &id, ClientId: &clientID, Enabled: &enabled}).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "CLIENT_ENABLED", "back-office", database.CtEventRealmName, realmName, database.CtEventClientID, clientID).Return(nil).Times(1)
+
+		err := managementComponent.EnableClient(ctx, realmName, id)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Disable a client", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(clientKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateClient(accessToken, realmName, id, kc.ClientRepresentation{Id: &id, ClientId: &clientID, Enabled: &disabled}).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "CLIENT_DISABLED", "back-office", database.CtEventRealmName, realmName, database.CtEventClientID, clientID).Return(nil).Times(1)
+
+		err := managementComponent.DisableClient(ctx, realmName, id)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Client does not exist", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(kc.ClientRepresentation{}, errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		err := managementComponent.DisableClient(ctx, realmName, id)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Keycloak fails to update the client", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(clientKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateClient(accessToken, realmName, id, gomock.Any()).Return(errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		err := managementComponent.EnableClient(ctx, realmName, id)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRegenerateClientSecret(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, nil, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var id = "1234-7894-58"
+	var clientID = "my-app"
+	var secret = "0d5a8a1e-5d4b-4f2a-b7d1-3c2a9a5f0e11"
+	var publicClient = true
+	var clientKc = kc.ClientRepresentation{Id: &id, ClientId: &clientID}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Regenerate the secret of a confidential client", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(clientKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().RegenerateSecret(accessToken, realmName, id).Return(kc.CredentialRepresentation{Value: &secret}, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "CLIENT_SECRET_REGENERATED", "back-office", database.CtEventRealmName, realmName, database.CtEventClientID, clientID).Return(nil).Times(1)
+
+		res, err := managementComponent.RegenerateClientSecret(ctx, realmName, id)
+
+		assert.Nil(t, err)
+		assert.Equal(t, secret, *res.Value)
+	})
+
+	t.Run("Public clients have no secret", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(kc.ClientRepresentation{Id: &id, PublicClient: &publicClient}, nil).Times(1)
+
+		_, err := managementComponent.RegenerateClientSecret(ctx, realmName, id)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Client does not exist", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(kc.ClientRepresentation{}, errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		_, err := managementComponent.RegenerateClientSecret(ctx, realmName, id)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Keycloak fails to regenerate the secret", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetClient(accessToken, realmName, id).Return(clientKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().RegenerateSecret(accessToken, realmName, id).Return(kc.CredentialRepresentation{}, errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		_, err := managementComponent.RegenerateClientSecret(ctx, realmName, id)
+
+		assert.NotNil(t, err)
+	})
+}
 func TestCreateUser(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -295,7 +676,7 @@ func TestCreateUser(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var username = "test"
@@ -303,6 +684,10 @@ func TestCreateUser(t *testing.T) {
 	var targetRealmName = "DEP"
 	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
 	var locationURL = "http://toto.com/realms/" + userID
+	var realmID = "12345"
+
+	mockKeycloakClient.EXPECT().GetRealm(accessToken, gomock.Any()).Return(kc.RealmRepresentation{Id: &realmID}, nil).AnyTimes()
+	mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, keycloakb.MissingRealmConfigurationErr{}).AnyTimes()
 
 	// Create with minimum properties
 	{
@@ -322,7 +707,7 @@ func TestCreateUser(t *testing.T) {
 			Username: &username,
 		}
 
-		location, err := managementComponent.CreateUser(ctx, targetRealmName, userRep)
+		location, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
 
 		assert.Nil(t, err)
 		assert.Equal(t, locationURL, location)
@@ -348,7 +733,7 @@ func TestCreateUser(t *testing.T) {
 			Username: &username,
 		}
 
-		location, err := managementComponent.CreateUser(ctx, realmName, userRep)
+		location, err := managementComponent.CreateUser(ctx, realmName, userRep, false)
 
 		assert.Nil(t, err)
 		assert.Equal(t, locationURL, location)
@@ -362,7 +747,7 @@ func TestCreateUser(t *testing.T) {
 		var emailVerified = true
 		var firstName = "Titi"
 		var lastName = "Tutu"
-		var phoneNumber = "+41789456"
+		var phoneNumber = "+41789456123"
 		var phoneNumberVerified = true
 		var label = "Label"
 		var gender = "M"
@@ -411,7 +796,7 @@ func TestCreateUser(t *testing.T) {
 			Locale:              &locale,
 		}
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ACCOUNT_CREATION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
-		location, err := managementComponent.CreateUser(ctx, targetRealmName, userRep)
+		location, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
 
 		assert.Nil(t, err)
 		assert.Equal(t, locationURL, location)
@@ -429,13 +814,255 @@ func TestCreateUser(t *testing.T) {
 		var userRep = api.UserRepresentation{}
 		mockLogger.EXPECT().Warn("err", "Invalid input")
 
-		location, err := managementComponent.CreateUser(ctx, targetRealmName, userRep)
+		location, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
 
 		assert.NotNil(t, err)
 		assert.Equal(t, "", location)
 	}
 }
 
+func TestCreateUserWithAttributes(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var username = "test"
+	var realmName = "master"
+	var targetRealmName = "DEP"
+	var realmID = "12345"
+	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var locationURL = "http://toto.com/realms/" + userID
+	var attrName = "employeeNumber"
+	var attrType = "number"
+	var trueBool = true
+	var config = dto.RealmConfiguration{
+		UserAttributes: &[]dto.AttributeDefinition{
+			{Name: &attrName, Type: &attrType, Required: &trueBool, EditableByBackOffice: &trueBool},
+		},
+	}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	// Attribute matching the schema
+	{
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, targetRealmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		mockKeycloakClient.EXPECT().CreateUser(accessToken, realmName, targetRealmName, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName, targetRealmName string, kcUserRep kc.UserRepresentation) (string, error) {
+				assert.Equal(t, []string{"42"}, (*kcUserRep.Attributes)[attrName])
+				return locationURL, nil
+			}).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ACCOUNT_CREATION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		var userRep = api.UserRepresentation{
+			Username:   &username,
+			Attributes: &map[string][]string{attrName: {"42"}},
+		}
+		location, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, locationURL, location)
+	}
+
+	// Missing required attribute
+	{
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, targetRealmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		mockLogger.EXPECT().Warn("err", gomock.Any()).Times(1)
+
+		var userRep = api.UserRepresentation{
+			Username: &username,
+		}
+		_, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	}
+
+	// Attribute not defined in the schema
+	{
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, targetRealmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		mockLogger.EXPECT().Warn("err", gomock.Any()).Times(1)
+
+		var userRep = api.UserRepresentation{
+			Username:   &username,
+			Attributes: &map[string][]string{attrName: {"42"}, "unknown": {"value"}},
+		}
+		_, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
+
+		assert.NotNil(t, err)
+	}
+
+	// Error while loading the configuration
+	{
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, targetRealmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{}, errors.New("error")).Times(1)
+		mockLogger.EXPECT().Error("err", "error").Times(1)
+
+		_, err := managementComponent.CreateUser(ctx, targetRealmName, api.UserRepresentation{Username: &username}, false)
+
+		assert.NotNil(t, err)
+	}
+}
+
+func TestCreateUserDuplicateCheck(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var username = "jdoe"
+	var realmName = "master"
+	var targetRealmName = "DEP"
+	var realmID = "12345"
+	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var locationURL = "http://toto.com/realms/" + userID
+	var email = "John.Doe+work@company.com"
+	var firstName = "Jérôme"
+	var lastName = "Doe"
+	var birthDate = "17.02.1990"
+	var phoneNumber = "+41 79 123 45 67"
+	var duplicateCheck = []string{api.DuplicateCheckEmail, api.DuplicateCheckIdentity}
+	var config = dto.RealmConfiguration{DuplicateCheck: &duplicateCheck}
+
+	var existingID1 = "11111111-32a9-4000-8c17-edc854c31231"
+	var existingID2 = "22222222-32a9-4000-8c17-edc854c31231"
+	var existingEmail = "john.doe@company.com"
+	var otherEmail = "john.doe.senior@company.com"
+	var existingFirstName = "jerome"
+	var existingAttributes = map[string][]string{"birthDate": {birthDate}, "phoneNumber": {"0041791234567"}}
+	var existingUsers = []kc.UserRepresentation{
+		{Id: &existingID1, Email: &existingEmail},
+		{Id: &existingID2, Email: &otherEmail, FirstName: &existingFirstName, LastName: &lastName, Attributes: &existingAttributes},
+	}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	var userRep = api.UserRepresentation{
+		Username:    &username,
+		Email:       &email,
+		FirstName:   &firstName,
+		LastName:    &lastName,
+		BirthDate:   &birthDate,
+		PhoneNumber: &phoneNumber,
+	}
+
+	mockKeycloakClient.EXPECT().GetRealm(accessToken, targetRealmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).AnyTimes()
+
+	var emptyPage = kc.UsersPageRepresentation{Users: []kc.UserRepresentation{}}
+	var expectSearches = func(emailUsers, lastNameUsers []kc.UserRepresentation) {
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "email", "john.doe", "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{Users: emailUsers}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "lastName", lastName, "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{Users: lastNameUsers}, nil).Times(1)
+		// first and last names are sometimes swapped
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "lastName", firstName, "first", "0", "max", "500").
+			Return(emptyPage, nil).Times(1)
+	}
+
+	t.Run("Duplicates found", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		expectSearches(existingUsers, existingUsers[1:])
+		mockLogger.EXPECT().Info(gomock.Any()).AnyTimes()
+
+		_, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
+
+		assert.Equal(t, DuplicateUserError{CandidateIDs: []string{existingID1, existingID2}}, err)
+	})
+
+	t.Run("Phone number checked with an attribute search", func(t *testing.T) {
+		var phoneConfig = dto.RealmConfiguration{DuplicateCheck: &[]string{api.DuplicateCheckPhoneNumber}}
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(phoneConfig, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "q", "phoneNumber:+41791234567", "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{Users: existingUsers}, nil).Times(1)
+		mockLogger.EXPECT().Info(gomock.Any()).AnyTimes()
+
+		_, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
+
+		assert.Equal(t, DuplicateUserError{CandidateIDs: []string{existingID2}}, err)
+	})
+
+	t.Run("Email checked with a Keycloak search", func(t *testing.T) {
+		var emailConfig = dto.RealmConfiguration{DuplicateCheck: &[]string{api.DuplicateCheckEmail}}
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(emailConfig, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "email", "john.doe", "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{Users: existingUsers}, nil).Times(1)
+		mockLogger.EXPECT().Info(gomock.Any()).AnyTimes()
+
+		_, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
+
+		assert.Equal(t, DuplicateUserError{CandidateIDs: []string{existingID1}}, err)
+	})
+
+	t.Run("Too many users returned by a search", func(t *testing.T) {
+		var total = maxSearchedUsers + 1
+		var batch = make([]kc.UserRepresentation, searchBatchSize)
+		for i := range batch {
+			batch[i] = existingUsers[0]
+		}
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "email", "john.doe", "first", gomock.Any(), "max", "500").
+			Return(kc.UsersPageRepresentation{Count: &total, Users: batch}, nil).Times(maxSearchedUsers/searchBatchSize + 1)
+
+		_, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, true)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 409, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("No duplicate", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		expectSearches([]kc.UserRepresentation{{Id: &existingID1, Email: &otherEmail}}, nil)
+		mockKeycloakClient.EXPECT().CreateUser(accessToken, realmName, targetRealmName, gomock.Any()).Return(locationURL, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ACCOUNT_CREATION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		location, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
+
+		assert.Nil(t, err)
+		assert.Equal(t, locationURL, location)
+	})
+
+	t.Run("Forced creation is audited", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		expectSearches(existingUsers[:1], nil)
+		mockKeycloakClient.EXPECT().CreateUser(accessToken, realmName, targetRealmName, gomock.Any()).Return(locationURL, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ACCOUNT_CREATION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ACCOUNT_CREATION_DUPLICATE_FORCED", "back-office", database.CtEventRealmName, targetRealmName, database.CtEventUserID, userID,
+			database.CtEventUsername, username, database.CtEventAdditionalInfo, `{"candidates":["`+existingID1+`"]}`).Return(nil).Times(1)
+
+		location, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, true)
+
+		assert.Nil(t, err)
+		assert.Equal(t, locationURL, location)
+	})
+
+	t.Run("Error while searching the users", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, gomock.Any()).Return(kc.UsersPageRepresentation{}, errors.New("error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "error").Times(1)
+
+		_, err := managementComponent.CreateUser(ctx, targetRealmName, userRep, false)
+
+		assert.NotNil(t, err)
+	})
+}
+
 func TestDeleteUser(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -444,7 +1071,7 @@ func TestDeleteUser(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
@@ -504,7 +1131,7 @@ func TestGetUser(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -616,13 +1243,18 @@ func TestUpdateUser(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
 	var id = "41dbf4a8-32a9-4000-8c17-edc854c31231"
 	var username = "username"
 	var enabled = true
+	var realmID = "12345"
+
+	mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).AnyTimes()
+	mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, nil).AnyTimes()
+
 	// Update user with succces
 	{
 
@@ -630,7 +1262,7 @@ func TestUpdateUser(t *testing.T) {
 		var emailVerified = true
 		var firstName = "Titi"
 		var lastName = "Tutu"
-		var phoneNumber = "+41789456"
+		var phoneNumber = "+41789456123"
 		var phoneNumberVerified = true
 		var label = "Label"
 		var gender = "M"
@@ -679,6 +1311,7 @@ func TestUpdateUser(t *testing.T) {
 
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "LOCK_ACCOUNT", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "UNLOCK_ACCOUNT", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "USER_UPDATED", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, id, database.CtEventUsername, gomock.Any(), database.CtEventAdditionalInfo, gomock.Any()).Return(nil).AnyTimes()
 
 		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).Times(1)
 
@@ -700,7 +1333,7 @@ func TestUpdateUser(t *testing.T) {
 				return nil
 			}).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, userRep)
+		err := managementComponent.UpdateUser(ctx, "master", id, userRep, "")
 
 		assert.Nil(t, err)
 
@@ -740,7 +1373,7 @@ func TestUpdateUser(t *testing.T) {
 		}
 		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).Times(1)
 
-		err = managementComponent.UpdateUser(ctx, "master", id, userRepLocked)
+		err = managementComponent.UpdateUser(ctx, "master", id, userRepLocked, "")
 
 		assert.Nil(t, err)
 		// update by changing the email address
@@ -759,13 +1392,13 @@ func TestUpdateUser(t *testing.T) {
 				return nil
 			}).Times(1)
 
-		err = managementComponent.UpdateUser(ctx, "master", id, userRep)
+		err = managementComponent.UpdateUser(ctx, "master", id, userRep, "")
 
 		assert.Nil(t, err)
 
 		// update by changing the phone number
 
-		var oldNumber = "+41789467"
+		var oldNumber = "+41789467123"
 		var oldAttributes = make(map[string][]string)
 		oldAttributes["phoneNumber"] = []string{oldNumber}
 		oldAttributes["phoneNumberVerified"] = []string{strconv.FormatBool(phoneNumberVerified)}
@@ -783,87 +1416,307 @@ func TestUpdateUser(t *testing.T) {
 				return nil
 			}).Times(1)
 
-		err = managementComponent.UpdateUser(ctx, "master", id, userRep)
+		err = managementComponent.UpdateUser(ctx, "master", id, userRep, "")
 
 		assert.Nil(t, err)
 
-		// update without attributes
-		var userRepWithoutAttr = api.UserRepresentation{
-			Username:  &username,
-			Email:     &email,
-			Enabled:   &enabled,
-			FirstName: &firstName,
-			LastName:  &lastName,
-		}
+		// update without attributes
+		var userRepWithoutAttr = api.UserRepresentation{
+			Username:  &username,
+			Email:     &email,
+			Enabled:   &enabled,
+			FirstName: &firstName,
+			LastName:  &lastName,
+		}
+
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(oldkcUserRep2, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "LOCK_ACCOUNT", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName, id string, kcUserRep kc.UserRepresentation) error {
+				verified, _ := strconv.ParseBool(((*kcUserRep.Attributes)["phoneNumberVerified"][0]))
+				assert.Equal(t, oldNumber, (*kcUserRep.Attributes)["phoneNumber"][0])
+				assert.Equal(t, true, verified)
+				return nil
+			}).Times(1)
+
+		err = managementComponent.UpdateUser(ctx, "master", id, userRepWithoutAttr, "")
+
+		assert.Nil(t, err)
+
+		// update with an equivalent phone number
+		var equivalentNumber = "0041 78 946 71 23"
+		var userRepEquivalentNumber = api.UserRepresentation{
+			Username:    &username,
+			Enabled:     &enabled,
+			PhoneNumber: &equivalentNumber,
+		}
+
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(oldkcUserRep2, nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "LOCK_ACCOUNT", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName, id string, kcUserRep kc.UserRepresentation) error {
+				verified, _ := strconv.ParseBool(((*kcUserRep.Attributes)["phoneNumberVerified"][0]))
+				assert.Equal(t, oldNumber, (*kcUserRep.Attributes)["phoneNumber"][0])
+				assert.Equal(t, true, verified)
+				return nil
+			}).Times(1)
+
+		err = managementComponent.UpdateUser(ctx, "master", id, userRepEquivalentNumber, "")
+
+		assert.Nil(t, err)
+
+		// update with an invalid phone number
+		var invalidNumber = "+41 78 946"
+		var userRepInvalidNumber = api.UserRepresentation{
+			PhoneNumber: &invalidNumber,
+		}
+
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(oldkcUserRep2, nil).Times(1)
+		mockLogger.EXPECT().Warn("err", gomock.Any()).Times(1)
+
+		err = managementComponent.UpdateUser(ctx, "master", id, userRepInvalidNumber, "")
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	}
+	// Update user with succces but with error when storing the event in the DB
+	{
+		enabled = true
+		var kcUserRep = kc.UserRepresentation{
+			Id:       &id,
+			Username: &username,
+			Enabled:  &enabled,
+		}
+
+		var userRep = api.UserRepresentation{
+			Username: &username,
+			Enabled:  &enabled,
+		}
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "UNLOCK_ACCOUNT", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, id, database.CtEventUsername, username).Return(errors.New("error")).Times(1)
+		m := map[string]interface{}{"event_name": "UNLOCK_ACCOUNT", database.CtEventRealmName: realmName, database.CtEventUserID: id, database.CtEventUsername: username}
+		eventJSON, _ := json.Marshal(m)
+		mockLogger.EXPECT().Error("err", "error", "event", string(eventJSON))
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(nil).Times(1)
+
+		err := managementComponent.UpdateUser(ctx, "master", id, userRep, "")
+
+		assert.Nil(t, err)
+
+	}
+
+	//Error - get user
+	{
+		var id = "1234-79894-7594"
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kc.UserRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		mockLogger.EXPECT().Warn("err", "Unexpected error")
+		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{}, "")
+
+		assert.NotNil(t, err)
+	}
+	//Error - update user
+	{
+		var id = "1234-79894-7594"
+		var kcUserRep = kc.UserRepresentation{
+			Id: &id,
+		}
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).AnyTimes()
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(fmt.Errorf("Unexpected error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "Unexpected error")
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{}, "")
+
+		assert.NotNil(t, err)
+	}
+
+	// If-Match matches the current version of the user
+	{
+		var id = "5678-79894-7594"
+		var kcUserRep = kc.UserRepresentation{
+			Id:       &id,
+			Username: &username,
+		}
+		var etag = api.ComputeETag(api.ConvertToAPIUser(kcUserRep))
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(nil).Times(1)
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{}, etag)
+
+		assert.Nil(t, err)
+	}
+
+	// Error - user modified since the ETag was computed
+	{
+		var id = "5678-79894-7594"
+		var newUsername = "modified"
+		var kcUserRep = kc.UserRepresentation{
+			Id:       &id,
+			Username: &username,
+		}
+		var etag = api.ComputeETag(api.ConvertToAPIUser(kcUserRep))
+		kcUserRep.Username = &newUsername
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).Times(1)
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{}, etag)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 412, err.(commonhttp.Error).Status)
+	}
+}
+
+func TestUpdateUserAttributes(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var realmID = "12345"
+	var id = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var trueBool = true
+	var falseBool = false
+	var department = "department"
+	var employeeNumber = "employeeNumber"
+	var config = dto.RealmConfiguration{
+		UserAttributes: &[]dto.AttributeDefinition{
+			{Name: &department, EditableByBackOffice: &trueBool},
+			{Name: &employeeNumber, Required: &trueBool, EditableByBackOffice: &trueBool},
+		},
+	}
+	var kcUserRep = kc.UserRepresentation{
+		Id: &id,
+		Attributes: &map[string][]string{
+			"phoneNumber":  {"+41789456"},
+			department:     {"IT"},
+			employeeNumber: {"42"},
+			"legacy":       {"value"},
+		},
+	}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 
-		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(oldkcUserRep2, nil).Times(1)
-		mockEventDBModule.EXPECT().ReportEvent(ctx, "LOCK_ACCOUNT", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	// Delete an attribute and send back an attribute which is not editable but unchanged
+	{
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).DoAndReturn(
 			func(accessToken, realmName, id string, kcUserRep kc.UserRepresentation) error {
-				verified, _ := strconv.ParseBool(((*kcUserRep.Attributes)["phoneNumberVerified"][0]))
-				assert.Equal(t, oldNumber, (*kcUserRep.Attributes)["phoneNumber"][0])
-				assert.Equal(t, true, verified)
+				var attributes = *kcUserRep.Attributes
+				_, ok := attributes[department]
+				assert.False(t, ok)
+				assert.Equal(t, []string{"43"}, attributes[employeeNumber])
+				assert.Equal(t, []string{"value"}, attributes["legacy"])
+				assert.Equal(t, []string{"+41789456"}, attributes["phoneNumber"])
 				return nil
 			}).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "USER_UPDATED", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, id, database.CtEventUsername, "", database.CtEventAdditionalInfo,
+			`{"changes":[{"field":"attributes.department","oldValue":"IT"},{"field":"attributes.employeeNumber","oldValue":"42","newValue":"43"}]}`).Return(nil).Times(1)
 
-		err = managementComponent.UpdateUser(ctx, "master", id, userRepWithoutAttr)
+		var userRep = api.UserRepresentation{
+			Attributes:        &map[string][]string{employeeNumber: {"43"}, "legacy": {"value"}},
+			DeletedAttributes: &[]string{department},
+		}
+		err := managementComponent.UpdateUser(ctx, realmName, id, userRep, "")
 
 		assert.Nil(t, err)
 	}
-	// Update user with succces but with error when storing the event in the DB
+
+	// Required attributes can't be deleted
 	{
-		enabled = true
-		var kcUserRep = kc.UserRepresentation{
-			Id:       &id,
-			Username: &username,
-			Enabled:  &enabled,
-		}
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(config, nil).Times(1)
+		mockLogger.EXPECT().Warn("err", keycloakb.MsgErrInvalidParam+"."+keycloakb.DeletedAttributes+"."+employeeNumber).Times(1)
 
 		var userRep = api.UserRepresentation{
-			Username: &username,
-			Enabled:  &enabled,
+			DeletedAttributes: &[]string{employeeNumber},
 		}
+		err := managementComponent.UpdateUser(ctx, realmName, id, userRep, "")
 
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
-		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+		assert.NotNil(t, err)
+	}
 
-		mockEventDBModule.EXPECT().ReportEvent(ctx, "UNLOCK_ACCOUNT", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, id, database.CtEventUsername, username).Return(errors.New("error")).Times(1)
-		m := map[string]interface{}{"event_name": "UNLOCK_ACCOUNT", database.CtEventRealmName: realmName, database.CtEventUserID: id, database.CtEventUsername: username}
-		eventJSON, _ := json.Marshal(m)
-		mockLogger.EXPECT().Error("err", "error", "event", string(eventJSON))
+	// Attributes not editable by the back-office can't be changed
+	{
+		var readOnlyConfig = dto.RealmConfiguration{
+			UserAttributes: &[]dto.AttributeDefinition{
+				{Name: &department, EditableByBackOffice: &falseBool},
+			},
+		}
 		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).Times(1)
-		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(readOnlyConfig, nil).Times(1)
+		mockLogger.EXPECT().Warn("err", keycloakb.MsgErrInvalidParam+"."+keycloakb.Attributes+"."+department).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, userRep)
-
-		assert.Nil(t, err)
+		var userRep = api.UserRepresentation{
+			Attributes: &map[string][]string{department: {"HR"}},
+		}
+		err := managementComponent.UpdateUser(ctx, realmName, id, userRep, "")
 
+		assert.NotNil(t, err)
 	}
+}
 
-	//Error - get user
+func TestGetUserHistory(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockAuditEventsReader = mock.NewAuditEventsReaderModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockAuditEventsReader, mockConfigurationDBModule, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	// History of a user
 	{
-		var id = "1234-79894-7594"
-		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kc.UserRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
+		var params = map[string]string{"realm": realmName, "userID": userID, "ctEventType": "USER_UPDATED", "max": "10"}
+		var auditEvents = []events_api.AuditRepresentation{
+			{AuditID: 2, AuditTime: 1571300000, Origin: "back-office", AgentUsername: "agent", AgentRealmName: "master", CtEventType: "USER_UPDATED",
+				AdditionalInfo: `{"changes":[{"field":"email","oldValue":"old@elca.ch","newValue":"new@elca.ch"}]}`},
+			{AuditID: 1, AuditTime: 1571200000, Origin: "back-office", CtEventType: "USER_UPDATED", AdditionalInfo: "{invalid"},
+		}
+		mockAuditEventsReader.EXPECT().GetEvents(ctx, params).Return(auditEvents, nil).Times(1)
+		mockLogger.EXPECT().Warn("err", gomock.Any(), "auditId", int64(1)).Times(1)
 
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		mockLogger.EXPECT().Warn("err", "Unexpected error")
-		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{})
+		history, err := managementComponent.GetUserHistory(ctx, realmName, userID, "max", "10")
 
-		assert.NotNil(t, err)
+		assert.Nil(t, err)
+		assert.Len(t, history, 1)
+		assert.Equal(t, int64(1571300000), *history[0].Time)
+		assert.Equal(t, "agent", *history[0].AgentUsername)
+		assert.Equal(t, "email", history[0].Changes[0].Field)
+		assert.Equal(t, "old@elca.ch", *history[0].Changes[0].OldValue)
+		assert.Equal(t, "new@elca.ch", *history[0].Changes[0].NewValue)
 	}
-	//Error - update user
+
+	// Error from the audit database
 	{
-		var id = "1234-79894-7594"
-		var kcUserRep = kc.UserRepresentation{
-			Id: &id,
-		}
-		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).AnyTimes()
-		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(fmt.Errorf("Unexpected error")).Times(1)
-		mockLogger.EXPECT().Warn("err", "Unexpected error")
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		mockAuditEventsReader.EXPECT().GetEvents(ctx, gomock.Any()).Return(nil, errors.New("error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "error").Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{})
+		_, err := managementComponent.GetUserHistory(ctx, realmName, userID)
 
 		assert.NotNil(t, err)
 	}
@@ -877,7 +1730,7 @@ func TestGetUsers(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -960,83 +1813,286 @@ func TestGetUsers(t *testing.T) {
 	}
 }
 
+func TestGetUsersAdvancedSearch(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, nil, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var targetRealmName = "DEP"
+	var groupID = "123-456-789"
+	var realmID = "12345"
+	var countries = []string{"CH"}
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+
+	var newUser = func(id, lastName, phoneNumber string, enabled bool) kc.UserRepresentation {
+		var attributes = map[string][]string{"phoneNumber": {phoneNumber}}
+		return kc.UserRepresentation{Id: &id, LastName: &lastName, Enabled: &enabled, Attributes: &attributes}
+	}
+	var users = []kc.UserRepresentation{
+		newUser("1", "Smith", "+41791111111", true),
+		newUser("2", "Doe", "+41792222222", true),
+		newUser("3", "Brown", "+41792222222", false),
+		newUser("4", "Adams", "+41792222222", true),
+	}
+	var count = len(users)
+
+	t.Run("Filter, sort and page", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, targetRealmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{AllowedPhoneNumberCountries: &countries}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "search", "e", "groupId", groupID, "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{Count: &count, Users: users}, nil).Times(1)
+
+		var res, err = managementComponent.GetUsers(ctx, targetRealmName, []string{groupID}, "search", "e", "phoneNumber", "079 222 22 22",
+			"enabled", "true", "sort", "lastName", "first", "1", "max", "10")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, *res.Count)
+		assert.Len(t, res.Users, 1)
+		assert.Equal(t, "Doe", *res.Users[0].LastName)
+	})
+
+	t.Run("Filter on brute force lock", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{Count: &count, Users: users}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetAttackDetectionStatus(accessToken, targetRealmName, "1").Return(map[string]interface{}{"disabled": false}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetAttackDetectionStatus(accessToken, targetRealmName, "2").Return(map[string]interface{}{"disabled": true}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetAttackDetectionStatus(accessToken, targetRealmName, "4").Return(map[string]interface{}{}, nil).Times(1)
+
+		var res, err = managementComponent.GetUsers(ctx, targetRealmName, []string{groupID}, "enabled", "true", "locked", "true")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, *res.Count)
+		assert.Equal(t, "2", *res.Users[0].ID)
+	})
+
+	t.Run("Users are requested by batches", func(t *testing.T) {
+		var total = searchBatchSize + 1
+		var batch = make([]kc.UserRepresentation, searchBatchSize)
+		for i := range batch {
+			batch[i] = users[0]
+		}
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{Count: &total, Users: batch}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "500", "max", "500").
+			Return(kc.UsersPageRepresentation{Count: &total, Users: users[1:2]}, nil).Times(1)
+
+		var res, err = managementComponent.GetUsers(ctx, targetRealmName, []string{groupID}, "sort", "lastName", "max", "1")
+		assert.Nil(t, err)
+		assert.Equal(t, total, *res.Count)
+		assert.Equal(t, "Doe", *res.Users[0].LastName)
+	})
+
+	t.Run("Too many users to scan", func(t *testing.T) {
+		var total = maxSearchedUsers + 1
+		var batch = make([]kc.UserRepresentation, searchBatchSize)
+		for i := range batch {
+			batch[i] = users[0]
+		}
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", gomock.Any(), "max", "500").
+			Return(kc.UsersPageRepresentation{Count: &total, Users: batch}, nil).Times(maxSearchedUsers/searchBatchSize + 1)
+
+		var _, err = managementComponent.GetUsers(ctx, targetRealmName, []string{groupID}, "sort", "lastName")
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Too many users to check the brute force lock", func(t *testing.T) {
+		var total = maxLockChecks + 1
+		var batch = make([]kc.UserRepresentation, total)
+		for i := range batch {
+			batch[i] = users[0]
+		}
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{Count: &total, Users: batch}, nil).Times(1)
+
+		var _, err = managementComponent.GetUsers(ctx, targetRealmName, []string{groupID}, "locked", "true")
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Brute force lock without another criteria", func(t *testing.T) {
+		var _, err = managementComponent.GetUsers(ctx, targetRealmName, nil, "enabled", "true", "locked", "true")
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Page too large", func(t *testing.T) {
+		var _, err = managementComponent.GetUsers(ctx, targetRealmName, []string{groupID}, "sort", "lastName", "max", "501")
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Default page size", func(t *testing.T) {
+		var total = defaultSearchPageSize + 1
+		var batch = make([]kc.UserRepresentation, total)
+		for i := range batch {
+			batch[i] = users[0]
+		}
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{Count: &total, Users: batch}, nil).Times(1)
+
+		var res, err = managementComponent.GetUsers(ctx, targetRealmName, []string{groupID}, "sort", "lastName")
+		assert.Nil(t, err)
+		assert.Equal(t, total, *res.Count)
+		assert.Len(t, res.Users, defaultSearchPageSize)
+	})
+
+	t.Run("Realm configuration error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, targetRealmName).Return(kc.RealmRepresentation{}, errors.New("error")).Times(1)
+
+		var _, err = managementComponent.GetUsers(ctx, targetRealmName, []string{groupID}, "phoneNumber", "+41792222222")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{}, errors.New("error")).Times(1)
+
+		var _, err = managementComponent.GetUsers(ctx, targetRealmName, []string{groupID}, "sort", "email")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Attack detection error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{Count: &count, Users: users}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetAttackDetectionStatus(accessToken, targetRealmName, "1").Return(nil, errors.New("error")).Times(1)
+
+		var _, err = managementComponent.GetUsers(ctx, targetRealmName, []string{groupID}, "locked", "false")
+		assert.NotNil(t, err)
+	})
+}
+
 func TestGetUserAccountStatus(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
 	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
 	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockAuditEventsReader = mock.NewAuditEventsReaderModule(mockCtrl)
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockAuditEventsReader, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
-	var realmReq = "master"
 	var realmName = "aRealm"
 	var userID = "789-789-456"
+	var enabled = true
+	var passwordType = "password"
+	var totpType = "totp"
+	var webauthnType = "webauthn"
+	var tenDaysAgo = time.Now().Add(-10*24*time.Hour-time.Hour).UnixNano() / int64(time.Millisecond)
+	var lastLogin = int64(1583400000)
+	var attributes = map[string][]string{"phoneNumberVerified": {"true"}}
+	var requiredActions = []string{"UPDATE_PASSWORD"}
+	var userKc = kc.UserRepresentation{
+		Enabled:         &enabled,
+		EmailVerified:   &enabled,
+		Attributes:      &attributes,
+		RequiredActions: &requiredActions,
+	}
+	var credsKc = []kc.CredentialRepresentation{{Type: &passwordType, CreatedDate: &tenDaysAgo}, {Type: &totpType}}
 
-	// GetUser returns an error
-	{
-		var userRep kc.UserRepresentation
-		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userRep, fmt.Errorf("Unexpected error")).Times(1)
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("GetUser returns an error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kc.UserRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
 		_, err := managementComponent.GetUserAccountStatus(ctx, realmName, userID)
 		assert.NotNil(t, err)
-	}
+	})
 
-	// GetUser returns a non-enabled user
-	{
-		var userRep kc.UserRepresentation
-		enabled := false
-		userRep.Enabled = &enabled
-		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userRep, nil).Times(1)
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		status, err := managementComponent.GetUserAccountStatus(ctx, realmName, userID)
-		assert.Nil(t, err)
-		assert.False(t, status["enabled"])
-	}
-
-	// GetUser returns an enabled user but GetCredentialsForUser fails
-	{
-		var userRep kc.UserRepresentation
-		enabled := true
-		userRep.Enabled = &enabled
-		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userRep, nil).Times(1)
+	t.Run("GetCredentials returns an error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userKc, nil).Times(1)
 		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(nil, fmt.Errorf("Unexpected error")).Times(1)
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		ctx = context.WithValue(ctx, cs.CtContextRealm, realmReq)
 		_, err := managementComponent.GetUserAccountStatus(ctx, realmName, userID)
 		assert.NotNil(t, err)
-	}
+	})
 
-	// GetUser returns an enabled user but GetCredentialsForUser have no credential
-	{
-		var userRep kc.UserRepresentation
-		enabled := true
-		userRep.Enabled = &enabled
-		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userRep, nil).Times(1)
-		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return([]kc.CredentialRepresentation{}, nil).Times(1)
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		ctx = context.WithValue(ctx, cs.CtContextRealm, realmReq)
+	t.Run("GetAttackDetectionStatus returns an error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetAttackDetectionStatus(accessToken, realmName, userID).Return(nil, fmt.Errorf("Unexpected error")).Times(1)
+		mockAuditEventsReader.EXPECT().GetLastUserConnection(ctx, realmName, userID).Return(lastLogin, nil).Times(1)
 		status, err := managementComponent.GetUserAccountStatus(ctx, realmName, userID)
 		assert.Nil(t, err)
-		assert.False(t, status["enabled"])
-	}
+		assert.Nil(t, status.TemporarilyLocked)
+		assert.Equal(t, lastLogin, *status.LastLogin)
+		assert.True(t, *status.Enabled)
+	})
 
-	// GetUser returns an enabled user and GetCredentialsForUser have credentials
-	{
-		var userRep kc.UserRepresentation
-		var creds1, creds2 kc.CredentialRepresentation
-		enabled := true
-		userRep.Enabled = &enabled
-		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userRep, nil).Times(1)
-		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return([]kc.CredentialRepresentation{creds1, creds2}, nil).Times(1)
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		ctx = context.WithValue(ctx, cs.CtContextRealm, realmReq)
+	t.Run("GetLastUserConnection returns an error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetAttackDetectionStatus(accessToken, realmName, userID).Return(map[string]interface{}{}, nil).Times(1)
+		mockAuditEventsReader.EXPECT().GetLastUserConnection(ctx, realmName, userID).Return(int64(0), fmt.Errorf("Unexpected error")).Times(1)
 		status, err := managementComponent.GetUserAccountStatus(ctx, realmName, userID)
 		assert.Nil(t, err)
-		assert.True(t, status["enabled"])
-	}
+		assert.Nil(t, status.LastLogin)
+		assert.False(t, *status.TemporarilyLocked)
+	})
+
+	t.Run("User with a password and a second factor", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetAttackDetectionStatus(accessToken, realmName, userID).Return(map[string]interface{}{"disabled": false, "numFailures": 1.0}, nil).Times(1)
+		mockAuditEventsReader.EXPECT().GetLastUserConnection(ctx, realmName, userID).Return(lastLogin, nil).Times(1)
+
+		status, err := managementComponent.GetUserAccountStatus(ctx, realmName, userID)
+
+		assert.Nil(t, err)
+		assert.True(t, *status.Enabled)
+		assert.True(t, *status.UserEnabled)
+		assert.True(t, *status.EmailVerified)
+		assert.True(t, *status.PhoneNumberVerified)
+		assert.True(t, *status.MFAConfigured)
+		assert.Equal(t, map[string]bool{"password": true, "otp": true, "webauthn": false, "sms": false}, *status.Credentials)
+		assert.Equal(t, requiredActions, *status.RequiredActions)
+		assert.False(t, *status.TemporarilyLocked)
+		assert.Equal(t, lastLogin, *status.LastLogin)
+		assert.Equal(t, int64(10), *status.PasswordAge)
+	})
+
+	t.Run("Disabled and locked user without password who never logged in", func(t *testing.T) {
+		var disabled = false
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kc.UserRepresentation{Enabled: &disabled}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return([]kc.CredentialRepresentation{{Type: &webauthnType}}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetAttackDetectionStatus(accessToken, realmName, userID).Return(map[string]interface{}{"disabled": true, "numFailures": 5.0}, nil).Times(1)
+		mockAuditEventsReader.EXPECT().GetLastUserConnection(ctx, realmName, userID).Return(int64(0), nil).Times(1)
+
+		status, err := managementComponent.GetUserAccountStatus(ctx, realmName, userID)
+
+		assert.Nil(t, err)
+		assert.False(t, *status.Enabled)
+		assert.False(t, *status.UserEnabled)
+		assert.False(t, *status.EmailVerified)
+		assert.False(t, *status.PhoneNumberVerified)
+		assert.True(t, *status.MFAConfigured)
+		assert.False(t, (*status.Credentials)["password"])
+		assert.True(t, (*status.Credentials)["webauthn"])
+		assert.Len(t, *status.RequiredActions, 0)
+		assert.True(t, *status.TemporarilyLocked)
+		assert.Nil(t, status.LastLogin)
+		assert.Nil(t, status.PasswordAge)
+	})
+
+	t.Run("User without second factor", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return([]kc.CredentialRepresentation{{Type: &passwordType}}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetAttackDetectionStatus(accessToken, realmName, userID).Return(map[string]interface{}{}, nil).Times(1)
+		mockAuditEventsReader.EXPECT().GetLastUserConnection(ctx, realmName, userID).Return(lastLogin, nil).Times(1)
+
+		status, err := managementComponent.GetUserAccountStatus(ctx, realmName, userID)
+
+		assert.Nil(t, err)
+		assert.False(t, *status.Enabled)
+		assert.True(t, *status.UserEnabled)
+		assert.False(t, *status.MFAConfigured)
+		assert.True(t, (*status.Credentials)["password"])
+		assert.False(t, *status.TemporarilyLocked)
+	})
 }
 
 func TestGetClientRolesForUser(t *testing.T) {
@@ -1047,7 +2103,7 @@ func TestGetClientRolesForUser(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1111,7 +2167,7 @@ func TestAddClientRolesToUser(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1189,7 +2245,7 @@ func TestGetRolesOfUser(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1252,7 +2308,7 @@ func TestGetGroupsOfUser(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1303,14 +2359,17 @@ func TestResetPassword(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
+	var realmID = "master_id"
 	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
 	var password = "P@ssw0rd"
 	var typePassword = "password"
 	var username = "username"
+	var kcRealmNoPolicy = kc.RealmRepresentation{Id: &realmID}
+	var missingConfigErr = keycloakb.MissingRealmConfigurationErr{}
 
 	// Change password
 	{
@@ -1319,6 +2378,8 @@ func TestResetPassword(t *testing.T) {
 			Value: &password,
 		}
 
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmNoPolicy, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, missingConfigErr).Times(1)
 		mockKeycloakClient.EXPECT().ResetPassword(accessToken, realmName, userID, kcCredRep).Return(nil).Times(1)
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
@@ -1342,6 +2403,8 @@ func TestResetPassword(t *testing.T) {
 			Value: &password,
 		}
 
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmNoPolicy, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, missingConfigErr).Times(1)
 		mockKeycloakClient.EXPECT().ResetPassword(accessToken, realmName, userID, kcCredRep).Return(nil).Times(1)
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
@@ -1363,15 +2426,14 @@ func TestResetPassword(t *testing.T) {
 
 	// No password offered
 	{
-		var id = "master_id"
 		var keycloakVersion = "4.8.3"
 		var realm = "master"
 		var displayName = "Master"
 		var enabled = true
 
-		var policy = "forceExpiredPasswordChange(365) and specialChars(1) and upperCase(1) and lowerCase(1) and length(4) and digits(1) and notUsername(undefined)"
+		var policy = "forceExpiredPasswordChange(365) and specialChars(1) and upperCase(2) and lowerCase(1) and length(12) and digits(1) and notUsername(undefined)"
 		var kcRealmRep = kc.RealmRepresentation{
-			Id:              &id,
+			Id:              &realmID,
 			KeycloakVersion: &keycloakVersion,
 			Realm:           &realm,
 			DisplayName:     &displayName,
@@ -1379,53 +2441,207 @@ func TestResetPassword(t *testing.T) {
 			PasswordPolicy:  &policy,
 		}
 
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kc.UserRepresentation{Username: &username}, nil).Times(1)
+		mockKeycloakClient.EXPECT().ResetPassword(accessToken, realmName, userID, gomock.Any()).Return(nil).Times(1)
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "INIT_PASSWORD", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		var passwordRep = api.PasswordRepresentation{
+			Value: nil,
+		}
+
+		pwd, err := managementComponent.ResetPassword(ctx, "master", userID, passwordRep)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, pwd)
+		assert.Len(t, pwd, 12)
+		parsedPolicy, _ := keycloakb.ParsePasswordPolicy(policy)
+		assert.Nil(t, parsedPolicy.Validate(pwd, username, ""))
+	}
+
+	// No password offered, no keycloak policy
+	{
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmNoPolicy, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, missingConfigErr).Times(1)
+		mockKeycloakClient.EXPECT().ResetPassword(accessToken, realmName, userID, gomock.Any()).Return(nil).Times(1)
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "INIT_PASSWORD", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		var passwordRep = api.PasswordRepresentation{
+			Value: nil,
+		}
+
+		pwd, err := managementComponent.ResetPassword(ctx, "master", userID, passwordRep)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, pwd)
+	}
+
+	// Policy of the realm overridden by the configuration
+	{
+		var kcPolicy = "length(4)"
+		var configPolicy = "length(20) and digits(3)"
+		var kcRealmRep = kc.RealmRepresentation{Id: &realmID, PasswordPolicy: &kcPolicy}
+
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{PasswordPolicy: &configPolicy}, nil).Times(1)
+		mockKeycloakClient.EXPECT().ResetPassword(accessToken, realmName, userID, gomock.Any()).Return(nil).Times(1)
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "INIT_PASSWORD", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		pwd, err := managementComponent.ResetPassword(ctx, "master", userID, api.PasswordRepresentation{})
+
+		assert.Nil(t, err)
+		assert.Len(t, pwd, 20)
+	}
+
+	// Password offered by the agent does not comply with the policy
+	{
+		var policy = "length(12)"
+		var kcRealmRep = kc.RealmRepresentation{Id: &realmID, PasswordPolicy: &policy}
+
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, missingConfigErr).Times(1)
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		_, err := managementComponent.ResetPassword(ctx, "master", userID, api.PasswordRepresentation{Value: &password})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	}
+
+	// Password offered by the agent is the username
+	{
+		var policy = "notUsername(undefined)"
+		var kcRealmRep = kc.RealmRepresentation{Id: &realmID, PasswordPolicy: &policy}
+		var usernamePassword = "UserName"
+
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, missingConfigErr).Times(1)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kc.UserRepresentation{Username: &username}, nil).Times(1)
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		_, err := managementComponent.ResetPassword(ctx, "master", userID, api.PasswordRepresentation{Value: &usernamePassword})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	}
+
+	// Password offered by the agent is breached
+	{
+		var breachedCheck = true
+		var mockBreachedPasswordChecker = mock.NewBreachedPasswordChecker(mockCtrl)
+		var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, mockBreachedPasswordChecker, nil, nil, mockLogger)
+
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmNoPolicy, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{BreachedPasswordCheck: &breachedCheck}, nil).Times(1)
+		mockBreachedPasswordChecker.EXPECT().IsBreached(gomock.Any(), password).Return(true, nil).Times(1)
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		_, err := managementComponent.ResetPassword(ctx, "master", userID, api.PasswordRepresentation{Value: &password})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+		assert.Contains(t, err.Error(), keycloakb.MsgErrBreachedPassword+"."+keycloakb.Password)
+	}
+
+	// Password offered by the agent is not breached
+	{
+		var breachedCheck = true
+		var mockBreachedPasswordChecker = mock.NewBreachedPasswordChecker(mockCtrl)
+		var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, mockBreachedPasswordChecker, nil, nil, mockLogger)
+
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmNoPolicy, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{BreachedPasswordCheck: &breachedCheck}, nil).Times(1)
+		mockBreachedPasswordChecker.EXPECT().IsBreached(gomock.Any(), password).Return(false, nil).Times(1)
 		mockKeycloakClient.EXPECT().ResetPassword(accessToken, realmName, userID, gomock.Any()).Return(nil).Times(1)
-		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).AnyTimes()
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
-		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
-
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "INIT_PASSWORD", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 
-		var passwordRep = api.PasswordRepresentation{
-			Value: nil,
-		}
-
-		pwd, err := managementComponent.ResetPassword(ctx, "master", userID, passwordRep)
+		_, err := managementComponent.ResetPassword(ctx, "master", userID, api.PasswordRepresentation{Value: &password})
 
 		assert.Nil(t, err)
-		assert.NotNil(t, pwd)
 	}
 
-	// No password offered, no keycloak policy
+	// Breached passwords database can't be read
 	{
-		var id = "master_id"
+		var breachedCheck = true
+		var mockBreachedPasswordChecker = mock.NewBreachedPasswordChecker(mockCtrl)
+		var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, mockBreachedPasswordChecker, nil, nil, mockLogger)
 
-		var kcRealmRep = kc.RealmRepresentation{
-			Id: &id,
-		}
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmNoPolicy, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{BreachedPasswordCheck: &breachedCheck}, nil).Times(1)
+		mockBreachedPasswordChecker.EXPECT().IsBreached(gomock.Any(), password).Return(false, errors.New("IO error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "IO error")
 
-		mockKeycloakClient.EXPECT().ResetPassword(accessToken, realmName, userID, gomock.Any()).Return(nil).Times(1)
-		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).AnyTimes()
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		_, err := managementComponent.ResetPassword(ctx, "master", userID, api.PasswordRepresentation{Value: &password})
+
+		assert.NotNil(t, err)
+	}
+
+	// Error while getting the realm
+	{
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "Unexpected error")
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
-		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
-		mockEventDBModule.EXPECT().ReportEvent(ctx, "INIT_PASSWORD", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		_, err := managementComponent.ResetPassword(ctx, "master", userID, api.PasswordRepresentation{Value: &password})
 
-		var passwordRep = api.PasswordRepresentation{
-			Value: nil,
-		}
+		assert.NotNil(t, err)
+	}
 
-		pwd, err := managementComponent.ResetPassword(ctx, "master", userID, passwordRep)
+	// Error while getting the configuration
+	{
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmNoPolicy, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, fmt.Errorf("DB error")).Times(1)
+		mockLogger.EXPECT().Error("err", "DB error")
 
-		assert.Nil(t, err)
-		assert.NotNil(t, pwd)
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		_, err := managementComponent.ResetPassword(ctx, "master", userID, api.PasswordRepresentation{Value: &password})
+
+		assert.NotNil(t, err)
+	}
+
+	// Invalid password policy
+	{
+		var policy = "length(abc)"
+		var kcRealmRep = kc.RealmRepresentation{Id: &realmID, PasswordPolicy: &policy}
+
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, missingConfigErr).Times(1)
+		mockLogger.EXPECT().Warn("err", gomock.Any())
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		_, err := managementComponent.ResetPassword(ctx, "master", userID, api.PasswordRepresentation{Value: &password})
+
+		assert.NotNil(t, err)
 	}
+
 	// Error
 	{
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmNoPolicy, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(gomock.Any(), realmID).Return(dto.RealmConfiguration{}, missingConfigErr).Times(1)
 		mockKeycloakClient.EXPECT().ResetPassword(accessToken, realmName, userID, gomock.Any()).Return(fmt.Errorf("Invalid input")).Times(1)
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
@@ -1449,7 +2665,7 @@ func TestSendVerifyEmail(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1492,7 +2708,7 @@ func TestExecuteActionsEmail(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1538,7 +2754,7 @@ func TestSendNewEnrolmentCode(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1594,7 +2810,7 @@ func TestSendReminderEmail(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1639,7 +2855,7 @@ func TestGetCredentialsForUser(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 	var accessToken = "TOKEN=="
 	var realmReq = "master"
 	var realmName = "otherRealm"
@@ -1666,12 +2882,13 @@ func TestDeleteCredentialsForUser(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 	var accessToken = "TOKEN=="
 	var realmReq = "master"
 	var realmName = "master"
 	var userID = "1245-7854-8963"
 	var credential = "987-654-321"
+	var realmID = "master_id"
 
 	// Delete credentials for user
 	{
@@ -1689,14 +2906,13 @@ func TestDeleteCredentialsForUser(t *testing.T) {
 	{
 		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return([]kc.CredentialRepresentation{}, errors.New("error")).Times(1)
 		mockLogger.EXPECT().Warn("msg", "Could not obtain list of credentials", "err", "error")
-		mockKeycloakClient.EXPECT().DeleteCredential(accessToken, realmName, userID, credential).Return(nil).Times(1)
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 		ctx = context.WithValue(ctx, cs.CtContextRealm, realmReq)
 
 		err := managementComponent.DeleteCredentialsForUser(ctx, realmName, userID, credential)
 
-		assert.Nil(t, err)
+		assert.NotNil(t, err)
 
 	}
 	// Delete credentials for user - error at deleting the credential
@@ -1729,56 +2945,385 @@ func TestDeleteCredentialsForUser(t *testing.T) {
 			Type: &totp,
 		}
 
-		var credsKc []kc.CredentialRepresentation
-		credsKc = append(credsKc, credKcPwd)
-		credsKc = append(credsKc, credKcOtp)
+		var credsKc []kc.CredentialRepresentation
+		credsKc = append(credsKc, credKcPwd)
+		credsKc = append(credsKc, credKcOtp)
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		ctx = context.WithValue(ctx, cs.CtContextRealm, realmReq)
+
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{}, keycloakb.MissingRealmConfigurationErr{}).Times(1)
+		mockKeycloakClient.EXPECT().DeleteCredential(accessToken, realmName, userID, otpId).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "2ND_FACTOR_REMOVED", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		err := managementComponent.DeleteCredentialsForUser(ctx, realmName, userID, otpId)
+
+		assert.Nil(t, err)
+	}
+	// Delete credentials for user - error at storing the event
+	{
+
+		pwdId := "51389847-08f4-4a0f-9f9c-694554e626f2"
+		pwd := "password"
+		var credKcPwd = kc.CredentialRepresentation{
+			Id:   &pwdId,
+			Type: &pwd,
+		}
+
+		otpId := "51389847-08f4-4a0f-9f9c-694554e626f3"
+		totp := "totp"
+		var credKcOtp = kc.CredentialRepresentation{
+			Id:   &otpId,
+			Type: &totp,
+		}
+
+		var credsKc []kc.CredentialRepresentation
+		credsKc = append(credsKc, credKcPwd)
+		credsKc = append(credsKc, credKcOtp)
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		ctx = context.WithValue(ctx, cs.CtContextRealm, realmReq)
+
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{}, keycloakb.MissingRealmConfigurationErr{}).Times(1)
+		mockKeycloakClient.EXPECT().DeleteCredential(accessToken, realmName, userID, otpId).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "2ND_FACTOR_REMOVED", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("error")).Times(1)
+		m := map[string]interface{}{"event_name": "2ND_FACTOR_REMOVED", database.CtEventRealmName: realmName, database.CtEventUserID: userID}
+		eventJSON, _ := json.Marshal(m)
+		mockLogger.EXPECT().Error("err", "error", "event", string(eventJSON))
+		err := managementComponent.DeleteCredentialsForUser(ctx, realmName, userID, otpId)
+
+		assert.Nil(t, err)
+	}
+
+	// Delete the last second factor when the realm requires MFA
+	{
+		pwdID := "51389847-08f4-4a0f-9f9c-694554e626f2"
+		pwd := "password"
+		otpID := "51389847-08f4-4a0f-9f9c-694554e626f3"
+		totp := "totp"
+		var credsKc = []kc.CredentialRepresentation{{Id: &pwdID, Type: &pwd}, {Id: &otpID, Type: &totp}}
+		var mfaRequired = true
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{MFARequired: &mfaRequired}, nil).Times(1)
+
+		err := managementComponent.DeleteCredentialsForUser(ctx, realmName, userID, otpID)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 409, err.(commonhttp.Error).Status)
+	}
+	// Delete a second factor which is not the last one
+	{
+		otpID := "51389847-08f4-4a0f-9f9c-694554e626f3"
+		totp := "totp"
+		webauthnID := "51389847-08f4-4a0f-9f9c-694554e626f4"
+		webauthn := "webauthn"
+		var credsKc = []kc.CredentialRepresentation{{Id: &otpID, Type: &totp}, {Id: &webauthnID, Type: &webauthn}}
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().DeleteCredential(accessToken, realmName, userID, otpID).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "2ND_FACTOR_REMOVED", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		err := managementComponent.DeleteCredentialsForUser(ctx, realmName, userID, otpID)
+
+		assert.Nil(t, err)
+	}
+	// Error while getting the configuration of the realm
+	{
+		otpID := "51389847-08f4-4a0f-9f9c-694554e626f3"
+		totp := "totp"
+		var credsKc = []kc.CredentialRepresentation{{Id: &otpID, Type: &totp}}
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{}, errors.New("DB error")).Times(1)
+		mockLogger.EXPECT().Error("err", "DB error")
+
+		err := managementComponent.DeleteCredentialsForUser(ctx, realmName, userID, otpID)
+
+		assert.NotNil(t, err)
+	}
+	// Error while getting the realm
+	{
+		otpID := "51389847-08f4-4a0f-9f9c-694554e626f3"
+		totp := "totp"
+		var credsKc = []kc.CredentialRepresentation{{Id: &otpID, Type: &totp}}
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error")
+
+		err := managementComponent.DeleteCredentialsForUser(ctx, realmName, userID, otpID)
+
+		assert.NotNil(t, err)
+	}
+
+}
+
+func TestResetCredentialsForUser(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var realmID = "master_id"
+	var userID = "1245-7854-8963"
+	var mfaRequired = true
+
+	var pwdID = "51389847-08f4-4a0f-9f9c-694554e626f2"
+	var otpID1 = "51389847-08f4-4a0f-9f9c-694554e626f3"
+	var otpID2 = "51389847-08f4-4a0f-9f9c-694554e626f4"
+	var webauthnID = "51389847-08f4-4a0f-9f9c-694554e626f5"
+	var pwd = "password"
+	var totp = "totp"
+	var hotp = "hotp"
+	var webauthn = "webauthn"
+	var credPwd = kc.CredentialRepresentation{Id: &pwdID, Type: &pwd}
+	var credOtp1 = kc.CredentialRepresentation{Id: &otpID1, Type: &totp}
+	var credOtp2 = kc.CredentialRepresentation{Id: &otpID2, Type: &hotp}
+	var credWebauthn = kc.CredentialRepresentation{Id: &webauthnID, Type: &webauthn}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Reset OTP credentials", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return([]kc.CredentialRepresentation{credPwd, credOtp1, credOtp2, credWebauthn}, nil).Times(1)
+		mockKeycloakClient.EXPECT().DeleteCredential(accessToken, realmName, userID, otpID1).Return(nil).Times(1)
+		mockKeycloakClient.EXPECT().DeleteCredential(accessToken, realmName, userID, otpID2).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "2ND_FACTOR_REMOVED", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID).Return(nil).Times(2)
+
+		err := managementComponent.ResetCredentialsForUser(ctx, realmName, userID, keycloakb.CredentialCategoryOTP)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("No credential of the category", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return([]kc.CredentialRepresentation{credPwd, credOtp1}, nil).Times(1)
+
+		err := managementComponent.ResetCredentialsForUser(ctx, realmName, userID, keycloakb.CredentialCategorySMS)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Last second factors when the realm requires MFA", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return([]kc.CredentialRepresentation{credPwd, credOtp1, credOtp2}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{MFARequired: &mfaRequired}, nil).Times(1)
+
+		err := managementComponent.ResetCredentialsForUser(ctx, realmName, userID, keycloakb.CredentialCategoryOTP)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 409, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Last second factors when the realm does not require MFA", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return([]kc.CredentialRepresentation{credPwd, credWebauthn}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{Id: &realmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{}, nil).Times(1)
+		mockKeycloakClient.EXPECT().DeleteCredential(accessToken, realmName, userID, webauthnID).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "2ND_FACTOR_REMOVED", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID).Return(nil).Times(1)
+
+		err := managementComponent.ResetCredentialsForUser(ctx, realmName, userID, keycloakb.CredentialCategoryWebAuthn)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Password can't be reset", func(t *testing.T) {
+		err := managementComponent.ResetCredentialsForUser(ctx, realmName, userID, keycloakb.CredentialCategoryPassword)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Error while getting the credentials", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(nil, errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		err := managementComponent.ResetCredentialsForUser(ctx, realmName, userID, keycloakb.CredentialCategoryOTP)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Error while deleting a credential", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return([]kc.CredentialRepresentation{credPwd, credOtp1, credWebauthn}, nil).Times(1)
+		mockKeycloakClient.EXPECT().DeleteCredential(accessToken, realmName, userID, otpID1).Return(errors.New("KC error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "KC error").Times(1)
+
+		err := managementComponent.ResetCredentialsForUser(ctx, realmName, userID, keycloakb.CredentialCategoryOTP)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestGetUserSessions(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var sessionID = "51dbf4a8-32a9-4000-8c17-edc854c31231"
+	var ipAddress = "127.0.0.1"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	// Get sessions with success
+	{
+		var sessionsKc = []kc.UserSessionRepresentation{
+			{Id: &sessionID, UserId: &userID, IpAddress: &ipAddress, Clients: &map[string]string{"123": "backoffice", "456": "account"}},
+		}
+		mockKeycloakClient.EXPECT().GetSessionsForUser(accessToken, realmName, userID).Return(sessionsKc, nil).Times(1)
+
+		sessions, err := managementComponent.GetUserSessions(ctx, realmName, userID)
+
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, sessionID, *sessions[0].ID)
+		assert.Equal(t, ipAddress, *sessions[0].IPAddress)
+		assert.Equal(t, []string{"account", "backoffice"}, *sessions[0].Clients)
+	}
+
+	// Error from KC client
+	{
+		mockKeycloakClient.EXPECT().GetSessionsForUser(accessToken, realmName, userID).Return(nil, fmt.Errorf("Unexpected error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "Unexpected error").Times(1)
+
+		_, err := managementComponent.GetUserSessions(ctx, realmName, userID)
+
+		assert.NotNil(t, err)
+	}
+}
+
+func TestLogoutUser(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	// Logout with success
+	{
+		mockKeycloakClient.EXPECT().LogoutUser(accessToken, realmName, userID).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "SESSIONS_REVOKED", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventAdditionalInfo, `{"sessionId":"*"}`).Return(nil).Times(1)
+
+		err := managementComponent.LogoutUser(ctx, realmName, userID)
+
+		assert.Nil(t, err)
+	}
+
+	// Logout with success but with error when storing the event in the DB
+	{
+		mockKeycloakClient.EXPECT().LogoutUser(accessToken, realmName, userID).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "SESSIONS_REVOKED", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("error")).Times(1)
+		m := map[string]interface{}{"event_name": "SESSIONS_REVOKED", database.CtEventRealmName: realmName, database.CtEventUserID: userID, database.CtEventAdditionalInfo: `{"sessionId":"*"}`}
+		eventJSON, _ := json.Marshal(m)
+		mockLogger.EXPECT().Error("err", "error", "event", string(eventJSON)).Times(1)
+
+		err := managementComponent.LogoutUser(ctx, realmName, userID)
+
+		assert.Nil(t, err)
+	}
+
+	// Error from KC client
+	{
+		mockKeycloakClient.EXPECT().LogoutUser(accessToken, realmName, userID).Return(fmt.Errorf("Unexpected error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "Unexpected error").Times(1)
+
+		err := managementComponent.LogoutUser(ctx, realmName, userID)
+
+		assert.NotNil(t, err)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		ctx = context.WithValue(ctx, cs.CtContextRealm, realmReq)
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var sessionID = "51dbf4a8-32a9-4000-8c17-edc854c31231"
+	var otherSessionID = "61dbf4a8-32a9-4000-8c17-edc854c31231"
+	var sessionsKc = []kc.UserSessionRepresentation{{Id: &sessionID}}
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 
-		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
-		mockKeycloakClient.EXPECT().DeleteCredential(accessToken, realmName, userID, otpId).Return(nil).Times(1)
-		mockEventDBModule.EXPECT().ReportEvent(ctx, "2ND_FACTOR_REMOVED", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	// Revoke with success
+	{
+		mockKeycloakClient.EXPECT().GetSessionsForUser(accessToken, realmName, userID).Return(sessionsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().DeleteSession(accessToken, realmName, sessionID).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "SESSIONS_REVOKED", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventAdditionalInfo, `{"sessionId":"`+sessionID+`"}`).Return(nil).Times(1)
 
-		err := managementComponent.DeleteCredentialsForUser(ctx, realmName, userID, otpId)
+		err := managementComponent.RevokeSession(ctx, realmName, userID, sessionID)
 
 		assert.Nil(t, err)
 	}
-	// Delete credentials for user - error at storing the event
-	{
 
-		pwdId := "51389847-08f4-4a0f-9f9c-694554e626f2"
-		pwd := "password"
-		var credKcPwd = kc.CredentialRepresentation{
-			Id:   &pwdId,
-			Type: &pwd,
-		}
+	// Session of another user
+	{
+		mockKeycloakClient.EXPECT().GetSessionsForUser(accessToken, realmName, userID).Return(sessionsKc, nil).Times(1)
 
-		otpId := "51389847-08f4-4a0f-9f9c-694554e626f3"
-		totp := "totp"
-		var credKcOtp = kc.CredentialRepresentation{
-			Id:   &otpId,
-			Type: &totp,
-		}
+		err := managementComponent.RevokeSession(ctx, realmName, userID, otherSessionID)
 
-		var credsKc []kc.CredentialRepresentation
-		credsKc = append(credsKc, credKcPwd)
-		credsKc = append(credsKc, credKcOtp)
+		assert.NotNil(t, err)
+		assert.Equal(t, 404, err.(commonhttp.Error).Status)
+	}
 
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		ctx = context.WithValue(ctx, cs.CtContextRealm, realmReq)
+	// Error while getting the sessions
+	{
+		mockKeycloakClient.EXPECT().GetSessionsForUser(accessToken, realmName, userID).Return(nil, fmt.Errorf("Unexpected error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "Unexpected error").Times(1)
 
-		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(credsKc, nil).Times(1)
-		mockKeycloakClient.EXPECT().DeleteCredential(accessToken, realmName, userID, otpId).Return(nil).Times(1)
-		mockEventDBModule.EXPECT().ReportEvent(ctx, "2ND_FACTOR_REMOVED", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("error")).Times(1)
-		m := map[string]interface{}{"event_name": "2ND_FACTOR_REMOVED", database.CtEventRealmName: realmName, database.CtEventUserID: userID}
-		eventJSON, _ := json.Marshal(m)
-		mockLogger.EXPECT().Error("err", "error", "event", string(eventJSON))
-		err := managementComponent.DeleteCredentialsForUser(ctx, realmName, userID, otpId)
+		err := managementComponent.RevokeSession(ctx, realmName, userID, sessionID)
 
-		assert.Nil(t, err)
+		assert.NotNil(t, err)
 	}
 
+	// Error while deleting the session
+	{
+		mockKeycloakClient.EXPECT().GetSessionsForUser(accessToken, realmName, userID).Return(sessionsKc, nil).Times(1)
+		mockKeycloakClient.EXPECT().DeleteSession(accessToken, realmName, sessionID).Return(fmt.Errorf("Unexpected error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "Unexpected error").Times(1)
+
+		err := managementComponent.RevokeSession(ctx, realmName, userID, sessionID)
+
+		assert.NotNil(t, err)
+	}
 }
 
 func TestGetRoles(t *testing.T) {
@@ -1789,7 +3334,7 @@ func TestGetRoles(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1851,7 +3396,7 @@ func TestGetRole(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1910,7 +3455,7 @@ func TestGetGroups(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1977,7 +3522,7 @@ func TestGetClientRoles(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -2040,7 +3585,7 @@ func TestCreateClientRole(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -2105,7 +3650,7 @@ func TestGetRealmCustomConfiguration(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmID = "master_id"
@@ -2234,7 +3779,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, mockConfigurationDBModule, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmID = "master_id"
@@ -2283,8 +3828,8 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 	{
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmID).Return(kcRealmRep, nil).Times(1)
 		mockKeycloakClient.EXPECT().GetClients(accessToken, realmID).Return(clients, nil).Times(1)
-		mockConfigurationDBModule.EXPECT().StoreOrUpdate(ctx, realmID, gomock.Any()).Return(nil).Times(1)
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdate(ctx, realmID, gomock.Any(), gomock.Any(), "comment").Return(nil).Times(1)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "comment")
 
 		assert.Nil(t, err)
 	}
@@ -2300,7 +3845,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 			DefaultClientID:    &clientID,
 			DefaultRedirectURI: &redirectURI,
 		}
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "comment")
 
 		assert.NotNil(t, err)
 		assert.IsType(t, commonhttp.Error{}, err)
@@ -2319,7 +3864,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 			DefaultClientID:    &clientID,
 			DefaultRedirectURI: &redirectURI,
 		}
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "comment")
 
 		assert.NotNil(t, err)
 		assert.IsType(t, commonhttp.Error{}, err)
@@ -2338,7 +3883,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 			DefaultClientID:    &clientID,
 			DefaultRedirectURI: &redirectURI,
 		}
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "comment")
 
 		assert.NotNil(t, err)
 		assert.IsType(t, commonhttp.Error{}, err)
@@ -2350,7 +3895,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 	{
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmID).Return(kcRealmRep, nil).Times(1)
 		mockKeycloakClient.EXPECT().GetClients(accessToken, realmID).Return([]kc.ClientRepresentation{}, errors.New("error")).Times(1)
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "comment")
 
 		assert.NotNil(t, err)
 	}
@@ -2358,8 +3903,439 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 	// error while calling GetRealm
 	{
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmID).Return(kc.RealmRepresentation{}, errors.New("error")).Times(1)
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "comment")
+
+		assert.NotNil(t, err)
+	}
+}
+
+func TestPatchRealmCustomConfiguration(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var realmID = "master_id"
+	var kcRealmRep = kc.RealmRepresentation{Id: &realmID}
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	var clientID = "clientID1"
+	var redirectURIs = []string{"https://www.cloudtrust.io/*"}
+	var clients = []kc.ClientRepresentation{{ClientId: &clientID, RedirectUris: &redirectURIs}}
+	var redirectURI = "https://www.cloudtrust.io/test"
+	var policy = "length(8)"
+	var enabled = true
+	var disabled = false
+	var currentConfig = dto.RealmConfiguration{
+		DefaultClientID:    &clientID,
+		DefaultRedirectURI: &redirectURI,
+		PasswordPolicy:     &policy,
+		MFARequired:        &disabled,
+		ShowPasswordTab:    &enabled,
+	}
+
+	t.Run("Fields which are not part of the patch are kept", func(t *testing.T) {
+		var expected = currentConfig
+		expected.MFARequired = &enabled
+		expected.PasswordPolicy = nil
+		expected.UserAttributes = &[]dto.AttributeDefinition{}
+
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(currentConfig, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmName).Return(clients, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdate(ctx, realmID, expected, gomock.Any(), "comment").Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "REALM_CONFIGURATION_PATCH", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventAdditionalInfo, `{"changed_keys":["mfa_required","password_policy"]}`).Return(nil).Times(1)
+
+		var err = managementComponent.PatchRealmCustomConfiguration(ctx, realmName, []byte(`{"mfa_required":true,"password_policy":null}`), "comment")
+		assert.Nil(t, err)
+	})
+
+	t.Run("Nothing changed", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(currentConfig, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmName).Return(clients, nil).Times(1)
+
+		var err = managementComponent.PatchRealmCustomConfiguration(ctx, realmName, []byte(`{"mfa_required":false}`), "comment")
+		assert.Nil(t, err)
+	})
+
+	t.Run("Patch a realm without configuration", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{}, keycloakb.MissingRealmConfigurationErr{}).Times(1)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmName).Return(clients, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdate(ctx, realmID, gomock.Any(), gomock.Any(), "").Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "REALM_CONFIGURATION_PATCH", "back-office", gomock.Any()).Return(errors.New("db error")).Times(1)
+
+		var err = managementComponent.PatchRealmCustomConfiguration(ctx, realmName, []byte(`{"show_password_tab":true}`), "")
+		assert.Nil(t, err)
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(currentConfig, nil).Times(1)
+
+		var err = managementComponent.PatchRealmCustomConfiguration(ctx, realmName, []byte(`{"mfa_requried":true}`), "comment")
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Invalid value", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(currentConfig, nil).Times(1)
+
+		var err = managementComponent.PatchRealmCustomConfiguration(ctx, realmName, []byte(`{"mfa_required":"yes"}`), "comment")
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Removing the default client only is not allowed", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(currentConfig, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmName).Return(clients, nil).Times(1)
+
+		var err = managementComponent.PatchRealmCustomConfiguration(ctx, realmName, []byte(`{"default_client_id":null}`), "comment")
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{}, errors.New("db error")).Times(1)
+
+		var err = managementComponent.PatchRealmCustomConfiguration(ctx, realmName, []byte(`{}`), "comment")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("GetRealm fails", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, errors.New("error")).Times(1)
+
+		var err = managementComponent.PatchRealmCustomConfiguration(ctx, realmName, []byte(`{}`), "comment")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestGetRealmCustomConfigurationHistory(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, nil, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var realmID = "master_id"
+	var kcRealmRep = kc.RealmRepresentation{Id: &realmID}
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Get history", func(t *testing.T) {
+		var versions = []dto.RealmConfigurationVersion{
+			{Version: 2, Author: "master/admin", Comment: "enable MFA", Time: 1234},
+			{Version: 1, Author: "master/admin", Time: 1200},
+		}
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationHistory(ctx, realmID).Return(versions, nil).Times(1)
+
+		var res, err = managementComponent.GetRealmCustomConfigurationHistory(ctx, realmName)
+		assert.Nil(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, 2, res[0].Version)
+		assert.Equal(t, "master/admin", *res[0].Author)
+		assert.Equal(t, "enable MFA", *res[0].Comment)
+		assert.Equal(t, int64(1234), *res[0].Time)
+		assert.Nil(t, res[1].Comment)
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationHistory(ctx, realmID).Return(nil, errors.New("db error")).Times(1)
+
+		var _, err = managementComponent.GetRealmCustomConfigurationHistory(ctx, realmName)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("GetRealm fails", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, errors.New("error")).Times(1)
+
+		var _, err = managementComponent.GetRealmCustomConfigurationHistory(ctx, realmName)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestGetRealmCustomConfigurationDiff(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, nil, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var realmID = "master_id"
+	var kcRealmRep = kc.RealmRepresentation{Id: &realmID}
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	var enabled = true
+	var disabled = false
+	var version1 = dto.RealmConfigurationVersion{Version: 1, Configuration: dto.RealmConfiguration{MFARequired: &disabled}}
+	var version2 = dto.RealmConfigurationVersion{Version: 2, Configuration: dto.RealmConfiguration{MFARequired: &enabled}}
+
+	t.Run("Compare two versions", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationVersion(ctx, realmID, 1).Return(version1, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationVersion(ctx, realmID, 2).Return(version2, nil).Times(1)
+
+		var res, err = managementComponent.GetRealmCustomConfigurationDiff(ctx, realmName, 1, 2)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, "mfa_required", res[0].Field)
+	})
+
+	t.Run("Compare with current configuration", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationVersion(ctx, realmID, 1).Return(version1, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(version1.Configuration, nil).Times(1)
+
+		var res, err = managementComponent.GetRealmCustomConfigurationDiff(ctx, realmName, 1, 0)
+		assert.Nil(t, err)
+		assert.Len(t, res, 0)
+	})
+
+	t.Run("Unknown version", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationVersion(ctx, realmID, 7).Return(dto.RealmConfigurationVersion{}, keycloakb.MissingRealmConfigurationVersionErr{}).Times(1)
+
+		var _, err = managementComponent.GetRealmCustomConfigurationDiff(ctx, realmName, 7, 0)
+		assert.NotNil(t, err)
+		assert.Equal(t, 404, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Current configuration can't be read", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationVersion(ctx, realmID, 1).Return(version1, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(dto.RealmConfiguration{}, errors.New("db error")).Times(1)
+
+		var _, err = managementComponent.GetRealmCustomConfigurationDiff(ctx, realmName, 1, 0)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRollbackRealmCustomConfiguration(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, nil, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var realmID = "master_id"
+	var kcRealmRep = kc.RealmRepresentation{Id: &realmID}
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, "master")
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "admin")
+	var enabled = true
+	var version = dto.RealmConfigurationVersion{Version: 3, Configuration: dto.RealmConfiguration{MFARequired: &enabled}}
+
+	t.Run("Rollback", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationVersion(ctx, realmID, 3).Return(version, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmName).Return([]kc.ClientRepresentation{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdate(ctx, realmID, version.Configuration, "master/admin", "Rollback to version 3").Return(nil).Times(1)
+
+		var err = managementComponent.RollbackRealmCustomConfiguration(ctx, realmName, 3)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Unknown version", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationVersion(ctx, realmID, 9).Return(dto.RealmConfigurationVersion{}, keycloakb.MissingRealmConfigurationVersionErr{}).Times(1)
+
+		var err = managementComponent.RollbackRealmCustomConfiguration(ctx, realmName, 9)
+		assert.NotNil(t, err)
+		assert.Equal(t, 404, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("Default redirection no longer allowed", func(t *testing.T) {
+		var clientID = "self-service"
+		var redirectURI = "https://self-service.com/*"
+		var previousRedirectURI = "https://former-self-service.com/"
+		var redirectVersion = dto.RealmConfigurationVersion{Version: 2, Configuration: dto.RealmConfiguration{DefaultClientID: &clientID, DefaultRedirectURI: &previousRedirectURI}}
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationVersion(ctx, realmID, 2).Return(redirectVersion, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmName).Return([]kc.ClientRepresentation{{ClientId: &clientID, RedirectUris: &[]string{redirectURI}}}, nil).Times(1)
+
+		var err = managementComponent.RollbackRealmCustomConfiguration(ctx, realmName, 2)
+		assert.NotNil(t, err)
+		assert.Equal(t, 400, err.(commonhttp.Error).Status)
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kcRealmRep, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetConfigurationVersion(ctx, realmID, 3).Return(version, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmName).Return([]kc.ClientRepresentation{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdate(ctx, realmID, version.Configuration, "master/admin", "Rollback to version 3").Return(errors.New("db error")).Times(1)
+
+		var err = managementComponent.RollbackRealmCustomConfiguration(ctx, realmName, 3)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestBulkUserAction(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockEventDBModule, nil, mockConfigurationDBModule, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var userID1 = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var userID2 = "52dbf4a8-32a9-4000-8c17-edc854c31232"
+	var userIDs = []string{userID1, userID2}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+
+	// Lock users: one success, one failure
+	{
+		var action = api.BulkActionLock
+		var bulk = api.BulkUserActionRepresentation{Action: &action, UserIDs: &userIDs}
+
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID1).Return(kc.UserRepresentation{Id: &userID1}, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, userID1, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName, id string, kcUserRep kc.UserRepresentation) error {
+				assert.False(t, *kcUserRep.Enabled)
+				return nil
+			}).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "LOCK_ACCOUNT", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID1, database.CtEventUsername, "").Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "USER_UPDATED", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID1, database.CtEventUsername, "", database.CtEventAdditionalInfo, `{"changes":[{"field":"enabled","newValue":"false"}]}`).Return(nil).Times(1)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID2).Return(kc.UserRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "Unexpected error").Times(1)
+
+		results, err := managementComponent.BulkUserAction(ctx, realmName, bulk)
+
+		assert.Nil(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, userID1, *results[0].UserID)
+		assert.Equal(t, api.BulkStatusSuccess, *results[0].Status)
+		assert.Nil(t, results[0].Error)
+		assert.Equal(t, userID2, *results[1].UserID)
+		assert.Equal(t, api.BulkStatusFailure, *results[1].Status)
+		assert.Equal(t, "Unexpected error", *results[1].Error)
+	}
+
+	// Unlock users
+	{
+		var action = api.BulkActionUnlock
+		var bulk = api.BulkUserActionRepresentation{Action: &action, UserIDs: &userIDs}
+
+		for _, userID := range userIDs {
+			var id = userID
+			mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kc.UserRepresentation{Id: &id}, nil).Times(1)
+			mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(nil).Times(1)
+			mockEventDBModule.EXPECT().ReportEvent(ctx, "UNLOCK_ACCOUNT", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, id, database.CtEventUsername, "").Return(nil).Times(1)
+			mockEventDBModule.EXPECT().ReportEvent(ctx, "USER_UPDATED", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, id, database.CtEventUsername, "", database.CtEventAdditionalInfo, gomock.Any()).Return(nil).Times(1)
+		}
+
+		results, err := managementComponent.BulkUserAction(ctx, realmName, bulk)
+
+		assert.Nil(t, err)
+		assert.Len(t, results, 2)
+		for _, result := range results {
+			assert.Equal(t, api.BulkStatusSuccess, *result.Status)
+		}
+	}
+
+	// Delete users selected with a filter
+	{
+		var action = api.BulkActionDelete
+		var groupIDs = []string{"f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"}
+		var search = "toto"
+		var bulk = api.BulkUserActionRepresentation{Action: &action, GroupIDs: &groupIDs, Search: &search}
+		var usersPage = kc.UsersPageRepresentation{Users: []kc.UserRepresentation{{Id: &userID1}}}
+
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, realmName, "search", search, "first", "0", "max", "500", "groupId", groupIDs[0]).Return(usersPage, nil).Times(1)
+		mockKeycloakClient.EXPECT().DeleteUser(accessToken, realmName, userID1).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ACCOUNT_DELETION", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID1).Return(nil).Times(1)
+
+		results, err := managementComponent.BulkUserAction(ctx, realmName, bulk)
+
+		assert.Nil(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, userID1, *results[0].UserID)
+		assert.Equal(t, api.BulkStatusSuccess, *results[0].Status)
+	}
+
+	// Users selected with a filter on several pages
+	{
+		var action = api.BulkActionDelete
+		var groupIDs = []string{"f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"}
+		var bulk = api.BulkUserActionRepresentation{Action: &action, GroupIDs: &groupIDs}
+		var count = searchBatchSize + 1
+		var firstPage = kc.UsersPageRepresentation{Count: &count}
+		for i := 0; i < searchBatchSize; i++ {
+			firstPage.Users = append(firstPage.Users, kc.UserRepresentation{Id: &userID1})
+		}
+		var secondPage = kc.UsersPageRepresentation{Users: []kc.UserRepresentation{{Id: &userID2}}, Count: &count}
+
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, realmName, "first", "0", "max", "500", "groupId", groupIDs[0]).Return(firstPage, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, realmName, "first", "500", "max", "500", "groupId", groupIDs[0]).Return(secondPage, nil).Times(1)
+
+		userIDs, err := getBulkUserIDs(ctx, managementComponent, realmName, bulk)
+
+		assert.Nil(t, err)
+		assert.Len(t, userIDs, count)
+		assert.Equal(t, userID2, userIDs[searchBatchSize])
+	}
+
+	// Filter error
+	{
+		var action = api.BulkActionDelete
+		var groupIDs = []string{"f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"}
+		var bulk = api.BulkUserActionRepresentation{Action: &action, GroupIDs: &groupIDs}
+
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, realmName, "first", "0", "max", "500", "groupId", groupIDs[0]).Return(kc.UsersPageRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
+		mockLogger.EXPECT().Warn("err", "Unexpected error").Times(1)
+
+		_, err := managementComponent.BulkUserAction(ctx, realmName, bulk)
 
 		assert.NotNil(t, err)
 	}
+
+	// Send emails
+	{
+		var executeActionsEmail = api.BulkActionExecuteActionsEmail
+		var requiredActions = []api.RequiredAction{"verify-email"}
+		var bulk = api.BulkUserActionRepresentation{Action: &executeActionsEmail, UserIDs: &userIDs, RequiredActions: &requiredActions}
+
+		for _, userID := range userIDs {
+			mockKeycloakClient.EXPECT().ExecuteActionsEmail(accessToken, realmName, userID, []string{"verify-email"}, "lifespan", "3600").Return(nil).Times(1)
+		}
+
+		results, err := managementComponent.BulkUserAction(ctx, realmName, bulk, "lifespan", "3600")
+		assert.Nil(t, err)
+		assert.Len(t, results, 2)
+
+		var sendReminderEmail = api.BulkActionSendReminderEmail
+		bulk = api.BulkUserActionRepresentation{Action: &sendReminderEmail, UserIDs: &userIDs}
+
+		for _, userID := range userIDs {
+			mockKeycloakClient.EXPECT().SendReminderEmail(accessToken, realmName, userID).Return(nil).Times(1)
+		}
+
+		results, err = managementComponent.BulkUserAction(ctx, realmName, bulk)
+		assert.Nil(t, err)
+		assert.Len(t, results, 2)
+	}
 }
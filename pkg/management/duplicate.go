@@ -0,0 +1,180 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/cloudtrust/common-service/database"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	kc "github.com/cloudtrust/keycloak-client"
+)
+
+// DuplicateUserError is returned when the user to create looks like users already present in the realm.
+// It is rendered as a conflict including the IDs of the existing users.
+type DuplicateUserError struct {
+	CandidateIDs []string
+}
+
+func (e DuplicateUserError) Error() string {
+	return internal.ComponentName + "." + internal.MsgErrDuplicateUser
+}
+
+// duplicateCheck contains the normalized values compared to the ones of the existing users. Empty values are not checked.
+type duplicateCheck struct {
+	email           string
+	emailSearch     string
+	phoneNumber     string
+	firstName       string
+	firstNameSearch string
+	lastName        string
+	lastNameSearch  string
+	birthDate       string
+	countries       []string
+}
+
+// newDuplicateCheck prepares the comparison of the user to create with the existing ones on the given criteria. The phone
+// numbers are compared with the countries allowed by the realm. It returns nil if the user has no value for the enabled criteria.
+func newDuplicateCheck(user api.UserRepresentation, criteria []string, countries []string) *duplicateCheck {
+	var check = duplicateCheck{countries: countries}
+	for _, criterion := range criteria {
+		switch criterion {
+		case api.DuplicateCheckEmail:
+			if user.Email != nil {
+				check.email = normalizeEmail(*user.Email)
+				check.emailSearch = strings.SplitN(check.email, "@", 2)[0]
+			}
+		case api.DuplicateCheckPhoneNumber:
+			if user.PhoneNumber != nil {
+				check.phoneNumber = strings.TrimSpace(*user.PhoneNumber)
+			}
+		case api.DuplicateCheckIdentity:
+			if user.FirstName != nil && user.LastName != nil && user.BirthDate != nil {
+				check.firstName = normalizeName(*user.FirstName)
+				check.firstNameSearch = strings.TrimSpace(*user.FirstName)
+				check.lastName = normalizeName(*user.LastName)
+				check.lastNameSearch = strings.TrimSpace(*user.LastName)
+				check.birthDate = *user.BirthDate
+			}
+		}
+	}
+
+	if check.email == "" && check.phoneNumber == "" && (check.lastName == "" || check.birthDate == "") {
+		return nil
+	}
+	return &check
+}
+
+// searches returns the Keycloak search parameters of the queries returning the potential duplicates: the users whose email
+// contains the local part of the email, the users with the same phone number attribute (phone numbers are stored in the
+// E.164 format) and the users whose last name contains the last name or the first name of the user, as they are
+// sometimes swapped.
+func (d *duplicateCheck) searches() [][]string {
+	var searches [][]string
+	if d.email != "" {
+		searches = append(searches, []string{"email", d.emailSearch})
+	}
+	if d.phoneNumber != "" {
+		searches = append(searches, []string{"q", "phoneNumber:" + d.phoneNumber})
+	}
+	if d.lastName != "" && d.birthDate != "" {
+		searches = append(searches, []string{"lastName", d.lastNameSearch}, []string{"lastName", d.firstNameSearch})
+	}
+	return searches
+}
+
+// matches checks whether an existing user looks like the user to create on one of the criteria
+func (d *duplicateCheck) matches(user api.UserRepresentation) bool {
+	if d.email != "" && user.Email != nil && normalizeEmail(*user.Email) == d.email {
+		return true
+	}
+	if d.phoneNumber != "" && user.PhoneNumber != nil && internal.SamePhoneNumber(*user.PhoneNumber, d.phoneNumber, d.countries) {
+		return true
+	}
+	if d.lastName != "" && d.birthDate != "" && user.FirstName != nil && user.LastName != nil && user.BirthDate != nil && *user.BirthDate == d.birthDate {
+		var firstName, lastName = normalizeName(*user.FirstName), normalizeName(*user.LastName)
+		// first name and last name are sometimes swapped
+		return (firstName == d.firstName && lastName == d.lastName) || (firstName == d.lastName && lastName == d.firstName)
+	}
+	return false
+}
+
+// findDuplicateCandidates returns the IDs of the existing users which look like the user to create on the given criteria.
+// The creation is refused when a search returns more than maxSearchedUsers users as the duplicates can't be detected.
+func (c *component) findDuplicateCandidates(accessToken, ctxRealm, realmName string, user api.UserRepresentation, criteria []string, countries []string) ([]string, error) {
+	var check = newDuplicateCheck(user, criteria, countries)
+	if check == nil {
+		return nil, nil
+	}
+
+	var candidateIDs []string
+	var found = make(map[string]bool)
+	for _, kcParams := range check.searches() {
+		var scanned = 0
+		err := c.forEachUser(accessToken, ctxRealm, realmName, kcParams, func(userKc kc.UserRepresentation) error {
+			if scanned++; scanned > maxSearchedUsers {
+				return errorhandler.Error{
+					Status:  http.StatusConflict,
+					Message: internal.ComponentName + "." + internal.MsgErrDuplicateUser + "." + internal.MsgErrTooManyResults,
+				}
+			}
+			if userKc.Id != nil && !found[*userKc.Id] && check.matches(api.ConvertToAPIUser(userKc)) {
+				found[*userKc.Id] = true
+				candidateIDs = append(candidateIDs, *userKc.Id)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return candidateIDs, nil
+}
+
+// reportDuplicateCreationForced stores in the DB the creation of a user despite the duplicate candidates found
+func (c *component) reportDuplicateCreationForced(ctx context.Context, realmName, userID, username string, candidateIDs []string) {
+	additionalInfo, _ := json.Marshal(map[string][]string{"candidates": candidateIDs})
+
+	var values = []string{database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username, database.CtEventAdditionalInfo, string(additionalInfo)}
+	if err := c.reportEvent(ctx, "API_ACCOUNT_CREATION_DUPLICATE_FORCED", values...); err != nil {
+		internal.LogUnstoredEvent(c.logger, err, "API_ACCOUNT_CREATION_DUPLICATE_FORCED", values...)
+	}
+}
+
+// normalizeEmail lowercases the email and removes the sub-address (john+news@company.com is john@company.com)
+func normalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	var at = strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	var local, domain = email[:at], email[at:]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	return local + domain
+}
+
+var accentsReplacer = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+)
+
+// normalizeName lowercases the name, removes the accents and keeps the letters only (Jean-Marc d'Épée is jeanmarcdepee)
+func normalizeName(name string) string {
+	name = accentsReplacer.Replace(strings.ToLower(name))
+	var res strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			res.WriteRune(r)
+		}
+	}
+	return res.String()
+}
@@ -0,0 +1,66 @@
+package management
+
+import (
+	"testing"
+
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	assert.Equal(t, "john.doe@company.com", normalizeEmail(" John.Doe@Company.com "))
+	assert.Equal(t, "john.doe@company.com", normalizeEmail("john.doe+news@company.com"))
+	assert.Equal(t, "+john@company.com", normalizeEmail("+john@company.com"))
+	assert.Equal(t, "not-an-email", normalizeEmail("Not-An-Email"))
+}
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "jeanmarcdepee", normalizeName("Jean-Marc d'Épée"))
+	assert.Equal(t, "muller", normalizeName("MÜLLER "))
+}
+
+func TestDuplicateCheck(t *testing.T) {
+	var email = "john+work@company.com"
+	var phoneNumber = "+41791234567"
+	var firstName = "John"
+	var lastName = "Doe"
+	var birthDate = "17.02.1990"
+	var user = api.UserRepresentation{Email: &email, PhoneNumber: &phoneNumber, FirstName: &firstName, LastName: &lastName, BirthDate: &birthDate}
+	var countries = []string{"CH"}
+
+	t.Run("Nothing to check", func(t *testing.T) {
+		assert.Nil(t, newDuplicateCheck(user, nil, countries))
+		assert.Nil(t, newDuplicateCheck(api.UserRepresentation{FirstName: &firstName}, []string{api.DuplicateCheckEmail, api.DuplicateCheckIdentity}, countries))
+	})
+
+	t.Run("Searches", func(t *testing.T) {
+		var check = newDuplicateCheck(user, []string{api.DuplicateCheckEmail}, countries)
+		assert.Equal(t, [][]string{{"email", "john"}}, check.searches())
+
+		check = newDuplicateCheck(user, []string{api.DuplicateCheckEmail, api.DuplicateCheckIdentity}, countries)
+		assert.Equal(t, [][]string{{"email", "john"}, {"lastName", "Doe"}, {"lastName", "John"}}, check.searches())
+
+		check = newDuplicateCheck(user, []string{api.DuplicateCheckPhoneNumber}, countries)
+		assert.Equal(t, [][]string{{"q", "phoneNumber:+41791234567"}}, check.searches())
+	})
+
+	t.Run("Matches", func(t *testing.T) {
+		var check = newDuplicateCheck(user, []string{api.DuplicateCheckEmail, api.DuplicateCheckPhoneNumber, api.DuplicateCheckIdentity}, countries)
+		var otherEmail = "JOHN@company.com"
+		var otherPhoneNumber = "0041 79 123 45 67"
+		var nationalPhoneNumber = "079 123 45 67"
+		var accentedLastName = "Döe"
+		var otherBirthDate = "18.02.1990"
+		var swappedFirstName = "doe"
+		var swappedLastName = "JOHN"
+
+		assert.True(t, check.matches(api.UserRepresentation{Email: &otherEmail}))
+		assert.True(t, check.matches(api.UserRepresentation{PhoneNumber: &otherPhoneNumber}))
+		assert.True(t, check.matches(api.UserRepresentation{PhoneNumber: &nationalPhoneNumber}))
+		assert.True(t, check.matches(api.UserRepresentation{FirstName: &firstName, LastName: &accentedLastName, BirthDate: &birthDate}))
+		assert.True(t, check.matches(api.UserRepresentation{FirstName: &firstName, LastName: &lastName, BirthDate: &birthDate}))
+		assert.True(t, check.matches(api.UserRepresentation{FirstName: &swappedFirstName, LastName: &swappedLastName, BirthDate: &birthDate}))
+		assert.False(t, check.matches(api.UserRepresentation{FirstName: &firstName, LastName: &lastName, BirthDate: &otherBirthDate}))
+		assert.False(t, check.matches(api.UserRepresentation{FirstName: &firstName, LastName: &lastName}))
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	cs "github.com/cloudtrust/common-service"
@@ -16,48 +17,72 @@ import (
 
 // Endpoints wraps a service behind a set of endpoints.
 type Endpoints struct {
-	GetRealms                      endpoint.Endpoint
-	GetRealm                       endpoint.Endpoint
-	GetClient                      endpoint.Endpoint
-	GetClients                     endpoint.Endpoint
-	DeleteUser                     endpoint.Endpoint
-	GetUser                        endpoint.Endpoint
-	UpdateUser                     endpoint.Endpoint
-	GetUsers                       endpoint.Endpoint
-	CreateUser                     endpoint.Endpoint
-	GetRolesOfUser                 endpoint.Endpoint
-	GetGroupsOfUser                endpoint.Endpoint
-	GetUserAccountStatus           endpoint.Endpoint
-	GetClientRoleForUser           endpoint.Endpoint
-	AddClientRoleToUser            endpoint.Endpoint
-	ResetPassword                  endpoint.Endpoint
-	SendVerifyEmail                endpoint.Endpoint
-	ExecuteActionsEmail            endpoint.Endpoint
-	SendNewEnrolmentCode           endpoint.Endpoint
-	SendReminderEmail              endpoint.Endpoint
-	GetCredentialsForUser          endpoint.Endpoint
-	DeleteCredentialsForUser       endpoint.Endpoint
-	GetRoles                       endpoint.Endpoint
-	GetRole                        endpoint.Endpoint
-	GetGroups                      endpoint.Endpoint
-	GetClientRoles                 endpoint.Endpoint
-	CreateClientRole               endpoint.Endpoint
-	GetRealmCustomConfiguration    endpoint.Endpoint
-	UpdateRealmCustomConfiguration endpoint.Endpoint
+	GetRealms                          endpoint.Endpoint
+	GetRealm                           endpoint.Endpoint
+	GetRealmSettings                   endpoint.Endpoint
+	UpdateRealmSettings                endpoint.Endpoint
+	GetClient                          endpoint.Endpoint
+	GetClients                         endpoint.Endpoint
+	DeleteUser                         endpoint.Endpoint
+	GetUser                            endpoint.Endpoint
+	UpdateUser                         endpoint.Endpoint
+	GetUsers                           endpoint.Endpoint
+	CreateClient                       endpoint.Endpoint
+	UpdateClient                       endpoint.Endpoint
+	EnableClient                       endpoint.Endpoint
+	DisableClient                      endpoint.Endpoint
+	RegenerateClientSecret             endpoint.Endpoint
+	CreateUser                         endpoint.Endpoint
+	GetRolesOfUser                     endpoint.Endpoint
+	GetGroupsOfUser                    endpoint.Endpoint
+	GetUserAccountStatus               endpoint.Endpoint
+	GetClientRoleForUser               endpoint.Endpoint
+	AddClientRoleToUser                endpoint.Endpoint
+	ResetPassword                      endpoint.Endpoint
+	SendVerifyEmail                    endpoint.Endpoint
+	ExecuteActionsEmail                endpoint.Endpoint
+	SendNewEnrolmentCode               endpoint.Endpoint
+	SendReminderEmail                  endpoint.Endpoint
+	GetCredentialsForUser              endpoint.Endpoint
+	DeleteCredentialsForUser           endpoint.Endpoint
+	ResetCredentialsForUser            endpoint.Endpoint
+	GetRoles                           endpoint.Endpoint
+	GetRole                            endpoint.Endpoint
+	GetGroups                          endpoint.Endpoint
+	GetClientRoles                     endpoint.Endpoint
+	CreateClientRole                   endpoint.Endpoint
+	GetRealmCustomConfiguration        endpoint.Endpoint
+	UpdateRealmCustomConfiguration     endpoint.Endpoint
+	PatchRealmCustomConfiguration      endpoint.Endpoint
+	GetRealmCustomConfigurationHistory endpoint.Endpoint
+	GetRealmCustomConfigurationDiff    endpoint.Endpoint
+	RollbackRealmCustomConfiguration   endpoint.Endpoint
+	BulkUserAction                     endpoint.Endpoint
+	GetUserHistory                     endpoint.Endpoint
+	GetUserSessions                    endpoint.Endpoint
+	LogoutUser                         endpoint.Endpoint
+	RevokeSession                      endpoint.Endpoint
 }
 
 // ManagementComponent is the interface of the component to send a query to Keycloak.
 type ManagementComponent interface {
 	GetRealms(ctx context.Context) ([]api.RealmRepresentation, error)
 	GetRealm(ctx context.Context, realmName string) (api.RealmRepresentation, error)
+	GetRealmSettings(ctx context.Context, realmName string) (api.RealmSettingsRepresentation, error)
+	UpdateRealmSettings(ctx context.Context, realmName string, settings api.RealmSettingsRepresentation) error
 	GetClient(ctx context.Context, realmName, idClient string) (api.ClientRepresentation, error)
 	GetClients(ctx context.Context, realmName string) ([]api.ClientRepresentation, error)
+	CreateClient(ctx context.Context, realmName string, client api.ClientRepresentation) (string, error)
+	UpdateClient(ctx context.Context, realmName, idClient string, client api.ClientRepresentation) error
+	EnableClient(ctx context.Context, realmName, idClient string) error
+	DisableClient(ctx context.Context, realmName, idClient string) error
+	RegenerateClientSecret(ctx context.Context, realmName, idClient string) (api.ClientSecretRepresentation, error)
 	DeleteUser(ctx context.Context, realmName, userID string) error
 	GetUser(ctx context.Context, realmName, userID string) (api.UserRepresentation, error)
-	UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation) error
+	UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation, ifMatch string) error
 	GetUsers(ctx context.Context, realmName string, groupIDs []string, paramKV ...string) (api.UsersPageRepresentation, error)
-	CreateUser(ctx context.Context, realmName string, user api.UserRepresentation) (string, error)
-	GetUserAccountStatus(ctx context.Context, realmName, userID string) (map[string]bool, error)
+	CreateUser(ctx context.Context, realmName string, user api.UserRepresentation, force bool) (string, error)
+	GetUserAccountStatus(ctx context.Context, realmName, userID string) (api.UserStatusRepresentation, error)
 	GetRolesOfUser(ctx context.Context, realmName, userID string) ([]api.RoleRepresentation, error)
 	GetGroupsOfUser(ctx context.Context, realmName, userID string) ([]api.GroupRepresentation, error)
 	GetClientRolesForUser(ctx context.Context, realmName, userID, clientID string) ([]api.RoleRepresentation, error)
@@ -69,13 +94,23 @@ type ManagementComponent interface {
 	SendReminderEmail(ctx context.Context, realmName string, userID string, paramKV ...string) error
 	GetCredentialsForUser(ctx context.Context, realmName string, userID string) ([]api.CredentialRepresentation, error)
 	DeleteCredentialsForUser(ctx context.Context, realmName string, userID string, credentialID string) error
+	ResetCredentialsForUser(ctx context.Context, realmName string, userID string, credentialCategory string) error
 	GetRoles(ctx context.Context, realmName string) ([]api.RoleRepresentation, error)
 	GetRole(ctx context.Context, realmName string, roleID string) (api.RoleRepresentation, error)
 	GetGroups(ctx context.Context, realmName string) ([]api.GroupRepresentation, error)
 	GetClientRoles(ctx context.Context, realmName, idClient string) ([]api.RoleRepresentation, error)
 	CreateClientRole(ctx context.Context, realmName, clientID string, role api.RoleRepresentation) (string, error)
 	GetRealmCustomConfiguration(ctx context.Context, realmID string) (api.RealmCustomConfiguration, error)
-	UpdateRealmCustomConfiguration(ctx context.Context, realmID string, customConfig api.RealmCustomConfiguration) error
+	UpdateRealmCustomConfiguration(ctx context.Context, realmID string, customConfig api.RealmCustomConfiguration, comment string) error
+	PatchRealmCustomConfiguration(ctx context.Context, realmName string, patch []byte, comment string) error
+	GetRealmCustomConfigurationHistory(ctx context.Context, realmName string) ([]api.RealmCustomConfigurationVersion, error)
+	GetRealmCustomConfigurationDiff(ctx context.Context, realmName string, fromVersion, toVersion int) ([]api.FieldChangeRepresentation, error)
+	RollbackRealmCustomConfiguration(ctx context.Context, realmName string, version int) error
+	BulkUserAction(ctx context.Context, realmName string, bulk api.BulkUserActionRepresentation, paramKV ...string) ([]api.BulkUserActionResultRepresentation, error)
+	GetUserHistory(ctx context.Context, realmName, userID string, paramKV ...string) ([]api.UserChangeRepresentation, error)
+	GetUserSessions(ctx context.Context, realmName, userID string) ([]api.UserSessionRepresentation, error)
+	LogoutUser(ctx context.Context, realmName, userID string) error
+	RevokeSession(ctx context.Context, realmName, userID, sessionID string) error
 }
 
 // MakeGetRealmsEndpoint makes the Realms endpoint to retrieve all available realms.
@@ -94,6 +129,35 @@ func MakeGetRealmEndpoint(managementComponent ManagementComponent) cs.Endpoint {
 	}
 }
 
+// MakeGetRealmSettingsEndpoint creates an endpoint for GetRealmSettings
+func MakeGetRealmSettingsEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return managementComponent.GetRealmSettings(ctx, m["realm"])
+	}
+}
+
+// MakeUpdateRealmSettingsEndpoint creates an endpoint for UpdateRealmSettings
+func MakeUpdateRealmSettingsEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		var settings api.RealmSettingsRepresentation
+
+		if err = json.Unmarshal([]byte(m["body"]), &settings); err != nil {
+			return nil, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Body)
+		}
+
+		if err = settings.Validate(); err != nil {
+			return nil, errorhandler.CreateBadRequestError(err.Error())
+		}
+
+		return nil, managementComponent.UpdateRealmSettings(ctx, m["realm"], settings)
+	}
+}
+
 // MakeGetClientEndpoint creates an endpoint for GetClient
 func MakeGetClientEndpoint(managementComponent ManagementComponent) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -112,6 +176,91 @@ func MakeGetClientsEndpoint(managementComponent ManagementComponent) cs.Endpoint
 	}
 }
 
+// MakeCreateClientEndpoint makes the endpoint to create a client.
+func MakeCreateClientEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		var client api.ClientRepresentation
+
+		if err = json.Unmarshal([]byte(m["body"]), &client); err != nil {
+			return nil, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Body)
+		}
+
+		if err = client.Validate(); err != nil {
+			return nil, errorhandler.CreateBadRequestError(err.Error())
+		}
+
+		if client.ClientID == nil {
+			return nil, errorhandler.CreateMissingParameterError(internal.ClientID)
+		}
+
+		var keycloakLocation string
+		keycloakLocation, err = managementComponent.CreateClient(ctx, m["realm"], client)
+
+		if err != nil {
+			return nil, err
+		}
+
+		url, err := convertLocationURL(keycloakLocation, m["scheme"], m["host"])
+		if err != nil {
+			return nil, err
+		}
+
+		return LocationHeader{
+			URL: url,
+		}, nil
+	}
+}
+
+// MakeUpdateClientEndpoint creates an endpoint for UpdateClient
+func MakeUpdateClientEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		var client api.ClientRepresentation
+
+		if err = json.Unmarshal([]byte(m["body"]), &client); err != nil {
+			return nil, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Body)
+		}
+
+		if err = client.Validate(); err != nil {
+			return nil, errorhandler.CreateBadRequestError(err.Error())
+		}
+
+		return nil, managementComponent.UpdateClient(ctx, m["realm"], m["clientID"], client)
+	}
+}
+
+// MakeEnableClientEndpoint creates an endpoint for EnableClient
+func MakeEnableClientEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return nil, managementComponent.EnableClient(ctx, m["realm"], m["clientID"])
+	}
+}
+
+// MakeDisableClientEndpoint creates an endpoint for DisableClient
+func MakeDisableClientEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return nil, managementComponent.DisableClient(ctx, m["realm"], m["clientID"])
+	}
+}
+
+// MakeRegenerateClientSecretEndpoint creates an endpoint for RegenerateClientSecret
+func MakeRegenerateClientSecretEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return managementComponent.RegenerateClientSecret(ctx, m["realm"], m["clientID"])
+	}
+}
+
 // MakeCreateUserEndpoint makes the endpoint to create a user.
 func MakeCreateUserEndpoint(managementComponent ManagementComponent) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -132,15 +281,20 @@ func MakeCreateUserEndpoint(managementComponent ManagementComponent) cs.Endpoint
 			return nil, errorhandler.CreateMissingParameterError(internal.Groups)
 		}
 
+		// force creates the user even if duplicates are detected
+		var force = m["force"] == "true"
+
 		var keycloakLocation string
-		keycloakLocation, err = managementComponent.CreateUser(ctx, m["realm"], user)
+		keycloakLocation, err = managementComponent.CreateUser(ctx, m["realm"], user, force)
 
 		if err != nil {
 			return nil, err
 		}
 
 		url, err := convertLocationURL(keycloakLocation, m["scheme"], m["host"])
-		// TODO: log the error and the unhappy url
+		if err != nil {
+			return nil, err
+		}
 
 		return LocationHeader{
 			URL: url,
@@ -162,7 +316,15 @@ func MakeGetUserEndpoint(managementComponent ManagementComponent) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		return managementComponent.GetUser(ctx, m["realm"], m["userID"])
+		user, err := managementComponent.GetUser(ctx, m["realm"], m["userID"])
+		if err != nil {
+			return nil, err
+		}
+
+		return ETagResponse{
+			ETag: api.ComputeETag(user),
+			Body: user,
+		}, nil
 	}
 }
 
@@ -182,7 +344,15 @@ func MakeUpdateUserEndpoint(managementComponent ManagementComponent) cs.Endpoint
 			return nil, errorhandler.CreateBadRequestError(err.Error())
 		}
 
-		return nil, managementComponent.UpdateUser(ctx, m["realm"], m["userID"], user)
+		// the ETag obtained with GetUser is mandatory to avoid overwriting concurrent modifications
+		if m["ifMatch"] == "" {
+			return nil, errorhandler.Error{
+				Status:  428,
+				Message: internal.MsgErrMissingParam + "." + internal.IfMatch,
+			}
+		}
+
+		return nil, managementComponent.UpdateUser(ctx, m["realm"], m["userID"], user, m["ifMatch"])
 	}
 }
 
@@ -192,7 +362,8 @@ func MakeGetUsersEndpoint(managementComponent ManagementComponent) cs.Endpoint {
 		var m = req.(map[string]string)
 
 		var paramKV []string
-		for _, key := range []string{"email", "firstName", "lastName", "username", "search", "first", "max"} {
+		for _, key := range []string{"email", "firstName", "lastName", "username", "search", "first", "max",
+			"phoneNumber", "label", "birthDate", "enabled", "locked", "emailVerified", "sort"} {
 			if m[key] != "" {
 				paramKV = append(paramKV, key, m[key])
 			}
@@ -381,6 +552,20 @@ func MakeDeleteCredentialsForUserEndpoint(managementComponent ManagementComponen
 	}
 }
 
+// MakeResetCredentialsForUserEndpoint creates an endpoint for ResetCredentialsForUser
+func MakeResetCredentialsForUserEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		credentialCategory, ok := m["type"]
+		if !ok {
+			return nil, errorhandler.CreateMissingParameterError(internal.Type)
+		}
+
+		return nil, managementComponent.ResetCredentialsForUser(ctx, m["realm"], m["userID"], credentialCategory)
+	}
+}
+
 // MakeGetRolesEndpoint creates an endpoint for GetRoles
 func MakeGetRolesEndpoint(managementComponent ManagementComponent) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -441,7 +626,9 @@ func MakeCreateClientRoleEndpoint(managementComponent ManagementComponent) cs.En
 		}
 
 		url, err := convertLocationURL(keycloakLocation, m["scheme"], m["host"])
-		// TODO: log the error and the unhappy url
+		if err != nil {
+			return nil, err
+		}
 
 		return LocationHeader{
 			URL: url,
@@ -476,7 +663,139 @@ func MakeUpdateRealmCustomConfigurationEndpoint(managementComponent ManagementCo
 			return nil, errorhandler.CreateBadRequestError(err.Error())
 		}
 
-		return nil, managementComponent.UpdateRealmCustomConfiguration(ctx, m["realm"], customConfig)
+		return nil, managementComponent.UpdateRealmCustomConfiguration(ctx, m["realm"], customConfig, m["comment"])
+	}
+}
+
+// MakePatchRealmCustomConfigurationEndpoint creates an endpoint for PatchRealmCustomConfiguration
+func MakePatchRealmCustomConfigurationEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		// a merge patch must be a JSON object
+		var patch map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(m["body"]), &patch); err != nil || patch == nil {
+			return nil, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Body)
+		}
+
+		return nil, managementComponent.PatchRealmCustomConfiguration(ctx, m["realm"], []byte(m["body"]), m["comment"])
+	}
+}
+
+// MakeGetRealmCustomConfigurationHistoryEndpoint creates an endpoint for GetRealmCustomConfigurationHistory
+func MakeGetRealmCustomConfigurationHistoryEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return managementComponent.GetRealmCustomConfigurationHistory(ctx, m["realm"])
+	}
+}
+
+// MakeGetRealmCustomConfigurationDiffEndpoint creates an endpoint for GetRealmCustomConfigurationDiff
+func MakeGetRealmCustomConfigurationDiffEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		if m["from"] == "" {
+			return nil, errorhandler.CreateMissingParameterError(internal.From)
+		}
+		fromVersion, err := strconv.Atoi(m["from"])
+		if err != nil {
+			return nil, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.From)
+		}
+
+		var toVersion = 0
+		if m["to"] != "" {
+			if toVersion, err = strconv.Atoi(m["to"]); err != nil {
+				return nil, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.To)
+			}
+		}
+
+		return managementComponent.GetRealmCustomConfigurationDiff(ctx, m["realm"], fromVersion, toVersion)
+	}
+}
+
+// MakeRollbackRealmCustomConfigurationEndpoint creates an endpoint for RollbackRealmCustomConfiguration
+func MakeRollbackRealmCustomConfigurationEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		version, err := strconv.Atoi(m["version"])
+		if err != nil {
+			return nil, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.ConfigVersion)
+		}
+
+		return nil, managementComponent.RollbackRealmCustomConfiguration(ctx, m["realm"], version)
+	}
+}
+
+// MakeBulkUserActionEndpoint creates an endpoint for BulkUserAction
+func MakeBulkUserActionEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		var paramKV []string
+		for _, key := range []string{"client_id", "redirect_uri", "lifespan"} {
+			if m[key] != "" {
+				paramKV = append(paramKV, key, m[key])
+			}
+		}
+
+		var bulk api.BulkUserActionRepresentation
+
+		if err = json.Unmarshal([]byte(m["body"]), &bulk); err != nil {
+			return nil, errorhandler.CreateBadRequestError(internal.MsgErrInvalidParam + "." + internal.Body)
+		}
+
+		if err = bulk.Validate(); err != nil {
+			return nil, errorhandler.CreateBadRequestError(err.Error())
+		}
+
+		return managementComponent.BulkUserAction(ctx, m["realm"], bulk, paramKV...)
+	}
+}
+
+// MakeGetUserHistoryEndpoint creates an endpoint for GetUserHistory
+func MakeGetUserHistoryEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		var paramKV []string
+		for _, key := range []string{"first", "max"} {
+			if m[key] != "" {
+				paramKV = append(paramKV, key, m[key])
+			}
+		}
+
+		return managementComponent.GetUserHistory(ctx, m["realm"], m["userID"], paramKV...)
+	}
+}
+
+// MakeGetUserSessionsEndpoint creates an endpoint for GetUserSessions
+func MakeGetUserSessionsEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return managementComponent.GetUserSessions(ctx, m["realm"], m["userID"])
+	}
+}
+
+// MakeLogoutUserEndpoint creates an endpoint for LogoutUser
+func MakeLogoutUserEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return nil, managementComponent.LogoutUser(ctx, m["realm"], m["userID"])
+	}
+}
+
+// MakeRevokeSessionEndpoint creates an endpoint for RevokeSession
+func MakeRevokeSessionEndpoint(managementComponent ManagementComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return nil, managementComponent.RevokeSession(ctx, m["realm"], m["userID"], m["sessionID"])
 	}
 }
 
@@ -485,6 +804,12 @@ type LocationHeader struct {
 	URL string
 }
 
+// ETagResponse type is a reply sent with an ETag header
+type ETagResponse struct {
+	ETag string
+	Body interface{}
+}
+
 // ConvertLocationError type
 type ConvertLocationError struct {
 	Location string
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"testing"
 
+	commonhttp "github.com/cloudtrust/common-service/errors"
 	api "github.com/cloudtrust/keycloak-bridge/api/management"
 	"github.com/cloudtrust/keycloak-bridge/pkg/management/mock"
 	"github.com/golang/mock/gomock"
@@ -48,6 +49,66 @@ func TestGetRealmEndpoint(t *testing.T) {
 	assert.NotNil(t, res)
 }
 
+func TestGetRealmSettingsEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeGetRealmSettingsEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var ctx = context.Background()
+	var req = make(map[string]string)
+	req["realm"] = realm
+
+	mockManagementComponent.EXPECT().GetRealmSettings(ctx, realm).Return(api.RealmSettingsRepresentation{}, nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.NotNil(t, res)
+}
+
+func TestUpdateRealmSettingsEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeUpdateRealmSettingsEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var ctx = context.Background()
+	var loginTheme = "cloudtrust"
+
+	// No error
+	{
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["body"] = `{"loginTheme":"cloudtrust"}`
+
+		mockManagementComponent.EXPECT().UpdateRealmSettings(ctx, realm, api.RealmSettingsRepresentation{LoginTheme: &loginTheme}).Return(nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	}
+
+	// Error - Cannot unmarshall
+	{
+		var req = make(map[string]string)
+		req["body"] = string("JSON")
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	}
+
+	// Error - Invalid settings
+	{
+		var req = make(map[string]string)
+		req["body"] = `{"otpPolicy":{"digits":7}}`
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	}
+}
+
 func TestGetClientEndpoint(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -88,6 +149,175 @@ func TestGetClientsEndpoint(t *testing.T) {
 	assert.NotNil(t, res)
 }
 
+func TestCreateClientEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeCreateClientEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var location = "https://location.url/auth/admin/master/clients/123456"
+	var clientID = "my-app"
+	var ctx = context.Background()
+
+	t.Run("No error", func(t *testing.T) {
+		var req = make(map[string]string)
+		req["scheme"] = "https"
+		req["host"] = "elca.ch"
+		req["realm"] = realm
+
+		clientJSON, _ := json.Marshal(api.ClientRepresentation{ClientID: &clientID})
+		req["body"] = string(clientJSON)
+
+		mockManagementComponent.EXPECT().CreateClient(ctx, realm, api.ClientRepresentation{ClientID: &clientID}).Return(location, nil).Times(1)
+		res, err := e(ctx, req)
+		assert.Nil(t, err)
+
+		locationHeader := res.(LocationHeader)
+		assert.Equal(t, "https://elca.ch/management/master/clients/123456", locationHeader.URL)
+	})
+
+	t.Run("Cannot unmarshall", func(t *testing.T) {
+		var req = make(map[string]string)
+		req["body"] = string("JSON")
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Invalid client", func(t *testing.T) {
+		var req = make(map[string]string)
+		req["body"] = `{"clientId":"my-app","accessType":"unknown"}`
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Missing client ID", func(t *testing.T) {
+		var req = make(map[string]string)
+		req["body"] = `{"name":"My application"}`
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Keycloak client error", func(t *testing.T) {
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["body"] = `{"clientId":"my-app"}`
+
+		mockManagementComponent.EXPECT().CreateClient(ctx, realm, gomock.Any()).Return("", fmt.Errorf("Error")).Times(1)
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid location", func(t *testing.T) {
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["body"] = `{"clientId":"my-app"}`
+
+		mockManagementComponent.EXPECT().CreateClient(ctx, realm, gomock.Any()).Return("http://localhost:8080/unexpected", nil).Times(1)
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestUpdateClientEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeUpdateClientEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var clientID = "1234-456"
+	var redirectURIs = []string{"https://my-app.com/*"}
+	var ctx = context.Background()
+
+	// No error
+	{
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["clientID"] = clientID
+		clientJSON, _ := json.Marshal(api.ClientRepresentation{RedirectURIs: &redirectURIs})
+		req["body"] = string(clientJSON)
+
+		mockManagementComponent.EXPECT().UpdateClient(ctx, realm, clientID, api.ClientRepresentation{RedirectURIs: &redirectURIs}).Return(nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	}
+
+	// Error - Cannot unmarshall
+	{
+		var req = make(map[string]string)
+		req["body"] = string("JSON")
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	}
+
+	// Error - Invalid redirect URI
+	{
+		var req = make(map[string]string)
+		req["body"] = `{"redirectUris":["not an URI"]}`
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestEnableDisableClientEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var realm = "master"
+	var clientID = "1234-456"
+	var ctx = context.Background()
+	var req = make(map[string]string)
+	req["realm"] = realm
+	req["clientID"] = clientID
+
+	// Enable
+	{
+		var e = MakeEnableClientEndpoint(mockManagementComponent)
+		mockManagementComponent.EXPECT().EnableClient(ctx, realm, clientID).Return(nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	}
+
+	// Disable
+	{
+		var e = MakeDisableClientEndpoint(mockManagementComponent)
+		mockManagementComponent.EXPECT().DisableClient(ctx, realm, clientID).Return(nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	}
+}
+
+func TestRegenerateClientSecretEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeRegenerateClientSecretEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var clientID = "1234-456"
+	var secret = "a-new-secret"
+	var ctx = context.Background()
+	var req = make(map[string]string)
+	req["realm"] = realm
+	req["clientID"] = clientID
+
+	mockManagementComponent.EXPECT().RegenerateClientSecret(ctx, realm, clientID).Return(api.ClientSecretRepresentation{Value: &secret}, nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, secret, *res.(api.ClientSecretRepresentation).Value)
+}
+
 func TestCreateUserEndpoint(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -111,7 +341,7 @@ func TestCreateUserEndpoint(t *testing.T) {
 		userJSON, _ := json.Marshal(api.UserRepresentation{Groups: &groups})
 		req["body"] = string(userJSON)
 
-		mockManagementComponent.EXPECT().CreateUser(ctx, realm, api.UserRepresentation{Groups: &groups}).Return(location, nil).Times(1)
+		mockManagementComponent.EXPECT().CreateUser(ctx, realm, api.UserRepresentation{Groups: &groups}, false).Return(location, nil).Times(1)
 		res, err := e(ctx, req)
 		assert.Nil(t, err)
 
@@ -136,7 +366,7 @@ func TestCreateUserEndpoint(t *testing.T) {
 		userJSON, _ := json.Marshal(api.UserRepresentation{Groups: &groups})
 		req["body"] = string(userJSON)
 
-		mockManagementComponent.EXPECT().CreateUser(ctx, realm, gomock.Any()).Return("", fmt.Errorf("Error")).Times(1)
+		mockManagementComponent.EXPECT().CreateUser(ctx, realm, gomock.Any(), false).Return("", fmt.Errorf("Error")).Times(1)
 		_, err := e(ctx, req)
 		assert.NotNil(t, err)
 	}
@@ -178,10 +408,22 @@ func TestGetUserEndpoint(t *testing.T) {
 	req["realm"] = realm
 	req["userID"] = userID
 
-	mockManagementComponent.EXPECT().GetUser(ctx, realm, userID).Return(api.UserRepresentation{}, nil).Times(1)
-	var res, err = e(ctx, req)
-	assert.Nil(t, err)
-	assert.NotNil(t, res)
+	// No error
+	{
+		var user = api.UserRepresentation{}
+		mockManagementComponent.EXPECT().GetUser(ctx, realm, userID).Return(user, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, ETagResponse{ETag: api.ComputeETag(user), Body: user}, res)
+	}
+
+	// Error
+	{
+		mockManagementComponent.EXPECT().GetUser(ctx, realm, userID).Return(api.UserRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
+		var res, err = e(ctx, req)
+		assert.NotNil(t, err)
+		assert.Nil(t, res)
+	}
 }
 
 func TestUpdateUserEndpoint(t *testing.T) {
@@ -202,13 +444,31 @@ func TestUpdateUserEndpoint(t *testing.T) {
 		req["userID"] = userID
 		userJSON, _ := json.Marshal(api.UserRepresentation{})
 		req["body"] = string(userJSON)
+		req["ifMatch"] = `"etag"`
 
-		mockManagementComponent.EXPECT().UpdateUser(ctx, realm, userID, gomock.Any()).Return(nil).Times(1)
+		mockManagementComponent.EXPECT().UpdateUser(ctx, realm, userID, gomock.Any(), `"etag"`).Return(nil).Times(1)
 		var res, err = e(ctx, req)
 		assert.Nil(t, err)
 		assert.Nil(t, res)
 	}
 
+	// Error - Missing If-Match
+	{
+		var realm = "master"
+		var userID = "1234-452-4578"
+		var ctx = context.Background()
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["userID"] = userID
+		userJSON, _ := json.Marshal(api.UserRepresentation{})
+		req["body"] = string(userJSON)
+
+		var res, err = e(ctx, req)
+		assert.NotNil(t, err)
+		assert.Equal(t, 428, err.(commonhttp.Error).Status)
+		assert.Nil(t, res)
+	}
+
 	// Error - JSON unmarshalling error
 	{
 		var realm = "master"
@@ -270,6 +530,31 @@ func TestGetUsersEndpoint(t *testing.T) {
 		assert.NotNil(t, res)
 	}
 
+	// No error - With advanced search params
+	{
+		var realm = "master"
+		var ctx = context.Background()
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["phoneNumber"] = "+41791234567"
+		req["label"] = "label"
+		req["birthDate"] = "1988-01-01"
+		req["enabled"] = "true"
+		req["locked"] = "false"
+		req["emailVerified"] = "true"
+		req["sort"] = "-lastName"
+		req["first"] = "10"
+		req["max"] = "5"
+		req["groupIds"] = "123-784dsf-sdf567"
+
+		mockManagementComponent.EXPECT().GetUsers(ctx, realm, []string{req["groupIds"]}, "first", "10", "max", "5", "phoneNumber", req["phoneNumber"],
+			"label", req["label"], "birthDate", req["birthDate"], "enabled", "true", "locked", "false", "emailVerified", "true", "sort", "-lastName").
+			Return(api.UsersPageRepresentation{}, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	}
+
 	// Missing mandatory parameter group
 	{
 		var realm = "master"
@@ -299,11 +584,10 @@ func TestGetUserAccountStatusEndpoint(t *testing.T) {
 		var req = make(map[string]string)
 		req["realm"] = realm
 		req["userID"] = userID
-		var m map[string]bool
-		m = make(map[string]bool)
-		m["enabled"] = false
+		var enabled = false
+		var status = api.UserStatusRepresentation{Enabled: &enabled}
 
-		mockManagementComponent.EXPECT().GetUserAccountStatus(ctx, realm, userID).Return(m, nil).Times(1)
+		mockManagementComponent.EXPECT().GetUserAccountStatus(ctx, realm, userID).Return(status, nil).Times(1)
 		var _, err = e(ctx, req)
 		assert.Nil(t, err)
 	}
@@ -880,8 +1164,9 @@ func TestUpdateRealmCustomConfigurationEndpoint(t *testing.T) {
 		req["realm"] = realmName
 		req["clientID"] = clientID
 		req["body"] = configJSON
+		req["comment"] = "new default client"
 
-		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, gomock.Any()).Return(nil).Times(1)
+		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, gomock.Any(), "new default client").Return(nil).Times(1)
 		var res, err = e(ctx, req)
 		assert.Nil(t, err)
 		assert.Nil(t, res)
@@ -898,7 +1183,317 @@ func TestUpdateRealmCustomConfigurationEndpoint(t *testing.T) {
 		req["clientID"] = clientID
 		req["body"] = configJSON
 
-		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, gomock.Any()).Return(nil).Times(0)
+		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, gomock.Any(), "").Return(nil).Times(0)
+		var res, err = e(ctx, req)
+		assert.NotNil(t, err)
+		assert.Nil(t, res)
+	}
+}
+
+func TestPatchRealmCustomConfigurationEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakePatchRealmCustomConfigurationEndpoint(mockManagementComponent)
+
+	var realmName = "master"
+	var ctx = context.Background()
+
+	t.Run("Patch", func(t *testing.T) {
+		var patch = `{"mfa_required":true,"password_policy":null}`
+		var req = map[string]string{"realm": realmName, "body": patch, "comment": "enforce MFA"}
+		mockManagementComponent.EXPECT().PatchRealmCustomConfiguration(ctx, realmName, []byte(patch), "enforce MFA").Return(nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+
+	t.Run("Patch is not a JSON object", func(t *testing.T) {
+		for _, patch := range []string{`["mfa_required"]`, `null`, `{"mfa_required":`} {
+			var req = map[string]string{"realm": realmName, "body": patch}
+			var _, err = e(ctx, req)
+			assert.NotNil(t, err)
+		}
+	})
+}
+
+func TestGetRealmCustomConfigurationHistoryEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeGetRealmCustomConfigurationHistoryEndpoint(mockManagementComponent)
+
+	var realmName = "master"
+	var ctx = context.Background()
+	var req = map[string]string{"realm": realmName}
+
+	mockManagementComponent.EXPECT().GetRealmCustomConfigurationHistory(ctx, realmName).Return([]api.RealmCustomConfigurationVersion{}, nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.NotNil(t, res)
+}
+
+func TestGetRealmCustomConfigurationDiffEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeGetRealmCustomConfigurationDiffEndpoint(mockManagementComponent)
+
+	var realmName = "master"
+	var ctx = context.Background()
+
+	t.Run("Compare two versions", func(t *testing.T) {
+		var req = map[string]string{"realm": realmName, "from": "2", "to": "5"}
+		mockManagementComponent.EXPECT().GetRealmCustomConfigurationDiff(ctx, realmName, 2, 5).Return([]api.FieldChangeRepresentation{}, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+
+	t.Run("Compare with current configuration", func(t *testing.T) {
+		var req = map[string]string{"realm": realmName, "from": "2"}
+		mockManagementComponent.EXPECT().GetRealmCustomConfigurationDiff(ctx, realmName, 2, 0).Return([]api.FieldChangeRepresentation{}, nil).Times(1)
+		var _, err = e(ctx, req)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Missing from parameter", func(t *testing.T) {
+		var req = map[string]string{"realm": realmName, "to": "5"}
+		var _, err = e(ctx, req)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Invalid to parameter", func(t *testing.T) {
+		var req = map[string]string{"realm": realmName, "from": "2", "to": "last"}
+		var _, err = e(ctx, req)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRollbackRealmCustomConfigurationEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeRollbackRealmCustomConfigurationEndpoint(mockManagementComponent)
+
+	var realmName = "master"
+	var ctx = context.Background()
+
+	t.Run("Rollback", func(t *testing.T) {
+		var req = map[string]string{"realm": realmName, "version": "3"}
+		mockManagementComponent.EXPECT().RollbackRealmCustomConfiguration(ctx, realmName, 3).Return(nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+
+	t.Run("Invalid version", func(t *testing.T) {
+		var req = map[string]string{"realm": realmName, "version": "x"}
+		var _, err = e(ctx, req)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestGetUserHistoryEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeGetUserHistoryEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var userID = "123-456-789"
+	var ctx = context.Background()
+
+	// Without paging parameters
+	{
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["userID"] = userID
+
+		mockManagementComponent.EXPECT().GetUserHistory(ctx, realm, userID).Return([]api.UserChangeRepresentation{}, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	}
+
+	// With paging parameters
+	{
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["userID"] = userID
+		req["first"] = "10"
+		req["max"] = "20"
+
+		mockManagementComponent.EXPECT().GetUserHistory(ctx, realm, userID, "first", "10", "max", "20").Return(nil, fmt.Errorf("Error")).Times(1)
+		var _, err = e(ctx, req)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestGetUserSessionsEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeGetUserSessionsEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var userID = "123-456-789"
+	var ctx = context.Background()
+	var req = make(map[string]string)
+	req["realm"] = realm
+	req["userID"] = userID
+
+	mockManagementComponent.EXPECT().GetUserSessions(ctx, realm, userID).Return([]api.UserSessionRepresentation{}, nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.NotNil(t, res)
+}
+
+func TestLogoutUserEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeLogoutUserEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var userID = "123-456-789"
+	var ctx = context.Background()
+	var req = make(map[string]string)
+	req["realm"] = realm
+	req["userID"] = userID
+
+	mockManagementComponent.EXPECT().LogoutUser(ctx, realm, userID).Return(nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+}
+
+func TestRevokeSessionEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeRevokeSessionEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var userID = "123-456-789"
+	var sessionID = "987-654-321"
+	var ctx = context.Background()
+	var req = make(map[string]string)
+	req["realm"] = realm
+	req["userID"] = userID
+	req["sessionID"] = sessionID
+
+	mockManagementComponent.EXPECT().RevokeSession(ctx, realm, userID, sessionID).Return(nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+}
+
+func TestResetCredentialsForUserEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeResetCredentialsForUserEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var userID = "123-456-789"
+	var credentialCategory = "otp"
+	var ctx = context.Background()
+
+	// No error
+	{
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["userID"] = userID
+		req["type"] = credentialCategory
+
+		mockManagementComponent.EXPECT().ResetCredentialsForUser(ctx, realm, userID, credentialCategory).Return(nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	}
+
+	// Missing credential type
+	{
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["userID"] = userID
+
+		var res, err = e(ctx, req)
+		assert.NotNil(t, err)
+		assert.Nil(t, res)
+	}
+}
+
+func TestBulkUserActionEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeBulkUserActionEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"
+	var action = api.BulkActionSendReminderEmail
+	var userIDs = []string{userID}
+	var bulk = api.BulkUserActionRepresentation{
+		Action:  &action,
+		UserIDs: &userIDs,
+	}
+	var status = api.BulkStatusSuccess
+	var results = []api.BulkUserActionResultRepresentation{{UserID: &userID, Status: &status}}
+	bulkJSON, _ := json.Marshal(bulk)
+
+	// No error - With params
+	{
+		var ctx = context.Background()
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["client_id"] = "123789"
+		req["redirect_uri"] = "http://redirect.com"
+		req["toto"] = "tutu" // Check this param is not transmitted
+		req["body"] = string(bulkJSON)
+
+		mockManagementComponent.EXPECT().BulkUserAction(ctx, realm, bulk, "client_id", req["client_id"], "redirect_uri", req["redirect_uri"]).Return(results, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, results, res)
+	}
+
+	// Error - Unmarshalling error
+	{
+		var ctx = context.Background()
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["body"] = "{"
+
+		var res, err = e(ctx, req)
+		assert.NotNil(t, err)
+		assert.Nil(t, res)
+	}
+
+	// Error - Validation error
+	{
+		var ctx = context.Background()
+		var req = make(map[string]string)
+		req["realm"] = realm
+		req["body"] = `{"action":"promote","userIds":["f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"]}`
+
 		var res, err = e(ctx, req)
 		assert.NotNil(t, err)
 		assert.Nil(t, res)
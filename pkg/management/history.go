@@ -0,0 +1,202 @@
+package management
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	events_api "github.com/cloudtrust/keycloak-bridge/api/events"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	kc "github.com/cloudtrust/keycloak-client"
+)
+
+const (
+	userUpdatedEvent = "USER_UPDATED"
+	maskedValue      = "****"
+)
+
+// userChanges is the content of the additional information stored with a USER_UPDATED event
+type userChanges struct {
+	Changes []api.FieldChangeRepresentation `json:"changes"`
+}
+
+// diffUsers computes the field-level differences between the current user and its updated representation.
+// Fields which are not set in the updated representation are not modified by Keycloak and thus ignored.
+// The values of the masked fields are replaced, only the fact that they changed is kept.
+func diffUsers(oldUser, newUser kc.UserRepresentation, maskedFields []string) []api.FieldChangeRepresentation {
+	var changes = []api.FieldChangeRepresentation{}
+
+	var addChange = func(field string, oldValue, newValue *string) {
+		if oldValue == nil && newValue == nil {
+			return
+		}
+		if oldValue != nil && newValue != nil && *oldValue == *newValue {
+			return
+		}
+		if isMaskedField(field, maskedFields) {
+			var masked = maskedValue
+			if oldValue != nil {
+				oldValue = &masked
+			}
+			if newValue != nil {
+				newValue = &masked
+			}
+		}
+		changes = append(changes, api.FieldChangeRepresentation{Field: field, OldValue: oldValue, NewValue: newValue})
+	}
+
+	if newUser.Username != nil {
+		addChange("username", oldUser.Username, newUser.Username)
+	}
+	if newUser.Email != nil {
+		addChange("email", oldUser.Email, newUser.Email)
+	}
+	if newUser.FirstName != nil {
+		addChange("firstName", oldUser.FirstName, newUser.FirstName)
+	}
+	if newUser.LastName != nil {
+		addChange("lastName", oldUser.LastName, newUser.LastName)
+	}
+	if newUser.Enabled != nil {
+		addChange("enabled", boolToString(oldUser.Enabled), boolToString(newUser.Enabled))
+	}
+	if newUser.EmailVerified != nil {
+		addChange("emailVerified", boolToString(oldUser.EmailVerified), boolToString(newUser.EmailVerified))
+	}
+
+	if newUser.Attributes != nil {
+		var oldAttributes = make(map[string][]string)
+		if oldUser.Attributes != nil {
+			oldAttributes = *oldUser.Attributes
+		}
+		var newAttributes = *newUser.Attributes
+
+		var keys []string
+		for key := range oldAttributes {
+			keys = append(keys, key)
+		}
+		for key := range newAttributes {
+			if _, ok := oldAttributes[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			var field = key
+			if !internal.IsReservedUserAttribute(key) {
+				field = "attributes." + key
+			}
+			addChange(field, joinValues(oldAttributes[key]), joinValues(newAttributes[key]))
+		}
+	}
+
+	return changes
+}
+
+// diffConfigurations computes the differences between two versions of a realm configuration. Fields are named as in the
+// API representation of the configuration and complex values are expressed as JSON.
+func diffConfigurations(oldConfig, newConfig dto.RealmConfiguration) []api.FieldChangeRepresentation {
+	var oldFields = configurationFields(oldConfig)
+	var newFields = configurationFields(newConfig)
+
+	var keys []string
+	for key := range oldFields {
+		keys = append(keys, key)
+	}
+	for key := range newFields {
+		if _, ok := oldFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes = []api.FieldChangeRepresentation{}
+	for _, key := range keys {
+		var oldValue, newValue = fieldValue(oldFields[key]), fieldValue(newFields[key])
+		if oldValue == nil && newValue == nil {
+			continue
+		}
+		if oldValue != nil && newValue != nil && *oldValue == *newValue {
+			continue
+		}
+		changes = append(changes, api.FieldChangeRepresentation{Field: key, OldValue: oldValue, NewValue: newValue})
+	}
+	return changes
+}
+
+func configurationFields(config dto.RealmConfiguration) map[string]json.RawMessage {
+	var fields = make(map[string]json.RawMessage)
+	// the API representation only contains marshallable fields
+	var configJSON, _ = json.Marshal(api.ConvertToAPIRealmCustomConfiguration(config))
+	_ = json.Unmarshal(configJSON, &fields)
+	return fields
+}
+
+func fieldValue(raw json.RawMessage) *string {
+	if raw == nil || string(raw) == "null" {
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		// not a string
+		value = string(raw)
+	}
+	return &value
+}
+
+// convertToUserChange converts a USER_UPDATED audit event into a user change
+func convertToUserChange(event events_api.AuditRepresentation) (api.UserChangeRepresentation, error) {
+	var changes userChanges
+	if event.AdditionalInfo != "" {
+		if err := json.Unmarshal([]byte(event.AdditionalInfo), &changes); err != nil {
+			return api.UserChangeRepresentation{}, err
+		}
+	}
+	if changes.Changes == nil {
+		changes.Changes = []api.FieldChangeRepresentation{}
+	}
+
+	var userChange = api.UserChangeRepresentation{
+		Time:    &event.AuditTime,
+		Changes: changes.Changes,
+	}
+	if event.Origin != "" {
+		userChange.Origin = &event.Origin
+	}
+	if event.AgentUsername != "" {
+		userChange.AgentUsername = &event.AgentUsername
+	}
+	if event.AgentRealmName != "" {
+		userChange.AgentRealmName = &event.AgentRealmName
+	}
+	return userChange, nil
+}
+
+func isMaskedField(field string, maskedFields []string) bool {
+	for _, masked := range maskedFields {
+		if masked == field || "attributes."+masked == field {
+			return true
+		}
+	}
+	return false
+}
+
+func boolToString(value *bool) *string {
+	if value == nil {
+		return nil
+	}
+	var res = strconv.FormatBool(*value)
+	return &res
+}
+
+func joinValues(values []string) *string {
+	if values == nil {
+		return nil
+	}
+	var res = strings.Join(values, ",")
+	return &res
+}
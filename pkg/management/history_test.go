@@ -0,0 +1,174 @@
+package management
+
+import (
+	"testing"
+
+	events_api "github.com/cloudtrust/keycloak-bridge/api/events"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffUsers(t *testing.T) {
+	var oldEmail = "old@elca.ch"
+	var newEmail = "new@elca.ch"
+	var firstName = "Titi"
+	var enabled = true
+	var disabled = false
+
+	var oldUser = kc.UserRepresentation{
+		Email:     &oldEmail,
+		FirstName: &firstName,
+		Enabled:   &enabled,
+		Attributes: &map[string][]string{
+			"phoneNumber": {"+41789456"},
+			"birthDate":   {"1988-01-01"},
+			"department":  {"IT"},
+		},
+	}
+
+	t.Run("No change", func(t *testing.T) {
+		var newUser = kc.UserRepresentation{FirstName: &firstName, Attributes: oldUser.Attributes}
+		assert.Len(t, diffUsers(oldUser, newUser, nil), 0)
+	})
+
+	t.Run("Fields which are not set are ignored", func(t *testing.T) {
+		assert.Len(t, diffUsers(oldUser, kc.UserRepresentation{}, nil), 0)
+	})
+
+	t.Run("Field-level changes", func(t *testing.T) {
+		var newUser = kc.UserRepresentation{
+			Email:   &newEmail,
+			Enabled: &disabled,
+			Attributes: &map[string][]string{
+				"phoneNumber": {"+41789999"},
+				"birthDate":   {"1988-01-01"},
+				"office":      {"B1", "B2"},
+			},
+		}
+		var changes = diffUsers(oldUser, newUser, nil)
+
+		assert.Len(t, changes, 5)
+		assert.Equal(t, "email", changes[0].Field)
+		assert.Equal(t, oldEmail, *changes[0].OldValue)
+		assert.Equal(t, newEmail, *changes[0].NewValue)
+		assert.Equal(t, "enabled", changes[1].Field)
+		assert.Equal(t, "true", *changes[1].OldValue)
+		assert.Equal(t, "false", *changes[1].NewValue)
+		assert.Equal(t, "attributes.department", changes[2].Field)
+		assert.Equal(t, "IT", *changes[2].OldValue)
+		assert.Nil(t, changes[2].NewValue)
+		assert.Equal(t, "attributes.office", changes[3].Field)
+		assert.Nil(t, changes[3].OldValue)
+		assert.Equal(t, "B1,B2", *changes[3].NewValue)
+		assert.Equal(t, "phoneNumber", changes[4].Field)
+	})
+
+	t.Run("Masked fields", func(t *testing.T) {
+		var newUser = kc.UserRepresentation{
+			Email: &newEmail,
+			Attributes: &map[string][]string{
+				"phoneNumber": {"+41789999"},
+				"office":      {"B1"},
+			},
+		}
+		var changes = diffUsers(oldUser, newUser, []string{"email", "phoneNumber", "office"})
+
+		for _, change := range changes {
+			switch change.Field {
+			case "email", "phoneNumber":
+				assert.Equal(t, maskedValue, *change.OldValue)
+				assert.Equal(t, maskedValue, *change.NewValue)
+			case "attributes.office":
+				assert.Nil(t, change.OldValue)
+				assert.Equal(t, maskedValue, *change.NewValue)
+			default:
+				assert.NotEqual(t, maskedValue, *change.OldValue)
+			}
+		}
+	})
+}
+
+func TestConvertToUserChange(t *testing.T) {
+	t.Run("Valid event", func(t *testing.T) {
+		var event = events_api.AuditRepresentation{
+			AuditTime:      1571300000,
+			Origin:         "back-office",
+			AgentUsername:  "agent",
+			AgentRealmName: "master",
+			AdditionalInfo: `{"changes":[{"field":"email","oldValue":"old@elca.ch","newValue":"new@elca.ch"}]}`,
+		}
+		var change, err = convertToUserChange(event)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1571300000), *change.Time)
+		assert.Equal(t, "back-office", *change.Origin)
+		assert.Equal(t, "agent", *change.AgentUsername)
+		assert.Equal(t, "master", *change.AgentRealmName)
+		assert.Len(t, change.Changes, 1)
+	})
+
+	t.Run("Event without additional information", func(t *testing.T) {
+		var change, err = convertToUserChange(events_api.AuditRepresentation{AuditTime: 1571300000})
+
+		assert.Nil(t, err)
+		assert.Nil(t, change.Origin)
+		assert.NotNil(t, change.Changes)
+		assert.Len(t, change.Changes, 0)
+	})
+
+	t.Run("Invalid additional information", func(t *testing.T) {
+		var _, err = convertToUserChange(events_api.AuditRepresentation{AdditionalInfo: "{"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestDiffConfigurations(t *testing.T) {
+	var clientID = "client"
+	var oldPolicy = "length(8)"
+	var newPolicy = "length(12)"
+	var enabled = true
+	var disabled = false
+	var attributeName = "department"
+
+	var oldConfig = dto.RealmConfiguration{
+		DefaultClientID:       &clientID,
+		PasswordPolicy:        &oldPolicy,
+		BreachedPasswordCheck: &disabled,
+	}
+
+	t.Run("No change", func(t *testing.T) {
+		assert.Len(t, diffConfigurations(oldConfig, oldConfig), 0)
+	})
+
+	t.Run("Field-level changes", func(t *testing.T) {
+		var newConfig = dto.RealmConfiguration{
+			PasswordPolicy:        &newPolicy,
+			BreachedPasswordCheck: &enabled,
+			MFARequired:           &enabled,
+			UserAttributes:        &[]dto.AttributeDefinition{{Name: &attributeName}},
+		}
+		var changes = diffConfigurations(oldConfig, newConfig)
+		assert.Len(t, changes, 5)
+
+		assert.Equal(t, "breached_password_check", changes[0].Field)
+		assert.Equal(t, "false", *changes[0].OldValue)
+		assert.Equal(t, "true", *changes[0].NewValue)
+
+		assert.Equal(t, "default_client_id", changes[1].Field)
+		assert.Equal(t, clientID, *changes[1].OldValue)
+		assert.Nil(t, changes[1].NewValue)
+
+		assert.Equal(t, "mfa_required", changes[2].Field)
+		assert.Nil(t, changes[2].OldValue)
+		assert.Equal(t, "true", *changes[2].NewValue)
+
+		assert.Equal(t, "password_policy", changes[3].Field)
+		assert.Equal(t, oldPolicy, *changes[3].OldValue)
+		assert.Equal(t, newPolicy, *changes[3].NewValue)
+
+		assert.Equal(t, "user_attributes", changes[4].Field)
+		assert.Equal(t, "[]", *changes[4].OldValue)
+		assert.Contains(t, *changes[4].NewValue, attributeName)
+	})
+}
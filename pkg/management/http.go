@@ -2,6 +2,7 @@ package management
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 
 	commonhttp "github.com/cloudtrust/common-service/http"
@@ -32,23 +33,47 @@ func decodeManagementRequest(ctx context.Context, req *http.Request) (interface{
 		"clientID":     management_api.RegExpClientID,
 		"roleID":       management_api.RegExpID,
 		"credentialID": management_api.RegExpID,
+		"sessionID":    management_api.RegExpID,
+		"version":      management_api.RegExpNumber,
 	}
 
 	var queryParams = map[string]string{
-		"email":        management_api.RegExpEmail,
-		"firstName":    management_api.RegExpFirstName,
-		"lastName":     management_api.RegExpLastName,
-		"username":     management_api.RegExpUsername,
-		"search":       management_api.RegExpSearch,
-		"client_id":    management_api.RegExpClientID,
-		"redirect_uri": management_api.RegExpRedirectURI,
-		"lifespan":     management_api.RegExpLifespan,
-		"groupIds":     management_api.RegExpGroupIds,
-		"first":        management_api.RegExpNumber,
-		"max":          management_api.RegExpNumber,
+		"email":         management_api.RegExpEmail,
+		"firstName":     management_api.RegExpFirstName,
+		"lastName":      management_api.RegExpLastName,
+		"username":      management_api.RegExpUsername,
+		"search":        management_api.RegExpSearch,
+		"client_id":     management_api.RegExpClientID,
+		"redirect_uri":  management_api.RegExpRedirectURI,
+		"lifespan":      management_api.RegExpLifespan,
+		"groupIds":      management_api.RegExpGroupIds,
+		"first":         management_api.RegExpNumber,
+		"max":           management_api.RegExpNumber,
+		"type":          management_api.RegExpCredentialCategory,
+		"phoneNumber":   management_api.RegExpPhoneNumber,
+		"label":         management_api.RegExpLabel,
+		"birthDate":     management_api.RegExpBirthDate,
+		"enabled":       management_api.RegExpBool,
+		"locked":        management_api.RegExpBool,
+		"emailVerified": management_api.RegExpBool,
+		"sort":          management_api.RegExpUserSort,
+		"comment":       management_api.RegExpComment,
+		"from":          management_api.RegExpNumber,
+		"to":            management_api.RegExpNumber,
+		"force":         management_api.RegExpBool,
 	}
 
-	return commonhttp.DecodeRequest(ctx, req, pathParams, queryParams)
+	request, err := commonhttp.DecodeRequest(ctx, req, pathParams, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var m = request.(map[string]string)
+	if ifMatch := req.Header.Get("If-Match"); ifMatch != "" {
+		m["ifMatch"] = ifMatch
+	}
+
+	return m, nil
 }
 
 // encodeManagementReply encodes the reply.
@@ -58,11 +83,20 @@ func encodeManagementReply(ctx context.Context, w http.ResponseWriter, rep inter
 		w.Header().Set("Location", r.URL)
 		w.WriteHeader(http.StatusCreated)
 		return nil
+	case ETagResponse:
+		w.Header().Set("ETag", r.ETag)
+		return commonhttp.EncodeReply(ctx, w, r.Body)
 	default:
 		return commonhttp.EncodeReply(ctx, w, rep)
 	}
 }
 
+// duplicateUserErrorBody is the reply sent when the user to create looks like existing users
+type duplicateUserErrorBody struct {
+	Message      string   `json:"message"`
+	CandidateIDs []string `json:"candidates"`
+}
+
 // managementErrorHandler encodes the reply when there is an error.
 func managementErrorHandler(logger log.Logger) func(context.Context, error, http.ResponseWriter) {
 	defaultHandler := commonhttp.ErrorHandler(logger)
@@ -71,6 +105,14 @@ func managementErrorHandler(logger log.Logger) func(context.Context, error, http
 		case kc_client.HTTPError:
 			w.WriteHeader(e.HTTPStatus)
 			w.Write([]byte(internal.ComponentName + "." + internal.MsgErrUnknown))
+		case DuplicateUserError:
+			var body, _ = json.Marshal(duplicateUserErrorBody{
+				Message:      e.Error(),
+				CandidateIDs: e.CandidateIDs,
+			})
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusConflict)
+			w.Write(body)
 		default:
 			defaultHandler(ctx, err, w)
 
@@ -77,7 +77,7 @@ func TestHTTPManagementHandler(t *testing.T) {
 		}
 		userJSON, _ := json.Marshal(user)
 
-		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user).Return("https://elca.com/auth/admin/realms/master/users/12456", nil).Times(1)
+		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user, false).Return("https://elca.com/auth/admin/realms/master/users/12456", nil).Times(1)
 
 		var body = strings.NewReader(string(userJSON))
 		res, err := http.Post(ts.URL+"/realms/master/users", "application/json", body)
@@ -110,6 +110,68 @@ func TestHTTPManagementHandler(t *testing.T) {
 
 }
 
+func TestHTTPETagHandler(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockComponent = mock.NewManagementComponent(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var getUserHandler = MakeManagementHandler(keycloakb.ToGoKitEndpoint(MakeGetUserEndpoint(mockComponent)), mockLogger)
+	var updateUserHandler = MakeManagementHandler(keycloakb.ToGoKitEndpoint(MakeUpdateUserEndpoint(mockComponent)), mockLogger)
+
+	r := mux.NewRouter()
+	r.Path("/realms/{realm}/users/{userID}").Methods("GET").Handler(getUserHandler)
+	r.Path("/realms/{realm}/users/{userID}").Methods("PUT").Handler(updateUserHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"
+	var username = "toto"
+	var user = api.UserRepresentation{
+		ID:       &userID,
+		Username: &username,
+	}
+	var etag = api.ComputeETag(user)
+
+	// Get - ETag header returned
+	{
+		mockComponent.EXPECT().GetUser(gomock.Any(), "master", userID).Return(user, nil).Times(1)
+
+		res, err := http.Get(ts.URL + "/realms/master/users/" + userID)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, etag, res.Header.Get("ETag"))
+	}
+
+	// Put - If-Match header given to the component
+	{
+		userJSON, _ := json.Marshal(user)
+		mockComponent.EXPECT().UpdateUser(gomock.Any(), "master", userID, user, etag).Return(nil).Times(1)
+
+		req, _ := http.NewRequest("PUT", ts.URL+"/realms/master/users/"+userID, strings.NewReader(string(userJSON)))
+		req.Header.Set("If-Match", etag)
+		res, err := http.DefaultClient.Do(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	// Put - Conflict
+	{
+		userJSON, _ := json.Marshal(user)
+		mockComponent.EXPECT().UpdateUser(gomock.Any(), "master", userID, user, `"other"`).Return(commonhttp.Error{Status: http.StatusPreconditionFailed}).Times(1)
+
+		req, _ := http.NewRequest("PUT", ts.URL+"/realms/master/users/"+userID, strings.NewReader(string(userJSON)))
+		req.Header.Set("If-Match", `"other"`)
+		res, err := http.DefaultClient.Do(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+	}
+}
+
 func TestHTTPErrorHandler(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -138,7 +200,7 @@ func TestHTTPErrorHandler(t *testing.T) {
 
 	// Internal server error.
 	{
-		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user).Return("", fmt.Errorf("Unexpected Error")).Times(1)
+		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user, false).Return("", fmt.Errorf("Unexpected Error")).Times(1)
 
 		var body = strings.NewReader(string(userJSON))
 		res, err := http.Post(ts.URL+"/realms/master/users", "application/json", body)
@@ -150,7 +212,7 @@ func TestHTTPErrorHandler(t *testing.T) {
 
 	// Forbidden error.
 	{
-		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user).Return("", security.ForbiddenError{}).Times(1)
+		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user, false).Return("", security.ForbiddenError{}).Times(1)
 
 		var body = strings.NewReader(string(userJSON))
 		res, err := http.Post(ts.URL+"/realms/master/users", "application/json", body)
@@ -194,7 +256,7 @@ func TestHTTPErrorHandler(t *testing.T) {
 			HTTPStatus: 404,
 			Message:    "Not found",
 		}
-		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user).Return("", kcError).Times(1)
+		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user, false).Return("", kcError).Times(1)
 
 		var body = strings.NewReader(string(userJSON))
 		res, err := http.Post(ts.URL+"/realms/master/users", "application/json", body)
@@ -209,7 +271,7 @@ func TestHTTPErrorHandler(t *testing.T) {
 			Status:  401,
 			Message: "Unauthorized",
 		}
-		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user).Return("", kcError).Times(1)
+		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user, false).Return("", kcError).Times(1)
 
 		var body = strings.NewReader(string(userJSON))
 		res, err := http.Post(ts.URL+"/realms/master/users", "application/json", body)
@@ -218,6 +280,33 @@ func TestHTTPErrorHandler(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
 		assert.NotEqual(t, http.NoBody, res.Body)
 	}
+
+	// Duplicate users
+	{
+		var duplicateErr = DuplicateUserError{CandidateIDs: []string{"f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"}}
+		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user, false).Return("", duplicateErr).Times(1)
+
+		var body = strings.NewReader(string(userJSON))
+		res, err := http.Post(ts.URL+"/realms/master/users", "application/json", body)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(res.Body)
+		assert.Equal(t, `{"message":"keycloak-bridge.duplicateUser","candidates":["f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"]}`, buf.String())
+	}
+
+	// Forced creation
+	{
+		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user, true).Return("https://elca.com/auth/admin/realms/master/users/12456", nil).Times(1)
+
+		var body = strings.NewReader(string(userJSON))
+		res, err := http.Post(ts.URL+"/realms/master/users?force=true", "application/json", body)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+	}
 }
 
 func TestHTTPXForwardHeaderHandler(t *testing.T) {
@@ -249,7 +338,7 @@ func TestHTTPXForwardHeaderHandler(t *testing.T) {
 		}
 		userJSON, _ := json.Marshal(user)
 
-		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user).Return("https://elca.com/auth/admin/realms/master/users/12456", nil).Times(1)
+		mockComponent.EXPECT().CreateUser(gomock.Any(), "master", user, false).Return("https://elca.com/auth/admin/realms/master/users/12456", nil).Times(1)
 
 		var body = strings.NewReader(string(userJSON))
 
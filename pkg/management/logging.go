@@ -25,11 +25,11 @@ func MakeConfigurationDBModuleLoggingMW(log log.Logger) func(ConfigurationDBModu
 }
 
 // configDBModuleLoggingMW implements ConfigurationDBModule.
-func (m *configDBModuleLoggingMW) StoreOrUpdate(ctx context.Context, realmName string, config dto.RealmConfiguration) error {
+func (m *configDBModuleLoggingMW) StoreOrUpdate(ctx context.Context, realmName string, config dto.RealmConfiguration, author string, comment string) error {
 	defer func(begin time.Time) {
-		m.logger.Info("method", "StoreOrUpdate", "args", realmName, config, "took", time.Since(begin))
+		m.logger.Info("method", "StoreOrUpdate", "args", realmName, config, author, comment, "took", time.Since(begin))
 	}(time.Now())
-	return m.next.StoreOrUpdate(ctx, realmName, config)
+	return m.next.StoreOrUpdate(ctx, realmName, config, author, comment)
 }
 
 // configDBModuleLoggingMW implements ConfigurationDBModule.
@@ -39,3 +39,19 @@ func (m *configDBModuleLoggingMW) GetConfiguration(ctx context.Context, realmNam
 	}(time.Now())
 	return m.next.GetConfiguration(ctx, realmName)
 }
+
+// configDBModuleLoggingMW implements ConfigurationDBModule.
+func (m *configDBModuleLoggingMW) GetConfigurationHistory(ctx context.Context, realmName string) ([]dto.RealmConfigurationVersion, error) {
+	defer func(begin time.Time) {
+		m.logger.Info("method", "GetConfigurationHistory", "args", realmName, "took", time.Since(begin))
+	}(time.Now())
+	return m.next.GetConfigurationHistory(ctx, realmName)
+}
+
+// configDBModuleLoggingMW implements ConfigurationDBModule.
+func (m *configDBModuleLoggingMW) GetConfigurationVersion(ctx context.Context, realmName string, version int) (dto.RealmConfigurationVersion, error) {
+	defer func(begin time.Time) {
+		m.logger.Info("method", "GetConfigurationVersion", "args", realmName, version, "took", time.Since(begin))
+	}(time.Now())
+	return m.next.GetConfigurationVersion(ctx, realmName, version)
+}
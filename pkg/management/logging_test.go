@@ -32,7 +32,17 @@ func TestComponentLoggingMW(t *testing.T) {
 	m.GetConfiguration(ctx, "realmID")
 
 	// Update configuration.
-	mockComponent.EXPECT().StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{}).Return(nil).Times(1)
-	mockLogger.EXPECT().Info("method", "StoreOrUpdate", "args", "realmID", dto.RealmConfiguration{}, "took", gomock.Any()).Return(nil).Times(1)
-	m.StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{})
+	mockComponent.EXPECT().StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{}, "author", "comment").Return(nil).Times(1)
+	mockLogger.EXPECT().Info("method", "StoreOrUpdate", "args", "realmID", dto.RealmConfiguration{}, "author", "comment", "took", gomock.Any()).Return(nil).Times(1)
+	m.StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{}, "author", "comment")
+
+	// Get configuration history.
+	mockComponent.EXPECT().GetConfigurationHistory(ctx, "realmID").Return([]dto.RealmConfigurationVersion{}, nil).Times(1)
+	mockLogger.EXPECT().Info("method", "GetConfigurationHistory", "args", "realmID", "took", gomock.Any()).Return(nil).Times(1)
+	m.GetConfigurationHistory(ctx, "realmID")
+
+	// Get configuration version.
+	mockComponent.EXPECT().GetConfigurationVersion(ctx, "realmID", 3).Return(dto.RealmConfigurationVersion{}, nil).Times(1)
+	mockLogger.EXPECT().Info("method", "GetConfigurationVersion", "args", "realmID", 3, "took", gomock.Any()).Return(nil).Times(1)
+	m.GetConfigurationVersion(ctx, "realmID", 3)
 }
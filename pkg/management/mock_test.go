@@ -6,4 +6,6 @@ package management
 //go:generate mockgen -destination=./mock/kc-auth.go -package=mock -mock_names=KeycloakClient=KcClientAuth github.com/cloudtrust/common-service/security KeycloakClient
 //go:generate mockgen -destination=./mock/logging.go -package=mock -mock_names=Logger=Logger github.com/cloudtrust/common-service/log Logger
 //go:generate mockgen -destination=./mock/tracing.go -package=mock -mock_names=OpentracingClient=OpentracingClient,Finisher=Finisher github.com/cloudtrust/common-service/tracing OpentracingClient,Finisher
+//go:generate mockgen -destination=./mock/auditeventsreader.go -package=mock -mock_names=AuditEventsReaderModule=AuditEventsReaderModule github.com/cloudtrust/keycloak-bridge/pkg/management AuditEventsReaderModule
 //go:generate mockgen -destination=./mock/keycloak_client.go -package=mock -mock_names=KeycloakClient=KeycloakClient github.com/cloudtrust/keycloak-bridge/pkg/management KeycloakClient
+//go:generate mockgen -destination=./mock/breachedpasswords.go -package=mock -mock_names=BreachedPasswordChecker=BreachedPasswordChecker github.com/cloudtrust/keycloak-bridge/internal/keycloakb BreachedPasswordChecker
@@ -25,14 +25,14 @@ func MakeConfigurationDBModuleTracingMW(tracer tracing.OpentracingClient) func(C
 }
 
 // configDBModuleTracingMW implements StatisticModule.
-func (m *configDBModuleTracingMW) StoreOrUpdate(ctx context.Context, realmName string, config dto.RealmConfiguration) error {
+func (m *configDBModuleTracingMW) StoreOrUpdate(ctx context.Context, realmName string, config dto.RealmConfiguration, author string, comment string) error {
 	var f tracing.Finisher
 	ctx, f = m.tracer.TryStartSpanWithTag(ctx, "configurationDB_module", "correlation_id", ctx.Value(cs.CtContextCorrelationID).(string))
 	if f != nil {
 		defer f.Finish()
 	}
 
-	return m.next.StoreOrUpdate(ctx, realmName, config)
+	return m.next.StoreOrUpdate(ctx, realmName, config, author, comment)
 }
 
 // configDBModuleTracingMW implements StatisticModule.
@@ -45,3 +45,25 @@ func (m *configDBModuleTracingMW) GetConfiguration(ctx context.Context, realmNam
 
 	return m.next.GetConfiguration(ctx, realmName)
 }
+
+// configDBModuleTracingMW implements StatisticModule.
+func (m *configDBModuleTracingMW) GetConfigurationHistory(ctx context.Context, realmName string) ([]dto.RealmConfigurationVersion, error) {
+	var f tracing.Finisher
+	ctx, f = m.tracer.TryStartSpanWithTag(ctx, "configurationDB_module", "correlation_id", ctx.Value(cs.CtContextCorrelationID).(string))
+	if f != nil {
+		defer f.Finish()
+	}
+
+	return m.next.GetConfigurationHistory(ctx, realmName)
+}
+
+// configDBModuleTracingMW implements StatisticModule.
+func (m *configDBModuleTracingMW) GetConfigurationVersion(ctx context.Context, realmName string, version int) (dto.RealmConfigurationVersion, error) {
+	var f tracing.Finisher
+	ctx, f = m.tracer.TryStartSpanWithTag(ctx, "configurationDB_module", "correlation_id", ctx.Value(cs.CtContextCorrelationID).(string))
+	if f != nil {
+		defer f.Finish()
+	}
+
+	return m.next.GetConfigurationVersion(ctx, realmName, version)
+}
@@ -34,13 +34,24 @@ func TestConfigurationDBModuleMW(t *testing.T) {
 	m.GetConfiguration(ctx, "realmID")
 
 	// Store configuration / Spawn
-	mockConfigDBModule.EXPECT().StoreOrUpdate(gomock.Any(), "realmID", dto.RealmConfiguration{}).Return(nil).Times(1)
+	mockConfigDBModule.EXPECT().StoreOrUpdate(gomock.Any(), "realmID", dto.RealmConfiguration{}, "author", "comment").Return(nil).Times(1)
 	mockTracer.EXPECT().TryStartSpanWithTag(ctx, "configurationDB_module", "correlation_id", corrID).Return(ctx, mockFinisher).Times(1)
 	mockFinisher.EXPECT().Finish().Times(1)
-	m.StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{})
+	m.StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{}, "author", "comment")
 
 	// Store configuration / Spawn
-	mockConfigDBModule.EXPECT().StoreOrUpdate(gomock.Any(), "realmID", dto.RealmConfiguration{}).Return(nil).Times(1)
+	mockConfigDBModule.EXPECT().StoreOrUpdate(gomock.Any(), "realmID", dto.RealmConfiguration{}, "author", "comment").Return(nil).Times(1)
 	mockTracer.EXPECT().TryStartSpanWithTag(ctx, "configurationDB_module", "correlation_id", corrID).Return(ctx, nil).Times(1)
-	m.StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{})
+	m.StoreOrUpdate(ctx, "realmID", dto.RealmConfiguration{}, "author", "comment")
+
+	// GetConfigurationHistory / Spawn
+	mockConfigDBModule.EXPECT().GetConfigurationHistory(gomock.Any(), "realmID").Return([]dto.RealmConfigurationVersion{}, nil).Times(1)
+	mockTracer.EXPECT().TryStartSpanWithTag(ctx, "configurationDB_module", "correlation_id", corrID).Return(ctx, mockFinisher).Times(1)
+	mockFinisher.EXPECT().Finish().Times(1)
+	m.GetConfigurationHistory(ctx, "realmID")
+
+	// GetConfigurationVersion / Not spawn
+	mockConfigDBModule.EXPECT().GetConfigurationVersion(gomock.Any(), "realmID", 3).Return(dto.RealmConfigurationVersion{}, nil).Times(1)
+	mockTracer.EXPECT().TryStartSpanWithTag(ctx, "configurationDB_module", "correlation_id", corrID).Return(ctx, nil).Times(1)
+	m.GetConfigurationVersion(ctx, "realmID", 3)
 }
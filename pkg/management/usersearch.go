@@ -0,0 +1,204 @@
+package management
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	internal "github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+)
+
+const (
+	// searchBatchSize is the number of users requested to Keycloak at once when the users are filtered by the bridge
+	searchBatchSize = 500
+	// maxSearchedUsers is the maximum number of users the bridge scans for a single search
+	maxSearchedUsers = 10000
+	// maxLockChecks is the maximum number of users whose brute force detection state is requested for a single search
+	maxLockChecks = 100
+	// defaultSearchPageSize is the number of users returned by a search filtered by the bridge when max is not given
+	defaultSearchPageSize = 100
+	// maxSearchPageSize is the maximum number of users returned by a search filtered by the bridge
+	maxSearchPageSize = searchBatchSize
+)
+
+// userSearch contains the search criteria which are not supported by Keycloak and are thus applied by the bridge
+type userSearch struct {
+	phoneNumber   *string
+	label         *string
+	birthDate     *string
+	enabled       *bool
+	emailVerified *bool
+	locked        *bool
+	sortField     string
+	descending    bool
+	first         int
+	max           int
+	countries     []string
+}
+
+// newUserSearch splits the search parameters between the ones forwarded to Keycloak and the ones applied by the bridge.
+// It returns nil if Keycloak can handle the whole search.
+func newUserSearch(paramKV []string) (*userSearch, []string) {
+	var search userSearch
+	var kcParams []string
+	var first, max string
+	var advanced = false
+
+	for i := 0; i+1 < len(paramKV); i += 2 {
+		var key, value = paramKV[i], paramKV[i+1]
+		switch key {
+		case "phoneNumber":
+			search.phoneNumber = &value
+		case "label":
+			search.label = &value
+		case "birthDate":
+			search.birthDate = &value
+		case "enabled":
+			search.enabled = parseBool(value)
+		case "emailVerified":
+			search.emailVerified = parseBool(value)
+		case "locked":
+			search.locked = parseBool(value)
+		case "sort":
+			search.descending = strings.HasPrefix(value, "-")
+			search.sortField = strings.TrimPrefix(value, "-")
+		case "first":
+			first = value
+			continue
+		case "max":
+			max = value
+			continue
+		default:
+			kcParams = append(kcParams, key, value)
+			continue
+		}
+		advanced = true
+	}
+
+	if !advanced {
+		if first != "" {
+			kcParams = append(kcParams, "first", first)
+		}
+		if max != "" {
+			kcParams = append(kcParams, "max", max)
+		}
+		return nil, kcParams
+	}
+
+	search.first, _ = strconv.Atoi(first)
+	search.max, _ = strconv.Atoi(max)
+	if search.max <= 0 {
+		search.max = defaultSearchPageSize
+	}
+	return &search, kcParams
+}
+
+// narrowed checks if the search has a criteria selecting a few users among the ones returned by Keycloak
+func (s *userSearch) narrowed() bool {
+	return s.phoneNumber != nil || s.label != nil || s.birthDate != nil
+}
+
+// matches checks the criteria which can be evaluated on the user representation
+func (s *userSearch) matches(user api.UserRepresentation) bool {
+	return matchesString(user.PhoneNumber, s.phoneNumber, s.samePhoneNumber) &&
+		matchesString(user.Label, s.label, containsIgnoreCase) &&
+		matchesString(user.BirthDate, s.birthDate, func(a, b string) bool { return a == b }) &&
+		matchesBool(user.Enabled, s.enabled) &&
+		matchesBool(user.EmailVerified, s.emailVerified)
+}
+
+// sort sorts the users on the requested field. Users without value come last.
+func (s *userSearch) sort(users []api.UserRepresentation) {
+	if s.sortField == "" {
+		return
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		var a, b = sortKey(users[i], s.sortField), sortKey(users[j], s.sortField)
+		if a == nil || b == nil {
+			return a != nil
+		}
+		if s.descending {
+			return *b < *a
+		}
+		return *a < *b
+	})
+}
+
+// page returns the requested page of the filtered users
+func (s *userSearch) page(users []api.UserRepresentation) api.UsersPageRepresentation {
+	var count = len(users)
+	var start = s.first
+	if start > count {
+		start = count
+	}
+	var end = count
+	if start+s.max < count {
+		end = start + s.max
+	}
+	return api.UsersPageRepresentation{
+		Users: users[start:end],
+		Count: &count,
+	}
+}
+
+func sortKey(user api.UserRepresentation, field string) *string {
+	var value *string
+	switch field {
+	case "username":
+		value = user.Username
+	case "firstName":
+		value = user.FirstName
+	case "lastName":
+		value = user.LastName
+	case "email":
+		value = user.Email
+	case "birthDate":
+		value = user.BirthDate
+	case "createdTimestamp":
+		if user.CreatedTimestamp != nil {
+			// fixed width so that the timestamps can be compared as strings
+			var timestamp = strconv.FormatInt(*user.CreatedTimestamp, 10)
+			timestamp = strings.Repeat("0", 20-len(timestamp)) + timestamp
+			return &timestamp
+		}
+		return nil
+	}
+	if value == nil {
+		return nil
+	}
+	var lower = strings.ToLower(*value)
+	return &lower
+}
+
+func matchesString(value, expected *string, match func(string, string) bool) bool {
+	if expected == nil {
+		return true
+	}
+	return value != nil && match(*value, *expected)
+}
+
+func matchesBool(value, expected *bool) bool {
+	if expected == nil {
+		return true
+	}
+	var actual = value != nil && *value
+	return actual == *expected
+}
+
+// samePhoneNumber compares the phone numbers once normalized with the countries allowed by the realm
+func (s *userSearch) samePhoneNumber(value, expected string) bool {
+	return internal.SamePhoneNumber(value, expected, s.countries)
+}
+
+func containsIgnoreCase(value, substring string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(substring))
+}
+
+func parseBool(value string) *bool {
+	var res, err = strconv.ParseBool(value)
+	if err != nil {
+		return nil
+	}
+	return &res
+}
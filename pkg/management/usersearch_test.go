@@ -0,0 +1,109 @@
+package management
+
+import (
+	"testing"
+
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUserSearch(t *testing.T) {
+	t.Run("Keycloak handles the whole search", func(t *testing.T) {
+		var search, kcParams = newUserSearch([]string{"first", "10", "email", "john@elca.ch", "max", "5"})
+		assert.Nil(t, search)
+		assert.Equal(t, []string{"email", "john@elca.ch", "first", "10", "max", "5"}, kcParams)
+	})
+
+	t.Run("Attribute filters and sort are applied by the bridge", func(t *testing.T) {
+		var search, kcParams = newUserSearch([]string{"lastName", "Doe", "phoneNumber", "+41791234567", "enabled", "false",
+			"sort", "-createdTimestamp", "first", "10", "max", "5"})
+		assert.Equal(t, []string{"lastName", "Doe"}, kcParams)
+		assert.Equal(t, "+41791234567", *search.phoneNumber)
+		assert.False(t, *search.enabled)
+		assert.Nil(t, search.locked)
+		assert.Equal(t, "createdTimestamp", search.sortField)
+		assert.True(t, search.descending)
+		assert.Equal(t, 10, search.first)
+		assert.Equal(t, 5, search.max)
+		assert.True(t, search.narrowed())
+	})
+
+	t.Run("Default page size", func(t *testing.T) {
+		var search, _ = newUserSearch([]string{"enabled", "true", "locked", "false"})
+		assert.Equal(t, defaultSearchPageSize, search.max)
+		assert.False(t, search.narrowed())
+	})
+}
+
+func TestUserSearchMatches(t *testing.T) {
+	var phoneNumber = "+41791234567"
+	var label = "Head of IT"
+	var birthDate = "1988-01-01"
+	var enabled = true
+	var user = api.UserRepresentation{PhoneNumber: &phoneNumber, Label: &label, BirthDate: &birthDate, Enabled: &enabled}
+
+	var search = func(paramKV ...string) *userSearch {
+		var s, _ = newUserSearch(paramKV)
+		return s
+	}
+
+	assert.True(t, search("phoneNumber", phoneNumber).matches(user))
+	assert.True(t, search("phoneNumber", "0041 79 123 45 67").matches(user))
+	assert.False(t, search("phoneNumber", "+41790000000").matches(user))
+	assert.True(t, search("label", "head of").matches(user))
+	assert.False(t, search("label", "sales").matches(user))
+	assert.True(t, search("birthDate", birthDate).matches(user))
+	assert.True(t, search("enabled", "true", "emailVerified", "false").matches(user))
+	assert.False(t, search("emailVerified", "true").matches(user))
+	assert.False(t, search("birthDate", birthDate).matches(api.UserRepresentation{}))
+
+	t.Run("National phone number with the countries of the realm", func(t *testing.T) {
+		var s = search("phoneNumber", "079 123 45 67")
+		assert.False(t, s.matches(user))
+		s.countries = []string{"CH"}
+		assert.True(t, s.matches(user))
+	})
+}
+
+func TestUserSearchSortAndPage(t *testing.T) {
+	var names = []string{"bob", "Alice", "", "carol"}
+	var created = []int64{30, 200, 100, 1000}
+	var users []api.UserRepresentation
+	for i := range names {
+		var user = api.UserRepresentation{CreatedTimestamp: &created[i]}
+		if names[i] != "" {
+			user.LastName = &names[i]
+		}
+		users = append(users, user)
+	}
+
+	var lastNames = func(users []api.UserRepresentation) []string {
+		var res []string
+		for _, user := range users {
+			if user.LastName == nil {
+				res = append(res, "")
+			} else {
+				res = append(res, *user.LastName)
+			}
+		}
+		return res
+	}
+
+	var search, _ = newUserSearch([]string{"sort", "lastName"})
+	search.sort(users)
+	assert.Equal(t, []string{"Alice", "bob", "carol", ""}, lastNames(users))
+
+	search, _ = newUserSearch([]string{"sort", "-createdTimestamp"})
+	search.sort(users)
+	assert.Equal(t, []string{"carol", "Alice", "", "bob"}, lastNames(users))
+
+	search, _ = newUserSearch([]string{"sort", "-createdTimestamp", "first", "1", "max", "2"})
+	var page = search.page(users)
+	assert.Equal(t, 4, *page.Count)
+	assert.Equal(t, []string{"Alice", ""}, lastNames(page.Users))
+
+	search, _ = newUserSearch([]string{"enabled", "true", "first", "10"})
+	page = search.page(users)
+	assert.Equal(t, 4, *page.Count)
+	assert.Len(t, page.Users, 0)
+}
@@ -0,0 +1,11 @@
+-- History of the custom configurations of the realms. Each stored configuration is a new version of the configuration
+-- of its realm: the key prevents concurrent updates of a realm from sharing a version number.
+CREATE TABLE IF NOT EXISTS realm_configuration_history (
+  realm_id VARCHAR(255) NOT NULL,
+  version INT NOT NULL,
+  configuration TEXT NOT NULL,
+  author VARCHAR(255) NOT NULL,
+  comment TEXT,
+  created_at BIGINT NOT NULL,
+  PRIMARY KEY (realm_id, version)
+);
@@ -0,0 +1,3 @@
+-- Version of the current custom configuration of the realms, incremented each time the configuration is stored. The
+-- instances of the keycloak bridge poll it to detect the configurations changed by the other instances.
+ALTER TABLE realm_configuration ADD COLUMN version INT NOT NULL DEFAULT 1;
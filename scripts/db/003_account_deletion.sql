@@ -0,0 +1,19 @@
+-- Accounts whose deletion was requested by their users, kept disabled until the end of the grace period of their realm.
+-- Times are in milliseconds since epoch.
+CREATE TABLE IF NOT EXISTS account_deletion (
+  realm_id VARCHAR(255) NOT NULL,
+  user_id VARCHAR(255) NOT NULL,
+  username VARCHAR(255) NOT NULL,
+  requested_at BIGINT NOT NULL,
+  deletion_time BIGINT NOT NULL,
+  PRIMARY KEY (realm_id, user_id),
+  INDEX (deletion_time)
+);
+-- Locks of the schedulers: the instance of the keycloak bridge owning a lock renews it until expires_at, the other
+-- instances take it once it has expired.
+CREATE TABLE IF NOT EXISTS scheduler_lock (
+  name VARCHAR(255) NOT NULL,
+  owner VARCHAR(255) NOT NULL,
+  expires_at BIGINT NOT NULL,
+  PRIMARY KEY (name)
+);
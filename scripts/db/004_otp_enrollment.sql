@@ -0,0 +1,11 @@
+-- OTP secrets generated for the users and waiting for a first code generated with them. A secret is removed once it is
+-- registered in Keycloak, when it expires or after too many wrong codes. expires_at is in milliseconds since epoch.
+CREATE TABLE IF NOT EXISTS otp_enrollment (
+  realm_id VARCHAR(255) NOT NULL,
+  user_id VARCHAR(255) NOT NULL,
+  secret VARCHAR(255) NOT NULL,
+  expires_at BIGINT NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (realm_id, user_id),
+  INDEX (expires_at)
+);
@@ -0,0 +1,14 @@
+-- Changes of email and phone number waiting for the confirmation of the users. code_hash is the salted hash of the
+-- verification code sent to the new value. A change is removed once it is confirmed, when it expires or after too many
+-- wrong codes. expires_at is in milliseconds since epoch.
+CREATE TABLE IF NOT EXISTS pending_change (
+  realm_id VARCHAR(255) NOT NULL,
+  user_id VARCHAR(255) NOT NULL,
+  field VARCHAR(50) NOT NULL,
+  value VARCHAR(255) NOT NULL,
+  code_hash VARCHAR(255) NOT NULL,
+  expires_at BIGINT NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (realm_id, user_id, field),
+  INDEX (expires_at)
+);